@@ -1,6 +1,9 @@
 package config
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 )
@@ -23,7 +26,55 @@ type AppConfig struct {
 	LogFormat string
 
 	// Scheduler
-	SchedulerEnabled bool
+	SchedulerEnabled             bool
+	SchedulerPollIntervalSeconds int
+	SchedulerBatchSize           int
+	SchedulerLeaseTTLSeconds     int
+	// Runs overdue by more than this are treated as misfires and follow the schedule's misfire policy
+	SchedulerMisfireThresholdSeconds int
+
+	// Outbox relay
+	OutboxPollIntervalSeconds int
+	OutboxBatchSize           int
+	OutboxRetryBaseSeconds    int
+	OutboxRetryMaxSeconds     int
+	// Claimed messages are left to other relays once this passes without being marked sent or failed
+	OutboxClaimLeaseSeconds int
+
+	// Report worker
+	WorkerOutputDir string
+	// A running execution is stale once its config timeout plus this grace period has passed since started_at
+	WorkerStaleGraceSeconds       int
+	WorkerRecoveryIntervalSeconds int
+	// Stale executions are queued again this many times before they are failed
+	WorkerMaxRequeues int
+
+	// Datasource connection tests
+	DatasourceTestTimeoutSeconds int
+
+	// Datasource schema introspection
+	SchemaCacheTTLSeconds     int
+	SchemaQueryTimeoutSeconds int
+
+	// Query sandbox hard caps and extra column name patterns to mask
+	SandboxMaxRows        int
+	SandboxTimeoutSeconds int
+	SandboxMaskedColumns  []string
+
+	// Read-only SQL guard exceptions: keywords/functions allowed everywhere, and configs exempt from the guard
+	SQLGuardAllowlist       map[string]bool
+	SQLGuardExemptConfigIDs map[int]bool
+
+	// Configs whose EXPLAIN-based load score is above this need cost_override to be saved
+	QueryCostMaxLoadScore float64
+
+	// Credential encryption: "id:base64key,..." master keys (32 bytes each) and the one new secrets use
+	EncryptionKeys        string
+	EncryptionActiveKeyID string
+
+	// Vault server for vault://path#key secret references
+	VaultAddr  string
+	VaultToken string
 }
 
 var Config AppConfig
@@ -52,6 +103,131 @@ func LoadConfig() {
 		LogLevel:         viper.GetString("LOG_LEVEL"),
 		LogFormat:        viper.GetString("LOG_FORMAT"),
 		SchedulerEnabled: viper.GetBool("SCHEDULER_ENABLED"),
+
+		SchedulerPollIntervalSeconds: viper.GetInt("SCHEDULER_POLL_INTERVAL_SECONDS"),
+		SchedulerBatchSize:           viper.GetInt("SCHEDULER_BATCH_SIZE"),
+		SchedulerLeaseTTLSeconds:     viper.GetInt("SCHEDULER_LEASE_TTL_SECONDS"),
+
+		SchedulerMisfireThresholdSeconds: viper.GetInt("SCHEDULER_MISFIRE_THRESHOLD_SECONDS"),
+
+		OutboxPollIntervalSeconds: viper.GetInt("OUTBOX_POLL_INTERVAL_SECONDS"),
+		OutboxBatchSize:           viper.GetInt("OUTBOX_BATCH_SIZE"),
+		OutboxRetryBaseSeconds:    viper.GetInt("OUTBOX_RETRY_BASE_SECONDS"),
+		OutboxRetryMaxSeconds:     viper.GetInt("OUTBOX_RETRY_MAX_SECONDS"),
+		OutboxClaimLeaseSeconds:   viper.GetInt("OUTBOX_CLAIM_LEASE_SECONDS"),
+
+		WorkerOutputDir:               viper.GetString("WORKER_OUTPUT_DIR"),
+		WorkerStaleGraceSeconds:       viper.GetInt("WORKER_STALE_GRACE_SECONDS"),
+		WorkerRecoveryIntervalSeconds: viper.GetInt("WORKER_RECOVERY_INTERVAL_SECONDS"),
+		WorkerMaxRequeues:             viper.GetInt("WORKER_MAX_REQUEUES"),
+
+		DatasourceTestTimeoutSeconds: viper.GetInt("DATASOURCE_TEST_TIMEOUT_SECONDS"),
+
+		SchemaCacheTTLSeconds:     viper.GetInt("SCHEMA_CACHE_TTL_SECONDS"),
+		SchemaQueryTimeoutSeconds: viper.GetInt("SCHEMA_QUERY_TIMEOUT_SECONDS"),
+
+		SandboxMaxRows:        viper.GetInt("SANDBOX_MAX_ROWS"),
+		SandboxTimeoutSeconds: viper.GetInt("SANDBOX_TIMEOUT_SECONDS"),
+
+		QueryCostMaxLoadScore: viper.GetFloat64("QUERY_COST_MAX_LOAD_SCORE"),
+
+		EncryptionKeys:        viper.GetString("ENCRYPTION_KEYS"),
+		EncryptionActiveKeyID: viper.GetString("ENCRYPTION_ACTIVE_KEY_ID"),
+
+		VaultAddr:  viper.GetString("VAULT_ADDR"),
+		VaultToken: viper.GetString("VAULT_TOKEN"),
+	}
+
+	// Scheduler defaults
+	if Config.SchedulerPollIntervalSeconds <= 0 {
+		Config.SchedulerPollIntervalSeconds = 30
+	}
+	if Config.SchedulerBatchSize <= 0 {
+		Config.SchedulerBatchSize = 100
+	}
+	if Config.SchedulerLeaseTTLSeconds <= 0 {
+		// Survive a couple of missed renewals before another replica takes over
+		Config.SchedulerLeaseTTLSeconds = 3 * Config.SchedulerPollIntervalSeconds
+	}
+	if Config.SchedulerMisfireThresholdSeconds <= 0 {
+		// A healthy leader picks a run up within one poll; allow one extra poll of slack
+		Config.SchedulerMisfireThresholdSeconds = 2 * Config.SchedulerPollIntervalSeconds
+	}
+
+	// Outbox relay defaults
+	if Config.OutboxPollIntervalSeconds <= 0 {
+		Config.OutboxPollIntervalSeconds = 5
+	}
+	if Config.OutboxBatchSize <= 0 {
+		Config.OutboxBatchSize = 100
+	}
+	if Config.OutboxRetryBaseSeconds <= 0 {
+		Config.OutboxRetryBaseSeconds = 5
+	}
+	if Config.OutboxRetryMaxSeconds <= 0 {
+		Config.OutboxRetryMaxSeconds = 300
+	}
+	if Config.OutboxClaimLeaseSeconds <= 0 {
+		Config.OutboxClaimLeaseSeconds = 120
+	}
+
+	// Report worker defaults
+	if Config.WorkerOutputDir == "" {
+		Config.WorkerOutputDir = "./reports"
+	}
+	if Config.WorkerStaleGraceSeconds <= 0 {
+		Config.WorkerStaleGraceSeconds = 300
+	}
+	if Config.WorkerRecoveryIntervalSeconds <= 0 {
+		Config.WorkerRecoveryIntervalSeconds = 60
+	}
+	if !viper.IsSet("WORKER_MAX_REQUEUES") {
+		Config.WorkerMaxRequeues = 1
+	}
+
+	// Datasource connection test defaults
+	if Config.DatasourceTestTimeoutSeconds <= 0 {
+		Config.DatasourceTestTimeoutSeconds = 10
+	}
+
+	// Schema introspection defaults
+	if Config.SchemaCacheTTLSeconds <= 0 {
+		Config.SchemaCacheTTLSeconds = 300
+	}
+	if Config.SchemaQueryTimeoutSeconds <= 0 {
+		Config.SchemaQueryTimeoutSeconds = 30
+	}
+
+	// Query sandbox defaults
+	if Config.SandboxMaxRows <= 0 {
+		Config.SandboxMaxRows = 100
+	}
+	if Config.SandboxTimeoutSeconds <= 0 {
+		Config.SandboxTimeoutSeconds = 30
+	}
+	for _, column := range strings.Split(viper.GetString("SANDBOX_MASKED_COLUMNS"), ",") {
+		if column = strings.ToLower(strings.TrimSpace(column)); column != "" {
+			Config.SandboxMaskedColumns = append(Config.SandboxMaskedColumns, column)
+		}
+	}
+
+	// Heavy-query gate default: about 100 million rows read per day
+	if Config.QueryCostMaxLoadScore <= 0 {
+		Config.QueryCostMaxLoadScore = 100000
+	}
+
+	// SQL guard exceptions
+	Config.SQLGuardAllowlist = map[string]bool{}
+	for _, keyword := range strings.Split(viper.GetString("SQL_GUARD_ALLOWLIST"), ",") {
+		if keyword = strings.ToUpper(strings.TrimSpace(keyword)); keyword != "" {
+			Config.SQLGuardAllowlist[keyword] = true
+		}
+	}
+	Config.SQLGuardExemptConfigIDs = map[int]bool{}
+	for _, id := range strings.Split(viper.GetString("SQL_GUARD_EXEMPT_CONFIG_IDS"), ",") {
+		if configID, err := strconv.Atoi(strings.TrimSpace(id)); err == nil {
+			Config.SQLGuardExemptConfigIDs[configID] = true
+		}
 	}
 
 	log.Info().Msg("Configuration loaded successfully")
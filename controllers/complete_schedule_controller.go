@@ -1,10 +1,12 @@
 package controllers
 
 import (
+	"errors"
 	"scheduling-report/models"
 	"scheduling-report/services"
 	"scheduling-report/utils"
 	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -37,12 +39,80 @@ func (ctrl *CompleteScheduleController) CreateComplete(c *fiber.Ctx) error {
 	// Create complete schedule
 	response, err := ctrl.service.CreateComplete(req)
 	if err != nil {
+		var refErr *utils.SecretReferenceError
+		if errors.As(err, &refErr) {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004002, "Validation failed: "+err.Error())
+		}
+		var guardErr *utils.SQLGuardError
+		if errors.As(err, &guardErr) {
+			return utils.ErrorResponseWithData(c, fiber.StatusBadRequest, 40004002, "Validation failed: "+err.Error(), guardErr.Violations)
+		}
+		var heavyErr *services.HeavyQueryError
+		if errors.As(err, &heavyErr) {
+			return utils.ErrorResponseWithData(c, fiber.StatusBadRequest, 40004002, "Validation failed: "+err.Error(), heavyErr.Estimate)
+		}
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40004099, "Failed to create schedule: "+err.Error())
 	}
 
+	c.Set(fiber.HeaderETag, response.ETag)
 	return utils.SuccessResponse(c, response, "Complete schedule created successfully")
 }
 
+// CloneComplete copies a complete schedule with its config, deliveries and recipients
+// POST /api/schedules/complete/:id/clone
+func (ctrl *CompleteScheduleController) CloneComplete(c *fiber.Ctx) error {
+	scheduleID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004003, "Invalid schedule ID")
+	}
+
+	var req models.CompleteScheduleCloneRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004004, "Invalid request body: "+err.Error())
+		}
+	}
+
+	// Set created_by from header if not provided
+	if req.CreatedBy == "" {
+		req.CreatedBy = c.Get("X-User-ID", "system")
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004007, "Validation failed: "+err.Error())
+	}
+
+	response, err := ctrl.service.CloneComplete(scheduleID, req)
+	if err != nil {
+		if err.Error() == "schedule not found" {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 40404004, "Schedule not found")
+		}
+		if strings.HasPrefix(err.Error(), "invalid cron expression") || strings.HasPrefix(err.Error(), "report config with name") {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004007, "Validation failed: "+err.Error())
+		}
+		var refErr *utils.SecretReferenceError
+		if errors.As(err, &refErr) {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004007, "Validation failed: "+err.Error())
+		}
+		var guardErr *utils.SQLGuardError
+		if errors.As(err, &guardErr) {
+			return utils.ErrorResponseWithData(c, fiber.StatusBadRequest, 40004007, "Validation failed: "+err.Error(), guardErr.Violations)
+		}
+		var heavyErr *services.HeavyQueryError
+		if errors.As(err, &heavyErr) {
+			return utils.ErrorResponseWithData(c, fiber.StatusBadRequest, 40004007, "Validation failed: "+err.Error(), heavyErr.Estimate)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40004099, "Failed to clone schedule: "+err.Error())
+	}
+
+	c.Set(fiber.HeaderETag, response.ETag)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"responseCode":    utils.BuildCode(fiber.StatusCreated, 0),
+		"responseMessage": "Complete schedule cloned successfully",
+		"data":            response,
+	})
+}
+
 // UpdateComplete updates a complete schedule with partial update support
 // PUT /api/schedules/complete/:id
 func (ctrl *CompleteScheduleController) UpdateComplete(c *fiber.Ctx) error {
@@ -63,6 +133,7 @@ func (ctrl *CompleteScheduleController) UpdateComplete(c *fiber.Ctx) error {
 	if req.UpdatedBy == "" {
 		req.UpdatedBy = c.Get("X-User-ID", "system")
 	}
+	req.IfMatch = c.Get(fiber.HeaderIfMatch)
 
 	// Validate request
 	if err := utils.ValidateStruct(req); err != nil {
@@ -75,8 +146,45 @@ func (ctrl *CompleteScheduleController) UpdateComplete(c *fiber.Ctx) error {
 		if err.Error() == "schedule not found" {
 			return utils.ErrorResponse(c, fiber.StatusNotFound, 40404004, "Schedule not found")
 		}
+		var staleErr *services.PreconditionFailedError
+		if errors.As(err, &staleErr) {
+			return preconditionFailedResponse(c, 41204006, staleErr)
+		}
+		var refErr *utils.SecretReferenceError
+		if errors.As(err, &refErr) {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004005, "Validation failed: "+err.Error())
+		}
+		var guardErr *utils.SQLGuardError
+		if errors.As(err, &guardErr) {
+			return utils.ErrorResponseWithData(c, fiber.StatusBadRequest, 40004005, "Validation failed: "+err.Error(), guardErr.Violations)
+		}
+		var heavyErr *services.HeavyQueryError
+		if errors.As(err, &heavyErr) {
+			return utils.ErrorResponseWithData(c, fiber.StatusBadRequest, 40004005, "Validation failed: "+err.Error(), heavyErr.Estimate)
+		}
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40004099, "Failed to update schedule: "+err.Error())
 	}
 
+	c.Set(fiber.HeaderETag, response.ETag)
 	return utils.SuccessResponse(c, response, "Complete schedule updated successfully")
 }
+
+// GetComplete retrieves a complete schedule with its ETag for a later If-Match
+// GET /api/schedules/complete/:id
+func (ctrl *CompleteScheduleController) GetComplete(c *fiber.Ctx) error {
+	scheduleID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004003, "Invalid schedule ID")
+	}
+
+	response, err := ctrl.service.GetComplete(scheduleID)
+	if err != nil {
+		if err.Error() == "schedule not found" {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 40404004, "Schedule not found")
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40004099, "Failed to get schedule: "+err.Error())
+	}
+
+	c.Set(fiber.HeaderETag, response.ETag)
+	return utils.SuccessResponse(c, response, "Complete schedule retrieved successfully")
+}
@@ -0,0 +1,13 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"scheduling-report/services"
+	"scheduling-report/utils"
+)
+
+// preconditionFailedResponse answers a stale If-Match with 412, the current ETag and the current state
+func preconditionFailedResponse(c *fiber.Ctx, errorCode int, err *services.PreconditionFailedError) error {
+	c.Set(fiber.HeaderETag, err.ETag)
+	return utils.ErrorResponseWithData(c, fiber.StatusPreconditionFailed, errorCode, err.Error(), err.Current)
+}
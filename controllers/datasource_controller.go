@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/go-playground/validator/v10"
@@ -10,32 +11,23 @@ import (
 )
 
 type DatasourceController struct {
-	service  *services.DatasourceService
-	validate *validator.Validate
+	service       *services.DatasourceService
+	schemaService *services.DatasourceSchemaService
+	validate      *validator.Validate
 }
 
 func NewDatasourceController() *DatasourceController {
 	return &DatasourceController{
-		service:  services.NewDatasourceService(),
-		validate: validator.New(),
+		service:       services.NewDatasourceService(),
+		schemaService: services.NewDatasourceSchemaService(),
+		validate:      validator.New(),
 	}
 }
 
 // GetDatasources handles GET /api/datasources
 func (ctrl *DatasourceController) GetDatasources(c *fiber.Ctx) error {
-	var isActive *bool
-
-	if c.Query("is_active") != "" {
-		val := c.Query("is_active") == "true"
-		isActive = &val
-	}
-
-	datasources, err := ctrl.service.GetAll(isActive)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 1, err.Error())
-	}
-
-	return utils.SuccessResponse(c, datasources, "Datasources retrieved successfully")
+	datasources, meta, err := ctrl.service.List(c.Queries())
+	return sendList(c, datasources, meta, err, 1, 1, "Datasources retrieved successfully")
 }
 
 // GetDatasourceByID handles GET /api/datasources/:id
@@ -69,6 +61,10 @@ func (ctrl *DatasourceController) CreateDatasource(c *fiber.Ctx) error {
 
 	datasource, err := ctrl.service.Create(input)
 	if err != nil {
+		var testErr *services.ConnectionTestError
+		if errors.As(err, &testErr) {
+			return utils.ErrorResponseWithData(c, fiber.StatusBadRequest, 4, err.Error(), testErr.Result)
+		}
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, 3, err.Error())
 	}
 
@@ -100,12 +96,77 @@ func (ctrl *DatasourceController) UpdateDatasource(c *fiber.Ctx) error {
 
 	datasource, err := ctrl.service.Update(id, input)
 	if err != nil {
+		var testErr *services.ConnectionTestError
+		if errors.As(err, &testErr) {
+			return utils.ErrorResponseWithData(c, fiber.StatusBadRequest, 4, err.Error(), testErr.Result)
+		}
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, 3, err.Error())
 	}
 
 	return utils.SuccessResponse(c, datasource, "Datasource updated successfully")
 }
 
+// TestDatasource handles POST /api/datasources/:id/test
+// A failed probe is still a successful request; the result carries the error category.
+func (ctrl *DatasourceController) TestDatasource(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid datasource ID")
+	}
+
+	result, err := ctrl.service.TestConnection(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Datasource not found")
+	}
+
+	message := "Datasource connection succeeded"
+	if !result.Success {
+		message = "Datasource connection failed"
+	}
+	return utils.SuccessResponse(c, result, message)
+}
+
+// GetDatasourceSchema handles GET /api/datasources/:id/schema
+// Served from cache; ?refresh=true reads the database's information schema again.
+func (ctrl *DatasourceController) GetDatasourceSchema(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid datasource ID")
+	}
+
+	schema, err := ctrl.schemaService.GetSchema(id, c.Query("refresh") == "true")
+	if err != nil {
+		if err.Error() == "datasource not found" {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Datasource not found")
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 3, err.Error())
+	}
+
+	return utils.SuccessResponse(c, schema, "Datasource schema retrieved successfully")
+}
+
+// GetTableStatistics handles GET /api/datasources/:id/schema/:table
+// table may be qualified as schema.table; ?sample_size caps the rows sampled.
+func (ctrl *DatasourceController) GetTableStatistics(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid datasource ID")
+	}
+
+	stats, err := ctrl.schemaService.GetTableStatistics(id, c.Params("table"), c.QueryInt("sample_size", services.DefaultStatisticsSampleSize))
+	if err != nil {
+		switch err.Error() {
+		case "datasource not found":
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Datasource not found")
+		case "table not found":
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Table not found")
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 3, err.Error())
+	}
+
+	return utils.SuccessResponse(c, stats, "Table statistics retrieved successfully")
+}
+
 // DeleteDatasource handles DELETE /api/datasources/:id
 func (ctrl *DatasourceController) DeleteDatasource(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
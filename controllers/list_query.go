@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"errors"
+	"scheduling-report/repositories"
+	"scheduling-report/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sendList answers a list endpoint with one page and its next_cursor/total meta.
+// An invalid list query (see the grammar in repositories/list_query.go) is a 400 with
+// badRequestCode, any other error a 500 with failureCode.
+func sendList(c *fiber.Ctx, data interface{}, meta *repository.PageMeta, err error, badRequestCode int, failureCode int, message string) error {
+	if err != nil {
+		var queryErr *repository.ListQueryError
+		if errors.As(err, &queryErr) {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, badRequestCode, err.Error())
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, failureCode, err.Error())
+	}
+
+	return utils.SuccessListResponse(c, data, meta, message)
+}
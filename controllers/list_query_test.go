@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"scheduling-report/repositories"
+	"scheduling-report/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestSendListStatus(t *testing.T) {
+	app := fiber.New()
+	app.Get("/executions", func(c *fiber.Ctx) error {
+		_, err := repository.ParseListQuery(c.Queries(), repository.ExecutionListSpec)
+		return sendList(c, []string{}, &repository.PageMeta{Limit: repository.DefaultListLimit}, err, 40003101, 40003199, "Executions retrieved successfully")
+	})
+	app.Get("/failing", func(c *fiber.Ctx) error {
+		return sendList(c, nil, nil, errors.New("database is down"), 40003101, 40003199, "")
+	})
+
+	tests := []struct {
+		name        string
+		target      string
+		wantStatus  int
+		wantMessage string
+	}{
+		{name: "valid query", target: "/executions?status[in]=queued,failed&sort=started_at", wantStatus: fiber.StatusOK, wantMessage: "Executions retrieved successfully"},
+		{name: "malformed filter", target: "/executions?config_id=seven", wantStatus: fiber.StatusBadRequest,
+			wantMessage: `invalid value for 'config_id': strconv.ParseInt: parsing "seven": invalid syntax`},
+		{name: "unknown field", target: "/executions?duration[gt]=5", wantStatus: fiber.StatusBadRequest, wantMessage: "cannot filter by 'duration'"},
+		{name: "non-sortable field", target: "/executions?sort=-status", wantStatus: fiber.StatusBadRequest, wantMessage: "cannot sort by 'status'"},
+		{name: "bad enum value", target: "/executions?status=done", wantStatus: fiber.StatusBadRequest,
+			wantMessage: "invalid status 'done', must be one of: queued, running, completed, failed, cancelled"},
+		{name: "tampered cursor", target: "/executions?cursor=eyJzIjoi", wantStatus: fiber.StatusBadRequest,
+			wantMessage: "invalid cursor, it must come from a page with the same sort"},
+		{name: "other failures", target: "/failing", wantStatus: fiber.StatusInternalServerError, wantMessage: "database is down"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", tt.target, nil))
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			var body utils.StandardResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus || body.Message != tt.wantMessage {
+				t.Errorf("response = %d %q, want %d %q", resp.StatusCode, body.Message, tt.wantStatus, tt.wantMessage)
+			}
+		})
+	}
+}
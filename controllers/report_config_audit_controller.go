@@ -19,33 +19,10 @@ func NewReportConfigAuditController() *ReportConfigAuditController {
 }
 
 // GetAudits handles GET /api/audits
-// Query params: ?config_id=1&action=update&performed_by=admin
+// Query params: list query grammar, e.g. ?config_id=1&action=update&sort=-performed_at&limit=20
 func (ctrl *ReportConfigAuditController) GetAudits(c *fiber.Ctx) error {
-	var configID *int
-	var action *string
-	var performedBy *string
-
-	// Parse query parameters
-	if configIDStr := c.Query("config_id"); configIDStr != "" {
-		if id, err := strconv.Atoi(configIDStr); err == nil {
-			configID = &id
-		}
-	}
-
-	if actionStr := c.Query("action"); actionStr != "" {
-		action = &actionStr
-	}
-
-	if performedByStr := c.Query("performed_by"); performedByStr != "" {
-		performedBy = &performedByStr
-	}
-
-	audits, err := ctrl.service.GetAll(configID, action, performedBy)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, err.Error())
-	}
-
-	return utils.SuccessResponse(c, audits, "Audit records retrieved successfully")
+	audits, meta, err := ctrl.service.List(c.Queries())
+	return sendList(c, audits, meta, err, 1, 1, "Audit records retrieved successfully")
 }
 
 // GetAuditByID handles GET /api/audits/:id
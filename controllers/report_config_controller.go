@@ -1,48 +1,46 @@
 package controllers
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"scheduling-report/models"
 	"scheduling-report/services"
 	"scheduling-report/utils"
 )
 
 type ReportConfigController struct {
-	service  *services.ReportConfigService
-	validate *validator.Validate
+	service        *services.ReportConfigService
+	sandboxService *services.QuerySandboxService
+	costService    *services.QueryCostService
+	versionService *services.ReportConfigVersionService
+	syncService    *services.ScheduleSyncService
+	validate       *validator.Validate
 }
 
 func NewReportConfigController() *ReportConfigController {
 	return &ReportConfigController{
-		service:  services.NewReportConfigService(),
-		validate: validator.New(),
+		service:        services.NewReportConfigService(),
+		sandboxService: services.NewQuerySandboxService(),
+		costService:    services.NewQueryCostService(),
+		versionService: services.NewReportConfigVersionService(),
+		syncService:    services.NewScheduleSyncService(),
+		validate:       validator.New(),
 	}
 }
 
 // GetReportConfigs handles GET /api/report-configs
+// Besides the list query grammar: ?tags=a,b (all of them), ?folder=path (with subfolders), ?owner=name
 func (ctrl *ReportConfigController) GetReportConfigs(c *fiber.Ctx) error {
-	var isActive *bool
-	var datasourceID *int
-
-	if c.Query("is_active") != "" {
-		val := c.Query("is_active") == "true"
-		isActive = &val
-	}
-
-	if c.Query("datasource_id") != "" {
-		if id, err := strconv.Atoi(c.Query("datasource_id")); err == nil {
-			datasourceID = &id
-		}
-	}
-
-	configs, err := ctrl.service.GetAll(isActive, datasourceID)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 1, err.Error())
+	var catalog models.ConfigCatalogFilters
+	if err := c.QueryParser(&catalog); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid query parameters")
 	}
 
-	return utils.SuccessResponse(c, configs, "Report configs retrieved successfully")
+	configs, meta, err := ctrl.service.List(c.Queries(), catalog)
+	return sendList(c, configs, meta, err, 1, 1, "Report configs retrieved successfully")
 }
 
 // GetReportConfigByID handles GET /api/report-configs/:id
@@ -57,6 +55,7 @@ func (ctrl *ReportConfigController) GetReportConfigByID(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Report config not found")
 	}
 
+	c.Set(fiber.HeaderETag, utils.VersionETag(config.Version))
 	return utils.SuccessResponse(c, config, "Report config retrieved successfully")
 }
 
@@ -84,6 +83,14 @@ func (ctrl *ReportConfigController) CreateReportConfig(c *fiber.Ctx) error {
 
 	config, err := ctrl.service.Create(input)
 	if err != nil {
+		var guardErr *utils.SQLGuardError
+		if errors.As(err, &guardErr) {
+			return utils.ErrorResponseWithData(c, fiber.StatusBadRequest, 2, err.Error(), guardErr.Violations)
+		}
+		var heavyErr *services.HeavyQueryError
+		if errors.As(err, &heavyErr) {
+			return utils.ErrorResponseWithData(c, fiber.StatusBadRequest, 5, err.Error(), heavyErr.Estimate)
+		}
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, 3, err.Error())
 	}
 
@@ -94,6 +101,58 @@ func (ctrl *ReportConfigController) CreateReportConfig(c *fiber.Ctx) error {
 	})
 }
 
+// RunSandbox handles POST /api/report-configs/sandbox
+// Runs a saved or draft config's query read-only, capped in rows and time, and returns the rows.
+func (ctrl *ReportConfigController) RunSandbox(c *fiber.Ctx) error {
+	var input services.SandboxInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid request body")
+	}
+
+	if err := ctrl.validate.Struct(input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 2, err.Error())
+	}
+
+	result, err := ctrl.sandboxService.Run(input)
+	if err != nil {
+		switch err.Error() {
+		case "report config not found":
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Report config not found")
+		case "datasource not found":
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Datasource not found")
+		}
+		var guardErr *utils.SQLGuardError
+		if errors.As(err, &guardErr) {
+			return utils.ErrorResponseWithData(c, fiber.StatusBadRequest, 2, err.Error(), guardErr.Violations)
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 3, err.Error())
+	}
+
+	return utils.SuccessResponse(c, result, "Sandbox query executed successfully")
+}
+
+// EstimateCost handles POST /api/report-configs/estimate-cost
+// Runs EXPLAIN on the rendered query and scores it with the schedule frequency; the query is not executed.
+func (ctrl *ReportConfigController) EstimateCost(c *fiber.Ctx) error {
+	var input services.CostEstimateInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid request body")
+	}
+
+	estimate, err := ctrl.costService.Estimate(input)
+	if err != nil {
+		switch err.Error() {
+		case "report config not found":
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Report config not found")
+		case "datasource not found":
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Datasource not found")
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 3, err.Error())
+	}
+
+	return utils.SuccessResponse(c, estimate, "Query cost estimated successfully")
+}
+
 // UpdateReportConfig handles PUT /api/report-configs/:id
 func (ctrl *ReportConfigController) UpdateReportConfig(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
@@ -108,6 +167,7 @@ func (ctrl *ReportConfigController) UpdateReportConfig(c *fiber.Ctx) error {
 
 	// Set user context for audit
 	input.UpdatedBy = c.Get("X-User-ID", "system")
+	input.IfMatch = c.Get(fiber.HeaderIfMatch)
 
 	// Capture IP and session for audit
 	ipAddr := c.IP()
@@ -123,12 +183,71 @@ func (ctrl *ReportConfigController) UpdateReportConfig(c *fiber.Ctx) error {
 
 	config, err := ctrl.service.Update(id, input)
 	if err != nil {
+		var staleErr *services.PreconditionFailedError
+		if errors.As(err, &staleErr) {
+			return preconditionFailedResponse(c, 6, staleErr)
+		}
+		var guardErr *utils.SQLGuardError
+		if errors.As(err, &guardErr) {
+			return utils.ErrorResponseWithData(c, fiber.StatusBadRequest, 2, err.Error(), guardErr.Violations)
+		}
+		var heavyErr *services.HeavyQueryError
+		if errors.As(err, &heavyErr) {
+			return utils.ErrorResponseWithData(c, fiber.StatusBadRequest, 5, err.Error(), heavyErr.Estimate)
+		}
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, 3, err.Error())
 	}
 
+	c.Set(fiber.HeaderETag, utils.VersionETag(config.Version))
 	return utils.SuccessResponse(c, config, "Report config updated successfully")
 }
 
+// UpdateMetadata handles PUT /api/report-configs/:id/metadata
+// Replaces the folder, owning team, owners and tags of a config; its version is unchanged.
+// The ETag is the metadata ETag, "metadata-<metadata_version>", and If-Match is checked against it.
+func (ctrl *ReportConfigController) UpdateMetadata(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid report config ID")
+	}
+
+	var input services.ConfigMetadataInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid request body")
+	}
+
+	// Set user context for audit
+	input.UpdatedBy = c.Get("X-User-ID", "system")
+	input.IfMatch = c.Get(fiber.HeaderIfMatch)
+
+	// Capture IP and session for audit
+	ipAddr := c.IP()
+	input.IPAddress = &ipAddr
+	sessionID := c.Get("X-Session-ID", "")
+	if sessionID != "" {
+		input.SessionID = &sessionID
+	}
+
+	if err := ctrl.validate.Struct(input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 2, err.Error())
+	}
+
+	config, err := ctrl.service.UpdateMetadata(id, input)
+	if err != nil {
+		if err.Error() == "report config not found" {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Report config not found")
+		}
+		var staleErr *services.PreconditionFailedError
+		if errors.As(err, &staleErr) {
+			return preconditionFailedResponse(c, 6, staleErr)
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 3, err.Error())
+	}
+
+	c.Set(fiber.HeaderETag, utils.MetadataETag(config.MetadataVersion))
+	return utils.SuccessResponse(c, config, "Report config metadata updated successfully")
+}
+
 // DeleteReportConfig handles DELETE /api/report-configs/:id
 func (ctrl *ReportConfigController) DeleteReportConfig(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
@@ -151,3 +270,140 @@ func (ctrl *ReportConfigController) DeleteReportConfig(c *fiber.Ctx) error {
 
 	return utils.SuccessResponse(c, nil, "Report config deleted successfully")
 }
+
+// GetDrift handles GET /api/report-configs/drift
+// Lists configs managed by manifest sync that were edited outside it since the last sync
+func (ctrl *ReportConfigController) GetDrift(c *fiber.Ctx) error {
+	drift, err := ctrl.syncService.GetDrift()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 3, err.Error())
+	}
+
+	return utils.SuccessResponse(c, drift, "Managed config drift retrieved successfully")
+}
+
+// GetVersions handles GET /api/report-configs/:id/versions
+func (ctrl *ReportConfigController) GetVersions(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid report config ID")
+	}
+
+	versions, err := ctrl.versionService.ListVersions(id)
+	if err != nil {
+		if err.Error() == "report config not found" {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Report config not found")
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 3, err.Error())
+	}
+
+	return utils.SuccessResponse(c, versions, "Report config versions retrieved successfully")
+}
+
+// GetVersion handles GET /api/report-configs/:id/versions/:version
+func (ctrl *ReportConfigController) GetVersion(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid report config ID")
+	}
+	version, err := strconv.Atoi(c.Params("version"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid version")
+	}
+
+	configVersion, err := ctrl.versionService.GetVersion(id, version)
+	if err != nil {
+		return versionNotFoundResponse(c, err)
+	}
+
+	return utils.SuccessResponse(c, configVersion, "Report config version retrieved successfully")
+}
+
+// DiffVersions handles GET /api/report-configs/:id/versions/diff?from=&to=
+func (ctrl *ReportConfigController) DiffVersions(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid report config ID")
+	}
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid from version")
+	}
+	to, err := strconv.Atoi(c.Query("to"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid to version")
+	}
+
+	diff, err := ctrl.versionService.DiffVersions(id, from, to)
+	if err != nil {
+		return versionNotFoundResponse(c, err)
+	}
+
+	return utils.SuccessResponse(c, diff, "Report config versions compared successfully")
+}
+
+// RestoreVersion handles POST /api/report-configs/:id/versions/:version/restore
+// The restored content is saved as a new version and audited as a rollback.
+func (ctrl *ReportConfigController) RestoreVersion(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid report config ID")
+	}
+	version, err := strconv.Atoi(c.Params("version"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid version")
+	}
+
+	var input services.RestoreVersionInput
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&input); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid request body")
+		}
+	}
+
+	// Set user context for audit
+	input.RestoredBy = c.Get("X-User-ID", "system")
+
+	// Capture IP and session for audit
+	ipAddr := c.IP()
+	input.IPAddress = &ipAddr
+	sessionID := c.Get("X-Session-ID", "")
+	if sessionID != "" {
+		input.SessionID = &sessionID
+	}
+
+	config, err := ctrl.versionService.RestoreVersion(id, version, input)
+	if err != nil {
+		switch err.Error() {
+		case "report config not found", "version not found":
+			return versionNotFoundResponse(c, err)
+		}
+		var staleErr *services.PreconditionFailedError
+		if errors.As(err, &staleErr) {
+			return preconditionFailedResponse(c, 6, staleErr)
+		}
+		var guardErr *utils.SQLGuardError
+		if errors.As(err, &guardErr) {
+			return utils.ErrorResponseWithData(c, fiber.StatusBadRequest, 2, err.Error(), guardErr.Violations)
+		}
+		var heavyErr *services.HeavyQueryError
+		if errors.As(err, &heavyErr) {
+			return utils.ErrorResponseWithData(c, fiber.StatusBadRequest, 5, err.Error(), heavyErr.Estimate)
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 3, err.Error())
+	}
+
+	c.Set(fiber.HeaderETag, utils.VersionETag(config.Version))
+	return utils.SuccessResponse(c, config, "Report config version restored successfully")
+}
+
+// versionNotFoundResponse maps version lookup errors to 404
+func versionNotFoundResponse(c *fiber.Ctx, err error) error {
+	if err.Error() == "report config not found" {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Report config not found")
+	}
+	if err.Error() == "version not found" {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Version not found")
+	}
+	return utils.ErrorResponse(c, fiber.StatusInternalServerError, 3, err.Error())
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"scheduling-report/config"
+	"scheduling-report/internal/testdb"
+	"scheduling-report/models"
+	"scheduling-report/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestUpdateMetadataHonoursIfMatch(t *testing.T) {
+	previous := config.DB
+	db := testdb.Open(t)
+	config.DB = db
+	t.Cleanup(func() { config.DB = previous })
+
+	datasource := models.DataSource{Name: "reports", DbType: "mysql", ConnectionURL: "report@tcp(localhost)/reports", IsActive: true, CreatedBy: "test", UpdatedBy: "test"}
+	if err := db.Create(&datasource).Error; err != nil {
+		t.Fatalf("seed datasource: %v", err)
+	}
+	folder := models.ReportFolder{Name: "finance", Path: "finance", CreatedBy: "test", UpdatedBy: "test"}
+	if err := db.Create(&folder).Error; err != nil {
+		t.Fatalf("seed folder: %v", err)
+	}
+	if err := db.Create(&models.ReportTag{Name: "monthly", CreatedBy: "test", UpdatedBy: "test"}).Error; err != nil {
+		t.Fatalf("seed tag: %v", err)
+	}
+	reportConfig := models.ReportConfig{ReportName: "Revenue", ReportQuery: "SELECT 1", OutputFormat: "csv", DatasourceID: datasource.ID,
+		IsActive: true, CreatedBy: "test", UpdatedBy: "test", Version: 1, MetadataVersion: 1}
+	if err := db.Create(&reportConfig).Error; err != nil {
+		t.Fatalf("seed config: %v", err)
+	}
+
+	app := fiber.New()
+	app.Put("/report-configs/:id/metadata", NewReportConfigController().UpdateMetadata)
+
+	body := `{"folder_id": ` + strconv.Itoa(folder.ID) + `, "owners": ["ana"], "tags": ["monthly"]}`
+	target := "/report-configs/" + strconv.Itoa(reportConfig.ID) + "/metadata"
+
+	// Steps run in order: the first write moves the metadata to version 2
+	steps := []struct {
+		name       string
+		target     string
+		ifMatch    string
+		body       string
+		wantStatus int
+		wantETag   string
+	}{
+		{name: "current metadata ETag", target: target, ifMatch: `"metadata-1"`, body: body, wantStatus: fiber.StatusOK, wantETag: `"metadata-2"`},
+		{name: "stale metadata ETag", target: target, ifMatch: `"metadata-1"`, body: body, wantStatus: fiber.StatusPreconditionFailed, wantETag: `"metadata-2"`},
+		{name: "config ETag", target: target, ifMatch: utils.VersionETag(reportConfig.Version), body: body, wantStatus: fiber.StatusPreconditionFailed, wantETag: `"metadata-2"`},
+		{name: "no If-Match", target: target, body: `{"owners": ["ben"]}`, wantStatus: fiber.StatusOK, wantETag: `"metadata-3"`},
+		{name: "unknown tag", target: target, body: `{"tags": ["hr"]}`, wantStatus: fiber.StatusBadRequest},
+		{name: "unknown config", target: "/report-configs/999/metadata", body: body, wantStatus: fiber.StatusNotFound},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", step.target, strings.NewReader(step.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			if step.ifMatch != "" {
+				req.Header.Set(fiber.HeaderIfMatch, step.ifMatch)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			var response utils.StandardResponse
+			if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.StatusCode != step.wantStatus {
+				t.Fatalf("status = %d (%s), want %d", resp.StatusCode, response.Message, step.wantStatus)
+			}
+			if etag := resp.Header.Get(fiber.HeaderETag); etag != step.wantETag {
+				t.Errorf("ETag = %s, want %s", etag, step.wantETag)
+			}
+		})
+	}
+
+	var stored models.ReportConfig
+	if err := db.First(&stored, reportConfig.ID).Error; err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if stored.Version != 1 || stored.FolderID != nil || !reflect.DeepEqual(stored.Owners, models.StringList{"ben"}) {
+		t.Errorf("stored config version = %d, folder = %v, owners = %v; want version 1 with only the last write", stored.Version, stored.FolderID, stored.Owners)
+	}
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"scheduling-report/services"
 	"scheduling-report/utils"
 	"strconv"
@@ -22,18 +23,8 @@ func NewReportDeliveryController() *ReportDeliveryController {
 }
 
 func (ctrl *ReportDeliveryController) GetDeliveries(c *fiber.Ctx) error {
-	var isActive *bool
-	if c.Query("is_active") != "" {
-		val := c.Query("is_active") == "true"
-		isActive = &val
-	}
-
-	deliveries, err := ctrl.service.GetAll(isActive)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40003199, err.Error())
-	}
-
-	return utils.SuccessResponse(c, deliveries, "Deliveries retrieved successfully")
+	deliveries, meta, err := ctrl.service.List(c.Queries())
+	return sendList(c, deliveries, meta, err, 40003101, 40003199, "Deliveries retrieved successfully")
 }
 
 func (ctrl *ReportDeliveryController) GetDeliveryByID(c *fiber.Ctx) error {
@@ -47,6 +38,7 @@ func (ctrl *ReportDeliveryController) GetDeliveryByID(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, 40403100, err.Error())
 	}
 
+	c.Set(fiber.HeaderETag, utils.VersionETag(delivery.Version))
 	return utils.SuccessResponse(c, delivery, "Delivery retrieved successfully")
 }
 
@@ -88,6 +80,10 @@ func (ctrl *ReportDeliveryController) CreateDelivery(c *fiber.Ctx) error {
 		if err.Error() == "report config not found" {
 			return utils.ErrorResponse(c, fiber.StatusBadRequest, 40003103, err.Error())
 		}
+		var refErr *utils.SecretReferenceError
+		if errors.As(err, &refErr) {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, 40003102, err.Error())
+		}
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40003199, err.Error())
 	}
 
@@ -111,6 +107,7 @@ func (ctrl *ReportDeliveryController) UpdateDelivery(c *fiber.Ctx) error {
 	}
 
 	input.UpdatedBy = c.Get("X-User-ID", "system")
+	input.IfMatch = c.Get(fiber.HeaderIfMatch)
 	ipAddr := c.IP()
 	input.IPAddress = &ipAddr
 	sessionID := c.Get("X-Session-ID", "")
@@ -124,12 +121,21 @@ func (ctrl *ReportDeliveryController) UpdateDelivery(c *fiber.Ctx) error {
 
 	delivery, err := ctrl.service.Update(id, input)
 	if err != nil {
+		var staleErr *services.PreconditionFailedError
+		if errors.As(err, &staleErr) {
+			return preconditionFailedResponse(c, 41203100, staleErr)
+		}
 		if err.Error() == "delivery not found" {
 			return utils.ErrorResponse(c, fiber.StatusNotFound, 40403100, err.Error())
 		}
+		var refErr *utils.SecretReferenceError
+		if errors.As(err, &refErr) {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, 40003102, err.Error())
+		}
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40003199, err.Error())
 	}
 
+	c.Set(fiber.HeaderETag, utils.VersionETag(delivery.Version))
 	return utils.SuccessResponse(c, delivery, "Delivery updated successfully")
 }
 
@@ -16,18 +16,8 @@ func NewReportDeliveryLogController() *ReportDeliveryLogController {
 }
 
 func (ctrl *ReportDeliveryLogController) GetDeliveryLogs(c *fiber.Ctx) error {
-	var status *string
-	if c.Query("status") != "" {
-		s := c.Query("status")
-		status = &s
-	}
-	limit, _ := strconv.Atoi(c.Query("limit", "100"))
-	
-	logs, err := ctrl.service.GetAll(status, limit)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40003199, err.Error())
-	}
-	return utils.SuccessResponse(c, logs, "Delivery logs retrieved successfully")
+	logs, meta, err := ctrl.service.List(c.Queries())
+	return sendList(c, logs, meta, err, 40003101, 40003199, "Delivery logs retrieved successfully")
 }
 
 func (ctrl *ReportDeliveryLogController) GetDeliveryLogByID(c *fiber.Ctx) error {
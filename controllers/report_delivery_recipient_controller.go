@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"scheduling-report/services"
 	"scheduling-report/utils"
 	"strconv"
@@ -22,18 +23,8 @@ func NewReportDeliveryRecipientController() *ReportDeliveryRecipientController {
 }
 
 func (ctrl *ReportDeliveryRecipientController) GetRecipients(c *fiber.Ctx) error {
-	var isActive *bool
-	if c.Query("is_active") != "" {
-		val := c.Query("is_active") == "true"
-		isActive = &val
-	}
-
-	recipients, err := ctrl.service.GetAll(isActive)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40003199, err.Error())
-	}
-
-	return utils.SuccessResponse(c, recipients, "Recipients retrieved successfully")
+	recipients, meta, err := ctrl.service.List(c.Queries())
+	return sendList(c, recipients, meta, err, 40003101, 40003199, "Recipients retrieved successfully")
 }
 
 func (ctrl *ReportDeliveryRecipientController) GetRecipientByID(c *fiber.Ctx) error {
@@ -47,6 +38,7 @@ func (ctrl *ReportDeliveryRecipientController) GetRecipientByID(c *fiber.Ctx) er
 		return utils.ErrorResponse(c, fiber.StatusNotFound, 40403100, err.Error())
 	}
 
+	c.Set(fiber.HeaderETag, utils.VersionETag(recipient.Version))
 	return utils.SuccessResponse(c, recipient, "Recipient retrieved successfully")
 }
 
@@ -101,6 +93,7 @@ func (ctrl *ReportDeliveryRecipientController) UpdateRecipient(c *fiber.Ctx) err
 	if err := c.BodyParser(&input); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, 40003101, "Invalid request body")
 	}
+	input.IfMatch = c.Get(fiber.HeaderIfMatch)
 
 	if err := ctrl.validate.Struct(input); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, 40003102, err.Error())
@@ -108,12 +101,17 @@ func (ctrl *ReportDeliveryRecipientController) UpdateRecipient(c *fiber.Ctx) err
 
 	recipient, err := ctrl.service.Update(id, input)
 	if err != nil {
+		var staleErr *services.PreconditionFailedError
+		if errors.As(err, &staleErr) {
+			return preconditionFailedResponse(c, 41203100, staleErr)
+		}
 		if err.Error() == "recipient not found" {
 			return utils.ErrorResponse(c, fiber.StatusNotFound, 40403100, err.Error())
 		}
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40003199, err.Error())
 	}
 
+	c.Set(fiber.HeaderETag, utils.VersionETag(recipient.Version))
 	return utils.SuccessResponse(c, recipient, "Recipient updated successfully")
 }
 
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"scheduling-report/services"
 	"scheduling-report/utils"
 	"strconv"
@@ -16,18 +17,8 @@ func NewReportExecutionController() *ReportExecutionController {
 }
 
 func (ctrl *ReportExecutionController) GetExecutions(c *fiber.Ctx) error {
-	var status *string
-	if c.Query("status") != "" {
-		s := c.Query("status")
-		status = &s
-	}
-	limit, _ := strconv.Atoi(c.Query("limit", "100"))
-	
-	executions, err := ctrl.service.GetAll(status, limit)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40003199, err.Error())
-	}
-	return utils.SuccessResponse(c, executions, "Executions retrieved successfully")
+	executions, meta, err := ctrl.service.List(c.Queries())
+	return sendList(c, executions, meta, err, 40003101, 40003199, "Executions retrieved successfully")
 }
 
 func (ctrl *ReportExecutionController) GetExecutionByID(c *fiber.Ctx) error {
@@ -53,6 +44,13 @@ func (ctrl *ReportExecutionController) GetExecutionsByConfigID(c *fiber.Ctx) err
 	return utils.SuccessResponse(c, executions, "Executions retrieved successfully")
 }
 
+// ExecuteAsyncRequest is the optional body of POST /api/executions/execute-async
+type ExecuteAsyncRequest struct {
+	// Parameters override the config's parameter values for this run only
+	Parameters map[string]interface{} `json:"parameters"`
+}
+
+// ExecuteAsync handles GET and POST /api/executions/execute-async; POST may carry parameter overrides
 func (ctrl *ReportExecutionController) ExecuteAsync(c *fiber.Ctx) error {
 	// Get config_id from query params
 	configID, err := strconv.Atoi(c.Query("config_id"))
@@ -70,12 +68,29 @@ func (ctrl *ReportExecutionController) ExecuteAsync(c *fiber.Ctx) error {
 		scheduleID = &id
 	}
 
+	// Get optional parameter overrides from body
+	var body ExecuteAsyncRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, 40003101, "Invalid request body")
+		}
+	}
+
 	// Get executed_by from header or default to "system"
 	executedBy := c.Get("X-User-ID", "system")
 
 	// Execute async
-	execution, err := ctrl.service.ExecuteAsync(configID, scheduleID, executedBy)
+	execution, err := ctrl.service.ExecuteAsync(services.ExecuteAsyncInput{
+		ConfigID:   configID,
+		ScheduleID: scheduleID,
+		ExecutedBy: executedBy,
+		Parameters: body.Parameters,
+	})
 	if err != nil {
+		var rejected *services.RejectedExecutionError
+		if errors.As(err, &rejected) {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, 40003102, err.Error())
+		}
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40003104, err.Error())
 	}
 
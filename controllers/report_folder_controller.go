@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"scheduling-report/services"
+	"scheduling-report/utils"
+)
+
+type ReportFolderController struct {
+	service  *services.ReportFolderService
+	validate *validator.Validate
+}
+
+func NewReportFolderController() *ReportFolderController {
+	return &ReportFolderController{
+		service:  services.NewReportFolderService(),
+		validate: validator.New(),
+	}
+}
+
+// GetFolders handles GET /api/folders
+func (ctrl *ReportFolderController) GetFolders(c *fiber.Ctx) error {
+	folders, meta, err := ctrl.service.List(c.Queries())
+	return sendList(c, folders, meta, err, 1, 1, "Folders retrieved successfully")
+}
+
+// GetFolderByID handles GET /api/folders/:id
+func (ctrl *ReportFolderController) GetFolderByID(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid folder ID")
+	}
+
+	folder, err := ctrl.service.GetByID(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Folder not found")
+	}
+
+	return utils.SuccessResponse(c, folder, "Folder retrieved successfully")
+}
+
+// CreateFolder handles POST /api/folders
+func (ctrl *ReportFolderController) CreateFolder(c *fiber.Ctx) error {
+	var input services.FolderInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid request body")
+	}
+
+	// Set user context for audit
+	input.PerformedBy = c.Get("X-User-ID", "system")
+
+	// Capture IP and session for audit
+	ipAddr := c.IP()
+	input.IPAddress = &ipAddr
+	sessionID := c.Get("X-Session-ID", "")
+	if sessionID != "" {
+		input.SessionID = &sessionID
+	}
+
+	if err := ctrl.validate.Struct(input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 2, err.Error())
+	}
+
+	folder, err := ctrl.service.Create(input)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 3, err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"responseCode":    utils.BuildCode(fiber.StatusCreated, 0),
+		"responseMessage": "Folder created successfully",
+		"data":            folder,
+	})
+}
+
+// UpdateFolder handles PUT /api/folders/:id
+// Renames or moves a folder (parent_id); its subfolders move along.
+func (ctrl *ReportFolderController) UpdateFolder(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid folder ID")
+	}
+
+	var input services.FolderInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid request body")
+	}
+
+	// Set user context for audit
+	input.PerformedBy = c.Get("X-User-ID", "system")
+
+	// Capture IP and session for audit
+	ipAddr := c.IP()
+	input.IPAddress = &ipAddr
+	sessionID := c.Get("X-Session-ID", "")
+	if sessionID != "" {
+		input.SessionID = &sessionID
+	}
+
+	if err := ctrl.validate.Struct(input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 2, err.Error())
+	}
+
+	folder, err := ctrl.service.Update(id, input)
+	if err != nil {
+		if err.Error() == "folder not found" {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Folder not found")
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 3, err.Error())
+	}
+
+	return utils.SuccessResponse(c, folder, "Folder updated successfully")
+}
+
+// DeleteFolder handles DELETE /api/folders/:id
+// Only empty folders, without subfolders or report configs, can be deleted.
+func (ctrl *ReportFolderController) DeleteFolder(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid folder ID")
+	}
+
+	// Get user context for audit
+	deletedBy := c.Get("X-User-ID", "system")
+	ipAddr := c.IP()
+	sessionID := c.Get("X-Session-ID", "")
+	var sessionPtr *string
+	if sessionID != "" {
+		sessionPtr = &sessionID
+	}
+
+	if err := ctrl.service.Delete(id, deletedBy, sessionPtr, &ipAddr); err != nil {
+		if err.Error() == "folder not found" {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Folder not found")
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 3, err.Error())
+	}
+
+	return utils.SuccessResponse(c, nil, "Folder deleted successfully")
+}
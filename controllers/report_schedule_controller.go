@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"scheduling-report/models"
 	"scheduling-report/services"
 	"scheduling-report/utils"
@@ -24,18 +25,8 @@ func NewReportScheduleController() *ReportScheduleController {
 
 // GetSchedules retrieves all schedules
 func (ctrl *ReportScheduleController) GetSchedules(c *fiber.Ctx) error {
-	var isActive *bool
-	if c.Query("is_active") != "" {
-		val := c.Query("is_active") == "true"
-		isActive = &val
-	}
-
-	schedules, err := ctrl.service.GetAll(isActive)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40003199, err.Error())
-	}
-
-	return utils.SuccessResponse(c, schedules, "Schedules retrieved successfully")
+	schedules, meta, err := ctrl.service.List(c.Queries())
+	return sendList(c, schedules, meta, err, 40003101, 40003199, "Schedules retrieved successfully")
 }
 
 // GetScheduleByID retrieves a schedule by ID
@@ -50,6 +41,7 @@ func (ctrl *ReportScheduleController) GetScheduleByID(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, 40403100, err.Error())
 	}
 
+	c.Set(fiber.HeaderETag, utils.VersionETag(schedule.Version))
 	return utils.SuccessResponse(c, schedule, "Schedule retrieved successfully")
 }
 
@@ -92,7 +84,7 @@ func (ctrl *ReportScheduleController) CreateSchedule(c *fiber.Ctx) error {
 	}
 
 	// Validate cron expression
-	validation := utils.ValidateCronExpression(input.CronExpression)
+	validation := utils.ValidateCronExpression(input.CronExpression, input.Timezone)
 	if !validation.Valid {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"responseCode":    "40003103",
@@ -124,6 +116,7 @@ func (ctrl *ReportScheduleController) CreateSchedule(c *fiber.Ctx) error {
 func (ctrl *ReportScheduleController) ValidateCronExpression(c *fiber.Ctx) error {
 	type ValidateRequest struct {
 		CronExpression string `json:"cron_expression" validate:"required"`
+		Timezone       string `json:"timezone"`
 	}
 
 	var input ValidateRequest
@@ -131,7 +124,7 @@ func (ctrl *ReportScheduleController) ValidateCronExpression(c *fiber.Ctx) error
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, 40003101, "Invalid request body")
 	}
 
-	validation := utils.ValidateCronExpression(input.CronExpression)
+	validation := utils.ValidateCronExpression(input.CronExpression, input.Timezone)
 
 	return c.JSON(fiber.Map{
 		"responseCode":    "20003100",
@@ -155,6 +148,7 @@ func (ctrl *ReportScheduleController) UpdateSchedule(c *fiber.Ctx) error {
 
 	// Get user ID from header
 	input.UpdatedBy = c.Get("X-User-ID", "system")
+	input.IfMatch = c.Get(fiber.HeaderIfMatch)
 
 	// Capture IP and session for audit
 	ipAddr := c.IP()
@@ -170,6 +164,10 @@ func (ctrl *ReportScheduleController) UpdateSchedule(c *fiber.Ctx) error {
 
 	schedule, err := ctrl.service.Update(id, input)
 	if err != nil {
+		var staleErr *services.PreconditionFailedError
+		if errors.As(err, &staleErr) {
+			return preconditionFailedResponse(c, 41203100, staleErr)
+		}
 		if err.Error() == "invalid cron expression" {
 			return utils.ErrorResponse(c, fiber.StatusBadRequest, 40003102, err.Error())
 		}
@@ -179,6 +177,7 @@ func (ctrl *ReportScheduleController) UpdateSchedule(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40003199, err.Error())
 	}
 
+	c.Set(fiber.HeaderETag, utils.VersionETag(schedule.Version))
 	return utils.SuccessResponse(c, schedule, "Schedule updated successfully")
 }
 
@@ -218,7 +217,6 @@ func (ctrl *ReportScheduleController) GetSchedulesWithDetails(c *fiber.Ctx) erro
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid query parameters")
 	}
 
-	details, _, responseMessage := ctrl.service.GetSchedulesWithDetails(filters)
-
-	return utils.SuccessResponse(c, details, responseMessage)
+	details, meta, responseMessage, err := ctrl.service.GetSchedulesWithDetails(filters, c.Queries())
+	return sendList(c, details, meta, err, 40003101, 40003199, responseMessage)
 }
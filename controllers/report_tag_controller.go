@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"scheduling-report/models"
+	"scheduling-report/services"
+	"scheduling-report/utils"
+)
+
+type ReportTagController struct {
+	service  *services.ReportTagService
+	validate *validator.Validate
+}
+
+func NewReportTagController() *ReportTagController {
+	return &ReportTagController{
+		service:  services.NewReportTagService(),
+		validate: validator.New(),
+	}
+}
+
+// GetTags handles GET /api/tags
+func (ctrl *ReportTagController) GetTags(c *fiber.Ctx) error {
+	tags, meta, err := ctrl.service.List(c.Queries())
+	return sendList(c, tags, meta, err, 1, 1, "Tags retrieved successfully")
+}
+
+// GetTagByID handles GET /api/tags/:id
+func (ctrl *ReportTagController) GetTagByID(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid tag ID")
+	}
+
+	tag, err := ctrl.service.GetByID(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Tag not found")
+	}
+
+	return utils.SuccessResponse(c, tag, "Tag retrieved successfully")
+}
+
+// CreateTag handles POST /api/tags
+func (ctrl *ReportTagController) CreateTag(c *fiber.Ctx) error {
+	var input services.TagInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid request body")
+	}
+
+	// Set user context for audit
+	input.PerformedBy = c.Get("X-User-ID", "system")
+
+	// Capture IP and session for audit
+	ipAddr := c.IP()
+	input.IPAddress = &ipAddr
+	sessionID := c.Get("X-Session-ID", "")
+	if sessionID != "" {
+		input.SessionID = &sessionID
+	}
+
+	if err := ctrl.validate.Struct(input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 2, err.Error())
+	}
+
+	tag, err := ctrl.service.Create(input)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 3, err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"responseCode":    utils.BuildCode(fiber.StatusCreated, 0),
+		"responseMessage": "Tag created successfully",
+		"data":            tag,
+	})
+}
+
+// UpdateTag handles PUT /api/tags/:id
+// Renaming a tag renames it on every config carrying it.
+func (ctrl *ReportTagController) UpdateTag(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid tag ID")
+	}
+
+	var input services.TagInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid request body")
+	}
+
+	// Set user context for audit
+	input.PerformedBy = c.Get("X-User-ID", "system")
+
+	// Capture IP and session for audit
+	ipAddr := c.IP()
+	input.IPAddress = &ipAddr
+	sessionID := c.Get("X-Session-ID", "")
+	if sessionID != "" {
+		input.SessionID = &sessionID
+	}
+
+	if err := ctrl.validate.Struct(input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 2, err.Error())
+	}
+
+	tag, err := ctrl.service.Update(id, input)
+	if err != nil {
+		if err.Error() == "tag not found" {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Tag not found")
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 3, err.Error())
+	}
+
+	return utils.SuccessResponse(c, tag, "Tag updated successfully")
+}
+
+// DeleteTag handles DELETE /api/tags/:id
+// The tag is removed from every config carrying it.
+func (ctrl *ReportTagController) DeleteTag(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid tag ID")
+	}
+
+	// Get user context for audit
+	deletedBy := c.Get("X-User-ID", "system")
+	ipAddr := c.IP()
+	sessionID := c.Get("X-Session-ID", "")
+	var sessionPtr *string
+	if sessionID != "" {
+		sessionPtr = &sessionID
+	}
+
+	if err := ctrl.service.Delete(id, deletedBy, sessionPtr, &ipAddr); err != nil {
+		if err.Error() == "tag not found" {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Tag not found")
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 3, err.Error())
+	}
+
+	return utils.SuccessResponse(c, nil, "Tag deleted successfully")
+}
+
+// BulkRetag handles POST /api/report-configs/tags/bulk
+// Sets, or adds and removes, tags on many configs in one transaction; each changed config is audited.
+func (ctrl *ReportTagController) BulkRetag(c *fiber.Ctx) error {
+	var req models.BulkRetagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 1, "Invalid request body")
+	}
+
+	// Set user context for audit
+	req.PerformedBy = c.Get("X-User-ID", "system")
+
+	// Capture IP and session for audit
+	ipAddr := c.IP()
+	sessionID := c.Get("X-Session-ID", "")
+	var sessionPtr *string
+	if sessionID != "" {
+		sessionPtr = &sessionID
+	}
+
+	if err := ctrl.validate.Struct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 2, err.Error())
+	}
+
+	results, err := ctrl.service.BulkRetag(req, sessionPtr, &ipAddr)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 3, err.Error())
+	}
+
+	return utils.SuccessResponse(c, results, "Report configs re-tagged successfully")
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"scheduling-report/models"
+	"scheduling-report/services"
+	"scheduling-report/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ScheduleBundleController struct {
+	service *services.ScheduleBundleService
+}
+
+func NewScheduleBundleController() *ScheduleBundleController {
+	return &ScheduleBundleController{
+		service: services.NewScheduleBundleService(),
+	}
+}
+
+// ExportSchedule downloads one complete schedule as a bundle
+// GET /api/schedules/complete/:id/export?format=json|yaml
+func (ctrl *ScheduleBundleController) ExportSchedule(c *fiber.Ctx) error {
+	scheduleID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004003, "Invalid schedule ID")
+	}
+	format := c.Query("format", "json")
+	if format != "json" && format != "yaml" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004008, "Invalid format, must be json or yaml")
+	}
+
+	bundle, err := ctrl.service.ExportSchedule(scheduleID, c.Get("X-User-ID", "system"))
+	if err != nil {
+		if err.Error() == "schedule not found" {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 40404004, "Schedule not found")
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40004099, "Failed to export schedule: "+err.Error())
+	}
+
+	return sendBundle(c, bundle, format, fmt.Sprintf("schedule-%d", scheduleID))
+}
+
+// ExportSchedules downloads several complete schedules, or all of them without ids, as one bundle
+// GET /api/schedules/export?ids=1,2,3&format=json|yaml
+func (ctrl *ScheduleBundleController) ExportSchedules(c *fiber.Ctx) error {
+	var ids []int
+	if idsParam := c.Query("ids"); idsParam != "" {
+		for _, part := range strings.Split(idsParam, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004008, "Invalid ids, must be comma-separated schedule IDs")
+			}
+			ids = append(ids, id)
+		}
+	}
+	format := c.Query("format", "json")
+	if format != "json" && format != "yaml" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004008, "Invalid format, must be json or yaml")
+	}
+
+	bundle, err := ctrl.service.ExportSchedules(ids, c.Get("X-User-ID", "system"))
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "schedule ") && strings.HasSuffix(err.Error(), " not found") {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 40404004, err.Error())
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40004099, "Failed to export schedules: "+err.Error())
+	}
+
+	return sendBundle(c, bundle, format, "schedules-"+bundle.ExportedAt.Format("20060102-150405"))
+}
+
+// sendBundle writes a bundle as a JSON or YAML attachment named <name>.<format>
+func sendBundle(c *fiber.Ctx, bundle *models.ScheduleBundle, format string, name string) error {
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40004099, "Failed to encode bundle: "+err.Error())
+	}
+
+	contentType := fiber.MIMEApplicationJSON
+	if format == "yaml" {
+		if data, err = utils.JSONToYAML(data); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40004099, "Failed to encode bundle: "+err.Error())
+		}
+		contentType = "application/yaml"
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	return c.Send(data)
+}
+
+// ImportSchedules imports a bundle, either wrapped in an import request or bare with the options as query
+// parameters (?dry_run=true&conflict_strategy=skip|overwrite|rename). YAML bodies need a YAML Content-Type.
+// POST /api/schedules/import
+func (ctrl *ScheduleBundleController) ImportSchedules(c *fiber.Ctx) error {
+	body := c.Body()
+	if strings.Contains(c.Get(fiber.HeaderContentType), "yaml") {
+		converted, err := utils.YAMLToJSON(body)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004009, "Invalid YAML body: "+err.Error())
+		}
+		body = converted
+	}
+
+	var req models.ScheduleImportRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004009, "Invalid request body: "+err.Error())
+	}
+	if req.Bundle.Kind == "" {
+		// A bundle as exported, not wrapped in an import request
+		if err := json.Unmarshal(body, &req.Bundle); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004009, "Invalid request body: "+err.Error())
+		}
+		req.DryRun = c.QueryBool("dry_run", false)
+		req.ConflictStrategy = c.Query("conflict_strategy")
+		req.CostOverride = c.QueryBool("cost_override", false)
+	}
+	req.ImportedBy = c.Get("X-User-ID", "system")
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004010, "Validation failed: "+err.Error())
+	}
+
+	results, err := ctrl.service.ImportSchedules(req)
+	if err != nil {
+		var bundleErr *services.BundleError
+		if errors.As(err, &bundleErr) {
+			if len(bundleErr.Secrets) > 0 {
+				return utils.ErrorResponseWithData(c, fiber.StatusBadRequest, 40004010, "Validation failed: "+err.Error(), fiber.Map{"missing_secrets": bundleErr.Secrets})
+			}
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004010, "Validation failed: "+err.Error())
+		}
+		if strings.Contains(err.Error(), "invalid cron expression") || strings.Contains(err.Error(), "invalid parameter schema") {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004010, "Validation failed: "+err.Error())
+		}
+		var refErr *utils.SecretReferenceError
+		if errors.As(err, &refErr) {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, 40004010, "Validation failed: "+err.Error())
+		}
+		var guardErr *utils.SQLGuardError
+		if errors.As(err, &guardErr) {
+			return utils.ErrorResponseWithData(c, fiber.StatusBadRequest, 40004010, "Validation failed: "+err.Error(), guardErr.Violations)
+		}
+		var heavyErr *services.HeavyQueryError
+		if errors.As(err, &heavyErr) {
+			return utils.ErrorResponseWithData(c, fiber.StatusBadRequest, 40004010, "Validation failed: "+err.Error(), heavyErr.Estimate)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, 40004099, "Failed to import schedules: "+err.Error())
+	}
+
+	if req.DryRun {
+		return utils.SuccessResponse(c, fiber.Map{"dry_run": true, "results": results}, "Import dry run completed, nothing was saved")
+	}
+	return utils.SuccessResponse(c, fiber.Map{"dry_run": false, "imported_at": time.Now(), "results": results}, "Schedules imported successfully")
+}
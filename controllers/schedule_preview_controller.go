@@ -1,30 +1,43 @@
 package controllers
 
 import (
-	"fmt"
-	"regexp"
+	"scheduling-report/services"
 	"scheduling-report/utils"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/robfig/cron/v3"
 )
 
-type SchedulePreviewController struct{}
+type SchedulePreviewController struct {
+	configService     *services.ReportConfigService
+	datasourceService *services.DatasourceService
+}
 
 func NewSchedulePreviewController() *SchedulePreviewController {
-	return &SchedulePreviewController{}
+	return &SchedulePreviewController{
+		configService:     services.NewReportConfigService(),
+		datasourceService: services.NewDatasourceService(),
+	}
 }
 
 type PreviewRequest struct {
 	ReportQuery    string `json:"report_query" validate:"required"`
 	CronExpression string `json:"cron_expression" validate:"required"`
+	// Timezone is the schedule's IANA timezone; defaults to UTC like report_schedules.timezone
+	Timezone string `json:"timezone"`
+	// ConfigID renders with the config's parameters and datasource, exactly as the worker would
+	ConfigID *int `json:"config_id"`
+	// DbType selects the bind parameter style when no config is given; defaults to mysql
+	DbType string `json:"db_type"`
+	// Parameters are untyped values, or per-run overrides checked against the config's schema
+	Parameters map[string]interface{} `json:"parameters"`
 }
 
 type ExecutionPreview struct {
 	ExecutionTime string            `json:"execution_time"`
 	TimeRange     map[string]string `json:"time_range"`
 	ExampleQuery  string            `json:"example_query"`
+	QueryArgs     []interface{}     `json:"query_args"`
 }
 
 // PreviewScheduleExecution previews how schedule will execute
@@ -36,7 +49,7 @@ func (ctrl *SchedulePreviewController) PreviewScheduleExecution(c *fiber.Ctx) er
 	}
 
 	// Validate cron expression
-	cronValidation := utils.ValidateCronExpression(input.CronExpression)
+	cronValidation := utils.ValidateCronExpression(input.CronExpression, input.Timezone)
 	if !cronValidation.Valid {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"responseCode":    "40003103",
@@ -45,9 +58,30 @@ func (ctrl *SchedulePreviewController) PreviewScheduleExecution(c *fiber.Ctx) er
 		})
 	}
 
-	// Parse cron
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	schedule, err := parser.Parse(input.CronExpression)
+	var parameterTypes map[string]utils.QueryParamType
+	if input.ConfigID != nil {
+		reportConfig, err := ctrl.configService.GetByID(*input.ConfigID)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Report config not found")
+		}
+		datasource, err := ctrl.datasourceService.GetByID(reportConfig.DatasourceID)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, 0, "Datasource not found")
+		}
+		parameters, err := services.ResolveReportParameters(reportConfig.ParameterSchema, reportConfig.Parameters, input.Parameters)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, 40003102, err.Error())
+		}
+		input.DbType = datasource.DbType
+		input.Parameters = parameters
+		parameterTypes = services.ParameterTypes(reportConfig.ParameterSchema)
+	}
+	if input.DbType == "" {
+		input.DbType = "mysql"
+	}
+
+	// Parse cron in the schedule's timezone, as the dispatcher does
+	schedule, err := utils.ParseCronInLocation(input.CronExpression, input.Timezone)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, 40003103, "Failed to parse cron expression")
 	}
@@ -61,10 +95,14 @@ func (ctrl *SchedulePreviewController) PreviewScheduleExecution(c *fiber.Ctx) er
 		nextRun := schedule.Next(currentTime)
 
 		// Calculate time range
-		timeRange := utils.CalculateTimeRange(lastRunAt, input.CronExpression, nextRun)
+		timeRange := utils.CalculateTimeRange(lastRunAt, input.CronExpression, input.Timezone, nextRun)
 
-		// Replace template variables in query
-		exampleQuery := replaceTemplateVariables(input.ReportQuery, timeRange)
+		// Render the query exactly as the worker will run it
+		rendered, err := utils.RenderReportQuery(input.ReportQuery, input.DbType, input.Parameters, parameterTypes,
+			utils.NewVariableContext(lastRunAt, input.CronExpression, input.Timezone, nextRun))
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, 40003102, err.Error())
+		}
 
 		previews = append(previews, ExecutionPreview{
 			ExecutionTime: nextRun.Format("2006-01-02 15:04:05"),
@@ -72,7 +110,8 @@ func (ctrl *SchedulePreviewController) PreviewScheduleExecution(c *fiber.Ctx) er
 				"start": timeRange["start_datetime"].(string),
 				"end":   timeRange["end_datetime"].(string),
 			},
-			ExampleQuery: exampleQuery,
+			ExampleQuery: rendered.SQL,
+			QueryArgs:    rendered.Args,
 		})
 
 		// Update for next iteration
@@ -89,24 +128,3 @@ func (ctrl *SchedulePreviewController) PreviewScheduleExecution(c *fiber.Ctx) er
 		},
 	})
 }
-
-// replaceTemplateVariables replaces {{variable}} placeholders in query
-func replaceTemplateVariables(query string, variables map[string]interface{}) string {
-	result := query
-
-	// Find all {{variable}} patterns
-	re := regexp.MustCompile(`\{\{(\w+)\}\}`)
-	matches := re.FindAllStringSubmatch(query, -1)
-
-	for _, match := range matches {
-		placeholder := match[0] // {{variable}}
-		varName := match[1]     // variable
-
-		if value, exists := variables[varName]; exists {
-			result = regexp.MustCompile(regexp.QuoteMeta(placeholder)).
-				ReplaceAllString(result, fmt.Sprintf("'%v'", value))
-		}
-	}
-
-	return result
-}
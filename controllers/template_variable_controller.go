@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"fmt"
+	"scheduling-report/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TemplateVariableController struct{}
+
+func NewTemplateVariableController() *TemplateVariableController {
+	return &TemplateVariableController{}
+}
+
+// TemplateVariableInfo describes one built-in variable with its value for the current time
+type TemplateVariableInfo struct {
+	Name        string               `json:"name"`
+	Type        utils.QueryParamType `json:"type"`
+	Description string               `json:"description"`
+	Example     string               `json:"example"`
+}
+
+// GetTemplateVariables handles GET /api/template-variables
+// Query params: ?timezone=Asia/Jakarta (examples are evaluated now in this timezone, default UTC)
+func (ctrl *TemplateVariableController) GetTemplateVariables(c *fiber.Ctx) error {
+	timezone := c.Query("timezone", "UTC")
+	if _, err := utils.LoadTimezone(timezone); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, 40003101, err.Error())
+	}
+
+	values := utils.ResolveTemplateVariables(utils.NewVariableContext(nil, "", timezone, time.Now()))
+
+	variables := []TemplateVariableInfo{}
+	for _, variable := range utils.ListTemplateVariables() {
+		variables = append(variables, TemplateVariableInfo{
+			Name:        variable.Name,
+			Type:        variable.Type,
+			Description: variable.Description,
+			Example:     formatTemplateExample(values[variable.Name], variable.Type),
+		})
+	}
+
+	return utils.SuccessResponse(c, fiber.Map{
+		"timezone":     timezone,
+		"variables":    variables,
+		"offset_units": utils.TemplateOffsetUnits,
+		"syntax": fiber.Map{
+			"variable": "{{start_date}}",
+			"offset":   "{{today-3d}}, {{current_month_start+1mo}}, {{today-2b}}",
+			"format":   `{{start_date|format:"20060102"}} (Go time layout)`,
+		},
+	}, "Template variables retrieved successfully")
+}
+
+// formatTemplateExample renders a resolved value the way it is bound by default
+func formatTemplateExample(value interface{}, paramType utils.QueryParamType) string {
+	if t, ok := value.(time.Time); ok {
+		if paramType == utils.QueryParamDate {
+			return t.Format("2006-01-02")
+		}
+		return t.Format("2006-01-02 15:04:05")
+	}
+	return fmt.Sprintf("%v", value)
+}
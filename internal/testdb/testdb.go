@@ -0,0 +1,339 @@
+// Package testdb opens a throwaway SQLite database with the service's tables, for tests that run
+// repositories and services against a real database without a MySQL server.
+//
+// The MySQL-only SQL the repositories use (NOW(), IF(), DATE_ADD/DATE_SUB with INTERVAL, CONCAT,
+// JSON_CONTAINS) is translated on the way through, and time arguments are stored in UTC so they compare as text.
+// Transactions begin IMMEDIATE, which serializes writers the way FOR UPDATE row locks would.
+package testdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"scheduling-report/models"
+
+	gosqlite "github.com/glebarez/go-sqlite"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// timeFormat is how the sqlite driver writes time.Time values
+const timeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// Models lists every table the tests create
+var Models = []interface{}{
+	&models.DataSource{},
+	&models.ReportConfig{},
+	&models.ReportConfigAudit{},
+	&models.ReportConfigVersion{},
+	&models.ReportConfigTag{},
+	&models.ReportTag{},
+	&models.ReportFolder{},
+	&models.ReportSchedule{},
+	&models.ReportDelivery{},
+	&models.ReportDeliveryRecipient{},
+	&models.ReportDeliveryLog{},
+	&models.ReportExecution{},
+	&models.ExecutionOutbox{},
+	&models.SchedulerLease{},
+}
+
+var (
+	registerOnce sync.Once
+	ifCall       = regexp.MustCompile(`(?i)\bIF\(`)
+	interval     = regexp.MustCompile(`(?i)INTERVAL\s+(\?|\d+)\s+(SECOND|MINUTE|HOUR|DAY)`)
+)
+
+// Open creates a file-backed SQLite database under tb.TempDir with every table in Models
+func Open(tb testing.TB) *gorm.DB {
+	tb.Helper()
+	registerOnce.Do(registerFunctions)
+
+	dsn := "file:" + filepath.Join(tb.TempDir(), "test.db") +
+		"?_pragma=busy_timeout(20000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	sqlDB, err := sql.Open(sqlite.DriverName, dsn)
+	if err != nil {
+		tb.Fatalf("open sqlite: %v", err)
+	}
+	tb.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(sqlite.Dialector{Conn: &pool{db: sqlDB}}, &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+		NowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
+	})
+	if err != nil {
+		tb.Fatalf("open gorm: %v", err)
+	}
+
+	if err := adaptSchemas(db); err != nil {
+		tb.Fatalf("adapt schemas: %v", err)
+	}
+	if err := db.AutoMigrate(Models...); err != nil {
+		tb.Fatalf("create tables: %v", err)
+	}
+
+	return db
+}
+
+// CountQueries counts the statements db runs from now on
+func CountQueries(db *gorm.DB) *atomic.Int64 {
+	var count atomic.Int64
+	increment := func(*gorm.DB) { count.Add(1) }
+
+	callbacks := db.Callback()
+	callbacks.Query().After("gorm:query").Register("testdb:count_query", increment)
+	callbacks.Row().After("gorm:row").Register("testdb:count_row", increment)
+	callbacks.Raw().After("gorm:raw").Register("testdb:count_raw", increment)
+	callbacks.Create().After("gorm:create").Register("testdb:count_create", increment)
+	callbacks.Update().After("gorm:update").Register("testdb:count_update", increment)
+	callbacks.Delete().After("gorm:delete").Register("testdb:count_delete", increment)
+
+	return &count
+}
+
+// adaptSchemas rewrites the MySQL column types in the parsed model schemas into ones SQLite accepts:
+// enums become text, CustomTime becomes datetime and ON UPDATE defaults are dropped
+func adaptSchemas(db *gorm.DB) error {
+	customTime := reflect.TypeOf(models.CustomTime{})
+
+	for _, model := range Models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+
+		for _, field := range stmt.Schema.Fields {
+			if strings.HasPrefix(strings.ToLower(string(field.DataType)), "enum") {
+				field.DataType = schema.String
+			}
+			if field.IndirectFieldType == customTime {
+				field.DataType = schema.Time
+			}
+			if i := strings.Index(strings.ToUpper(field.DefaultValue), " ON UPDATE"); i >= 0 {
+				field.DefaultValue = field.DefaultValue[:i]
+			}
+		}
+	}
+
+	return nil
+}
+
+// registerFunctions adds the MySQL functions the repositories call to every new SQLite connection
+func registerFunctions() {
+	gosqlite.MustRegisterScalarFunction("NOW", 0, func(ctx *gosqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		return time.Now().UTC().Format(timeFormat), nil
+	})
+
+	shift := func(sign time.Duration) func(ctx *gosqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		return func(ctx *gosqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			t, err := parseTime(args[0])
+			if err != nil {
+				return nil, err
+			}
+			amount, ok := args[1].(int64)
+			if !ok {
+				return nil, fmt.Errorf("interval amount %v is not an integer", args[1])
+			}
+			unit := map[string]time.Duration{"SECOND": time.Second, "MINUTE": time.Minute, "HOUR": time.Hour, "DAY": 24 * time.Hour}[strings.ToUpper(fmt.Sprint(args[2]))]
+			return t.Add(sign * time.Duration(amount) * unit).Format(timeFormat), nil
+		}
+	}
+	gosqlite.MustRegisterScalarFunction("DATE_ADD", 3, shift(1))
+	gosqlite.MustRegisterScalarFunction("DATE_SUB", 3, shift(-1))
+
+	gosqlite.MustRegisterScalarFunction("CONCAT", -1, func(ctx *gosqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		var b strings.Builder
+		for _, arg := range args {
+			if arg == nil {
+				return nil, nil
+			}
+			if bytes, ok := arg.([]byte); ok {
+				arg = string(bytes)
+			}
+			fmt.Fprint(&b, arg)
+		}
+		return b.String(), nil
+	})
+
+	// JSON_QUOTE is SQLite's json_quote already; JSON_CONTAINS has no SQLite equivalent
+	gosqlite.MustRegisterScalarFunction("JSON_CONTAINS", 2, func(ctx *gosqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		if args[0] == nil || args[1] == nil {
+			return nil, nil
+		}
+		var target, candidate interface{}
+		if err := json.Unmarshal([]byte(text(args[0])), &target); err != nil {
+			return nil, fmt.Errorf("JSON_CONTAINS target: %w", err)
+		}
+		if err := json.Unmarshal([]byte(text(args[1])), &candidate); err != nil {
+			return nil, fmt.Errorf("JSON_CONTAINS candidate: %w", err)
+		}
+		if jsonContains(target, candidate) {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	})
+}
+
+// text reads a string argument, which the driver passes as a string or as bytes
+func text(value driver.Value) string {
+	if bytes, ok := value.([]byte); ok {
+		return string(bytes)
+	}
+	return fmt.Sprint(value)
+}
+
+// jsonContains follows MySQL's JSON_CONTAINS: an array contains a candidate array when it contains each of
+// its elements and a scalar when one of its elements does, and an object contains the keys of a candidate object
+func jsonContains(target interface{}, candidate interface{}) bool {
+	switch t := target.(type) {
+	case []interface{}:
+		if c, ok := candidate.([]interface{}); ok {
+			for _, element := range c {
+				if !jsonContains(t, element) {
+					return false
+				}
+			}
+			return true
+		}
+		for _, element := range t {
+			if jsonContains(element, candidate) {
+				return true
+			}
+		}
+		return false
+	case map[string]interface{}:
+		c, ok := candidate.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range c {
+			if element, ok := t[key]; !ok || !jsonContains(element, value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(target, candidate)
+	}
+}
+
+func parseTime(value driver.Value) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC(), nil
+	case string:
+		for _, layout := range []string{timeFormat, "2006-01-02 15:04:05.999999999", "2006-01-02 15:04:05"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t.UTC(), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot read %v as a time", value)
+}
+
+// translate rewrites the MySQL-only syntax in query
+func translate(query string) string {
+	query = ifCall.ReplaceAllString(query, "IIF(")
+	return interval.ReplaceAllString(query, "$1, '$2'")
+}
+
+// normalizeArgs resolves driver.Valuer arguments and moves times to UTC
+func normalizeArgs(args []interface{}) []interface{} {
+	normalized := make([]interface{}, len(args))
+	for i, arg := range args {
+		if valuer, ok := arg.(driver.Valuer); ok {
+			if rv := reflect.ValueOf(arg); rv.Kind() == reflect.Ptr && rv.IsNil() {
+				arg = nil
+			} else if value, err := valuer.Value(); err == nil {
+				arg = value
+			}
+		}
+		switch t := arg.(type) {
+		case time.Time:
+			arg = t.UTC()
+		case *time.Time:
+			if t != nil {
+				arg = t.UTC()
+			}
+		}
+		normalized[i] = arg
+	}
+	return normalized
+}
+
+// pool is the gorm connection pool with translation applied to every statement
+type pool struct {
+	db *sql.DB
+}
+
+func (p *pool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.db.PrepareContext(ctx, translate(query))
+}
+
+func (p *pool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.db.ExecContext(ctx, translate(query), normalizeArgs(args)...)
+}
+
+func (p *pool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.db.QueryContext(ctx, translate(query), normalizeArgs(args)...)
+}
+
+func (p *pool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.db.QueryRowContext(ctx, translate(query), normalizeArgs(args)...)
+}
+
+func (p *pool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	tx, err := p.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &txPool{tx: tx}, nil
+}
+
+func (p *pool) GetDBConn() (*sql.DB, error) {
+	return p.db, nil
+}
+
+// txPool is pool inside a transaction
+type txPool struct {
+	tx *sql.Tx
+}
+
+func (t *txPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.tx.PrepareContext(ctx, translate(query))
+}
+
+func (t *txPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.tx.ExecContext(ctx, translate(query), normalizeArgs(args)...)
+}
+
+func (t *txPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.tx.QueryContext(ctx, translate(query), normalizeArgs(args)...)
+}
+
+func (t *txPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return t.tx.QueryRowContext(ctx, translate(query), normalizeArgs(args)...)
+}
+
+func (t *txPool) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *txPool) Rollback() error {
+	return t.tx.Rollback()
+}
@@ -1,35 +1,129 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 
 	"scheduling-report/config"
 	"scheduling-report/middlewares"
+	"scheduling-report/migrations"
+	"scheduling-report/models"
 	"scheduling-report/routes"
 	"scheduling-report/services"
+	"scheduling-report/utils"
 )
 
 func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [command]\n\nCommands:\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "  serve      run the HTTP API (default)")
+		fmt.Fprintln(flag.CommandLine.Output(), "  worker     consume execution requests and generate report files")
+		fmt.Fprintln(flag.CommandLine.Output(), "  reencrypt  re-encrypt stored credentials with the active encryption key")
+		fmt.Fprintln(flag.CommandLine.Output(), "  sync       apply a directory of schedule manifests (sync -h for options)")
+		fmt.Fprintln(flag.CommandLine.Output(), "  migrate    apply pending database schema migrations")
+	}
+	flag.Parse()
+
+	command := flag.Arg(0)
+	if command == "" {
+		command = "serve"
+	}
+
+	// Parse sync options before connecting, so -h and bad flags need no database
+	syncFlags := flag.NewFlagSet("sync", flag.ExitOnError)
+	syncDir := syncFlags.String("dir", "manifests", "directory of schedule manifests (.yaml, .yml, .json)")
+	syncOpts := services.SyncOptions{}
+	syncFlags.BoolVar(&syncOpts.DryRun, "dry-run", false, "print the plan and check every change without saving")
+	syncFlags.BoolVar(&syncOpts.Prune, "prune", false, "deactivate managed configs that no manifest declares")
+	syncFlags.StringVar(&syncOpts.Actor, "actor", "sync", "performed_by of the audit entries")
+	syncFlags.BoolVar(&syncOpts.CostOverride, "cost-override", false, "save queries above the load score threshold")
+	if command == "sync" {
+		syncFlags.Parse(flag.Args()[1:])
+	}
+
 	// Load configuration
 	config.LoadConfig()
 
 	// Initialize logger
 	config.InitLogger()
 
+	// Load credential encryption keys
+	if err := utils.InitSecretKeys(config.Config.EncryptionKeys, config.Config.EncryptionActiveKeyID); err != nil {
+		log.Fatalf("Invalid encryption key configuration: %v", err)
+	}
+	if !utils.SecretEncryptionEnabled() {
+		log.Println("⚠️  ENCRYPTION_KEYS not set, datasource and delivery credentials are stored unencrypted")
+	}
+
+	// Resolve vault:// secret references against the configured Vault server
+	if config.Config.VaultAddr != "" {
+		utils.RegisterSecretResolver("vault", utils.NewVaultSecretResolver(config.Config.VaultAddr, config.Config.VaultToken, 10*time.Second))
+	}
+
 	// Connect to database
 	config.ConnectDB()
 
+	switch command {
+	case "serve":
+		runServer()
+	case "worker":
+		runWorker()
+	case "reencrypt":
+		runReencrypt()
+	case "sync":
+		runSync(*syncDir, syncOpts)
+	case "migrate":
+		runMigrate()
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// runServer runs the HTTP API along with the outbox relay, result consumer and scheduler
+func runServer() {
 	// Initialize Kafka producer
 	if err := services.InitKafkaProducer(); err != nil {
 		log.Fatalf("Failed to initialize Kafka producer: %v", err)
 	}
 
+	// Start outbox relay (publishes queued execution requests to Kafka)
+	outboxRelay := services.NewOutboxRelay()
+	outboxRelay.Start()
+
+	// Start execution result consumer (applies worker outcomes to executions and schedules)
+	var resultConsumer *services.KafkaConsumer
+	if topic := services.ExecutionResultTopic(); topic != "" {
+		consumer, err := services.NewKafkaConsumer(
+			"execution-results",
+			services.ExecutionResultConsumerGroup(),
+			[]string{topic},
+			services.NewExecutionResultService().HandleMessage,
+		)
+		if err != nil {
+			log.Fatalf("Failed to initialize execution result consumer: %v", err)
+		}
+		resultConsumer = consumer
+		resultConsumer.Start()
+	} else {
+		log.Println("⚠️  KAFKA_TOPIC_EXECUTION_RESULTS not set, results published by external workers are not consumed and their executions stay queued")
+	}
+
+	// Start schedule dispatcher
+	var dispatcher *services.ScheduleDispatcher
+	if config.Config.SchedulerEnabled {
+		dispatcher = services.NewScheduleDispatcher()
+		dispatcher.Start()
+	}
+
 	// Initialize Fiber app
 	app := fiber.New(fiber.Config{
 		AppName: "Scheduling Report System v1.0",
@@ -53,9 +147,7 @@ func main() {
 	log.Printf("📋 API: http://localhost%s/api/report-configs", port)
 
 	// Graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
-	<-quit
+	waitForShutdown()
 
 	log.Println("🛑 Shutting down server...")
 
@@ -63,6 +155,19 @@ func main() {
 		log.Fatalf("Error shutting down Fiber: %v", err)
 	}
 
+	// Stop schedule dispatcher and outbox relay before the producer they publish through
+	if dispatcher != nil {
+		dispatcher.Stop()
+	}
+	outboxRelay.Stop()
+
+	// Stop execution result consumer
+	if resultConsumer != nil {
+		if err := resultConsumer.Stop(); err != nil {
+			log.Printf("Error closing execution result consumer: %v", err)
+		}
+	}
+
 	// Close Kafka producer
 	if kafkaProducer := services.GetKafkaProducer(); kafkaProducer != nil {
 		if err := kafkaProducer.Close(); err != nil {
@@ -72,3 +177,97 @@ func main() {
 
 	log.Println("✅ Server exited gracefully")
 }
+
+// runWorker consumes execution requests and runs the reports they reference
+func runWorker() {
+	topic := services.ExecutionRequestTopic()
+	if topic == "" {
+		log.Fatalf("KAFKA_TOPIC_EXECUTION_REQUESTS is required in worker mode")
+	}
+
+	worker := services.NewReportWorker()
+	consumer, err := services.NewKafkaConsumer(
+		"report-worker",
+		services.WorkerConsumerGroup(),
+		[]string{topic},
+		worker.HandleMessage,
+	)
+	if err != nil {
+		log.Fatalf("Failed to initialize report worker consumer: %v", err)
+	}
+	consumer.Start()
+	log.Printf("✅ Report worker is consuming %s", topic)
+
+	// Re-queue or fail executions left running by a worker that died mid-run
+	recovery := services.NewStaleExecutionRecovery()
+	recovery.Start()
+
+	// Graceful shutdown
+	waitForShutdown()
+
+	log.Println("🛑 Shutting down worker...")
+
+	recovery.Stop()
+
+	if err := consumer.Stop(); err != nil {
+		log.Printf("Error closing report worker consumer: %v", err)
+	}
+
+	log.Println("✅ Worker exited gracefully")
+}
+
+// runReencrypt rewrites every stored credential under the active encryption key, for key rotation
+func runReencrypt() {
+	summary, err := services.NewSecretReencryptionService().ReencryptAll()
+	if err != nil {
+		log.Fatalf("Failed to re-encrypt credentials: %v", err)
+	}
+	log.Printf("✅ Re-encrypted %d datasources and %d deliveries with key %s", summary.Datasources, summary.Deliveries, summary.ActiveKeyID)
+}
+
+// runMigrate applies the embedded schema migrations that the database has not seen yet
+func runMigrate() {
+	versions, err := migrations.Apply(config.DB)
+	for _, version := range versions {
+		log.Printf("Applied migration %s", version)
+	}
+	if err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+	log.Printf("✅ Database schema is up to date (%d migrations applied)", len(versions))
+}
+
+// runSync applies a manifest directory and prints the plan, one line per slug
+func runSync(dir string, opts services.SyncOptions) {
+	plan, err := services.NewScheduleSyncService().SyncManifests(dir, opts)
+	if err != nil {
+		log.Fatalf("Sync failed: %v", err)
+	}
+
+	counts := map[string]int{}
+	for _, item := range plan {
+		counts[item.Action]++
+		line := fmt.Sprintf("%-10s %s (%s)", item.Action, item.Slug, item.ReportName)
+		if len(item.Changes) > 0 {
+			line += " changes: " + strings.Join(item.Changes, ", ")
+		}
+		if item.Drifted {
+			line += " [drifted: edited outside sync]"
+		}
+		log.Println(line)
+	}
+
+	mode := "Applied"
+	if opts.DryRun {
+		mode = "Dry run, nothing saved:"
+	}
+	log.Printf("✅ %s %d create, %d update, %d deactivate, %d unchanged", mode,
+		counts[models.SyncActionCreate], counts[models.SyncActionUpdate], counts[models.SyncActionDeactivate], counts[models.SyncActionNoop])
+}
+
+// waitForShutdown blocks until the process receives an interrupt or termination signal
+func waitForShutdown() {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
+	<-quit
+}
@@ -0,0 +1,9 @@
+-- Lease row that elects a single schedule dispatcher across replicas
+CREATE TABLE scheduler_leases (
+    name        VARCHAR(100) NOT NULL,
+    holder_id   VARCHAR(200) NOT NULL,
+    acquired_at DATETIME(3)  NOT NULL,
+    expires_at  DATETIME(3)  NOT NULL,
+    PRIMARY KEY (name),
+    INDEX idx_scheduler_leases_expires_at (expires_at)
+);
@@ -0,0 +1,4 @@
+-- Per-schedule misfire handling for runs missed while the dispatcher was down
+ALTER TABLE report_schedules
+    ADD COLUMN misfire_policy ENUM('skip', 'run_once', 'run_all') NOT NULL DEFAULT 'run_once',
+    ADD COLUMN misfire_max_runs INT NOT NULL DEFAULT 10;
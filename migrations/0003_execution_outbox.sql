@@ -0,0 +1,18 @@
+-- Transactional outbox for execution requests, relayed to Kafka
+CREATE TABLE report_execution_outbox (
+    id              BIGINT       NOT NULL AUTO_INCREMENT,
+    execution_id    VARCHAR(36)  NOT NULL,
+    topic           VARCHAR(200) NOT NULL,
+    message_key     VARCHAR(200) NOT NULL,
+    payload         TEXT         NOT NULL,
+    status          ENUM('pending', 'sent') NOT NULL DEFAULT 'pending',
+    attempts        INT          NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME(3)  NOT NULL,
+    last_error      TEXT         NULL,
+    claimed_by      VARCHAR(200) NULL,
+    created_at      DATETIME(3)  NULL DEFAULT CURRENT_TIMESTAMP(3),
+    sent_at         DATETIME(3)  NULL,
+    PRIMARY KEY (id),
+    INDEX idx_report_execution_outbox_execution_id (execution_id),
+    INDEX idx_outbox_pending (status, next_attempt_at)
+);
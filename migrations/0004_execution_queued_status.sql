@@ -0,0 +1,3 @@
+-- Executions are created queued and claimed by a worker
+ALTER TABLE report_executions
+    MODIFY COLUMN status ENUM('queued', 'running', 'completed', 'failed', 'cancelled') NOT NULL DEFAULT 'running';
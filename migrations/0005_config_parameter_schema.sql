@@ -0,0 +1,3 @@
+-- Typed parameter definitions for report configs; NULL means parameters are untyped
+ALTER TABLE report_configs
+    ADD COLUMN parameter_schema JSON NULL AFTER parameters;
@@ -0,0 +1,34 @@
+-- One snapshot of the versioned fields of a report config per config version
+CREATE TABLE report_config_versions (
+    id            BIGINT       NOT NULL AUTO_INCREMENT,
+    config_id     INT          NOT NULL,
+    version       INT          NOT NULL,
+    snapshot      JSON         NOT NULL,
+    change_type   ENUM('create', 'update', 'rollback') NOT NULL,
+    restored_from INT          NULL,
+    created_by    VARCHAR(100) NOT NULL,
+    created_at    DATETIME(3)  NULL DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_config_version (config_id, version)
+);
+
+-- Backfill a baseline snapshot for configs saved before versioning, at the version they are at
+-- (1 unless they were edited), so each has a version to diff against and roll back to
+INSERT INTO report_config_versions (config_id, version, snapshot, change_type, created_by, created_at)
+SELECT c.id, c.version,
+       JSON_OBJECT(
+           'report_name', c.report_name,
+           'report_query', c.report_query,
+           'output_format', c.output_format,
+           'datasource_id', c.datasource_id,
+           'file_name', c.file_name,
+           'parameters', c.parameters,
+           'parameter_schema', c.parameter_schema,
+           'timeout_seconds', c.timeout_seconds,
+           'max_rows', c.max_rows
+       ),
+       'create', c.updated_by, c.updated_at
+FROM report_configs c
+WHERE NOT EXISTS (
+    SELECT 1 FROM report_config_versions v WHERE v.config_id = c.id AND v.version = c.version
+);
@@ -0,0 +1,3 @@
+-- Restoring a config version is audited as a rollback
+ALTER TABLE report_config_audits
+    MODIFY COLUMN action ENUM('create', 'update', 'delete', 'activate', 'deactivate', 'rollback') NOT NULL;
@@ -0,0 +1,9 @@
+-- Optimistic concurrency: bumped on every edit and returned as the ETag
+ALTER TABLE report_schedules
+    ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE report_deliveries
+    ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE report_delivery_recipients
+    ADD COLUMN version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+-- Cloned complete schedules are audited as clones linked to their source
+ALTER TABLE report_config_audits
+    MODIFY COLUMN action ENUM('create', 'update', 'delete', 'activate', 'deactivate', 'rollback', 'clone') NOT NULL;
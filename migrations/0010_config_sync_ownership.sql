@@ -0,0 +1,7 @@
+-- Configs owned by manifest sync: the manager, the manifest slug and the checksum of the state it last applied
+ALTER TABLE report_configs
+    ADD COLUMN managed_by VARCHAR(50) NULL,
+    ADD COLUMN slug VARCHAR(200) NULL,
+    ADD COLUMN managed_checksum VARCHAR(64) NULL,
+    ADD INDEX idx_report_configs_managed_by (managed_by),
+    ADD UNIQUE INDEX idx_report_configs_slug (slug);
@@ -0,0 +1,42 @@
+-- Catalog metadata for report configs: nested folders, tags and ownership
+CREATE TABLE report_folders (
+    id          INT          NOT NULL AUTO_INCREMENT,
+    name        VARCHAR(100) NOT NULL,
+    parent_id   INT          NULL,
+    path        VARCHAR(500) NOT NULL,
+    description TEXT         NULL,
+    created_at  DATETIME     NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  DATETIME     NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    created_by  VARCHAR(100) NOT NULL,
+    updated_by  VARCHAR(100) NOT NULL,
+    PRIMARY KEY (id),
+    INDEX idx_report_folders_parent_id (parent_id),
+    UNIQUE INDEX idx_report_folders_path (path)
+);
+
+CREATE TABLE report_tags (
+    id          INT          NOT NULL AUTO_INCREMENT,
+    name        VARCHAR(50)  NOT NULL,
+    color       VARCHAR(20)  NULL,
+    description TEXT         NULL,
+    created_at  DATETIME     NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  DATETIME     NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    created_by  VARCHAR(100) NOT NULL,
+    updated_by  VARCHAR(100) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_report_tags_name (name)
+);
+
+CREATE TABLE report_config_tags (
+    config_id INT NOT NULL,
+    tag_id    INT NOT NULL,
+    PRIMARY KEY (config_id, tag_id),
+    INDEX idx_report_config_tags_tag_id (tag_id)
+);
+
+ALTER TABLE report_configs
+    ADD COLUMN folder_id INT NULL,
+    ADD COLUMN owner_team VARCHAR(100) NULL,
+    ADD COLUMN owners JSON NULL,
+    ADD INDEX idx_report_configs_folder_id (folder_id),
+    ADD INDEX idx_report_configs_owner_team (owner_team);
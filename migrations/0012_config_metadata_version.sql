@@ -0,0 +1,4 @@
+-- Optimistic concurrency for catalog metadata: bumped on every folder, ownership or tag change
+-- and returned as the ETag of PUT /api/report-configs/:id/metadata
+ALTER TABLE report_configs
+    ADD COLUMN metadata_version INT NOT NULL DEFAULT 1;
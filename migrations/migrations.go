@@ -0,0 +1,121 @@
+// Package migrations holds the schema changes applied by the `migrate` command.
+//
+// Each change is a NNNN_description.sql file of MySQL statements separated by semicolons at
+// the end of a line. Files are applied once each, in file name order, and recorded in
+// schema_migrations. Tables that predate the migrations are expected to exist already.
+// MySQL commits DDL implicitly, so a file that fails halfway must be finished by hand
+// before the command is run again.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Migration is one embedded schema change
+type Migration struct {
+	Version    string // file name without .sql, e.g. 0001_scheduler_leases
+	Statements []string
+}
+
+// All returns the embedded migrations in the order they are applied
+func All() ([]Migration, error) {
+	names, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	migrations := make([]Migration, 0, len(names))
+	for _, name := range names {
+		content, err := files.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{
+			Version:    strings.TrimSuffix(name, ".sql"),
+			Statements: splitStatements(string(content)),
+		})
+	}
+	return migrations, nil
+}
+
+// Apply runs every migration not yet recorded in schema_migrations and returns the versions it applied
+func Apply(db *gorm.DB) ([]string, error) {
+	if err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version VARCHAR(255) NOT NULL PRIMARY KEY,
+		applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`).Error; err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var done []string
+	if err := db.Table("schema_migrations").Pluck("version", &done).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	applied := map[string]bool{}
+	for _, version := range done {
+		applied[version] = true
+	}
+
+	migrations, err := All()
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, migration := range migrations {
+		if applied[migration.Version] {
+			continue
+		}
+
+		for i, statement := range migration.Statements {
+			if err := db.Exec(statement).Error; err != nil {
+				return versions, fmt.Errorf("migration %s statement %d failed: %w", migration.Version, i+1, err)
+			}
+		}
+
+		if err := db.Exec("INSERT INTO schema_migrations (version) VALUES (?)", migration.Version).Error; err != nil {
+			return versions, fmt.Errorf("failed to record migration %s: %w", migration.Version, err)
+		}
+		versions = append(versions, migration.Version)
+	}
+
+	return versions, nil
+}
+
+// splitStatements splits a migration file on semicolons that end a line, dropping -- comment lines
+func splitStatements(content string) []string {
+	var statements []string
+	var current strings.Builder
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if strings.HasSuffix(trimmed, ";") {
+			statement := strings.TrimSuffix(strings.TrimSpace(current.String()), ";")
+			statements = append(statements, statement)
+			current.Reset()
+		}
+	}
+
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+
+	return statements
+}
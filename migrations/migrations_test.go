@@ -0,0 +1,128 @@
+package migrations
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"scheduling-report/models"
+
+	"gorm.io/gorm/schema"
+)
+
+func TestAllMigrationsAreNumberedAndNonEmpty(t *testing.T) {
+	migrations, err := All()
+	if err != nil {
+		t.Fatalf("All() error = %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+
+	name := regexp.MustCompile(`^\d{4}_[a-z0-9_]+$`)
+	seen := map[string]bool{}
+	for _, migration := range migrations {
+		if !name.MatchString(migration.Version) {
+			t.Errorf("migration %q is not named NNNN_description", migration.Version)
+		}
+		prefix := migration.Version[:4]
+		if seen[prefix] {
+			t.Errorf("migration number %s is used twice", prefix)
+		}
+		seen[prefix] = true
+		if len(migration.Statements) == 0 {
+			t.Errorf("migration %s has no statements", migration.Version)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	content := `-- comment
+CREATE TABLE a (
+    id INT -- trailing comment stays
+);
+
+ALTER TABLE a ADD COLUMN b VARCHAR(10) DEFAULT ';';
+UPDATE a SET b = 'x'`
+
+	want := []string{
+		"CREATE TABLE a (\n    id INT -- trailing comment stays\n)",
+		"ALTER TABLE a ADD COLUMN b VARCHAR(10) DEFAULT ';'",
+		"UPDATE a SET b = 'x'",
+	}
+	if got := splitStatements(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("splitStatements() = %q, want %q", got, want)
+	}
+}
+
+var (
+	createTable = regexp.MustCompile(`(?is)^CREATE TABLE (\w+) \((.*)\)$`)
+	alterTable  = regexp.MustCompile(`(?is)^ALTER TABLE (\w+)\s`)
+	addColumn   = regexp.MustCompile(`(?i)ADD COLUMN (\w+)`)
+)
+
+// migratedColumns lists the columns the migrations create or add, by table
+func migratedColumns(t *testing.T) map[string]map[string]bool {
+	t.Helper()
+
+	migrations, err := All()
+	if err != nil {
+		t.Fatalf("All() error = %v", err)
+	}
+	columns := map[string]map[string]bool{}
+	add := func(table string, column string) {
+		if columns[table] == nil {
+			columns[table] = map[string]bool{}
+		}
+		columns[table][column] = true
+	}
+	for _, migration := range migrations {
+		for _, statement := range migration.Statements {
+			if match := createTable.FindStringSubmatch(statement); match != nil {
+				for _, line := range strings.Split(match[2], "\n") {
+					fields := strings.Fields(line)
+					if len(fields) == 0 {
+						continue
+					}
+					switch strings.ToUpper(fields[0]) {
+					case "PRIMARY", "INDEX", "UNIQUE", "KEY", "CONSTRAINT":
+					default:
+						add(match[1], fields[0])
+					}
+				}
+			}
+			if match := alterTable.FindStringSubmatch(statement); match != nil {
+				for _, column := range addColumn.FindAllStringSubmatch(statement, -1) {
+					add(match[1], column[1])
+				}
+			}
+		}
+	}
+	return columns
+}
+
+func TestConfigCatalogMigrationMatchesModels(t *testing.T) {
+	columns := migratedColumns(t)
+
+	// The catalog tables are created by 0011_config_catalog with every column of their models
+	for _, model := range []interface{}{&models.ReportFolder{}, &models.ReportTag{}, &models.ReportConfigTag{}} {
+		parsed, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+		if err != nil {
+			t.Fatalf("parse %T: %v", model, err)
+		}
+		for _, field := range parsed.Fields {
+			if field.DBName != "" && !columns[parsed.Table][field.DBName] {
+				t.Errorf("%s.%s is not created by a migration", parsed.Table, field.DBName)
+			}
+		}
+	}
+
+	// report_configs predates the migrations; only its catalog columns are added by them
+	for _, column := range []string{"folder_id", "owner_team", "owners", "metadata_version"} {
+		if !columns["report_configs"][column] {
+			t.Errorf("report_configs.%s is not added by a migration", column)
+		}
+	}
+}
@@ -8,8 +8,11 @@ type CompleteScheduleRequest struct {
 	Timezone       string                       `json:"timezone" validate:"required"`
 	IsActive       bool                         `json:"is_active"`
 	LastRunAt      *CustomTime                  `json:"last_run_at"`
+	MisfirePolicy  string                       `json:"misfire_policy" validate:"omitempty,oneof=skip run_once run_all"`
+	MisfireMaxRuns *int                         `json:"misfire_max_runs" validate:"omitempty,min=1,max=1000"`
 	CreatedBy      string                       `json:"created_by" validate:"required"`
 	UpdatedBy      string                       `json:"updated_by"`
+	CostOverride   bool                         `json:"cost_override"` // Save even if the query's load score is above the threshold
 	Configs        ConfigWithDeliveriesRequest  `json:"configs" validate:"required"`
 }
 
@@ -19,10 +22,25 @@ type CompleteScheduleUpdateRequest struct {
 	Timezone       *string                       `json:"timezone"`
 	IsActive       *bool                         `json:"is_active"`
 	LastRunAt      *CustomTime                   `json:"last_run_at"`
+	MisfirePolicy  *string                       `json:"misfire_policy" validate:"omitempty,oneof=skip run_once run_all"`
+	MisfireMaxRuns *int                          `json:"misfire_max_runs" validate:"omitempty,min=1,max=1000"`
 	UpdatedBy      string                        `json:"updated_by" validate:"required"`
+	CostOverride   bool                          `json:"cost_override"` // Save even if the query's load score is above the threshold
+	IfMatch        string                        `json:"-"`             // ETag from the If-Match header; empty skips the check
 	Configs        *ConfigWithDeliveriesRequest  `json:"configs"`
 }
 
+// CompleteScheduleCloneRequest overrides fields of a cloned schedule; anything unset is copied from the source
+type CompleteScheduleCloneRequest struct {
+	ReportName     *string `json:"report_name" validate:"omitempty,min=3,max=200"` // defaults to "<source name> (copy)"
+	CronExpression *string `json:"cron_expression"`
+	Timezone       *string `json:"timezone"`
+	DatasourceID   *int    `json:"datasource_id"`
+	IsActive       *bool   `json:"is_active"` // defaults to false
+	CreatedBy      string  `json:"created_by" validate:"required"`
+	CostOverride   bool    `json:"cost_override"` // Save even if the query's load score is above the threshold
+}
+
 // ConfigWithDeliveriesRequest represents report config with nested deliveries for create/update
 type ConfigWithDeliveriesRequest struct {
 	ReportName     string                        `json:"report_name" validate:"required"`
@@ -31,6 +49,7 @@ type ConfigWithDeliveriesRequest struct {
 	DatasourceID   int                           `json:"datasource_id" validate:"required"`
 	FileName       *string                       `json:"file_name"`
 	Parameters     json.RawMessage               `json:"parameters"`
+	ParameterSchema ParameterSchema              `json:"parameter_schema"`
 	TimeoutSeconds *int                          `json:"timeout_seconds"`
 	MaxRows        *int                          `json:"max_rows"`
 	Deliveries     []DeliveryWithRecipientsRequest `json:"deliveries" validate:"required,min=1"`
@@ -50,9 +69,11 @@ type DeliveryWithRecipientsRequest struct {
 
 // RecipientRequest represents recipient in nested structure for create/update
 type RecipientRequest struct {
-	ID             *int   `json:"id"` // For updates - if provided, update existing
-	RecipientValue string `json:"recipient_value" validate:"required"`
-	IsActive       *bool  `json:"is_active"`
+	ID              *int            `json:"id"`             // For updates - if provided, update existing
+	RecipientType   string          `json:"recipient_type"` // Defaults to email on create, kept on update when empty
+	RecipientValue  string          `json:"recipient_value" validate:"required"`
+	RecipientConfig RecipientConfig `json:"recipient_config"`
+	IsActive        *bool           `json:"is_active"`
 }
 
 // CompleteScheduleResponse represents the full schedule response
@@ -64,11 +85,15 @@ type CompleteScheduleResponse struct {
 	IsActive       bool                     `json:"is_active"`
 	LastRunAt      *CustomTime              `json:"last_run_at"`
 	NextRunAt      *CustomTime              `json:"next_run_at"`
+	MisfirePolicy  string                   `json:"misfire_policy"`
+	MisfireMaxRuns int                      `json:"misfire_max_runs"`
 	CreatedAt      CustomTime               `json:"created_at"`
 	UpdatedAt      CustomTime               `json:"updated_at"`
 	CreatedBy      string                   `json:"created_by"`
 	UpdatedBy      string                   `json:"updated_by"`
 	Config         ConfigResponseNested     `json:"config"`
+	// ETag covers the schedule, config, deliveries and recipients; sent as the ETag header, not in the body
+	ETag string `json:"-"`
 }
 
 // ConfigResponseNested for response
@@ -80,6 +105,7 @@ type ConfigResponseNested struct {
 	DatasourceID   int                      `json:"datasource_id"`
 	FileName       *string                  `json:"file_name"`
 	Parameters     json.RawMessage          `json:"parameters"`
+	ParameterSchema ParameterSchema         `json:"parameter_schema"`
 	TimeoutSeconds int                      `json:"timeout_seconds"`
 	MaxRows        int                      `json:"max_rows"`
 	IsActive       bool                     `json:"is_active"`
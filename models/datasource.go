@@ -3,20 +3,22 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+
+	"scheduling-report/utils"
 )
 
 // ConnectionConfig stores additional connection parameters as JSON
 type ConnectionConfig map[string]interface{}
 
-// Value implements driver.Valuer for JSON marshaling
+// Value implements driver.Valuer for JSON marshaling, encrypting secret fields
 func (c ConnectionConfig) Value() (driver.Value, error) {
 	if c == nil {
 		return nil, nil
 	}
-	return json.Marshal(c)
+	return encryptSecretFields(c)
 }
 
-// Scan implements sql.Scanner for JSON unmarshaling
+// Scan implements sql.Scanner for JSON unmarshaling, decrypting secret fields
 func (c *ConnectionConfig) Scan(value interface{}) error {
 	if value == nil {
 		*c = nil
@@ -26,14 +28,27 @@ func (c *ConnectionConfig) Scan(value interface{}) error {
 	if !ok {
 		return nil
 	}
-	return json.Unmarshal(bytes, c)
+	config, err := decryptSecretFields(bytes)
+	if err != nil {
+		return err
+	}
+	*c = config
+	return nil
+}
+
+// MarshalJSON masks secret fields so API responses never return them
+func (c ConnectionConfig) MarshalJSON() ([]byte, error) {
+	if c == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(utils.MaskSecretFields(c))
 }
 
 // DataSource matches report_datasources table schema
 type DataSource struct {
 	ID               int              `gorm:"primaryKey;autoIncrement" json:"id"`
 	Name             string           `gorm:"size:100;not null;uniqueIndex" json:"name"`
-	ConnectionURL    string           `gorm:"type:text;not null;column:connection_url" json:"connection_url"`
+	ConnectionURL    EncryptedString  `gorm:"type:text;not null;column:connection_url" json:"connection_url"`
 	DbType           string           `gorm:"type:enum('mysql','postgresql','oracle','sqlserver','mongodb','bigquery','snowflake');not null;index;column:db_type" json:"db_type"`
 	ConnectionConfig ConnectionConfig `gorm:"type:json;column:connection_config" json:"connection_config"`
 	IsActive         bool             `gorm:"not null;default:1;index;column:is_active" json:"is_active"`
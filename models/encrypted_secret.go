@@ -0,0 +1,64 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"scheduling-report/utils"
+)
+
+// EncryptedString is a credential column (e.g. a connection URL) that is envelope-encrypted at rest.
+// It holds plaintext in memory and is masked when marshalled to JSON.
+type EncryptedString string
+
+// Value implements driver.Valuer, encrypting with the active master key
+func (s EncryptedString) Value() (driver.Value, error) {
+	return utils.EncryptSecret(string(s))
+}
+
+// Scan implements sql.Scanner, decrypting with the key the value was written with
+func (s *EncryptedString) Scan(value interface{}) error {
+	var raw string
+	switch v := value.(type) {
+	case nil:
+		*s = ""
+		return nil
+	case []byte:
+		raw = string(v)
+	case string:
+		raw = v
+	default:
+		return fmt.Errorf("cannot scan %T into EncryptedString", value)
+	}
+
+	plaintext, err := utils.DecryptSecret(raw)
+	if err != nil {
+		return err
+	}
+	*s = EncryptedString(plaintext)
+	return nil
+}
+
+// MarshalJSON never exposes the password part of the value
+func (s EncryptedString) MarshalJSON() ([]byte, error) {
+	return json.Marshal(utils.MaskConnectionURL(string(s)))
+}
+
+// encryptSecretFields marshals a config map with its secret fields encrypted
+func encryptSecretFields(config map[string]interface{}) (driver.Value, error) {
+	encrypted, err := utils.TransformSecretFields(config, utils.EncryptSecret)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(encrypted)
+}
+
+// decryptSecretFields unmarshals a config column and decrypts its secret fields
+func decryptSecretFields(data []byte) (map[string]interface{}, error) {
+	var config map[string]interface{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return utils.TransformSecretFields(config, utils.DecryptSecret)
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"scheduling-report/utils"
+)
+
+// useSecretKey encrypts with a single test key until the test ends
+func useSecretKey(t *testing.T) {
+	t.Helper()
+
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	if err := utils.InitSecretKeys("k1:"+key, "k1"); err != nil {
+		t.Fatalf("InitSecretKeys: %v", err)
+	}
+	t.Cleanup(func() { _ = utils.InitSecretKeys("", "") })
+}
+
+func TestEncryptedStringValueAndScan(t *testing.T) {
+	useSecretKey(t)
+
+	original := EncryptedString("report:s3cret@tcp(db:3306)/reports")
+	stored, err := original.Value()
+	if err != nil {
+		t.Fatalf("Value: %v", err)
+	}
+	raw, ok := stored.(string)
+	if !ok || utils.SecretKeyID(raw) != "k1" {
+		t.Fatalf("stored value = %#v, want a string encrypted with k1", stored)
+	}
+
+	for _, column := range []interface{}{raw, []byte(raw)} {
+		var scanned EncryptedString
+		if err := scanned.Scan(column); err != nil {
+			t.Fatalf("Scan(%T): %v", column, err)
+		}
+		if scanned != original {
+			t.Errorf("Scan(%T) = %q, want %q", column, scanned, original)
+		}
+	}
+
+	// Rows written before encryption was enabled still read back
+	var legacy EncryptedString
+	if err := legacy.Scan("report:plain@tcp(db:3306)/reports"); err != nil || legacy != "report:plain@tcp(db:3306)/reports" {
+		t.Errorf("Scan(plaintext) = %q, %v; want the plaintext", legacy, err)
+	}
+
+	var empty EncryptedString = "leftover"
+	if err := empty.Scan(nil); err != nil || empty != "" {
+		t.Errorf("Scan(nil) = %q, %v; want an empty value", empty, err)
+	}
+	if err := empty.Scan(42); err == nil {
+		t.Error("Scan(int) succeeded, want an error")
+	}
+}
+
+func TestEncryptedStringMarshalJSONMasksPassword(t *testing.T) {
+	tests := []struct {
+		value EncryptedString
+		want  string
+	}{
+		{value: "report:s3cret@tcp(db:3306)/reports", want: `"report:***MASKED***@tcp(db:3306)/reports"`},
+		{value: "postgres://report:s3cret@db:5432/reports", want: `"postgres://report:***MASKED***@db:5432/reports"`},
+		{value: "vault://secret/data/reports#dsn", want: `"vault://secret/data/reports#dsn"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.value), func(t *testing.T) {
+			got, err := json.Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("JSON = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliveryConfigEncryptsOnlySecretFields(t *testing.T) {
+	useSecretKey(t)
+
+	deliveryConfig := DeliveryConfig{"host": "smtp.example.com", "smtp_password": "s3cret"}
+	stored, err := deliveryConfig.Value()
+	if err != nil {
+		t.Fatalf("Value: %v", err)
+	}
+
+	var columns map[string]interface{}
+	if err := json.Unmarshal(stored.([]byte), &columns); err != nil {
+		t.Fatalf("unmarshal stored config: %v", err)
+	}
+	if columns["host"] != "smtp.example.com" {
+		t.Errorf("host stored as %v, want plaintext", columns["host"])
+	}
+	if password, _ := columns["smtp_password"].(string); !utils.IsEncryptedSecret(password) {
+		t.Errorf("smtp_password stored as %v, want it encrypted", columns["smtp_password"])
+	}
+
+	var scanned DeliveryConfig
+	if err := scanned.Scan(stored); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if scanned["smtp_password"] != "s3cret" {
+		t.Errorf("scanned smtp_password = %v, want it decrypted", scanned["smtp_password"])
+	}
+
+	masked, err := json.Marshal(scanned)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if strings.Contains(string(masked), "s3cret") || !strings.Contains(string(masked), utils.MaskedSecret) {
+		t.Errorf("JSON = %s, want smtp_password masked", masked)
+	}
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Outbox statuses
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+)
+
+// ExecutionOutbox holds Kafka messages written in the same transaction as their report_executions row.
+// The outbox relay claims pending rows, publishes them and marks them sent, giving at-least-once delivery.
+// While claimed_by is set, next_attempt_at is the end of that relay's claim lease.
+type ExecutionOutbox struct {
+	ID            int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ExecutionID   string     `gorm:"size:36;not null;index;column:execution_id" json:"execution_id"`
+	Topic         string     `gorm:"size:200;not null;column:topic" json:"topic"`
+	MessageKey    string     `gorm:"size:200;not null;column:message_key" json:"message_key"`
+	Payload       string     `gorm:"type:text;not null;column:payload" json:"payload"`
+	Status        string     `gorm:"type:enum('pending','sent');not null;default:'pending';index:idx_outbox_pending,priority:1;column:status" json:"status"`
+	Attempts      int        `gorm:"not null;default:0;column:attempts" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;index:idx_outbox_pending,priority:2;column:next_attempt_at" json:"next_attempt_at"`
+	LastError     *string    `gorm:"type:text;column:last_error" json:"last_error"`
+	ClaimedBy     *string    `gorm:"size:200;column:claimed_by" json:"claimed_by"`
+	CreatedAt     time.Time  `gorm:"default:CURRENT_TIMESTAMP;column:created_at" json:"created_at"`
+	SentAt        *time.Time `gorm:"column:sent_at" json:"sent_at"`
+}
+
+func (ExecutionOutbox) TableName() string {
+	return "report_execution_outbox"
+}
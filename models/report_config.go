@@ -29,23 +29,106 @@ func (p *Parameters) Scan(value interface{}) error {
 	return json.Unmarshal(bytes, p)
 }
 
+// Parameter types a report config can declare
+const (
+	ParameterTypeDate     = "date"
+	ParameterTypeDatetime = "datetime"
+	ParameterTypeInt      = "int"
+	ParameterTypeString   = "string"
+	ParameterTypeEnum     = "enum"
+	ParameterTypeList     = "list"
+)
+
+// ParameterDefinition declares one query parameter a report expects
+type ParameterDefinition struct {
+	Name     string        `json:"name"`
+	Type     string        `json:"type"`
+	Default  interface{}   `json:"default,omitempty"`
+	Required bool          `json:"required"`
+	Options  []interface{} `json:"options,omitempty"`   // allowed values for enum
+	ItemType string        `json:"item_type,omitempty"` // element type for list, defaults to string
+}
+
+// ParameterSchema stores a config's parameter definitions as JSON
+type ParameterSchema []ParameterDefinition
+
+// Value implements driver.Valuer for JSON marshaling
+func (p ParameterSchema) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+// Scan implements sql.Scanner for JSON unmarshaling
+func (p *ParameterSchema) Scan(value interface{}) error {
+	if value == nil {
+		*p = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, p)
+}
+
+// StringList stores a list of strings as JSON
+type StringList []string
+
+// Value implements driver.Valuer for JSON marshaling
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	return json.Marshal(l)
+}
+
+// Scan implements sql.Scanner for JSON unmarshaling
+func (l *StringList) Scan(value interface{}) error {
+	if value == nil {
+		*l = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, l)
+}
+
 // ReportConfig matches report_configs table schema
 type ReportConfig struct {
-	ID             int        `gorm:"primaryKey;autoIncrement" json:"id"`
-	ReportName     string     `gorm:"size:200;not null;index;column:report_name" json:"report_name"`
-	ReportQuery    string     `gorm:"type:text;not null;column:report_query" json:"report_query"`
-	OutputFormat   string     `gorm:"size:50;not null;default:'csv';column:output_format" json:"output_format"`
-	DatasourceID   int        `gorm:"not null;index;column:datasource_id" json:"datasource_id"`
-	FileName       *string    `gorm:"size:100;column:file_name" json:"file_name"`
-	Parameters     Parameters `gorm:"type:json;column:parameters" json:"parameters"`
-	TimeoutSeconds int        `gorm:"default:300;column:timeout_seconds" json:"timeout_seconds"`
-	MaxRows        int        `gorm:"default:10000;column:max_rows" json:"max_rows"`
-	IsActive       bool       `gorm:"not null;default:1;index;column:is_active" json:"is_active"`
-	CreatedAt      CustomTime `gorm:"default:CURRENT_TIMESTAMP;index;column:created_at" json:"created_at"`
-	UpdatedAt      CustomTime `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;column:updated_at" json:"updated_at"`
-	CreatedBy      string     `gorm:"size:100;not null;column:created_by" json:"created_by"`
-	UpdatedBy      string     `gorm:"size:100;not null;column:updated_by" json:"updated_by"`
-	Version        int        `gorm:"not null;default:1;column:version" json:"version"`
+	ID           int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	ReportName   string     `gorm:"size:200;not null;index;column:report_name" json:"report_name"`
+	ReportQuery  string     `gorm:"type:text;not null;column:report_query" json:"report_query"`
+	OutputFormat string     `gorm:"size:50;not null;default:'csv';column:output_format" json:"output_format"`
+	DatasourceID int        `gorm:"not null;index;column:datasource_id" json:"datasource_id"`
+	FileName     *string    `gorm:"size:100;column:file_name" json:"file_name"`
+	Parameters   Parameters `gorm:"type:json;column:parameters" json:"parameters"`
+	// ParameterSchema types the parameters; Parameters holds config-level values over the schema defaults
+	ParameterSchema ParameterSchema `gorm:"type:json;column:parameter_schema" json:"parameter_schema"`
+	TimeoutSeconds  int             `gorm:"default:300;column:timeout_seconds" json:"timeout_seconds"`
+	MaxRows         int             `gorm:"default:10000;column:max_rows" json:"max_rows"`
+	IsActive        bool            `gorm:"not null;default:1;index;column:is_active" json:"is_active"`
+	CreatedAt       CustomTime      `gorm:"default:CURRENT_TIMESTAMP;index;column:created_at" json:"created_at"`
+	UpdatedAt       CustomTime      `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;column:updated_at" json:"updated_at"`
+	CreatedBy       string          `gorm:"size:100;not null;column:created_by" json:"created_by"`
+	UpdatedBy       string          `gorm:"size:100;not null;column:updated_by" json:"updated_by"`
+	Version         int             `gorm:"not null;default:1;column:version" json:"version"`
+	// ManagedBy is set on configs owned by manifest sync, keyed there by Slug. ManagedChecksum is the
+	// state sync last applied; a config whose state no longer matches it was edited outside sync (drift).
+	ManagedBy       *string `gorm:"size:50;index;column:managed_by" json:"managed_by"`
+	Slug            *string `gorm:"size:200;uniqueIndex;column:slug" json:"slug"`
+	ManagedChecksum *string `gorm:"size:64;column:managed_checksum" json:"-"`
+	// Catalog metadata: the folder a config is filed in, the team owning it and its individual owners.
+	// Tags are stored in report_config_tags and loaded by the repository. Metadata is not part of
+	// Version; MetadataVersion is bumped on every change of it instead.
+	FolderID        *int       `gorm:"index;column:folder_id" json:"folder_id"`
+	OwnerTeam       *string    `gorm:"size:100;index;column:owner_team" json:"owner_team"`
+	Owners          StringList `gorm:"type:json;column:owners" json:"owners"`
+	Tags            []string   `gorm:"-" json:"tags"`
+	MetadataVersion int        `gorm:"not null;default:1;column:metadata_version" json:"metadata_version"`
 }
 
 func (ReportConfig) TableName() string {
@@ -5,7 +5,7 @@ import "time"
 type ReportConfigAudit struct {
 	ID            int64     `gorm:"primaryKey;autoIncrement" json:"id"`
 	ConfigID      *int      `gorm:"index" json:"config_id"`
-	Action        string    `gorm:"type:enum('create','update','delete','activate','deactivate');not null;index" json:"action"`
+	Action        string    `gorm:"type:enum('create','update','delete','activate','deactivate','rollback','clone');not null;index" json:"action"`
 	FieldName     *string   `gorm:"size:100" json:"field_name"`
 	BeforeValue   *string   `gorm:"type:text" json:"before_value"`
 	AfterValue    *string   `gorm:"type:text" json:"after_value"`
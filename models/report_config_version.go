@@ -0,0 +1,83 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ConfigSnapshot is the versioned content of a report config.
+// is_active is not part of it; activation does not create a version.
+type ConfigSnapshot struct {
+	ReportName      string          `json:"report_name"`
+	ReportQuery     string          `json:"report_query"`
+	OutputFormat    string          `json:"output_format"`
+	DatasourceID    int             `json:"datasource_id"`
+	FileName        *string         `json:"file_name"`
+	Parameters      Parameters      `json:"parameters"`
+	ParameterSchema ParameterSchema `json:"parameter_schema"`
+	TimeoutSeconds  int             `json:"timeout_seconds"`
+	MaxRows         int             `json:"max_rows"`
+}
+
+// NewConfigSnapshot captures the versioned fields of config
+func NewConfigSnapshot(config *ReportConfig) ConfigSnapshot {
+	return ConfigSnapshot{
+		ReportName:      config.ReportName,
+		ReportQuery:     config.ReportQuery,
+		OutputFormat:    config.OutputFormat,
+		DatasourceID:    config.DatasourceID,
+		FileName:        config.FileName,
+		Parameters:      config.Parameters,
+		ParameterSchema: config.ParameterSchema,
+		TimeoutSeconds:  config.TimeoutSeconds,
+		MaxRows:         config.MaxRows,
+	}
+}
+
+// Value implements driver.Valuer for JSON marshaling
+func (s ConfigSnapshot) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+// Scan implements sql.Scanner for JSON unmarshaling
+func (s *ConfigSnapshot) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return errors.New("unsupported type for ConfigSnapshot")
+	}
+	return json.Unmarshal(bytes, s)
+}
+
+// Version change types
+const (
+	VersionChangeCreate   = "create"
+	VersionChangeUpdate   = "update"
+	VersionChangeRollback = "rollback"
+)
+
+// ReportConfigVersion matches report_config_versions table schema: one row per config version
+type ReportConfigVersion struct {
+	ID         int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	ConfigID   int            `gorm:"not null;uniqueIndex:idx_config_version,priority:1;column:config_id" json:"config_id"`
+	Version    int            `gorm:"not null;uniqueIndex:idx_config_version,priority:2;column:version" json:"version"`
+	Snapshot   ConfigSnapshot `gorm:"type:json;not null;column:snapshot" json:"snapshot"`
+	ChangeType string         `gorm:"type:enum('create','update','rollback');not null;column:change_type" json:"change_type"`
+	// RestoredFrom is the version a rollback copied its snapshot from
+	RestoredFrom *int      `gorm:"column:restored_from" json:"restored_from,omitempty"`
+	CreatedBy    string    `gorm:"size:100;not null;column:created_by" json:"created_by"`
+	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP;column:created_at" json:"created_at"`
+}
+
+func (ReportConfigVersion) TableName() string {
+	return "report_config_versions"
+}
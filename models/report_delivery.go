@@ -3,12 +3,15 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+
+	"scheduling-report/utils"
 )
 
 type DeliveryConfig map[string]interface{}
 
+// Value encrypts secret fields (passwords, keys, tokens) before they are stored
 func (dc DeliveryConfig) Value() (driver.Value, error) {
-	return json.Marshal(dc)
+	return encryptSecretFields(dc)
 }
 
 func (dc *DeliveryConfig) Scan(value interface{}) error {
@@ -20,7 +23,20 @@ func (dc *DeliveryConfig) Scan(value interface{}) error {
 	if !ok {
 		return nil
 	}
-	return json.Unmarshal(bytes, dc)
+	config, err := decryptSecretFields(bytes)
+	if err != nil {
+		return err
+	}
+	*dc = config
+	return nil
+}
+
+// MarshalJSON masks secret fields so API responses never return them
+func (dc DeliveryConfig) MarshalJSON() ([]byte, error) {
+	if dc == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(utils.MaskSecretFields(dc))
 }
 
 type ReportDelivery struct {
@@ -36,6 +52,7 @@ type ReportDelivery struct {
 	UpdatedAt        CustomTime      `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;column:updated_at" json:"updated_at"`
 	CreatedBy            string         `gorm:"size:100;not null;column:created_by" json:"created_by"`
 	UpdatedBy            string         `gorm:"size:100;not null;column:updated_by" json:"updated_by"`
+	Version              int            `gorm:"not null;default:1;column:version" json:"version"` // bumped on every edit, used as ETag
 }
 
 func (ReportDelivery) TableName() string {
@@ -32,6 +32,7 @@ type ReportDeliveryRecipient struct {
 	IsActive        bool            `gorm:"not null;default:1;column:is_active" json:"is_active"`
 	CreatedAt        CustomTime       `gorm:"default:CURRENT_TIMESTAMP;column:created_at" json:"created_at"`
 	UpdatedAt        CustomTime       `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;column:updated_at" json:"updated_at"`
+	Version         int             `gorm:"not null;default:1;column:version" json:"version"` // bumped on every edit, used as ETag
 }
 
 func (ReportDeliveryRecipient) TableName() string {
@@ -28,7 +28,7 @@ type ReportExecution struct {
 	ID                   string           `gorm:"primaryKey;type:varchar(36)" json:"id"`
 	ConfigID             int              `gorm:"not null;index;column:config_id" json:"config_id"`
 	ScheduleID           *int             `gorm:"index;column:schedule_id" json:"schedule_id"`
-	Status               string           `gorm:"type:enum('running','completed','failed','cancelled');not null;default:'running';index" json:"status"`
+	Status               string           `gorm:"type:enum('queued','running','completed','failed','cancelled');not null;default:'running';index" json:"status"`
 	StartedAt            time.Time        `gorm:"default:CURRENT_TIMESTAMP;index;column:started_at" json:"started_at"`
 	CompletedAt          *time.Time       `gorm:"column:completed_at" json:"completed_at"`
 	ExecutedBy           string           `gorm:"size:100;not null;index;column:executed_by" json:"executed_by"`
@@ -0,0 +1,19 @@
+package models
+
+// ReportFolder matches report_folders table schema. Folders nest through ParentID;
+// Path is the slash-joined folder names from the root, e.g. "finance/monthly".
+type ReportFolder struct {
+	ID          int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string     `gorm:"size:100;not null;column:name" json:"name"`
+	ParentID    *int       `gorm:"index;column:parent_id" json:"parent_id"`
+	Path        string     `gorm:"size:500;not null;uniqueIndex;column:path" json:"path"`
+	Description *string    `gorm:"type:text;column:description" json:"description"`
+	CreatedAt   CustomTime `gorm:"default:CURRENT_TIMESTAMP;column:created_at" json:"created_at"`
+	UpdatedAt   CustomTime `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;column:updated_at" json:"updated_at"`
+	CreatedBy   string     `gorm:"size:100;not null;column:created_by" json:"created_by"`
+	UpdatedBy   string     `gorm:"size:100;not null;column:updated_by" json:"updated_by"`
+}
+
+func (ReportFolder) TableName() string {
+	return "report_folders"
+}
@@ -1,5 +1,12 @@
 package models
 
+// Misfire policies applied when a schedule's next_run_at is found well in the past
+// (for example after the service was down across several cron ticks)
+const (
+	MisfirePolicySkip    = "skip"     // drop the missed runs and resume from the next occurrence
+	MisfirePolicyRunOnce = "run_once" // run once, covering the whole missed window
+	MisfirePolicyRunAll  = "run_all"  // replay every missed run, up to misfire_max_runs
+)
 
 type ReportSchedule struct {
 	ID             int         `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -9,10 +16,14 @@ type ReportSchedule struct {
 	IsActive       bool        `gorm:"not null;index;column:is_active" json:"is_active"`
 	LastRunAt      *CustomTime `gorm:"column:last_run_at" json:"last_run_at"`
 	NextRunAt      *CustomTime `gorm:"column:next_run_at" json:"next_run_at"`
+	MisfirePolicy  string      `gorm:"type:enum('skip','run_once','run_all');not null;default:'run_once';column:misfire_policy" json:"misfire_policy"`
+	MisfireMaxRuns int         `gorm:"not null;default:10;column:misfire_max_runs" json:"misfire_max_runs"`
 	CreatedAt      CustomTime  `gorm:"default:CURRENT_TIMESTAMP;column:created_at" json:"created_at"`
 	UpdatedAt      CustomTime  `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;column:updated_at" json:"updated_at"`
 	CreatedBy      string      `gorm:"size:100;not null;column:created_by" json:"created_by"`
 	UpdatedBy      string      `gorm:"size:100;not null;column:updated_by" json:"updated_by"`
+	// Version is bumped on every user edit and is the schedule's ETag; runs do not change it
+	Version int `gorm:"not null;default:1;column:version" json:"version"`
 }
 
 func (ReportSchedule) TableName() string {
@@ -0,0 +1,53 @@
+package models
+
+// ReportTag matches report_tags table schema
+type ReportTag struct {
+	ID          int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string     `gorm:"size:50;not null;uniqueIndex;column:name" json:"name"`
+	Color       *string    `gorm:"size:20;column:color" json:"color"`
+	Description *string    `gorm:"type:text;column:description" json:"description"`
+	CreatedAt   CustomTime `gorm:"default:CURRENT_TIMESTAMP;column:created_at" json:"created_at"`
+	UpdatedAt   CustomTime `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;column:updated_at" json:"updated_at"`
+	CreatedBy   string     `gorm:"size:100;not null;column:created_by" json:"created_by"`
+	UpdatedBy   string     `gorm:"size:100;not null;column:updated_by" json:"updated_by"`
+}
+
+func (ReportTag) TableName() string {
+	return "report_tags"
+}
+
+// ReportConfigTag matches report_config_tags table schema: the tags of a config
+type ReportConfigTag struct {
+	ConfigID int `gorm:"primaryKey;column:config_id" json:"config_id"`
+	TagID    int `gorm:"primaryKey;index;column:tag_id" json:"tag_id"`
+}
+
+func (ReportConfigTag) TableName() string {
+	return "report_config_tags"
+}
+
+// ConfigCatalogFilters narrow report configs by catalog metadata that is not a plain column.
+// Used by GET /api/report-configs and /api/schedules/details.
+type ConfigCatalogFilters struct {
+	Tags   string `query:"tags"`   // Comma-separated tag names; configs carrying all of them
+	Folder string `query:"folder"` // Folder path; configs in it or any of its subfolders
+	Owner  string `query:"owner"`  // Configs listing this owner
+}
+
+// BulkRetagRequest is the body of POST /api/report-configs/tags/bulk.
+// Set replaces the tags of every config; otherwise Add and Remove are applied.
+type BulkRetagRequest struct {
+	ConfigIDs   []int     `json:"config_ids" validate:"required,min=1,max=500"`
+	Add         []string  `json:"add"`
+	Remove      []string  `json:"remove"`
+	Set         *[]string `json:"set"`
+	PerformedBy string    `json:"-"`
+}
+
+// BulkRetagResult reports the tags of one config after a bulk re-tag
+type BulkRetagResult struct {
+	ConfigID int      `json:"config_id"`
+	Before   []string `json:"before"`
+	After    []string `json:"after"`
+	Changed  bool     `json:"changed"`
+}
@@ -0,0 +1,103 @@
+package models
+
+import "time"
+
+// Bundle identification. FormatVersion changes whenever a field's meaning changes;
+// importers reject bundles with a newer version than they know.
+const (
+	BundleKind          = "scheduling-report.bundle"
+	BundleFormatVersion = 1
+)
+
+// ScheduleBundle is the portable export of one or more complete schedules.
+//
+// Entities are identified by name rather than ID so a bundle can move between
+// environments: the config by report_name, its datasource by datasource name,
+// its folder by path and its tags by tag name.
+// Secret delivery settings are exported as placeholders "${secret:<name>}" listed in
+// RequiredSecrets; secret references (env://, file://, vault://) are exported as they are.
+type ScheduleBundle struct {
+	Kind            string           `json:"kind"`
+	FormatVersion   int              `json:"format_version"`
+	ExportedAt      time.Time        `json:"exported_at"`
+	ExportedBy      string           `json:"exported_by"`
+	RequiredSecrets []string         `json:"required_secrets,omitempty"`
+	Schedules       []BundleSchedule `json:"schedules"`
+}
+
+// BundleSchedule is one schedule with its config
+type BundleSchedule struct {
+	CronExpression string       `json:"cron_expression"`
+	Timezone       string       `json:"timezone"`
+	IsActive       bool         `json:"is_active"`
+	MisfirePolicy  string       `json:"misfire_policy"`
+	MisfireMaxRuns int          `json:"misfire_max_runs"`
+	Config         BundleConfig `json:"config"`
+}
+
+// BundleConfig is a report config; Datasource is the datasource name
+type BundleConfig struct {
+	ReportName      string                 `json:"report_name"`
+	ReportQuery     string                 `json:"report_query"`
+	OutputFormat    string                 `json:"output_format"`
+	Datasource      string                 `json:"datasource"`
+	FileName        *string                `json:"file_name,omitempty"`
+	Parameters      map[string]interface{} `json:"parameters,omitempty"`
+	ParameterSchema ParameterSchema        `json:"parameter_schema,omitempty"`
+	TimeoutSeconds  int                    `json:"timeout_seconds"`
+	MaxRows         int                    `json:"max_rows"`
+	// Catalog metadata. The folder and tags must exist where the bundle is imported; they are the
+	// config's whole metadata, so overwriting a config clears what the bundle leaves out.
+	Folder     string           `json:"folder,omitempty"`
+	OwnerTeam  *string          `json:"owner_team,omitempty"`
+	Owners     []string         `json:"owners,omitempty"`
+	Tags       []string         `json:"tags,omitempty"`
+	Deliveries []BundleDelivery `json:"deliveries"`
+}
+
+// BundleDelivery is a delivery with its recipients
+type BundleDelivery struct {
+	DeliveryName         string                 `json:"delivery_name"`
+	Method               string                 `json:"method"`
+	MaxRetry             int                    `json:"max_retry"`
+	RetryIntervalMinutes int                    `json:"retry_interval_minutes"`
+	IsActive             bool                   `json:"is_active"`
+	DeliveryConfig       map[string]interface{} `json:"delivery_config"`
+	Recipients           []BundleRecipient      `json:"recipients"`
+}
+
+// BundleRecipient is a delivery recipient
+type BundleRecipient struct {
+	RecipientType   string                 `json:"recipient_type"`
+	RecipientValue  string                 `json:"recipient_value"`
+	RecipientConfig map[string]interface{} `json:"recipient_config,omitempty"`
+	IsActive        bool                   `json:"is_active"`
+}
+
+// Import conflict strategies, applied when a bundled report_name already exists
+const (
+	ImportConflictSkip      = "skip"      // leave the existing config alone
+	ImportConflictOverwrite = "overwrite" // replace the existing config, its first schedule and its deliveries
+	ImportConflictRename    = "rename"    // import under "<name> (imported)"
+)
+
+// ScheduleImportRequest is the body of POST /api/schedules/import, in JSON or YAML
+type ScheduleImportRequest struct {
+	Bundle           ScheduleBundle    `json:"bundle"`
+	DryRun           bool              `json:"dry_run"`
+	ConflictStrategy string            `json:"conflict_strategy" validate:"omitempty,oneof=skip overwrite rename"` // defaults to skip
+	DatasourceMap    map[string]string `json:"datasource_map"`                                                     // bundle datasource name -> local name
+	Secrets          map[string]string `json:"secrets"`                                                            // placeholder name -> value or secret reference
+	CostOverride     bool              `json:"cost_override"`                                                      // Save even if a query's load score is above the threshold
+	ImportedBy       string            `json:"-"`
+}
+
+// ScheduleImportResult reports what importing one bundled schedule did (or would do, in a dry run)
+type ScheduleImportResult struct {
+	ReportName string `json:"report_name"`
+	Action     string `json:"action"` // created, overwritten, renamed or skipped
+	ImportedAs string `json:"imported_as,omitempty"`
+	Datasource string `json:"datasource"`
+	ScheduleID int    `json:"schedule_id,omitempty"` // not set for new schedules in a dry run
+	ConfigID   int    `json:"config_id,omitempty"`
+}
@@ -8,6 +8,8 @@ type ScheduleDetail struct {
 	Timezone       string                `json:"timezone"`
 	IsActive       bool                  `json:"is_active"`
 	LastRunAt      *CustomTime           `json:"last_run_at"`
+	MisfirePolicy  string                `json:"misfire_policy"`
+	MisfireMaxRuns int                   `json:"misfire_max_runs"`
 	CreatedAt      CustomTime            `json:"created_at"`
 	UpdatedAt      CustomTime            `json:"updated_at"`
 	CreatedBy      string                `json:"created_by"`
@@ -22,6 +24,7 @@ type ConfigWithDeliveries struct {
 	OutputFormat   string                   `json:"output_format"`
 	DatasourceID   int                      `json:"datasource_id"`
 	Parameters     Parameters               `json:"parameters"`
+	ParameterSchema ParameterSchema         `json:"parameter_schema"`
 	TimeoutSeconds int                      `json:"timeout_seconds"`
 	MaxRows        int                      `json:"max_rows"`
 	IsActive       bool                     `json:"is_active"`
@@ -30,6 +33,10 @@ type ConfigWithDeliveries struct {
 	CreatedBy      string                   `json:"created_by"`
 	UpdatedBy      string                   `json:"updated_by"`
 	Version        int                      `json:"version"`
+	FolderID       *int                     `json:"folder_id"`
+	OwnerTeam      *string                  `json:"owner_team"`
+	Owners         StringList               `json:"owners"`
+	Tags           []string                 `json:"tags"`
 	Deliveries     []DeliveryWithRecipients `json:"deliveries"`
 }
 
@@ -50,12 +57,9 @@ type DeliveryWithRecipients struct {
 	UpdatedBy            string                    `json:"updated_by"`
 }
 
-// ScheduleDetailFilters represents query filters for schedules/details endpoint
+// ScheduleDetailFilters represents query filters for schedules/details endpoint.
+// Schedule fields (is_active, timezone, config_id, created_by, ...) are filtered with the list query grammar.
 type ScheduleDetailFilters struct {
-	IsActive           *bool   `query:"is_active"`             // Schedule active status
-	Timezone           string  `query:"timezone"`              // Schedule timezone
-	ConfigID           *int    `query:"config_id"`             // Specific config ID
-	CreatedBy          string  `query:"created_by"`            // Schedule creator
 	ConfigIsActive     *bool   `query:"config_is_active"`      // Config active status
 	DatasourceID       *int    `query:"datasource_id"`         // Filter by datasource
 	OutputFormat       string  `query:"output_format"`         // csv, excel, json, pdf
@@ -63,4 +67,7 @@ type ScheduleDetailFilters struct {
 	DeliveryIsActive   *bool   `query:"delivery_is_active"`    // Delivery active status
 	DeliveryMethod     string  `query:"delivery_method"`       // email, sftp, webhook, s3, file_share
 	HasRun             *bool   `query:"has_run"`               // Filter if last_run_at is not null
+	FolderID           *int    `query:"folder_id"`             // Config folder
+	OwnerTeam          string  `query:"owner_team"`            // Config owning team
+	ConfigCatalogFilters                                         // tags, folder (path) and owner
 }
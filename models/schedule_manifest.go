@@ -0,0 +1,42 @@
+package models
+
+// ManagedBySync is the managed_by value of configs owned by manifest sync
+const ManagedBySync = "sync"
+
+// ScheduleManifest is one file of a sync manifest directory: a complete schedule in bundle form,
+// keyed by a slug that stays the same when the report is renamed. Slug defaults to the file name.
+//
+// Unlike bundles, manifests default is_active to true and may omit defaulted settings.
+// Secret delivery settings must be secret references (env://, file://, vault://), or placeholders
+// "${secret:<name>}" that keep the stored value of an existing delivery.
+type ScheduleManifest struct {
+	Slug string `json:"slug"`
+	BundleSchedule
+}
+
+// Sync plan actions
+const (
+	SyncActionCreate     = "create"
+	SyncActionUpdate     = "update"
+	SyncActionDeactivate = "deactivate" // managed config with no manifest, with --prune
+	SyncActionNoop       = "noop"
+)
+
+// SyncPlanItem is what sync does (or would do, in a dry run) for one slug
+type SyncPlanItem struct {
+	Slug       string   `json:"slug"`
+	ReportName string   `json:"report_name"`
+	Action     string   `json:"action"`
+	Changes    []string `json:"changes,omitempty"` // fields an update changes
+	Drifted    bool     `json:"drifted"`           // edited outside sync since it was last applied
+	ConfigID   int      `json:"config_id,omitempty"`
+	ScheduleID int      `json:"schedule_id,omitempty"`
+}
+
+// ManagedConfigDrift reports a managed config edited outside sync
+type ManagedConfigDrift struct {
+	ConfigID   int    `json:"config_id"`
+	Slug       string `json:"slug"`
+	ReportName string `json:"report_name"`
+	ManagedBy  string `json:"managed_by"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// SchedulerLease is a named lock row that elects a single scheduler leader across replicas
+type SchedulerLease struct {
+	Name       string    `gorm:"primaryKey;size:100;column:name" json:"name"`
+	HolderID   string    `gorm:"size:200;not null;column:holder_id" json:"holder_id"`
+	AcquiredAt time.Time `gorm:"not null;column:acquired_at" json:"acquired_at"`
+	ExpiresAt  time.Time `gorm:"not null;index;column:expires_at" json:"expires_at"`
+}
+
+func (SchedulerLease) TableName() string {
+	return "scheduler_leases"
+}
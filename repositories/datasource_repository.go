@@ -28,6 +28,27 @@ func (r *DatasourceRepository) GetAll(isActive *bool) ([]models.DataSource, erro
 	return datasources, err
 }
 
+// DatasourceListSpec is what GET /api/datasources can filter and sort on
+var DatasourceListSpec = ListSpec{
+	Fields: map[string]ListField{
+		"id":         {Column: "id", Type: IntField, Sortable: true},
+		"name":       {Column: "name", Type: StringField, Sortable: true},
+		"db_type":    {Column: "db_type", Type: StringField, Enum: []string{"mysql", "postgresql", "oracle", "sqlserver", "mongodb", "bigquery", "snowflake"}},
+		"is_active":  {Column: "is_active", Type: BoolField},
+		"created_at": {Column: "created_at", Type: TimeField, Sortable: true},
+		"updated_at": {Column: "updated_at", Type: TimeField, Sortable: true},
+		"created_by": {Column: "created_by", Type: StringField},
+	},
+	DefaultSort: "id",
+	KeyColumn:   "id",
+	KeyType:     IntField,
+}
+
+// List retrieves one page of datasources
+func (r *DatasourceRepository) List(q *ListQuery) ([]models.DataSource, *PageMeta, error) {
+	return FindPage[models.DataSource](r.DB, DatasourceListSpec, q)
+}
+
 // GetByID retrieves a datasource by ID
 func (r *DatasourceRepository) GetByID(id int) (*models.DataSource, error) {
 	var datasource models.DataSource
@@ -65,3 +86,11 @@ func (r *DatasourceRepository) CheckNameExists(name string, excludeID int) (bool
 	err := query.Count(&count).Error
 	return count > 0, err
 }
+
+// UpdateSecrets rewrites the credential columns so they are encrypted with the active key
+func (r *DatasourceRepository) UpdateSecrets(datasource *models.DataSource) error {
+	return r.DB.Model(&models.DataSource{}).Where("id = ?", datasource.ID).UpdateColumns(map[string]interface{}{
+		"connection_url":    datasource.ConnectionURL,
+		"connection_config": datasource.ConnectionConfig,
+	}).Error
+}
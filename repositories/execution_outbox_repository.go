@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"time"
+
+	"scheduling-report/config"
+	"scheduling-report/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ExecutionOutboxRepository struct {
+	DB *gorm.DB
+}
+
+func NewExecutionOutboxRepository() *ExecutionOutboxRepository {
+	return &ExecutionOutboxRepository{DB: config.DB}
+}
+
+// Create inserts a new outbox message
+func (r *ExecutionOutboxRepository) Create(message *models.ExecutionOutbox) error {
+	return r.DB.Create(message).Error
+}
+
+// GetLatestByExecutionID retrieves the most recent message written for an execution
+func (r *ExecutionOutboxRepository) GetLatestByExecutionID(executionID string) (*models.ExecutionOutbox, error) {
+	var message models.ExecutionOutbox
+	err := r.DB.Where("execution_id = ?", executionID).Order("id DESC").First(&message).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// ClaimPending leases up to limit pending messages due at now to claimant until leaseUntil and returns them.
+// The rows are locked with SKIP LOCKED only for the claim itself; pushing next_attempt_at out to the lease end
+// keeps other relays off them while they are published outside any transaction. A relay that dies mid-publish
+// leaves its rows to be claimed again once the lease runs out.
+func (r *ExecutionOutboxRepository) ClaimPending(now time.Time, limit int, claimant string, leaseUntil time.Time) ([]models.ExecutionOutbox, error) {
+	var messages []models.ExecutionOutbox
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.OutboxStatusPending, now).
+			Order("id ASC").
+			Limit(limit).
+			Find(&messages).Error; err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+
+		ids := make([]int64, len(messages))
+		for i, message := range messages {
+			ids[i] = message.ID
+		}
+
+		return tx.Model(&models.ExecutionOutbox{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"claimed_by":      claimant,
+				"next_attempt_at": leaseUntil,
+			}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// MarkSent records a published message. It applies even when another relay has reclaimed the row meanwhile,
+// since the message is on the topic either way.
+func (r *ExecutionOutboxRepository) MarkSent(id int64) error {
+	return r.DB.Model(&models.ExecutionOutbox{}).
+		Where("id = ? AND status = ?", id, models.OutboxStatusPending).
+		Updates(map[string]interface{}{
+			"status":     models.OutboxStatusSent,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"sent_at":    time.Now(),
+			"last_error": nil,
+			"claimed_by": nil,
+		}).Error
+}
+
+// MarkFailed records a failed publish and schedules the next attempt, unless the message has been
+// sent or reclaimed by another relay since claimant claimed it
+func (r *ExecutionOutboxRepository) MarkFailed(id int64, claimant string, nextAttemptAt time.Time, errMsg string) error {
+	return r.DB.Model(&models.ExecutionOutbox{}).
+		Where("id = ? AND status = ? AND claimed_by = ?", id, models.OutboxStatusPending, claimant).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      errMsg,
+			"claimed_by":      nil,
+		}).Error
+}
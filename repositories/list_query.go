@@ -0,0 +1,404 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// List query grammar, shared by every list endpoint. Query parameters:
+//
+//	field=value          field equals value
+//	field[op]=value      op is one of:
+//	                       eq, ne            equal, not equal
+//	                       in, nin           in / not in a comma-separated list
+//	                       like              contains value (string fields)
+//	                       gt, gte, lt, lte  ranges (number and date fields)
+//	sort=field           ascending, sort=-field descending; ties are broken by id
+//	limit=n              page size, 1 to MaxListLimit (default DefaultListLimit)
+//	cursor=c             next_cursor of the previous page; keep the same sort and filters
+//
+// Values are typed by field: numbers, true/false, or dates as "2006-01-02",
+// "2006-01-02 15:04:05" or RFC 3339. Only the fields a resource lists in its ListSpec
+// can be filtered, and only the ones marked Sortable can be sorted on. Other plain
+// parameters are left to the endpoint; unknown field[op] parameters are rejected.
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 500
+)
+
+// FieldType says how a list field's filter and cursor values are parsed
+type FieldType int
+
+const (
+	StringField FieldType = iota
+	IntField
+	BoolField
+	TimeField
+)
+
+// ListField maps an API field to its column
+type ListField struct {
+	Column   string
+	Type     FieldType
+	Sortable bool     // only NOT NULL columns, so a keyset cursor can resume after any row
+	Enum     []string // allowed values, if restricted
+}
+
+// ListSpec whitelists the fields of one resource that list queries may use
+type ListSpec struct {
+	Fields      map[string]ListField
+	DefaultSort string // "field" or "-field"
+	KeyColumn   string // unique column breaking sort ties
+	KeyType     FieldType
+}
+
+// Qualified returns the spec with every column prefixed by table, for queries that join other tables
+func (s ListSpec) Qualified(table string) ListSpec {
+	fields := make(map[string]ListField, len(s.Fields))
+	for name, field := range s.Fields {
+		field.Column = table + "." + field.Column
+		fields[name] = field
+	}
+	s.Fields = fields
+	s.KeyColumn = table + "." + s.KeyColumn
+	return s
+}
+
+// ListFilter is one parsed field[op]=value condition
+type ListFilter struct {
+	Field  string
+	Op     string
+	Values []interface{}
+}
+
+// ListQuery is a parsed list request
+type ListQuery struct {
+	Filters   []ListFilter
+	SortField string
+	SortDesc  bool
+	Limit     int
+	cursor    *listCursor
+}
+
+// PageMeta is the pagination metadata of a list response
+type PageMeta struct {
+	NextCursor *string `json:"next_cursor"` // null on the last page
+	Total      int64   `json:"total"`       // rows matching the filters, across all pages
+	Limit      int     `json:"limit"`
+}
+
+// ListQueryError rejects a filter, sort or cursor the spec does not allow
+type ListQueryError struct {
+	Reason string
+}
+
+func (e *ListQueryError) Error() string {
+	return e.Reason
+}
+
+// listCursor is the position after the last row of a page: its sort value and key
+type listCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	Key   string `json:"k"`
+}
+
+var listParamPattern = regexp.MustCompile(`^([a-z_]+)\[([a-z]+)\]$`)
+
+var listOps = map[string]bool{
+	"eq": true, "ne": true, "in": true, "nin": true, "like": true,
+	"gt": true, "gte": true, "lt": true, "lte": true,
+}
+
+// ParseListQuery reads the list grammar from query parameters against spec
+func ParseListQuery(params map[string]string, spec ListSpec) (*ListQuery, error) {
+	q := &ListQuery{Limit: DefaultListLimit}
+
+	sort := spec.DefaultSort
+	if value := params["sort"]; value != "" {
+		sort = value
+	}
+	q.SortField = strings.TrimPrefix(sort, "-")
+	q.SortDesc = strings.HasPrefix(sort, "-")
+	if field, ok := spec.Fields[q.SortField]; !ok || !field.Sortable {
+		return nil, &ListQueryError{Reason: fmt.Sprintf("cannot sort by '%s'", q.SortField)}
+	}
+
+	if value := params["limit"]; value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > MaxListLimit {
+			return nil, &ListQueryError{Reason: fmt.Sprintf("limit must be between 1 and %d", MaxListLimit)}
+		}
+		q.Limit = limit
+	}
+
+	if value := params["cursor"]; value != "" {
+		cursor, err := decodeListCursor(value)
+		if err != nil || cursor.Sort != sort {
+			return nil, &ListQueryError{Reason: "invalid cursor, it must come from a page with the same sort"}
+		}
+		q.cursor = cursor
+	}
+
+	for param, value := range params {
+		name, op := param, "eq"
+		if match := listParamPattern.FindStringSubmatch(param); match != nil {
+			name, op = match[1], match[2]
+			if _, ok := spec.Fields[name]; !ok {
+				return nil, &ListQueryError{Reason: fmt.Sprintf("cannot filter by '%s'", name)}
+			}
+		}
+		field, ok := spec.Fields[name]
+		if !ok || value == "" {
+			continue
+		}
+
+		filter, err := parseListFilter(name, op, value, field)
+		if err != nil {
+			return nil, err
+		}
+		q.Filters = append(q.Filters, *filter)
+	}
+
+	return q, nil
+}
+
+func parseListFilter(name string, op string, value string, field ListField) (*ListFilter, error) {
+	if !listOps[op] {
+		return nil, &ListQueryError{Reason: fmt.Sprintf("unknown operator '%s' on '%s'", op, name)}
+	}
+	switch {
+	case op == "like" && field.Type != StringField:
+		return nil, &ListQueryError{Reason: fmt.Sprintf("'%s' does not support like", name)}
+	case (op == "gt" || op == "gte" || op == "lt" || op == "lte") && field.Type != IntField && field.Type != TimeField:
+		return nil, &ListQueryError{Reason: fmt.Sprintf("'%s' does not support ranges", name)}
+	case (op == "in" || op == "nin") && field.Type == BoolField:
+		return nil, &ListQueryError{Reason: fmt.Sprintf("'%s' does not support %s", name, op)}
+	}
+
+	raw := []string{value}
+	if op == "in" || op == "nin" {
+		raw = strings.Split(value, ",")
+	}
+
+	filter := &ListFilter{Field: name, Op: op}
+	for _, item := range raw {
+		item = strings.TrimSpace(item)
+		if op == "like" {
+			filter.Values = append(filter.Values, item)
+			continue
+		}
+		if len(field.Enum) > 0 && !containsString(field.Enum, item) {
+			return nil, &ListQueryError{Reason: fmt.Sprintf("invalid %s '%s', must be one of: %s", name, item, strings.Join(field.Enum, ", "))}
+		}
+		parsed, err := parseListValue(item, field.Type)
+		if err != nil {
+			return nil, &ListQueryError{Reason: fmt.Sprintf("invalid value for '%s': %v", name, err)}
+		}
+		filter.Values = append(filter.Values, parsed)
+	}
+	return filter, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// parseListValue converts a filter or cursor value to the Go type of its column
+func parseListValue(value string, fieldType FieldType) (interface{}, error) {
+	switch fieldType {
+	case IntField:
+		return strconv.ParseInt(value, 10, 64)
+	case BoolField:
+		return strconv.ParseBool(value)
+	case TimeField:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("'%s' is not a date", value)
+	}
+	return value, nil
+}
+
+// HasFilter reports whether the query filters on field
+func (q *ListQuery) HasFilter(field string) bool {
+	for _, filter := range q.Filters {
+		if filter.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// AddFilter adds a condition, for defaults an endpoint applies when the client sets none
+func (q *ListQuery) AddFilter(field string, op string, value interface{}) {
+	q.Filters = append(q.Filters, ListFilter{Field: field, Op: op, Values: []interface{}{value}})
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ApplyFilters adds the query's filters to db
+func (q *ListQuery) ApplyFilters(db *gorm.DB, spec ListSpec) *gorm.DB {
+	for _, filter := range q.Filters {
+		column := spec.Fields[filter.Field].Column
+		switch filter.Op {
+		case "eq":
+			db = db.Where(column+" = ?", filter.Values[0])
+		case "ne":
+			db = db.Where(column+" <> ?", filter.Values[0])
+		case "in":
+			db = db.Where(column+" IN ?", filter.Values)
+		case "nin":
+			db = db.Where(column+" NOT IN ?", filter.Values)
+		case "like":
+			db = db.Where(column+" LIKE ?", "%"+likeEscaper.Replace(filter.Values[0].(string))+"%")
+		case "gt":
+			db = db.Where(column+" > ?", filter.Values[0])
+		case "gte":
+			db = db.Where(column+" >= ?", filter.Values[0])
+		case "lt":
+			db = db.Where(column+" < ?", filter.Values[0])
+		case "lte":
+			db = db.Where(column+" <= ?", filter.Values[0])
+		}
+	}
+	return db
+}
+
+// FindPage runs a list query: it counts the matching rows, then loads one page ordered by the sort
+// field and key, resuming after the cursor. db carries the model's table and any joins.
+func FindPage[T any](db *gorm.DB, spec ListSpec, q *ListQuery) ([]T, *PageMeta, error) {
+	filtered := q.ApplyFilters(db.Model(new(T)), spec).Session(&gorm.Session{})
+
+	var total int64
+	if err := filtered.Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	sortColumn := spec.Fields[q.SortField].Column
+	direction, compare := "ASC", ">"
+	if q.SortDesc {
+		direction, compare = "DESC", "<"
+	}
+
+	query := filtered
+	if q.cursor != nil {
+		key, err := parseListValue(q.cursor.Key, spec.KeyType)
+		if err != nil {
+			return nil, nil, &ListQueryError{Reason: "invalid cursor"}
+		}
+		if sortColumn == spec.KeyColumn {
+			query = query.Where(spec.KeyColumn+" "+compare+" ?", key)
+		} else {
+			value, err := parseListValue(q.cursor.Value, spec.Fields[q.SortField].Type)
+			if err != nil {
+				return nil, nil, &ListQueryError{Reason: "invalid cursor"}
+			}
+			query = query.Where(fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", sortColumn, compare, sortColumn, spec.KeyColumn, compare), value, value, key)
+		}
+	}
+	query = query.Order(sortColumn + " " + direction)
+	if sortColumn != spec.KeyColumn {
+		query = query.Order(spec.KeyColumn + " " + direction)
+	}
+
+	var rows []T
+	if err := query.Limit(q.Limit + 1).Find(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+
+	meta := &PageMeta{Total: total, Limit: q.Limit}
+	if len(rows) > q.Limit {
+		rows = rows[:q.Limit]
+		cursor, err := pageCursor(db, spec, q, &rows[len(rows)-1])
+		if err != nil {
+			return nil, nil, err
+		}
+		meta.NextCursor = &cursor
+	}
+	if rows == nil {
+		rows = []T{}
+	}
+	return rows, meta, nil
+}
+
+// pageCursor encodes the sort value and key of the last row of a page
+func pageCursor(db *gorm.DB, spec ListSpec, q *ListQuery, last interface{}) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(last); err != nil {
+		return "", err
+	}
+	rowValue := reflect.ValueOf(last).Elem()
+	columnValue := func(column string) (string, error) {
+		if i := strings.LastIndex(column, "."); i >= 0 {
+			column = column[i+1:]
+		}
+		field := stmt.Schema.LookUpField(column)
+		if field == nil {
+			return "", fmt.Errorf("list column %s is not a field of %s", column, stmt.Schema.Name)
+		}
+		value, _ := field.ValueOf(db.Statement.Context, rowValue)
+		return formatListValue(value)
+	}
+
+	sort := q.SortField
+	if q.SortDesc {
+		sort = "-" + sort
+	}
+	cursor := listCursor{Sort: sort}
+	var err error
+	if cursor.Key, err = columnValue(spec.KeyColumn); err != nil {
+		return "", err
+	}
+	if cursor.Value, err = columnValue(spec.Fields[q.SortField].Column); err != nil {
+		return "", err
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// formatListValue writes a column value the way parseListValue reads it back
+func formatListValue(value interface{}) (string, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		var err error
+		if value, err = valuer.Value(); err != nil {
+			return "", err
+		}
+	}
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case nil:
+		return "", nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+func decodeListCursor(value string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
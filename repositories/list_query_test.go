@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"scheduling-report/internal/testdb"
+	"scheduling-report/models"
+)
+
+func TestParseListQueryRejects(t *testing.T) {
+	// A next_cursor of an ascending page, replayed against the default descending sort
+	ascendingCursor := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"started_at","v":"2026-03-01T06:00:00Z","k":"e1"}`))
+
+	tests := []struct {
+		name   string
+		spec   ListSpec
+		params map[string]string
+		want   string
+	}{
+		{name: "sort on a non-sortable field", spec: ExecutionListSpec, params: map[string]string{"sort": "status"}, want: "cannot sort by 'status'"},
+		{name: "sort on an unknown field", spec: ExecutionListSpec, params: map[string]string{"sort": "-duration"}, want: "cannot sort by 'duration'"},
+		{name: "filter on an unknown field", spec: ExecutionListSpec, params: map[string]string{"duration[gt]": "5"}, want: "cannot filter by 'duration'"},
+		{name: "unknown operator", spec: ExecutionListSpec, params: map[string]string{"status[regex]": "fail.*"}, want: "unknown operator 'regex' on 'status'"},
+		{name: "like on a date", spec: ExecutionListSpec, params: map[string]string{"started_at[like]": "2026"}, want: "'started_at' does not support like"},
+		{name: "range on a string", spec: ExecutionListSpec, params: map[string]string{"executed_by[gt]": "a"}, want: "'executed_by' does not support ranges"},
+		{name: "in on a bool", spec: ReportConfigListSpec, params: map[string]string{"is_active[in]": "true,false"}, want: "'is_active' does not support in"},
+		{name: "bad enum value", spec: ExecutionListSpec, params: map[string]string{"status": "done"},
+			want: "invalid status 'done', must be one of: queued, running, completed, failed, cancelled"},
+		{name: "bad enum value in a list", spec: ExecutionListSpec, params: map[string]string{"status[nin]": "queued, done"},
+			want: "invalid status 'done', must be one of: queued, running, completed, failed, cancelled"},
+		{name: "malformed number", spec: ExecutionListSpec, params: map[string]string{"config_id": "seven"},
+			want: `invalid value for 'config_id': strconv.ParseInt: parsing "seven": invalid syntax`},
+		{name: "malformed date", spec: ExecutionListSpec, params: map[string]string{"started_at[gte]": "yesterday"},
+			want: "invalid value for 'started_at': 'yesterday' is not a date"},
+		{name: "malformed bool", spec: ReportConfigListSpec, params: map[string]string{"is_active": "yes"},
+			want: `invalid value for 'is_active': strconv.ParseBool: parsing "yes": invalid syntax`},
+		{name: "zero limit", spec: ExecutionListSpec, params: map[string]string{"limit": "0"}, want: "limit must be between 1 and 500"},
+		{name: "limit above the maximum", spec: ExecutionListSpec, params: map[string]string{"limit": "501"}, want: "limit must be between 1 and 500"},
+		{name: "cursor that is not base64", spec: ExecutionListSpec, params: map[string]string{"cursor": "not a cursor!"},
+			want: "invalid cursor, it must come from a page with the same sort"},
+		{name: "cursor that is not JSON", spec: ExecutionListSpec, params: map[string]string{"cursor": base64.RawURLEncoding.EncodeToString([]byte("page=2"))},
+			want: "invalid cursor, it must come from a page with the same sort"},
+		{name: "cursor from another sort", spec: ExecutionListSpec, params: map[string]string{"cursor": ascendingCursor},
+			want: "invalid cursor, it must come from a page with the same sort"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseListQuery(tt.params, tt.spec)
+			var queryErr *ListQueryError
+			if !errors.As(err, &queryErr) {
+				t.Fatalf("ParseListQuery = %v, want a ListQueryError", err)
+			}
+			if queryErr.Reason != tt.want {
+				t.Errorf("reason = %q, want %q", queryErr.Reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseListQuery(t *testing.T) {
+	q, err := ParseListQuery(map[string]string{
+		"status[in]":  "queued, failed",
+		"config_id":   "7",
+		"executed_by": "", // empty values are no filter
+		"format":      "csv",
+		"limit":       "20",
+	}, ExecutionListSpec)
+	if err != nil {
+		t.Fatalf("ParseListQuery: %v", err)
+	}
+
+	if q.SortField != "started_at" || !q.SortDesc || q.Limit != 20 {
+		t.Errorf("sort = %s desc=%t, limit = %d; want the default -started_at and 20", q.SortField, q.SortDesc, q.Limit)
+	}
+	filters := map[string]ListFilter{}
+	for _, filter := range q.Filters {
+		filters[filter.Field] = filter
+	}
+	want := map[string]ListFilter{
+		"status":    {Field: "status", Op: "in", Values: []interface{}{"queued", "failed"}},
+		"config_id": {Field: "config_id", Op: "eq", Values: []interface{}{int64(7)}},
+	}
+	if !reflect.DeepEqual(filters, want) {
+		t.Errorf("filters = %+v, want %+v", filters, want)
+	}
+}
+
+func TestFindPageWalksTiesAcrossPages(t *testing.T) {
+	db := testdb.Open(t)
+
+	// Three executions share a start time, so only the id tiebreak orders them
+	base := time.Date(2026, 3, 1, 6, 0, 0, 0, time.UTC)
+	starts := map[string]time.Time{
+		"e1": base,
+		"e2": base.Add(time.Hour),
+		"e3": base.Add(time.Hour),
+		"e4": base.Add(time.Hour),
+		"e5": base.Add(2 * time.Hour),
+		"e6": base.Add(3 * time.Hour),
+	}
+	for id, startedAt := range starts {
+		status := "completed"
+		if id == "e6" {
+			status = "failed"
+		}
+		if err := db.Create(&models.ReportExecution{ID: id, ConfigID: 1, Status: status, StartedAt: startedAt, ExecutedBy: "test"}).Error; err != nil {
+			t.Fatalf("seed execution: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		want   []string
+	}{
+		{name: "ascending", params: map[string]string{"sort": "started_at"}, want: []string{"e1", "e2", "e3", "e4", "e5", "e6"}},
+		{name: "descending", params: map[string]string{"sort": "-started_at"}, want: []string{"e6", "e5", "e4", "e3", "e2", "e1"}},
+		{name: "descending with a filter", params: map[string]string{"sort": "-started_at", "status": "completed"}, want: []string{"e5", "e4", "e3", "e2", "e1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := map[string]string{"limit": "2"}
+			for key, value := range tt.params {
+				params[key] = value
+			}
+
+			var got []string
+			for page := 1; ; page++ {
+				q, err := ParseListQuery(params, ExecutionListSpec)
+				if err != nil {
+					t.Fatalf("page %d: ParseListQuery: %v", page, err)
+				}
+				rows, meta, err := FindPage[models.ReportExecution](db, ExecutionListSpec, q)
+				if err != nil {
+					t.Fatalf("page %d: FindPage: %v", page, err)
+				}
+				if meta.Total != int64(len(tt.want)) {
+					t.Errorf("page %d: total = %d, want %d", page, meta.Total, len(tt.want))
+				}
+				for _, row := range rows {
+					got = append(got, row.ID)
+				}
+				if meta.NextCursor == nil {
+					break
+				}
+				if page > len(tt.want) {
+					t.Fatalf("still paging after %d pages", page)
+				}
+				params["cursor"] = *meta.NextCursor
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindPageRejectsTamperedCursor(t *testing.T) {
+	db := testdb.Open(t)
+	seedScheduleDetails(t, db, 3)
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "key of the wrong type", cursor: `{"s":"-created_at","v":"2026-03-01T06:00:00Z","k":"three"}`},
+		{name: "sort value of the wrong type", cursor: `{"s":"-created_at","v":"last tuesday","k":"3"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseListQuery(map[string]string{"cursor": base64.RawURLEncoding.EncodeToString([]byte(tt.cursor))}, ReportConfigListSpec)
+			if err != nil {
+				t.Fatalf("ParseListQuery: %v", err)
+			}
+			_, _, err = FindPage[models.ReportConfig](db, ReportConfigListSpec, q)
+			var queryErr *ListQueryError
+			if !errors.As(err, &queryErr) || queryErr.Reason != "invalid cursor" {
+				t.Errorf("FindPage = %v, want an invalid cursor ListQueryError", err)
+			}
+		})
+	}
+}
+
+func TestFindPageCursorSortsOnIntKey(t *testing.T) {
+	db := testdb.Open(t)
+	seedScheduleDetails(t, db, 5)
+
+	var want []int
+	if err := db.Model(&models.ReportConfig{}).Order("id DESC").Pluck("id", &want).Error; err != nil {
+		t.Fatalf("load ids: %v", err)
+	}
+
+	params := map[string]string{"sort": "-id", "limit": "2"}
+	var got []int
+	for page := 1; page <= len(want); page++ {
+		q, err := ParseListQuery(params, ReportConfigListSpec)
+		if err != nil {
+			t.Fatalf("page %d: ParseListQuery: %v", page, err)
+		}
+		rows, meta, err := FindPage[models.ReportConfig](db, ReportConfigListSpec, q)
+		if err != nil {
+			t.Fatalf("page %d: FindPage: %v", page, err)
+		}
+		for _, row := range rows {
+			got = append(got, row.ID)
+		}
+		if meta.NextCursor == nil {
+			break
+		}
+		params["cursor"] = *meta.NextCursor
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ids = %v, want %v", got, want)
+	}
+}
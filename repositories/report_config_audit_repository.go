@@ -11,25 +11,24 @@ func NewReportConfigAuditRepository() *ReportConfigAuditRepository {
 	return &ReportConfigAuditRepository{}
 }
 
-// GetAll retrieves all audit records with optional filters
-func (r *ReportConfigAuditRepository) GetAll(configID *int, action *string, performedBy *string) ([]models.ReportConfigAudit, error) {
-	var audits []models.ReportConfigAudit
-	query := config.DB
-
-	if configID != nil {
-		query = query.Where("config_id = ?", *configID)
-	}
-
-	if action != nil {
-		query = query.Where("action = ?", *action)
-	}
-
-	if performedBy != nil {
-		query = query.Where("performed_by = ?", *performedBy)
-	}
+// AuditListSpec is what GET /api/audits can filter and sort on
+var AuditListSpec = ListSpec{
+	Fields: map[string]ListField{
+		"id":           {Column: "id", Type: IntField, Sortable: true},
+		"config_id":    {Column: "config_id", Type: IntField},
+		"action":       {Column: "action", Type: StringField, Enum: []string{"create", "update", "delete", "activate", "deactivate", "rollback", "clone"}},
+		"field_name":   {Column: "field_name", Type: StringField},
+		"performed_by": {Column: "performed_by", Type: StringField},
+		"performed_at": {Column: "performed_at", Type: TimeField, Sortable: true},
+	},
+	DefaultSort: "-performed_at",
+	KeyColumn:   "id",
+	KeyType:     IntField,
+}
 
-	err := query.Order("performed_at DESC").Find(&audits).Error
-	return audits, err
+// List retrieves one page of audit records
+func (r *ReportConfigAuditRepository) List(q *ListQuery) ([]models.ReportConfigAudit, *PageMeta, error) {
+	return FindPage[models.ReportConfigAudit](config.DB, AuditListSpec, q)
 }
 
 // GetByID retrieves a single audit record by ID
@@ -1,12 +1,18 @@
 package repository
 
 import (
+	"errors"
+	"strings"
+
 	"scheduling-report/config"
 	"scheduling-report/models"
 
 	"gorm.io/gorm"
 )
 
+// ErrVersionConflict is returned by conditional updates when the row's version changed since it was read
+var ErrVersionConflict = errors.New("version conflict")
+
 type ReportConfigRepository struct {
 	DB *gorm.DB
 }
@@ -15,21 +21,82 @@ func NewReportConfigRepository() *ReportConfigRepository {
 	return &ReportConfigRepository{DB: config.DB}
 }
 
-// GetAll retrieves all report configs with optional filters
-func (r *ReportConfigRepository) GetAll(isActive *bool, datasourceID *int) ([]models.ReportConfig, error) {
-	var configs []models.ReportConfig
-	query := r.DB
+// ReportConfigListSpec is what GET /api/report-configs can filter and sort on
+var ReportConfigListSpec = ListSpec{
+	Fields: map[string]ListField{
+		"id":            {Column: "id", Type: IntField, Sortable: true},
+		"report_name":   {Column: "report_name", Type: StringField, Sortable: true},
+		"output_format": {Column: "output_format", Type: StringField},
+		"datasource_id": {Column: "datasource_id", Type: IntField},
+		"is_active":     {Column: "is_active", Type: BoolField},
+		"version":       {Column: "version", Type: IntField},
+		"created_at":    {Column: "created_at", Type: TimeField, Sortable: true},
+		"updated_at":    {Column: "updated_at", Type: TimeField, Sortable: true},
+		"created_by":    {Column: "created_by", Type: StringField},
+		"updated_by":    {Column: "updated_by", Type: StringField},
+		"managed_by":    {Column: "managed_by", Type: StringField},
+		"slug":          {Column: "slug", Type: StringField},
+		"folder_id":     {Column: "folder_id", Type: IntField},
+		"owner_team":    {Column: "owner_team", Type: StringField},
+	},
+	DefaultSort: "-created_at",
+	KeyColumn:   "id",
+	KeyType:     IntField,
+}
 
-	if isActive != nil {
-		query = query.Where("is_active = ?", *isActive)
+// List retrieves one page of report configs, with their tags
+func (r *ReportConfigRepository) List(q *ListQuery, catalog models.ConfigCatalogFilters) ([]models.ReportConfig, *PageMeta, error) {
+	configs, meta, err := FindPage[models.ReportConfig](applyCatalogFilters(r.DB, "report_configs", catalog), ReportConfigListSpec, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := r.LoadTags(configs); err != nil {
+		return nil, nil, err
 	}
+	return configs, meta, nil
+}
 
-	if datasourceID != nil {
-		query = query.Where("datasource_id = ?", *datasourceID)
+// applyCatalogFilters narrows a query on table (report_configs, or a join of it) by tags, folder path and owner
+func applyCatalogFilters(db *gorm.DB, table string, catalog models.ConfigCatalogFilters) *gorm.DB {
+	for _, tag := range strings.Split(catalog.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag == "" {
+			continue
+		}
+		db = db.Where(table+".id IN (?)", db.Session(&gorm.Session{NewDB: true}).
+			Table("report_config_tags").
+			Select("report_config_tags.config_id").
+			Joins("JOIN report_tags ON report_tags.id = report_config_tags.tag_id").
+			Where("report_tags.name = ?", tag))
+	}
+	if folder := strings.Trim(catalog.Folder, "/"); folder != "" {
+		db = db.Where(table+".folder_id IN (?)", db.Session(&gorm.Session{NewDB: true}).
+			Model(&models.ReportFolder{}).
+			Select("id").
+			Where("path = ? OR path LIKE ?", folder, likeEscaper.Replace(folder)+"/%"))
 	}
+	if catalog.Owner != "" {
+		db = db.Where("JSON_CONTAINS("+table+".owners, JSON_QUOTE(?))", catalog.Owner)
+	}
+	return db
+}
 
-	err := query.Order("created_at DESC").Find(&configs).Error
-	return configs, err
+// LoadTags fills the Tags of configs, with one query
+func (r *ReportConfigRepository) LoadTags(configs []models.ReportConfig) error {
+	configIDs := make([]int, 0, len(configs))
+	for _, config := range configs {
+		configIDs = append(configIDs, config.ID)
+	}
+	names, err := (&ReportTagRepository{DB: r.DB}).GetNamesByConfigIDs(configIDs)
+	if err != nil {
+		return err
+	}
+	for i := range configs {
+		configs[i].Tags = names[configs[i].ID]
+		if configs[i].Tags == nil {
+			configs[i].Tags = []string{}
+		}
+	}
+	return nil
 }
 
 // GetByID retrieves a report config by ID
@@ -39,7 +106,11 @@ func (r *ReportConfigRepository) GetByID(id int) (*models.ReportConfig, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &config, nil
+	configs := []models.ReportConfig{config}
+	if err := r.LoadTags(configs); err != nil {
+		return nil, err
+	}
+	return &configs[0], nil
 }
 
 // Create creates a new report config
@@ -47,20 +118,58 @@ func (r *ReportConfigRepository) Create(config *models.ReportConfig) error {
 	return r.DB.Create(config).Error
 }
 
-// Update updates an existing report config
+// Update updates an existing report config if it is still at config.Version.
+// Returns ErrVersionConflict if another write got there first.
 func (r *ReportConfigRepository) Update(config *models.ReportConfig) error {
 	// Increment version on update
-	return r.DB.Model(config).Updates(map[string]interface{}{
-		"report_name":     config.ReportName,
-		"report_query":    config.ReportQuery,
-		"output_format":   config.OutputFormat,
-		"datasource_id":   config.DatasourceID,
-		"parameters":      config.Parameters,
-		"timeout_seconds": config.TimeoutSeconds,
-		"max_rows":        config.MaxRows,
-		"updated_by":      config.UpdatedBy,
-		"version":         gorm.Expr("version + 1"),
-	}).Error
+	result := r.DB.Model(config).Where("version = ?", config.Version).Updates(map[string]interface{}{
+		"report_name":      config.ReportName,
+		"report_query":     config.ReportQuery,
+		"output_format":    config.OutputFormat,
+		"datasource_id":    config.DatasourceID,
+		"file_name":        config.FileName,
+		"parameters":       config.Parameters,
+		"parameter_schema": config.ParameterSchema,
+		"timeout_seconds":  config.TimeoutSeconds,
+		"max_rows":         config.MaxRows,
+		"updated_by":       config.UpdatedBy,
+		"version":          gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// UpdateMetadata saves the folder and ownership of a config if its metadata version is still
+// config.MetadataVersion, and bumps it. The config version is not changed.
+func (r *ReportConfigRepository) UpdateMetadata(config *models.ReportConfig) error {
+	result := r.DB.Model(config).Where("metadata_version = ?", config.MetadataVersion).UpdateColumns(map[string]interface{}{
+		"folder_id":        config.FolderID,
+		"owner_team":       config.OwnerTeam,
+		"owners":           config.Owners,
+		"updated_by":       config.UpdatedBy,
+		"metadata_version": gorm.Expr("metadata_version + 1"),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// BumpMetadataVersions marks the metadata of configs as changed, for tag changes made outside UpdateMetadata
+func (r *ReportConfigRepository) BumpMetadataVersions(ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.DB.Model(&models.ReportConfig{}).Where("id IN ?", ids).
+		UpdateColumn("metadata_version", gorm.Expr("metadata_version + 1")).Error
 }
 
 // Delete performs soft delete by setting is_active = false
@@ -81,6 +190,13 @@ func (r *ReportConfigRepository) CheckNameExists(name string, excludeID int) (bo
 	return count > 0, err
 }
 
+// CountByIDs counts the configs among ids that exist
+func (r *ReportConfigRepository) CountByIDs(ids []int) (int64, error) {
+	var count int64
+	err := r.DB.Model(&models.ReportConfig{}).Where("id IN ?", ids).Count(&count).Error
+	return count, err
+}
+
 // GetByDatasourceID retrieves all configs for a specific datasource
 func (r *ReportConfigRepository) GetByDatasourceID(datasourceID int) ([]models.ReportConfig, error) {
 	var configs []models.ReportConfig
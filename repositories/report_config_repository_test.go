@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"scheduling-report/internal/testdb"
+	"scheduling-report/models"
+
+	"gorm.io/gorm"
+)
+
+// seedCatalog files four configs in folders, with tags and owners, and returns their ids by report name:
+//
+//	Revenue   finance           finance, monthly   ana, ben
+//	Close     finance/monthly   monthly            ben
+//	Ops       financeops        finance            anabel
+//	Pipeline  sales             -                  -
+func seedCatalog(tb testing.TB, db *gorm.DB) map[string]int {
+	tb.Helper()
+
+	datasource := models.DataSource{Name: "reports", DbType: "mysql", ConnectionURL: "report@tcp(localhost)/reports", IsActive: true, CreatedBy: "test", UpdatedBy: "test"}
+	if err := db.Create(&datasource).Error; err != nil {
+		tb.Fatalf("seed datasource: %v", err)
+	}
+
+	folders := map[string]int{}
+	for _, path := range []string{"finance", "finance/monthly", "financeops", "sales"} {
+		folder := models.ReportFolder{Name: path, Path: path, CreatedBy: "test", UpdatedBy: "test"}
+		if err := db.Create(&folder).Error; err != nil {
+			tb.Fatalf("seed folder %s: %v", path, err)
+		}
+		folders[path] = folder.ID
+	}
+	tags := map[string]int{}
+	for _, name := range []string{"finance", "monthly"} {
+		tag := models.ReportTag{Name: name, CreatedBy: "test", UpdatedBy: "test"}
+		if err := db.Create(&tag).Error; err != nil {
+			tb.Fatalf("seed tag %s: %v", name, err)
+		}
+		tags[name] = tag.ID
+	}
+
+	ids := map[string]int{}
+	for _, seed := range []struct {
+		name   string
+		folder string
+		tags   []string
+		owners models.StringList
+	}{
+		{name: "Revenue", folder: "finance", tags: []string{"finance", "monthly"}, owners: models.StringList{"ana", "ben"}},
+		{name: "Close", folder: "finance/monthly", tags: []string{"monthly"}, owners: models.StringList{"ben"}},
+		{name: "Ops", folder: "financeops", tags: []string{"finance"}, owners: models.StringList{"anabel"}},
+		{name: "Pipeline", folder: "sales"},
+	} {
+		folderID := folders[seed.folder]
+		reportConfig := models.ReportConfig{
+			ReportName:      seed.name,
+			ReportQuery:     "SELECT 1",
+			OutputFormat:    "csv",
+			DatasourceID:    datasource.ID,
+			IsActive:        true,
+			CreatedBy:       "test",
+			UpdatedBy:       "test",
+			Version:         1,
+			FolderID:        &folderID,
+			Owners:          seed.owners,
+			MetadataVersion: 1,
+		}
+		if err := db.Create(&reportConfig).Error; err != nil {
+			tb.Fatalf("seed config %s: %v", seed.name, err)
+		}
+		for _, tag := range seed.tags {
+			if err := db.Create(&models.ReportConfigTag{ConfigID: reportConfig.ID, TagID: tags[tag]}).Error; err != nil {
+				tb.Fatalf("seed config tag: %v", err)
+			}
+		}
+		ids[seed.name] = reportConfig.ID
+	}
+	return ids
+}
+
+func TestListCatalogFilters(t *testing.T) {
+	db := testdb.Open(t)
+	seedCatalog(t, db)
+	repo := &ReportConfigRepository{DB: db}
+
+	tests := []struct {
+		name    string
+		catalog models.ConfigCatalogFilters
+		want    []string
+	}{
+		{name: "no filter", want: []string{"Close", "Ops", "Pipeline", "Revenue"}},
+		{name: "one tag", catalog: models.ConfigCatalogFilters{Tags: "finance"}, want: []string{"Ops", "Revenue"}},
+		{name: "every tag is required", catalog: models.ConfigCatalogFilters{Tags: "finance, monthly"}, want: []string{"Revenue"}},
+		{name: "unknown tag", catalog: models.ConfigCatalogFilters{Tags: "hr"}, want: nil},
+		{name: "folder with its subfolders", catalog: models.ConfigCatalogFilters{Folder: "finance"}, want: []string{"Close", "Revenue"}},
+		{name: "subfolder with slashes", catalog: models.ConfigCatalogFilters{Folder: "/finance/monthly/"}, want: []string{"Close"}},
+		{name: "owner is matched whole", catalog: models.ConfigCatalogFilters{Owner: "ana"}, want: []string{"Revenue"}},
+		{name: "owner listed with others", catalog: models.ConfigCatalogFilters{Owner: "ben"}, want: []string{"Close", "Revenue"}},
+		{name: "all three", catalog: models.ConfigCatalogFilters{Tags: "monthly", Folder: "finance", Owner: "ben"}, want: []string{"Close", "Revenue"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseListQuery(map[string]string{"sort": "report_name"}, ReportConfigListSpec)
+			if err != nil {
+				t.Fatalf("ParseListQuery: %v", err)
+			}
+			configs, meta, err := repo.List(q, tt.catalog)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			var got []string
+			for _, reportConfig := range configs {
+				got = append(got, reportConfig.ReportName)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("configs = %v, want %v", got, tt.want)
+			}
+			if meta.Total != int64(len(tt.want)) {
+				t.Errorf("total = %d, want %d", meta.Total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestUpdateMetadataChecksMetadataVersion(t *testing.T) {
+	db := testdb.Open(t)
+	ids := seedCatalog(t, db)
+	repo := &ReportConfigRepository{DB: db}
+
+	loaded, err := repo.GetByID(ids["Pipeline"])
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	team := "sales-ops"
+	loaded.OwnerTeam = &team
+	loaded.Owners = models.StringList{"cleo"}
+	loaded.UpdatedBy = "editor"
+	if err := repo.UpdateMetadata(loaded); err != nil {
+		t.Fatalf("UpdateMetadata: %v", err)
+	}
+
+	updated, err := repo.GetByID(ids["Pipeline"])
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if updated.OwnerTeam == nil || *updated.OwnerTeam != team || !reflect.DeepEqual(updated.Owners, models.StringList{"cleo"}) {
+		t.Errorf("metadata = %v %v, want sales-ops [cleo]", updated.OwnerTeam, updated.Owners)
+	}
+	if updated.MetadataVersion != 2 || updated.Version != 1 {
+		t.Errorf("metadata_version = %d, version = %d; want 2 and the version unchanged", updated.MetadataVersion, updated.Version)
+	}
+
+	// A second write from the same read has lost the race
+	loaded.Owners = models.StringList{"dan"}
+	if err := repo.UpdateMetadata(loaded); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("stale UpdateMetadata = %v, want ErrVersionConflict", err)
+	}
+	if current, _ := repo.GetByID(ids["Pipeline"]); !reflect.DeepEqual(current.Owners, models.StringList{"cleo"}) {
+		t.Errorf("owners = %v after a stale write, want [cleo]", current.Owners)
+	}
+}
+
+func TestTagChangesBumpMetadataVersion(t *testing.T) {
+	db := testdb.Open(t)
+	ids := seedCatalog(t, db)
+	tagRepo := &ReportTagRepository{DB: db}
+
+	if err := (&ReportConfigRepository{DB: db}).BumpMetadataVersions([]int{ids["Pipeline"]}); err != nil {
+		t.Fatalf("BumpMetadataVersions: %v", err)
+	}
+	monthly, err := tagRepo.GetByNames([]string{"monthly"})
+	if err != nil || len(monthly) != 1 {
+		t.Fatalf("GetByNames = %v, %v", monthly, err)
+	}
+	if err := tagRepo.Delete(monthly[0].ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	want := map[string]int{"Revenue": 2, "Close": 2, "Ops": 1, "Pipeline": 2}
+	for name, id := range ids {
+		var reportConfig models.ReportConfig
+		if err := db.First(&reportConfig, id).Error; err != nil {
+			t.Fatalf("load %s: %v", name, err)
+		}
+		if reportConfig.MetadataVersion != want[name] {
+			t.Errorf("%s metadata_version = %d, want %d", name, reportConfig.MetadataVersion, want[name])
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"scheduling-report/config"
+	"scheduling-report/models"
+
+	"gorm.io/gorm"
+)
+
+type ReportConfigVersionRepository struct {
+	DB *gorm.DB
+}
+
+func NewReportConfigVersionRepository() *ReportConfigVersionRepository {
+	return &ReportConfigVersionRepository{DB: config.DB}
+}
+
+// Create inserts a version snapshot
+func (r *ReportConfigVersionRepository) Create(version *models.ReportConfigVersion) error {
+	return r.DB.Create(version).Error
+}
+
+// GetByConfigID retrieves all versions of a config, newest first
+func (r *ReportConfigVersionRepository) GetByConfigID(configID int) ([]models.ReportConfigVersion, error) {
+	var versions []models.ReportConfigVersion
+	err := r.DB.Where("config_id = ?", configID).
+		Order("version DESC").
+		Find(&versions).Error
+	return versions, err
+}
+
+// GetByVersion retrieves one version of a config
+func (r *ReportConfigVersionRepository) GetByVersion(configID int, version int) (*models.ReportConfigVersion, error) {
+	var configVersion models.ReportConfigVersion
+	err := r.DB.Where("config_id = ? AND version = ?", configID, version).First(&configVersion).Error
+	if err != nil {
+		return nil, err
+	}
+	return &configVersion, nil
+}
@@ -14,19 +14,26 @@ func NewReportDeliveryLogRepository() *ReportDeliveryLogRepository {
 	return &ReportDeliveryLogRepository{DB: config.DB}
 }
 
-func (r *ReportDeliveryLogRepository) GetAll(status *string, limit int) ([]models.ReportDeliveryLog, error) {
-	var logs []models.ReportDeliveryLog
-	query := r.DB.Order("sent_at DESC")
-	
-	if status != nil {
-		query = query.Where("status = ?", *status)
-	}
-	if limit > 0 {
-		query = query.Limit(limit)
-	}
-	
-	err := query.Find(&logs).Error
-	return logs, err
+// DeliveryLogListSpec is what GET /api/delivery-logs can filter and sort on
+var DeliveryLogListSpec = ListSpec{
+	Fields: map[string]ListField{
+		"id":           {Column: "id", Type: IntField, Sortable: true},
+		"config_id":    {Column: "config_id", Type: IntField},
+		"delivery_id":  {Column: "delivery_id", Type: IntField},
+		"schedule_id":  {Column: "schedule_id", Type: IntField},
+		"execution_id": {Column: "execution_id", Type: StringField},
+		"status":       {Column: "status", Type: StringField, Enum: []string{"pending", "success", "failed", "retry"}},
+		"sent_at":      {Column: "sent_at", Type: TimeField, Sortable: true},
+		"completed_at": {Column: "completed_at", Type: TimeField},
+	},
+	DefaultSort: "-sent_at",
+	KeyColumn:   "id",
+	KeyType:     IntField,
+}
+
+// List retrieves one page of delivery logs
+func (r *ReportDeliveryLogRepository) List(q *ListQuery) ([]models.ReportDeliveryLog, *PageMeta, error) {
+	return FindPage[models.ReportDeliveryLog](r.DB, DeliveryLogListSpec, q)
 }
 
 func (r *ReportDeliveryLogRepository) GetByID(id int64) (*models.ReportDeliveryLog, error) {
@@ -53,3 +60,16 @@ func (r *ReportDeliveryLogRepository) GetByDeliveryID(deliveryID int, limit int)
 	err := query.Find(&logs).Error
 	return logs, err
 }
+
+func (r *ReportDeliveryLogRepository) Create(log *models.ReportDeliveryLog) error {
+	return r.DB.Create(log).Error
+}
+
+// Exists reports whether a delivery attempt was already logged for an execution
+func (r *ReportDeliveryLogRepository) Exists(executionID string, deliveryID int, retryCount int) (bool, error) {
+	var count int64
+	err := r.DB.Model(&models.ReportDeliveryLog{}).
+		Where("execution_id = ? AND delivery_id = ? AND retry_count = ?", executionID, deliveryID, retryCount).
+		Count(&count).Error
+	return count > 0, err
+}
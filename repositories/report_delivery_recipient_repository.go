@@ -17,17 +17,28 @@ func NewReportDeliveryRecipientRepository() *ReportDeliveryRecipientRepository {
 	}
 }
 
-// GetAll retrieves all active recipients
-func (r *ReportDeliveryRecipientRepository) GetAll(isActive *bool) ([]models.ReportDeliveryRecipient, error) {
-	var recipients []models.ReportDeliveryRecipient
-	query := r.DB.Where("is_active = ?", true)
+// RecipientListSpec is what GET /api/recipients can filter and sort on
+var RecipientListSpec = ListSpec{
+	Fields: map[string]ListField{
+		"id":              {Column: "id", Type: IntField, Sortable: true},
+		"delivery_id":     {Column: "delivery_id", Type: IntField},
+		"recipient_type":  {Column: "recipient_type", Type: StringField},
+		"recipient_value": {Column: "recipient_value", Type: StringField, Sortable: true},
+		"is_active":       {Column: "is_active", Type: BoolField},
+		"created_at":      {Column: "created_at", Type: TimeField, Sortable: true},
+		"updated_at":      {Column: "updated_at", Type: TimeField, Sortable: true},
+	},
+	DefaultSort: "-created_at",
+	KeyColumn:   "id",
+	KeyType:     IntField,
+}
 
-	if isActive != nil {
-		query = r.DB.Where("is_active = ?", *isActive)
+// List retrieves one page of recipients; only active ones unless the query filters on is_active
+func (r *ReportDeliveryRecipientRepository) List(q *ListQuery) ([]models.ReportDeliveryRecipient, *PageMeta, error) {
+	if !q.HasFilter("is_active") {
+		q.AddFilter("is_active", "eq", true)
 	}
-
-	err := query.Order("created_at DESC").Find(&recipients).Error
-	return recipients, err
+	return FindPage[models.ReportDeliveryRecipient](r.DB, RecipientListSpec, q)
 }
 
 // GetByID retrieves a recipient by ID
@@ -52,9 +63,19 @@ func (r *ReportDeliveryRecipientRepository) Create(recipient *models.ReportDeliv
 	return r.DB.Create(recipient).Error
 }
 
-// Update updates an existing recipient
+// Update saves all fields of recipient if it is still at recipient.Version and bumps the version.
+// Returns ErrVersionConflict if another write got there first.
 func (r *ReportDeliveryRecipientRepository) Update(recipient *models.ReportDeliveryRecipient) error {
-	return r.DB.Save(recipient).Error
+	expectedVersion := recipient.Version
+	recipient.Version++
+	result := r.DB.Model(recipient).Where("version = ?", expectedVersion).Select("*").Updates(recipient)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrVersionConflict
+	}
+	if result.Error != nil {
+		recipient.Version = expectedVersion
+	}
+	return result.Error
 }
 
 // Delete soft deletes a recipient
@@ -30,6 +30,32 @@ func (r *ReportDeliveryRepository) GetAll(isActive *bool) ([]models.ReportDelive
 	return deliveries, err
 }
 
+// DeliveryListSpec is what GET /api/deliveries can filter and sort on
+var DeliveryListSpec = ListSpec{
+	Fields: map[string]ListField{
+		"id":            {Column: "id", Type: IntField, Sortable: true},
+		"config_id":     {Column: "config_id", Type: IntField},
+		"delivery_name": {Column: "delivery_name", Type: StringField, Sortable: true},
+		"method":        {Column: "method", Type: StringField, Enum: []string{"email", "sftp", "webhook", "s3", "file_share"}},
+		"is_active":     {Column: "is_active", Type: BoolField},
+		"created_at":    {Column: "created_at", Type: TimeField, Sortable: true},
+		"updated_at":    {Column: "updated_at", Type: TimeField, Sortable: true},
+		"created_by":    {Column: "created_by", Type: StringField},
+		"updated_by":    {Column: "updated_by", Type: StringField},
+	},
+	DefaultSort: "-created_at",
+	KeyColumn:   "id",
+	KeyType:     IntField,
+}
+
+// List retrieves one page of deliveries; only active ones unless the query filters on is_active
+func (r *ReportDeliveryRepository) List(q *ListQuery) ([]models.ReportDelivery, *PageMeta, error) {
+	if !q.HasFilter("is_active") {
+		q.AddFilter("is_active", "eq", true)
+	}
+	return FindPage[models.ReportDelivery](r.DB, DeliveryListSpec, q)
+}
+
 // GetByID retrieves a delivery by ID
 func (r *ReportDeliveryRepository) GetByID(id int) (*models.ReportDelivery, error) {
 	var delivery models.ReportDelivery
@@ -52,9 +78,19 @@ func (r *ReportDeliveryRepository) Create(delivery *models.ReportDelivery) error
 	return r.DB.Create(delivery).Error
 }
 
-// Update updates an existing delivery
+// Update saves all fields of delivery if it is still at delivery.Version and bumps the version.
+// Returns ErrVersionConflict if another write got there first.
 func (r *ReportDeliveryRepository) Update(delivery *models.ReportDelivery) error {
-	return r.DB.Save(delivery).Error
+	expectedVersion := delivery.Version
+	delivery.Version++
+	result := r.DB.Model(delivery).Where("version = ?", expectedVersion).Select("*").Updates(delivery)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrVersionConflict
+	}
+	if result.Error != nil {
+		delivery.Version = expectedVersion
+	}
+	return result.Error
 }
 
 // Delete soft deletes a delivery
@@ -72,3 +108,8 @@ func (r *ReportDeliveryRepository) CheckConfigExists(configID int) (bool, error)
 		Count(&count).Error
 	return count > 0, err
 }
+
+// UpdateSecrets rewrites delivery_config so its secrets are encrypted with the active key
+func (r *ReportDeliveryRepository) UpdateSecrets(delivery *models.ReportDelivery) error {
+	return r.DB.Model(&models.ReportDelivery{}).Where("id = ?", delivery.ID).UpdateColumn("delivery_config", delivery.DeliveryConfig).Error
+}
@@ -3,6 +3,8 @@ package repository
 import (
 	"scheduling-report/config"
 	"scheduling-report/models"
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -14,19 +16,25 @@ func NewReportExecutionRepository() *ReportExecutionRepository {
 	return &ReportExecutionRepository{DB: config.DB}
 }
 
-func (r *ReportExecutionRepository) GetAll(status *string, limit int) ([]models.ReportExecution, error) {
-	var executions []models.ReportExecution
-	query := r.DB.Order("started_at DESC")
-	
-	if status != nil {
-		query = query.Where("status = ?", *status)
-	}
-	if limit > 0 {
-		query = query.Limit(limit)
-	}
-	
-	err := query.Find(&executions).Error
-	return executions, err
+// ExecutionListSpec is what GET /api/executions can filter and sort on
+var ExecutionListSpec = ListSpec{
+	Fields: map[string]ListField{
+		"id":           {Column: "id", Type: StringField},
+		"config_id":    {Column: "config_id", Type: IntField},
+		"schedule_id":  {Column: "schedule_id", Type: IntField},
+		"status":       {Column: "status", Type: StringField, Enum: []string{"queued", "running", "completed", "failed", "cancelled"}},
+		"executed_by":  {Column: "executed_by", Type: StringField},
+		"started_at":   {Column: "started_at", Type: TimeField, Sortable: true},
+		"completed_at": {Column: "completed_at", Type: TimeField},
+	},
+	DefaultSort: "-started_at",
+	KeyColumn:   "id",
+	KeyType:     StringField,
+}
+
+// List retrieves one page of executions
+func (r *ReportExecutionRepository) List(q *ListQuery) ([]models.ReportExecution, *PageMeta, error) {
+	return FindPage[models.ReportExecution](r.DB, ExecutionListSpec, q)
 }
 
 func (r *ReportExecutionRepository) GetByID(id string) (*models.ReportExecution, error) {
@@ -51,3 +59,60 @@ func (r *ReportExecutionRepository) GetByConfigID(configID int, limit int) ([]mo
 func (r *ReportExecutionRepository) Create(execution *models.ReportExecution) error {
 	return r.DB.Create(execution).Error
 }
+
+// TransitionStatus applies updates only while the execution is in one of fromStatuses,
+// so redelivered or out-of-order results are ignored. Reports whether the row changed.
+func (r *ReportExecutionRepository) TransitionStatus(id string, fromStatuses []string, updates map[string]interface{}) (bool, error) {
+	result := r.DB.Model(&models.ReportExecution{}).
+		Where("id = ? AND status IN ?", id, fromStatuses).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindStaleRunning returns the running executions whose started_at plus their config's timeout_seconds
+// (defaultTimeoutSeconds when unset) plus grace lies before now, i.e. whose worker has stopped responding
+func (r *ReportExecutionRepository) FindStaleRunning(now time.Time, defaultTimeoutSeconds int, grace time.Duration) ([]models.ReportExecution, error) {
+	// Nothing younger than the grace period can be stale, whatever its timeout
+	var running []models.ReportExecution
+	if err := r.DB.Where("status = ? AND started_at < ?", "running", now.Add(-grace)).
+		Order("started_at ASC").
+		Find(&running).Error; err != nil {
+		return nil, err
+	}
+	if len(running) == 0 {
+		return nil, nil
+	}
+
+	configIDs := make([]int, 0, len(running))
+	for _, execution := range running {
+		configIDs = append(configIDs, execution.ConfigID)
+	}
+
+	var timeouts []struct {
+		ID             int
+		TimeoutSeconds int
+	}
+	if err := r.DB.Model(&models.ReportConfig{}).
+		Select("id, timeout_seconds").
+		Where("id IN ?", configIDs).
+		Scan(&timeouts).Error; err != nil {
+		return nil, err
+	}
+	timeoutByConfig := map[int]int{}
+	for _, timeout := range timeouts {
+		timeoutByConfig[timeout.ID] = timeout.TimeoutSeconds
+	}
+
+	var stale []models.ReportExecution
+	for _, execution := range running {
+		timeoutSeconds := timeoutByConfig[execution.ConfigID]
+		if timeoutSeconds <= 0 {
+			timeoutSeconds = defaultTimeoutSeconds
+		}
+		if execution.StartedAt.Add(time.Duration(timeoutSeconds)*time.Second + grace).Before(now) {
+			stale = append(stale, execution)
+		}
+	}
+
+	return stale, nil
+}
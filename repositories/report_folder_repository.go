@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"scheduling-report/config"
+	"scheduling-report/models"
+
+	"gorm.io/gorm"
+)
+
+type ReportFolderRepository struct {
+	DB *gorm.DB
+}
+
+func NewReportFolderRepository() *ReportFolderRepository {
+	return &ReportFolderRepository{DB: config.DB}
+}
+
+// ReportFolderListSpec is what GET /api/folders can filter and sort on
+var ReportFolderListSpec = ListSpec{
+	Fields: map[string]ListField{
+		"id":         {Column: "id", Type: IntField, Sortable: true},
+		"name":       {Column: "name", Type: StringField, Sortable: true},
+		"path":       {Column: "path", Type: StringField, Sortable: true},
+		"parent_id":  {Column: "parent_id", Type: IntField},
+		"created_at": {Column: "created_at", Type: TimeField, Sortable: true},
+		"created_by": {Column: "created_by", Type: StringField},
+	},
+	DefaultSort: "path",
+	KeyColumn:   "id",
+	KeyType:     IntField,
+}
+
+// List retrieves one page of folders
+func (r *ReportFolderRepository) List(q *ListQuery) ([]models.ReportFolder, *PageMeta, error) {
+	return FindPage[models.ReportFolder](r.DB, ReportFolderListSpec, q)
+}
+
+// GetByID retrieves a folder by ID
+func (r *ReportFolderRepository) GetByID(id int) (*models.ReportFolder, error) {
+	var folder models.ReportFolder
+	err := r.DB.First(&folder, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// GetByPath retrieves a folder by its path
+func (r *ReportFolderRepository) GetByPath(path string) (*models.ReportFolder, error) {
+	var folder models.ReportFolder
+	err := r.DB.Where("path = ?", path).First(&folder).Error
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// CheckPathExists checks if a folder path is taken (excluding given ID)
+func (r *ReportFolderRepository) CheckPathExists(path string, excludeID int) (bool, error) {
+	var count int64
+	query := r.DB.Model(&models.ReportFolder{}).Where("path = ?", path)
+
+	if excludeID > 0 {
+		query = query.Where("id != ?", excludeID)
+	}
+
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+// Create creates a new folder
+func (r *ReportFolderRepository) Create(folder *models.ReportFolder) error {
+	return r.DB.Create(folder).Error
+}
+
+// Update saves a folder and moves its subfolders along when its path changed
+func (r *ReportFolderRepository) Update(folder *models.ReportFolder, oldPath string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(folder).Error; err != nil {
+			return err
+		}
+		if folder.Path == oldPath {
+			return nil
+		}
+		return tx.Model(&models.ReportFolder{}).
+			Where("path LIKE ?", likeEscaper.Replace(oldPath)+"/%").
+			Update("path", gorm.Expr("CONCAT(?, SUBSTRING(path, ?))", folder.Path, len(oldPath)+1)).Error
+	})
+}
+
+// Delete removes a folder
+func (r *ReportFolderRepository) Delete(id int) error {
+	return r.DB.Delete(&models.ReportFolder{}, id).Error
+}
+
+// CountChildren counts the subfolders and the configs directly in a folder
+func (r *ReportFolderRepository) CountChildren(id int) (folders int64, configs int64, err error) {
+	if err = r.DB.Model(&models.ReportFolder{}).Where("parent_id = ?", id).Count(&folders).Error; err != nil {
+		return 0, 0, err
+	}
+	err = r.DB.Model(&models.ReportConfig{}).Where("folder_id = ?", id).Count(&configs).Error
+	return folders, configs, err
+}
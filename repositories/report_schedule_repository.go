@@ -1,10 +1,13 @@
 package repository
 
 import (
+	"errors"
 	"scheduling-report/config"
 	"scheduling-report/models"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ReportScheduleRepository struct {
@@ -17,17 +20,33 @@ func NewReportScheduleRepository() *ReportScheduleRepository {
 	}
 }
 
-// GetAll retrieves all active report schedules with optional filtering
-func (r *ReportScheduleRepository) GetAll(isActive *bool) ([]models.ReportSchedule, error) {
-	var schedules []models.ReportSchedule
-	query := r.DB.Where("is_active = ?", true)
+// ScheduleListSpec is what GET /api/schedules and /api/schedules/details can filter and sort on
+var ScheduleListSpec = ListSpec{
+	Fields: map[string]ListField{
+		"id":              {Column: "id", Type: IntField, Sortable: true},
+		"config_id":       {Column: "config_id", Type: IntField},
+		"cron_expression": {Column: "cron_expression", Type: StringField},
+		"timezone":        {Column: "timezone", Type: StringField},
+		"is_active":       {Column: "is_active", Type: BoolField},
+		"misfire_policy":  {Column: "misfire_policy", Type: StringField, Enum: []string{models.MisfirePolicySkip, models.MisfirePolicyRunOnce, models.MisfirePolicyRunAll}},
+		"last_run_at":     {Column: "last_run_at", Type: TimeField},
+		"next_run_at":     {Column: "next_run_at", Type: TimeField},
+		"created_at":      {Column: "created_at", Type: TimeField, Sortable: true},
+		"updated_at":      {Column: "updated_at", Type: TimeField, Sortable: true},
+		"created_by":      {Column: "created_by", Type: StringField},
+		"updated_by":      {Column: "updated_by", Type: StringField},
+	},
+	DefaultSort: "-created_at",
+	KeyColumn:   "id",
+	KeyType:     IntField,
+}
 
-	if isActive != nil {
-		query = r.DB.Where("is_active = ?", *isActive)
+// List retrieves one page of report schedules; only active ones unless the query filters on is_active
+func (r *ReportScheduleRepository) List(q *ListQuery) ([]models.ReportSchedule, *PageMeta, error) {
+	if !q.HasFilter("is_active") {
+		q.AddFilter("is_active", "eq", true)
 	}
-
-	err := query.Order("created_at DESC").Find(&schedules).Error
-	return schedules, err
+	return FindPage[models.ReportSchedule](r.DB, ScheduleListSpec, q)
 }
 
 // GetByID retrieves a single report schedule by ID
@@ -52,9 +71,19 @@ func (r *ReportScheduleRepository) Create(schedule *models.ReportSchedule) error
 	return r.DB.Create(schedule).Error
 }
 
-// Update updates an existing report schedule
+// Update saves all fields of schedule if it is still at schedule.Version and bumps the version.
+// Returns ErrVersionConflict if another write got there first.
 func (r *ReportScheduleRepository) Update(schedule *models.ReportSchedule) error {
-	return r.DB.Save(schedule).Error
+	expectedVersion := schedule.Version
+	schedule.Version++
+	result := r.DB.Model(schedule).Where("version = ?", expectedVersion).Select("*").Updates(schedule)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrVersionConflict
+	}
+	if result.Error != nil {
+		schedule.Version = expectedVersion
+	}
+	return result.Error
 }
 
 // Delete soft deletes a report schedule by setting is_active to false
@@ -73,24 +102,120 @@ func (r *ReportScheduleRepository) CheckConfigExists(configID int) (bool, error)
 	return count > 0, err
 }
 
-// GetSchedulesWithDetails retrieves schedules with full config and delivery details
-func (r *ReportScheduleRepository) GetSchedulesWithDetails(filters models.ScheduleDetailFilters) ([]models.ScheduleDetail, error) {
-	var schedules []models.ReportSchedule
-	query := r.DB.Model(&models.ReportSchedule{})
+// ErrScheduleRejected is wrapped by a ClaimDueSchedules claim function when the schedule can never run
+// as configured (broken cron or timezone, missing config); such schedules are deactivated instead of
+// being locked and retried on every poll
+var ErrScheduleRejected = errors.New("schedule rejected")
+
+// ClaimDueSchedules locks up to limit due schedules with FOR UPDATE SKIP LOCKED and hands each one to claim
+// inside the same transaction, so concurrent dispatchers never claim the same run and the advanced
+// next_run_at commits together with whatever claim wrote (the queued executions and their outbox rows).
+// claim returns the schedule's new last_run_at (nil keeps the stored value) and next_run_at.
+// Each schedule runs in its own savepoint: when claim fails its writes are rolled back and the schedule
+// stays due for the next poll, or is deactivated when the error wraps ErrScheduleRejected.
+// Returned schedules keep their pre-claim values.
+func (r *ReportScheduleRepository) ClaimDueSchedules(now time.Time, limit int, claim func(tx *gorm.DB, schedule models.ReportSchedule) (*time.Time, time.Time, error)) (claimed []models.ReportSchedule, deactivated []models.ReportSchedule, err error) {
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		var due []models.ReportSchedule
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("is_active = ? AND next_run_at IS NOT NULL AND next_run_at <= ?", true, now).
+			Order("next_run_at ASC")
+		if limit > 0 {
+			query = query.Limit(limit)
+		}
+		if err := query.Find(&due).Error; err != nil {
+			return err
+		}
+
+		for _, schedule := range due {
+			var lastRunAt *time.Time
+			var nextRunAt time.Time
+
+			claimErr := tx.Transaction(func(sp *gorm.DB) error {
+				var err error
+				lastRunAt, nextRunAt, err = claim(sp, schedule)
+				return err
+			})
+			if errors.Is(claimErr, ErrScheduleRejected) {
+				if err := tx.Model(&models.ReportSchedule{}).
+					Where("id = ?", schedule.ID).
+					Update("is_active", false).Error; err != nil {
+					return err
+				}
+				deactivated = append(deactivated, schedule)
+				continue
+			}
+			if claimErr != nil {
+				continue
+			}
+
+			updates := map[string]interface{}{
+				"next_run_at": nextRunAt,
+			}
+			if lastRunAt != nil {
+				updates["last_run_at"] = *lastRunAt
+			}
+
+			if err := tx.Model(&models.ReportSchedule{}).
+				Where("id = ?", schedule.ID).
+				Updates(updates).Error; err != nil {
+				return err
+			}
+
+			claimed = append(claimed, schedule)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return claimed, deactivated, nil
+}
+
+// AdvanceLastRunAt moves last_run_at forward to lastRunAt; older values never overwrite newer ones
+func (r *ReportScheduleRepository) AdvanceLastRunAt(id int, lastRunAt time.Time) error {
+	return r.DB.Model(&models.ReportSchedule{}).
+		Where("id = ? AND (last_run_at IS NULL OR last_run_at < ?)", id, lastRunAt).
+		Update("last_run_at", lastRunAt).Error
+}
+
+// scheduleDetailListSpec is ScheduleListSpec for the details query, which joins report_configs
+var scheduleDetailListSpec = ScheduleListSpec.Qualified("report_schedules")
+
+// GetSchedulesWithDetails retrieves one page of schedules with full config and delivery details.
+// The list query filters and sorts the schedules; filters narrow them by config and has_run,
+// and the deliveries shown by delivery_is_active and delivery_method.
+//
+// It runs a fixed number of queries whatever the page size: the count and the page of schedules,
+// joined to their configs so the config filters apply in SQL, then one batch each for the page's
+// configs, their tags, their deliveries and the deliveries' recipients.
+func (r *ReportScheduleRepository) GetSchedulesWithDetails(filters models.ScheduleDetailFilters, q *ListQuery) ([]models.ScheduleDetail, *PageMeta, error) {
+	query := r.DB.Model(&models.ReportSchedule{}).
+		Joins("JOIN report_configs ON report_configs.id = report_schedules.config_id")
 
-	// Schedule filters
-	if filters.IsActive != nil {
-		query = query.Where("report_schedules.is_active = ?", *filters.IsActive)
+	// Config filters
+	if filters.ConfigIsActive != nil {
+		query = query.Where("report_configs.is_active = ?", *filters.ConfigIsActive)
 	}
-	if filters.Timezone != "" {
-		query = query.Where("report_schedules.timezone = ?", filters.Timezone)
+	if filters.DatasourceID != nil {
+		query = query.Where("report_configs.datasource_id = ?", *filters.DatasourceID)
 	}
-	if filters.ConfigID != nil {
-		query = query.Where("report_schedules.config_id = ?", *filters.ConfigID)
+	if filters.OutputFormat != "" {
+		query = query.Where("report_configs.output_format = ?", filters.OutputFormat)
 	}
-	if filters.CreatedBy != "" {
-		query = query.Where("report_schedules.created_by = ?", filters.CreatedBy)
+	if filters.ConfigName != "" {
+		query = query.Where("report_configs.report_name LIKE ?", "%"+filters.ConfigName+"%")
 	}
+	if filters.FolderID != nil {
+		query = query.Where("report_configs.folder_id = ?", *filters.FolderID)
+	}
+	if filters.OwnerTeam != "" {
+		query = query.Where("report_configs.owner_team = ?", filters.OwnerTeam)
+	}
+	query = applyCatalogFilters(query, "report_configs", filters.ConfigCatalogFilters)
+
 	if filters.HasRun != nil {
 		if *filters.HasRun {
 			query = query.Where("report_schedules.last_run_at IS NOT NULL")
@@ -99,115 +224,121 @@ func (r *ReportScheduleRepository) GetSchedulesWithDetails(filters models.Schedu
 		}
 	}
 
-	// Execute query to get schedules
-	err := query.Order("report_schedules.created_at DESC").Find(&schedules).Error
+	schedules, meta, err := FindPage[models.ReportSchedule](query, scheduleDetailListSpec, q)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if len(schedules) == 0 {
+		return []models.ScheduleDetail{}, meta, nil
 	}
 
-	// Build detailed response
-	var details []models.ScheduleDetail
+	// Configs of the page
+	configIDs := make([]int, 0, len(schedules))
 	for _, schedule := range schedules {
-		// Get config
-		var config models.ReportConfig
-		configQuery := r.DB.Where("id = ?", schedule.ConfigID)
-		
-		// Apply config filters
-		if filters.ConfigIsActive != nil {
-			configQuery = configQuery.Where("is_active = ?", *filters.ConfigIsActive)
-		}
-		if filters.DatasourceID != nil {
-			configQuery = configQuery.Where("datasource_id = ?", *filters.DatasourceID)
-		}
-		if filters.OutputFormat != "" {
-			configQuery = configQuery.Where("output_format = ?", filters.OutputFormat)
-		}
-		if filters.ConfigName != "" {
-			configQuery = configQuery.Where("report_name LIKE ?", "%"+filters.ConfigName+"%")
-		}
-
-		err := configQuery.First(&config).Error
-		if err != nil {
-			// Skip schedules where config doesn't match filters
-			continue
-		}
+		configIDs = append(configIDs, schedule.ConfigID)
+	}
+	var configs []models.ReportConfig
+	if err := r.DB.Where("id IN ?", configIDs).Find(&configs).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := (&ReportConfigRepository{DB: r.DB}).LoadTags(configs); err != nil {
+		return nil, nil, err
+	}
 
-		// Get deliveries
-		var deliveries []models.ReportDelivery
-		deliveryQuery := r.DB.Where("config_id = ?", config.ID)
+	// Their deliveries
+	var deliveries []models.ReportDelivery
+	deliveryQuery := r.DB.Where("config_id IN ?", configIDs)
+	if filters.DeliveryIsActive != nil {
+		deliveryQuery = deliveryQuery.Where("is_active = ?", *filters.DeliveryIsActive)
+	}
+	if filters.DeliveryMethod != "" {
+		deliveryQuery = deliveryQuery.Where("method = ?", filters.DeliveryMethod)
+	}
+	if err := deliveryQuery.Order("id").Find(&deliveries).Error; err != nil {
+		return nil, nil, err
+	}
 
-		// Apply delivery filters
-		if filters.DeliveryIsActive != nil {
-			deliveryQuery = deliveryQuery.Where("is_active = ?", *filters.DeliveryIsActive)
+	// And the deliveries' active recipients
+	recipientsByDelivery := make(map[int][]models.ReportDeliveryRecipient)
+	if len(deliveries) > 0 {
+		deliveryIDs := make([]int, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			deliveryIDs = append(deliveryIDs, delivery.ID)
 		}
-		if filters.DeliveryMethod != "" {
-			deliveryQuery = deliveryQuery.Where("method = ?", filters.DeliveryMethod)
+		var recipients []models.ReportDeliveryRecipient
+		if err := r.DB.Where("delivery_id IN ? AND is_active = ?", deliveryIDs, true).Order("id").Find(&recipients).Error; err != nil {
+			return nil, nil, err
 		}
-
-		deliveryQuery.Find(&deliveries)
-
-		// Build deliveries with recipients
-		var deliveriesWithRecipients []models.DeliveryWithRecipients
-		for _, delivery := range deliveries {
-			// Get recipients for this delivery
-			var recipients []models.ReportDeliveryRecipient
-			r.DB.Where("delivery_id = ? AND is_active = ?", delivery.ID, true).Find(&recipients)
-
-			// Build delivery with recipients
-			deliveryWithRecipients := models.DeliveryWithRecipients{
-				ID:                   delivery.ID,
-				ConfigID:             delivery.ConfigID,
-				DeliveryName:         delivery.DeliveryName,
-				Method:               delivery.Method,
-				DeliveryConfig:       delivery.DeliveryConfig,
-				MaxRetry:             delivery.MaxRetry,
-				Recipients:           recipients,
-				RetryIntervalMinutes: delivery.RetryIntervalMinutes,
-				IsActive:             delivery.IsActive,
-				CreatedAt:            delivery.CreatedAt,
-				UpdatedAt:            delivery.UpdatedAt,
-				CreatedBy:            delivery.CreatedBy,
-				UpdatedBy:            delivery.UpdatedBy,
-			}
-
-			deliveriesWithRecipients = append(deliveriesWithRecipients, deliveryWithRecipients)
+		for _, recipient := range recipients {
+			recipientsByDelivery[recipient.DeliveryID] = append(recipientsByDelivery[recipient.DeliveryID], recipient)
 		}
+	}
+
+	// Build deliveries with recipients, by config
+	deliveriesByConfig := make(map[int][]models.DeliveryWithRecipients)
+	for _, delivery := range deliveries {
+		deliveriesByConfig[delivery.ConfigID] = append(deliveriesByConfig[delivery.ConfigID], models.DeliveryWithRecipients{
+			ID:                   delivery.ID,
+			ConfigID:             delivery.ConfigID,
+			DeliveryName:         delivery.DeliveryName,
+			Method:               delivery.Method,
+			DeliveryConfig:       delivery.DeliveryConfig,
+			MaxRetry:             delivery.MaxRetry,
+			Recipients:           recipientsByDelivery[delivery.ID],
+			RetryIntervalMinutes: delivery.RetryIntervalMinutes,
+			IsActive:             delivery.IsActive,
+			CreatedAt:            delivery.CreatedAt,
+			UpdatedAt:            delivery.UpdatedAt,
+			CreatedBy:            delivery.CreatedBy,
+			UpdatedBy:            delivery.UpdatedBy,
+		})
+	}
 
-		// Build config with deliveries
-		configWithDeliveries := models.ConfigWithDeliveries{
-			ID:             config.ID,
-			ReportName:     config.ReportName,
-			ReportQuery:    config.ReportQuery,
-			OutputFormat:   config.OutputFormat,
-			DatasourceID:   config.DatasourceID,
-			Parameters:     config.Parameters,
-			TimeoutSeconds: config.TimeoutSeconds,
-			MaxRows:        config.MaxRows,
-			IsActive:       config.IsActive,
-			CreatedAt:      config.CreatedAt,
-			UpdatedAt:      config.UpdatedAt,
-			CreatedBy:      config.CreatedBy,
-			UpdatedBy:      config.UpdatedBy,
-			Version:        config.Version,
-			Deliveries:     deliveriesWithRecipients,
+	// Build configs with deliveries
+	configsByID := make(map[int]*models.ConfigWithDeliveries, len(configs))
+	for _, config := range configs {
+		configsByID[config.ID] = &models.ConfigWithDeliveries{
+			ID:              config.ID,
+			ReportName:      config.ReportName,
+			ReportQuery:     config.ReportQuery,
+			OutputFormat:    config.OutputFormat,
+			DatasourceID:    config.DatasourceID,
+			Parameters:      config.Parameters,
+			ParameterSchema: config.ParameterSchema,
+			TimeoutSeconds:  config.TimeoutSeconds,
+			MaxRows:         config.MaxRows,
+			IsActive:        config.IsActive,
+			CreatedAt:       config.CreatedAt,
+			UpdatedAt:       config.UpdatedAt,
+			CreatedBy:       config.CreatedBy,
+			UpdatedBy:       config.UpdatedBy,
+			Version:         config.Version,
+			FolderID:        config.FolderID,
+			OwnerTeam:       config.OwnerTeam,
+			Owners:          config.Owners,
+			Tags:            config.Tags,
+			Deliveries:      deliveriesByConfig[config.ID],
 		}
+	}
 
-		// Build schedule detail
-		detail := models.ScheduleDetail{
+	// Build schedule details, in page order
+	details := make([]models.ScheduleDetail, 0, len(schedules))
+	for _, schedule := range schedules {
+		details = append(details, models.ScheduleDetail{
 			ID:             schedule.ID,
-			Config:         &configWithDeliveries,
+			Config:         configsByID[schedule.ConfigID],
 			CronExpression: schedule.CronExpression,
 			Timezone:       schedule.Timezone,
 			IsActive:       schedule.IsActive,
 			LastRunAt:      schedule.LastRunAt,
+			MisfirePolicy:  schedule.MisfirePolicy,
+			MisfireMaxRuns: schedule.MisfireMaxRuns,
 			CreatedAt:      schedule.CreatedAt,
 			UpdatedAt:      schedule.UpdatedAt,
 			CreatedBy:      schedule.CreatedBy,
 			UpdatedBy:      schedule.UpdatedBy,
-		}
-
-		details = append(details, detail)
+		})
 	}
 
-	return details, nil
+	return details, meta, nil
 }
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+
+	"scheduling-report/internal/testdb"
+	"scheduling-report/models"
+
+	"gorm.io/gorm"
+)
+
+// seedScheduleDetails inserts n schedules, each on its own tagged config with two deliveries of two recipients
+func seedScheduleDetails(tb testing.TB, db *gorm.DB, n int) {
+	tb.Helper()
+
+	datasource := models.DataSource{Name: "reports", DbType: "mysql", ConnectionURL: "report@tcp(localhost)/reports", IsActive: true, CreatedBy: "test", UpdatedBy: "test"}
+	if err := db.Create(&datasource).Error; err != nil {
+		tb.Fatalf("seed datasource: %v", err)
+	}
+	tag := models.ReportTag{Name: "finance", CreatedBy: "test", UpdatedBy: "test"}
+	if err := db.Create(&tag).Error; err != nil {
+		tb.Fatalf("seed tag: %v", err)
+	}
+
+	for i := 0; i < n; i++ {
+		reportConfig := models.ReportConfig{
+			ReportName:   fmt.Sprintf("Report %d", i),
+			ReportQuery:  "SELECT 1",
+			OutputFormat: "csv",
+			DatasourceID: datasource.ID,
+			IsActive:     true,
+			CreatedBy:    "test",
+			UpdatedBy:    "test",
+			Version:      1,
+		}
+		if err := db.Create(&reportConfig).Error; err != nil {
+			tb.Fatalf("seed config: %v", err)
+		}
+		if err := db.Create(&models.ReportConfigTag{ConfigID: reportConfig.ID, TagID: tag.ID}).Error; err != nil {
+			tb.Fatalf("seed config tag: %v", err)
+		}
+		if err := db.Create(&models.ReportSchedule{
+			ConfigID:       reportConfig.ID,
+			CronExpression: "0 6 * * *",
+			Timezone:       "UTC",
+			IsActive:       true,
+			MisfirePolicy:  models.MisfirePolicyRunOnce,
+			CreatedBy:      "test",
+			UpdatedBy:      "test",
+			Version:        1,
+		}).Error; err != nil {
+			tb.Fatalf("seed schedule: %v", err)
+		}
+
+		for d := 0; d < 2; d++ {
+			delivery := models.ReportDelivery{
+				ConfigID:       reportConfig.ID,
+				DeliveryName:   fmt.Sprintf("Delivery %d", d),
+				Method:         "email",
+				DeliveryConfig: models.DeliveryConfig{"subject": "Daily report"},
+				IsActive:       true,
+				CreatedBy:      "test",
+				UpdatedBy:      "test",
+				Version:        1,
+			}
+			if err := db.Create(&delivery).Error; err != nil {
+				tb.Fatalf("seed delivery: %v", err)
+			}
+			for r := 0; r < 2; r++ {
+				if err := db.Create(&models.ReportDeliveryRecipient{
+					DeliveryID:     delivery.ID,
+					RecipientType:  "email",
+					RecipientValue: fmt.Sprintf("user%d@example.com", r),
+					IsActive:       true,
+					Version:        1,
+				}).Error; err != nil {
+					tb.Fatalf("seed recipient: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// detailQueryCount loads one page of n schedule details and returns how many statements it ran
+func detailQueryCount(tb testing.TB, n int) int64 {
+	tb.Helper()
+
+	db := testdb.Open(tb)
+	seedScheduleDetails(tb, db, n)
+
+	q, err := ParseListQuery(map[string]string{"limit": fmt.Sprint(MaxListLimit)}, ScheduleListSpec)
+	if err != nil {
+		tb.Fatalf("ParseListQuery: %v", err)
+	}
+
+	count := testdb.CountQueries(db)
+	details, _, err := (&ReportScheduleRepository{DB: db}).GetSchedulesWithDetails(models.ScheduleDetailFilters{}, q)
+	if err != nil {
+		tb.Fatalf("GetSchedulesWithDetails: %v", err)
+	}
+	if len(details) != n {
+		tb.Fatalf("got %d schedule details, want %d", len(details), n)
+	}
+	return count.Load()
+}
+
+func TestGetSchedulesWithDetailsQueryCountIsConstant(t *testing.T) {
+	want := detailQueryCount(t, 1)
+	for _, n := range []int{10, 100} {
+		if got := detailQueryCount(t, n); got != want {
+			t.Errorf("%d schedules ran %d queries, want %d as for 1 schedule", n, got, want)
+		}
+	}
+}
+
+func BenchmarkGetSchedulesWithDetails(b *testing.B) {
+	var baseline int64
+	for _, n := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("schedules=%d", n), func(b *testing.B) {
+			db := testdb.Open(b)
+			seedScheduleDetails(b, db, n)
+			q, err := ParseListQuery(map[string]string{"limit": fmt.Sprint(MaxListLimit)}, ScheduleListSpec)
+			if err != nil {
+				b.Fatalf("ParseListQuery: %v", err)
+			}
+			repo := &ReportScheduleRepository{DB: db}
+			count := testdb.CountQueries(db)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := repo.GetSchedulesWithDetails(models.ScheduleDetailFilters{}, q); err != nil {
+					b.Fatalf("GetSchedulesWithDetails: %v", err)
+				}
+			}
+			b.StopTimer()
+
+			perOp := count.Load() / int64(b.N)
+			b.ReportMetric(float64(perOp), "queries/op")
+			if baseline == 0 {
+				baseline = perOp
+			} else if perOp != baseline {
+				b.Errorf("%d schedules ran %d queries per call, want %d as for 1 schedule", n, perOp, baseline)
+			}
+		})
+	}
+}
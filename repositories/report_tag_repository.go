@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"sort"
+
+	"scheduling-report/config"
+	"scheduling-report/models"
+
+	"gorm.io/gorm"
+)
+
+type ReportTagRepository struct {
+	DB *gorm.DB
+}
+
+func NewReportTagRepository() *ReportTagRepository {
+	return &ReportTagRepository{DB: config.DB}
+}
+
+// ReportTagListSpec is what GET /api/tags can filter and sort on
+var ReportTagListSpec = ListSpec{
+	Fields: map[string]ListField{
+		"id":         {Column: "id", Type: IntField, Sortable: true},
+		"name":       {Column: "name", Type: StringField, Sortable: true},
+		"created_at": {Column: "created_at", Type: TimeField, Sortable: true},
+		"created_by": {Column: "created_by", Type: StringField},
+	},
+	DefaultSort: "name",
+	KeyColumn:   "id",
+	KeyType:     IntField,
+}
+
+// List retrieves one page of tags
+func (r *ReportTagRepository) List(q *ListQuery) ([]models.ReportTag, *PageMeta, error) {
+	return FindPage[models.ReportTag](r.DB, ReportTagListSpec, q)
+}
+
+// GetByID retrieves a tag by ID
+func (r *ReportTagRepository) GetByID(id int) (*models.ReportTag, error) {
+	var tag models.ReportTag
+	err := r.DB.First(&tag, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// GetByNames retrieves the tags with the given names; missing names are left out
+func (r *ReportTagRepository) GetByNames(names []string) ([]models.ReportTag, error) {
+	var tags []models.ReportTag
+	if len(names) == 0 {
+		return tags, nil
+	}
+	err := r.DB.Where("name IN ?", names).Find(&tags).Error
+	return tags, err
+}
+
+// CheckNameExists checks if a tag name already exists (excluding given ID)
+func (r *ReportTagRepository) CheckNameExists(name string, excludeID int) (bool, error) {
+	var count int64
+	query := r.DB.Model(&models.ReportTag{}).Where("name = ?", name)
+
+	if excludeID > 0 {
+		query = query.Where("id != ?", excludeID)
+	}
+
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+// Create creates a new tag
+func (r *ReportTagRepository) Create(tag *models.ReportTag) error {
+	return r.DB.Create(tag).Error
+}
+
+// Update saves a tag
+func (r *ReportTagRepository) Update(tag *models.ReportTag) error {
+	return r.DB.Save(tag).Error
+}
+
+// Delete removes a tag and untags every config carrying it, bumping their metadata version
+func (r *ReportTagRepository) Delete(id int) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		configIDs := tx.Session(&gorm.Session{NewDB: true}).Model(&models.ReportConfigTag{}).Select("config_id").Where("tag_id = ?", id)
+		if err := tx.Model(&models.ReportConfig{}).Where("id IN (?)", configIDs).
+			UpdateColumn("metadata_version", gorm.Expr("metadata_version + 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tag_id = ?", id).Delete(&models.ReportConfigTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.ReportTag{}, id).Error
+	})
+}
+
+// GetConfigIDs retrieves the IDs of the configs carrying a tag
+func (r *ReportTagRepository) GetConfigIDs(tagID int) ([]int, error) {
+	var configIDs []int
+	err := r.DB.Model(&models.ReportConfigTag{}).Where("tag_id = ?", tagID).Order("config_id").Pluck("config_id", &configIDs).Error
+	return configIDs, err
+}
+
+// GetNamesByConfigIDs retrieves the sorted tag names of each config, with one query
+func (r *ReportTagRepository) GetNamesByConfigIDs(configIDs []int) (map[int][]string, error) {
+	names := make(map[int][]string, len(configIDs))
+	if len(configIDs) == 0 {
+		return names, nil
+	}
+
+	var rows []struct {
+		ConfigID int
+		Name     string
+	}
+	err := r.DB.Table("report_config_tags").
+		Select("report_config_tags.config_id, report_tags.name").
+		Joins("JOIN report_tags ON report_tags.id = report_config_tags.tag_id").
+		Where("report_config_tags.config_id IN ?", configIDs).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		names[row.ConfigID] = append(names[row.ConfigID], row.Name)
+	}
+	for _, configNames := range names {
+		sort.Strings(configNames)
+	}
+	return names, nil
+}
+
+// SetConfigTags replaces the tags of a config
+func (r *ReportTagRepository) SetConfigTags(configID int, tagIDs []int) error {
+	if err := r.DB.Where("config_id = ?", configID).Delete(&models.ReportConfigTag{}).Error; err != nil {
+		return err
+	}
+	if len(tagIDs) == 0 {
+		return nil
+	}
+	links := make([]models.ReportConfigTag, 0, len(tagIDs))
+	for _, tagID := range tagIDs {
+		links = append(links, models.ReportConfigTag{ConfigID: configID, TagID: tagID})
+	}
+	return r.DB.Create(&links).Error
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"errors"
+
+	"scheduling-report/config"
+	"scheduling-report/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SchedulerLeaseRepository struct {
+	DB *gorm.DB
+}
+
+func NewSchedulerLeaseRepository() *SchedulerLeaseRepository {
+	return &SchedulerLeaseRepository{DB: config.DB}
+}
+
+// TryAcquire takes or renews the named lease for holderID and reports whether holderID owns it.
+// Expiry is evaluated with the database clock so replicas with skewed clocks agree on the holder.
+func (r *SchedulerLeaseRepository) TryAcquire(name string, holderID string, ttlSeconds int) (bool, error) {
+	// Make sure the lease row exists (first replica to start creates it already expired)
+	if err := r.DB.Model(&models.SchedulerLease{}).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(map[string]interface{}{
+			"name":        name,
+			"holder_id":   "",
+			"acquired_at": gorm.Expr("NOW()"),
+			"expires_at":  gorm.Expr("NOW()"),
+		}).Error; err != nil {
+		return false, err
+	}
+
+	// Renew our own lease or take over an expired one.
+	// GORM emits map assignments in key order, so acquired_at still sees the previous holder_id.
+	err := r.DB.Model(&models.SchedulerLease{}).
+		Where("name = ? AND (holder_id = ? OR expires_at < NOW())", name, holderID).
+		Updates(map[string]interface{}{
+			"acquired_at": gorm.Expr("IF(holder_id = ?, acquired_at, NOW())", holderID),
+			"holder_id":   holderID,
+			"expires_at":  gorm.Expr("DATE_ADD(NOW(), INTERVAL ? SECOND)", ttlSeconds),
+		}).Error
+	if err != nil {
+		return false, err
+	}
+
+	// RowsAffected is unreliable when the renewed values are unchanged, so read back the holder
+	var lease models.SchedulerLease
+	if err := r.DB.Where("name = ?", name).First(&lease).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return lease.HolderID == holderID, nil
+}
+
+// Release expires the lease immediately if holderID still owns it, so another replica can take over
+func (r *SchedulerLeaseRepository) Release(name string, holderID string) error {
+	return r.DB.Model(&models.SchedulerLease{}).
+		Where("name = ? AND holder_id = ?", name, holderID).
+		Update("expires_at", gorm.Expr("DATE_SUB(NOW(), INTERVAL 1 SECOND)")).Error
+}
@@ -13,12 +13,16 @@ func SetupRoutes(app *fiber.App) {
 	reportConfigCtrl := controllers.NewReportConfigController()
 	scheduleCtrl := controllers.NewReportScheduleController()
 	completeScheduleCtrl := controllers.NewCompleteScheduleController()
+	bundleCtrl := controllers.NewScheduleBundleController()
 	previewCtrl := controllers.NewSchedulePreviewController()
 	deliveryCtrl := controllers.NewReportDeliveryController()
 	recipientCtrl := controllers.NewReportDeliveryRecipientController()
 	executionCtrl := controllers.NewReportExecutionController()
 	deliveryLogCtrl := controllers.NewReportDeliveryLogController()
 	auditCtrl := controllers.NewReportConfigAuditController()
+	templateVariableCtrl := controllers.NewTemplateVariableController()
+	folderCtrl := controllers.NewReportFolderController()
+	tagCtrl := controllers.NewReportTagController()
 
 	// API routes
 	api := app.Group("/api")
@@ -28,15 +32,39 @@ func SetupRoutes(app *fiber.App) {
 	api.Get("/datasources/:id", datasourceCtrl.GetDatasourceByID)
 	api.Post("/datasources", datasourceCtrl.CreateDatasource)
 	api.Put("/datasources/:id", datasourceCtrl.UpdateDatasource)
+	api.Post("/datasources/:id/test", datasourceCtrl.TestDatasource) // Probe connection: latency, server version, error category
+	api.Get("/datasources/:id/schema", datasourceCtrl.GetDatasourceSchema) // Tables, columns and row estimates (cached, ?refresh=true)
+	api.Get("/datasources/:id/schema/:table", datasourceCtrl.GetTableStatistics) // Sample column statistics
 	api.Delete("/datasources/:id", datasourceCtrl.DeleteDatasource)
 
 	// Report Configs endpoints (Phase 2)
 	api.Get("/report-configs", reportConfigCtrl.GetReportConfigs)
+	api.Get("/report-configs/drift", reportConfigCtrl.GetDrift) // Sync-managed configs edited outside sync - MUST be before :id
 	api.Get("/report-configs/:id", reportConfigCtrl.GetReportConfigByID)
 	api.Post("/report-configs", reportConfigCtrl.CreateReportConfig)
+	api.Post("/report-configs/sandbox", reportConfigCtrl.RunSandbox) // Run a saved or draft query read-only with row/time caps
+	api.Post("/report-configs/estimate-cost", reportConfigCtrl.EstimateCost) // EXPLAIN-based cost and load score
+	api.Post("/report-configs/tags/bulk", tagCtrl.BulkRetag) // Set, add or remove tags on many configs at once
 	api.Put("/report-configs/:id", reportConfigCtrl.UpdateReportConfig)
+	api.Put("/report-configs/:id/metadata", reportConfigCtrl.UpdateMetadata) // Folder, owning team, owners and tags (own metadata version and ETag)
+	api.Get("/report-configs/:id/versions", reportConfigCtrl.GetVersions)
+	api.Get("/report-configs/:id/versions/diff", reportConfigCtrl.DiffVersions) // Field-level diff ?from=&to= - MUST be before :version
+	api.Get("/report-configs/:id/versions/:version", reportConfigCtrl.GetVersion)
+	api.Post("/report-configs/:id/versions/:version/restore", reportConfigCtrl.RestoreVersion) // Restore as a new version, audited as rollback
 	api.Delete("/report-configs/:id", reportConfigCtrl.DeleteReportConfig)
 
+	// Folders and tags endpoints (report config catalog; changes are audited)
+	api.Get("/folders", folderCtrl.GetFolders)
+	api.Get("/folders/:id", folderCtrl.GetFolderByID)
+	api.Post("/folders", folderCtrl.CreateFolder)
+	api.Put("/folders/:id", folderCtrl.UpdateFolder) // Rename or move; subfolders move along
+	api.Delete("/folders/:id", folderCtrl.DeleteFolder) // Empty folders only
+	api.Get("/tags", tagCtrl.GetTags)
+	api.Get("/tags/:id", tagCtrl.GetTagByID)
+	api.Post("/tags", tagCtrl.CreateTag)
+	api.Put("/tags/:id", tagCtrl.UpdateTag)
+	api.Delete("/tags/:id", tagCtrl.DeleteTag) // Also untags every config
+
 	// Schedules endpoints (Phase 3)
 	api.Get("/schedules", scheduleCtrl.GetSchedules)
 	api.Get("/schedules/details", scheduleCtrl.GetSchedulesWithDetails) // Schedule details with full config and deliveries - MUST be before :id
@@ -45,7 +73,12 @@ func SetupRoutes(app *fiber.App) {
 
 	// Complete Schedule endpoints (Single API for frontend) - MUST be before :id
 	api.Post("/schedules/complete", completeScheduleCtrl.CreateComplete) // Create complete schedule with config, deliveries, recipients
+	api.Get("/schedules/complete/:id", completeScheduleCtrl.GetComplete) // Complete schedule with its ETag for If-Match
 	api.Put("/schedules/complete/:id", completeScheduleCtrl.UpdateComplete) // Update complete schedule (partial update)
+	api.Post("/schedules/complete/:id/clone", completeScheduleCtrl.CloneComplete) // Copy config, deliveries and recipients with optional overrides
+	api.Get("/schedules/complete/:id/export", bundleCtrl.ExportSchedule) // Portable bundle, ?format=json|yaml
+	api.Get("/schedules/export", bundleCtrl.ExportSchedules) // Bulk bundle export ?ids=1,2&format=json|yaml - MUST be before :id
+	api.Post("/schedules/import", bundleCtrl.ImportSchedules) // Import a JSON or YAML bundle with dry_run and conflict_strategy
 
 	api.Get("/schedules/:id", scheduleCtrl.GetScheduleByID)
 	api.Get("/schedules/config/:config_id", scheduleCtrl.GetSchedulesByConfigID)
@@ -72,6 +105,7 @@ func SetupRoutes(app *fiber.App) {
 	// Executions endpoints (Phase 5 - read-only + async execution)
 	api.Get("/executions", executionCtrl.GetExecutions)
 	api.Get("/executions/execute-async", executionCtrl.ExecuteAsync) // NEW: Async execution via Kafka - MUST be before :id
+	api.Post("/executions/execute-async", executionCtrl.ExecuteAsync) // Async execution with per-run parameter overrides
 	api.Get("/executions/:id", executionCtrl.GetExecutionByID)
 	api.Get("/executions/config/:config_id", executionCtrl.GetExecutionsByConfigID)
 
@@ -81,6 +115,9 @@ func SetupRoutes(app *fiber.App) {
 	api.Get("/delivery-logs/execution/:execution_id", deliveryLogCtrl.GetDeliveryLogsByExecutionID)
 	api.Get("/delivery-logs/delivery/:delivery_id", deliveryLogCtrl.GetDeliveryLogsByDeliveryID)
 
+	// Template variables endpoint (read-only - built-in query variables, offsets and formats)
+	api.Get("/template-variables", templateVariableCtrl.GetTemplateVariables)
+
 	// Audit Trail endpoints (read-only - audit logs created automatically)
 	api.Get("/audits", auditCtrl.GetAudits)
 	api.Get("/audits/:id", auditCtrl.GetAuditByID)
@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"scheduling-report/config"
 	"scheduling-report/models"
+	"scheduling-report/repositories"
+	"scheduling-report/utils"
+	"strings"
 	"time"
 
-	"github.com/robfig/cron/v3"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CompleteScheduleService struct{}
@@ -20,232 +23,510 @@ func NewCompleteScheduleService() *CompleteScheduleService {
 
 // maskSensitiveFields masks sensitive fields in delivery_config for security
 func maskSensitiveFields(deliveryConfig models.DeliveryConfig, method string) models.DeliveryConfig {
-	// Only mask for methods that might have sensitive data
-	if method == "sftp" || method == "webhook" || method == "s3" || method == "email" {
-		maskedConfig := make(models.DeliveryConfig)
-		for k, v := range deliveryConfig {
-			// Mask sensitive field names
-			if k == "password" || k == "api_key" || k == "secret" || k == "secret_key" || k == "access_key" {
-				maskedConfig[k] = "***MASKED***"
-			} else {
-				maskedConfig[k] = v
-			}
-		}
-		return maskedConfig
-	}
-	return deliveryConfig
+	return utils.MaskSecretFields(deliveryConfig)
 }
 
 // CreateComplete creates a complete schedule with config, deliveries, and recipients in a single transaction
 func (s *CompleteScheduleService) CreateComplete(req models.CompleteScheduleRequest) (*models.CompleteScheduleResponse, error) {
-	db := config.DB
+	// Heavy queries need cost_override; EXPLAIN runs before the transaction is opened
+	costEstimate, err := checkCreateCost(config.DB, req)
+	if err != nil {
+		return nil, err
+	}
+
 	var response *models.CompleteScheduleResponse
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		response, err = s.createComplete(tx, req, nil, costEstimate)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	// Start transaction
-	err := db.Transaction(func(tx *gorm.DB) error {
-		now := time.Now()
+	return response, nil
+}
 
-		// Step 1: Parse and prepare config data
-		var parameters models.Parameters
-		if req.Configs.Parameters != nil {
-			if err := json.Unmarshal(req.Configs.Parameters, &parameters); err != nil {
-				return fmt.Errorf("invalid parameters JSON: %w", err)
-			}
+// createComplete inserts the config, schedule, deliveries and recipients of req using tx.
+// source is set when cloning and supplies what the request cannot carry, such as stored credentials.
+// costEstimate is what the cost gate returned for req before tx was opened; it is recorded in the audit trail.
+func (s *CompleteScheduleService) createComplete(tx *gorm.DB, req models.CompleteScheduleRequest, source *cloneSource, costEstimate *QueryCostEstimate) (*models.CompleteScheduleResponse, error) {
+	now := time.Now()
+
+	// Step 1: Parse and prepare config data
+	var parameters models.Parameters
+	if req.Configs.Parameters != nil {
+		if err := json.Unmarshal(req.Configs.Parameters, &parameters); err != nil {
+			return nil, fmt.Errorf("invalid parameters JSON: %w", err)
 		}
+	}
 
-		timeoutSeconds := 300
-		maxRows := 10000
-		if req.Configs.TimeoutSeconds != nil {
-			timeoutSeconds = *req.Configs.TimeoutSeconds
+	if err := ValidateParameterSchema(req.Configs.ParameterSchema); err != nil {
+		return nil, fmt.Errorf("invalid parameter schema: %w", err)
+	}
+
+	// Only single read-only statements may be scheduled against the datasource
+	var datasource models.DataSource
+	if err := tx.First(&datasource, req.Configs.DatasourceID).Error; err != nil {
+		return nil, fmt.Errorf("datasource id %d not found: %w", req.Configs.DatasourceID, err)
+	}
+	if err := CheckReportQuery(0, req.Configs.ReportQuery, datasource.DbType); err != nil {
+		return nil, err
+	}
+
+	timeoutSeconds := 300
+	maxRows := 10000
+	if req.Configs.TimeoutSeconds != nil {
+		timeoutSeconds = *req.Configs.TimeoutSeconds
+	}
+	if req.Configs.MaxRows != nil {
+		maxRows = *req.Configs.MaxRows
+	}
+
+	configModel := models.ReportConfig{
+		ReportName:      req.Configs.ReportName,
+		ReportQuery:     req.Configs.ReportQuery,
+		OutputFormat:    req.Configs.OutputFormat,
+		DatasourceID:    req.Configs.DatasourceID,
+		FileName:        req.Configs.FileName,
+		Parameters:      parameters,
+		ParameterSchema: req.Configs.ParameterSchema,
+		TimeoutSeconds:  timeoutSeconds,
+		MaxRows:         maxRows,
+		IsActive:        true,
+		CreatedAt:       models.CustomTime{Time: now},
+		UpdatedAt:       models.CustomTime{Time: now},
+		CreatedBy:       req.CreatedBy,
+		UpdatedBy:       req.CreatedBy,
+		Version:         1,
+	}
+
+	if err := tx.Create(&configModel).Error; err != nil {
+		return nil, fmt.Errorf("failed to create config: %w", err)
+	}
+
+	// Create audit trail for config creation (store full config in after_value)
+	if err := writeConfigAudit(tx, configAuditEntry{
+		ConfigID:    configModel.ID,
+		Action:      "create",
+		After:       configModel,
+		Summary:     source.auditSummary(),
+		Cost:        costEstimate,
+		PerformedBy: req.CreatedBy,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := recordConfigVersion(&repository.ReportConfigVersionRepository{DB: tx}, &configModel, models.VersionChangeCreate, nil, req.CreatedBy); err != nil {
+		return nil, fmt.Errorf("failed to record config version: %w", err)
+	}
+
+	// Step 2: Calculate next_run_at from cron expression
+	nextRunAt, err := s.calculateNextRun(req.CronExpression, req.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression: %w", err)
+	}
+
+	// Step 3: Create schedule
+	misfirePolicy := models.MisfirePolicyRunOnce
+	misfireMaxRuns := 10
+	if req.MisfirePolicy != "" {
+		misfirePolicy = req.MisfirePolicy
+	}
+	if req.MisfireMaxRuns != nil {
+		misfireMaxRuns = *req.MisfireMaxRuns
+	}
+
+	scheduleModel := models.ReportSchedule{
+		ConfigID:       configModel.ID,
+		CronExpression: req.CronExpression,
+		Timezone:       req.Timezone,
+		IsActive:       req.IsActive,
+		LastRunAt:      req.LastRunAt,
+		NextRunAt:      nextRunAt,
+		MisfirePolicy:  misfirePolicy,
+		MisfireMaxRuns: misfireMaxRuns,
+		CreatedAt:      models.CustomTime{Time: now},
+		UpdatedAt:      models.CustomTime{Time: now},
+		CreatedBy:      req.CreatedBy,
+		UpdatedBy:      req.CreatedBy,
+		Version:        1,
+	}
+
+	if err := tx.Create(&scheduleModel).Error; err != nil {
+		return nil, fmt.Errorf("failed to create schedule: %w", err)
+	}
+
+	// Step 4: Create deliveries and recipients
+	deliveryResponses := []models.DeliveryResponseNested{}
+	for i, deliveryReq := range req.Configs.Deliveries {
+		// Parse delivery config; a clone copies the stored one, credentials included
+		var deliveryConfig models.DeliveryConfig
+		if source != nil {
+			deliveryConfig = source.deliveryConfigs[i]
+		} else if deliveryReq.DeliveryConfig != nil {
+			if err := json.Unmarshal(deliveryReq.DeliveryConfig, &deliveryConfig); err != nil {
+				return nil, fmt.Errorf("invalid delivery_config JSON: %w", err)
+			}
 		}
-		if req.Configs.MaxRows != nil {
-			maxRows = *req.Configs.MaxRows
-		}
-
-		configModel := models.ReportConfig{
-			ReportName:     req.Configs.ReportName,
-			ReportQuery:    req.Configs.ReportQuery,
-			OutputFormat:   req.Configs.OutputFormat,
-			DatasourceID:   req.Configs.DatasourceID,
-			FileName:       req.Configs.FileName,
-			Parameters:     parameters,
-			TimeoutSeconds: timeoutSeconds,
-			MaxRows:        maxRows,
-			IsActive:       true,
-			CreatedAt:      models.CustomTime{Time: now},
-			UpdatedAt:      models.CustomTime{Time: now},
-			CreatedBy:      req.CreatedBy,
-			UpdatedBy:      req.CreatedBy,
-			Version:        1,
+		if err := utils.ValidateSecretReferences(deliveryConfig); err != nil {
+			return nil, err
 		}
 
-		if err := tx.Create(&configModel).Error; err != nil {
-			return fmt.Errorf("failed to create config: %w", err)
+		// Set defaults for delivery
+		maxRetry := 3
+		retryInterval := 5
+		isActive := true
+		if deliveryReq.MaxRetry != nil {
+			maxRetry = *deliveryReq.MaxRetry
 		}
-
-		// Create audit trail for config creation (store full config in after_value)
-		afterValueJSON, _ := json.Marshal(configModel)
-		afterValueStr := string(afterValueJSON)
-
-		auditCreate := models.ReportConfigAudit{
-			ConfigID:    &configModel.ID,
-			Action:      "create",
-			AfterValue:  &afterValueStr,
-			PerformedBy: req.CreatedBy,
-			PerformedAt: now,
+		if deliveryReq.RetryIntervalMinutes != nil {
+			retryInterval = *deliveryReq.RetryIntervalMinutes
 		}
-		if err := tx.Create(&auditCreate).Error; err != nil {
-			return fmt.Errorf("failed to create audit trail: %w", err)
+		if deliveryReq.IsActive != nil {
+			isActive = *deliveryReq.IsActive
 		}
 
-		// Step 2: Calculate next_run_at from cron expression
-		nextRunAt, err := s.calculateNextRun(req.CronExpression, req.Timezone)
-		if err != nil {
-			return fmt.Errorf("invalid cron expression: %w", err)
-		}
-
-		// Step 3: Create schedule
-		scheduleModel := models.ReportSchedule{
-			ConfigID:       configModel.ID,
-			CronExpression: req.CronExpression,
-			Timezone:       req.Timezone,
-			IsActive:       req.IsActive,
-			LastRunAt:      req.LastRunAt,
-			NextRunAt:      nextRunAt,
-			CreatedAt:      models.CustomTime{Time: now},
-			UpdatedAt:      models.CustomTime{Time: now},
-			CreatedBy:      req.CreatedBy,
-			UpdatedBy:      req.CreatedBy,
+		// Create delivery
+		deliveryModel := models.ReportDelivery{
+			ConfigID:             configModel.ID,
+			DeliveryName:         deliveryReq.DeliveryName,
+			Method:               deliveryReq.Method,
+			MaxRetry:             maxRetry,
+			RetryIntervalMinutes: retryInterval,
+			IsActive:             isActive,
+			DeliveryConfig:       deliveryConfig,
+			CreatedAt:            models.CustomTime{Time: now},
+			UpdatedAt:            models.CustomTime{Time: now},
+			CreatedBy:            req.CreatedBy,
+			UpdatedBy:            req.CreatedBy,
+			Version:              1,
 		}
 
-		if err := tx.Create(&scheduleModel).Error; err != nil {
-			return fmt.Errorf("failed to create schedule: %w", err)
+		if err := tx.Create(&deliveryModel).Error; err != nil {
+			return nil, fmt.Errorf("failed to create delivery '%s': %w", deliveryReq.DeliveryName, err)
 		}
 
-		// Step 4: Create deliveries and recipients
-		deliveryResponses := []models.DeliveryResponseNested{}
-		for _, deliveryReq := range req.Configs.Deliveries {
-			// Parse delivery config
-			var deliveryConfig models.DeliveryConfig
-			if deliveryReq.DeliveryConfig != nil {
-				if err := json.Unmarshal(deliveryReq.DeliveryConfig, &deliveryConfig); err != nil {
-					return fmt.Errorf("invalid delivery_config JSON: %w", err)
-				}
+		// Create recipients for this delivery
+		recipientResponses := []models.RecipientResponseNested{}
+		for _, recipientReq := range deliveryReq.Recipients {
+			// Set default active
+			recipientActive := true
+			if recipientReq.IsActive != nil {
+				recipientActive = *recipientReq.IsActive
 			}
 
-			// Set defaults for delivery
-			maxRetry := 3
-			retryInterval := 5
-			isActive := true
-			if deliveryReq.MaxRetry != nil {
-				maxRetry = *deliveryReq.MaxRetry
-			}
-			if deliveryReq.RetryIntervalMinutes != nil {
-				retryInterval = *deliveryReq.RetryIntervalMinutes
-			}
-			if deliveryReq.IsActive != nil {
-				isActive = *deliveryReq.IsActive
+			recipientModel := models.ReportDeliveryRecipient{
+				DeliveryID:      deliveryModel.ID,
+				RecipientType:   recipientReq.RecipientType,
+				RecipientValue:  recipientReq.RecipientValue,
+				RecipientConfig: recipientReq.RecipientConfig,
+				IsActive:        recipientActive,
+				CreatedAt:       models.CustomTime{Time: now},
+				UpdatedAt:       models.CustomTime{Time: now},
+				Version:         1,
 			}
 
-			// Create delivery
-			deliveryModel := models.ReportDelivery{
-				ConfigID:             configModel.ID,
-				DeliveryName:         deliveryReq.DeliveryName,
-				Method:               deliveryReq.Method,
-				MaxRetry:             maxRetry,
-				RetryIntervalMinutes: retryInterval,
-				IsActive:             isActive,
-				DeliveryConfig:       deliveryConfig,
-				CreatedAt:            models.CustomTime{Time: now},
-				UpdatedAt:            models.CustomTime{Time: now},
-				CreatedBy:            req.CreatedBy,
-				UpdatedBy:            req.CreatedBy,
+			if err := tx.Create(&recipientModel).Error; err != nil {
+				return nil, fmt.Errorf("failed to create recipient '%s': %w", recipientReq.RecipientValue, err)
 			}
 
-			if err := tx.Create(&deliveryModel).Error; err != nil {
-				return fmt.Errorf("failed to create delivery '%s': %w", deliveryReq.DeliveryName, err)
+			recipientResponses = append(recipientResponses, models.RecipientResponseNested{
+				ID:             recipientModel.ID,
+				RecipientValue: recipientModel.RecipientValue,
+				IsActive:       recipientModel.IsActive,
+			})
+		}
+
+		// Marshal delivery config back to json.RawMessage for response
+		// Mask sensitive fields (password, api_key, secret)
+		maskedConfig := maskSensitiveFields(deliveryModel.DeliveryConfig, deliveryModel.Method)
+		deliveryConfigJSON, _ := json.Marshal(maskedConfig)
+
+		deliveryResponses = append(deliveryResponses, models.DeliveryResponseNested{
+			ID:                   deliveryModel.ID,
+			DeliveryName:         deliveryModel.DeliveryName,
+			Method:               deliveryModel.Method,
+			MaxRetry:             deliveryModel.MaxRetry,
+			RetryIntervalMinutes: deliveryModel.RetryIntervalMinutes,
+			IsActive:             deliveryModel.IsActive,
+			DeliveryConfig:       deliveryConfigJSON,
+			Recipients:           recipientResponses,
+		})
+	}
+
+	response := newCompleteScheduleResponse(&scheduleModel, &configModel, deliveryResponses)
+
+	// ETag for the client's first If-Match
+	response.ETag, err = completeScheduleETag(tx, scheduleModel.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute etag: %w", err)
+	}
+
+	return response, nil
+}
+
+// checkConfigCost runs the cost gate for reportConfig scheduled on cronExpression. EXPLAIN goes to the
+// datasource, so callers run it before opening their transaction rather than holding it open meanwhile.
+func checkConfigCost(db *gorm.DB, reportConfig *models.ReportConfig, cronExpression string, timezone string, override bool) (*QueryCostEstimate, error) {
+	if err := ValidateParameterSchema(reportConfig.ParameterSchema); err != nil {
+		return nil, fmt.Errorf("invalid parameter schema: %w", err)
+	}
+
+	var datasource models.DataSource
+	if err := db.First(&datasource, reportConfig.DatasourceID).Error; err != nil {
+		return nil, fmt.Errorf("datasource id %d not found: %w", reportConfig.DatasourceID, err)
+	}
+	if err := CheckReportQuery(reportConfig.ID, reportConfig.ReportQuery, datasource.DbType); err != nil {
+		return nil, err
+	}
+
+	return CheckQueryCost(reportConfig, &datasource, cronExpression, timezone,
+		utils.ValidateCronExpression(cronExpression, timezone).ExecutionsPerDay, override)
+}
+
+// checkCreateCost runs the cost gate for the config a create request would insert
+func checkCreateCost(db *gorm.DB, req models.CompleteScheduleRequest) (*QueryCostEstimate, error) {
+	var parameters models.Parameters
+	if req.Configs.Parameters != nil {
+		if err := json.Unmarshal(req.Configs.Parameters, &parameters); err != nil {
+			return nil, fmt.Errorf("invalid parameters JSON: %w", err)
+		}
+	}
+
+	return checkConfigCost(db, &models.ReportConfig{
+		ReportQuery:     req.Configs.ReportQuery,
+		DatasourceID:    req.Configs.DatasourceID,
+		Parameters:      parameters,
+		ParameterSchema: req.Configs.ParameterSchema,
+	}, req.CronExpression, req.Timezone, req.CostOverride)
+}
+
+// checkCloneCost runs the cost gate for a clone moved to another datasource. A clone on the
+// source's datasource runs a query that was already gated, so it is not checked again.
+func checkCloneCost(db *gorm.DB, sourceScheduleID int, req models.CompleteScheduleCloneRequest) (*QueryCostEstimate, error) {
+	if req.DatasourceID == nil {
+		return nil, nil
+	}
+
+	var schedule models.ReportSchedule
+	if err := db.First(&schedule, sourceScheduleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("schedule not found")
+		}
+		return nil, fmt.Errorf("failed to find schedule: %w", err)
+	}
+	var sourceConfig models.ReportConfig
+	if err := db.First(&sourceConfig, schedule.ConfigID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find config: %w", err)
+	}
+
+	cloneConfig := sourceConfig
+	cloneConfig.ID = 0
+	cloneConfig.DatasourceID = *req.DatasourceID
+	if !costInputsChanged(&sourceConfig, &cloneConfig) {
+		return nil, nil
+	}
+
+	cronExpression, timezone := schedule.CronExpression, schedule.Timezone
+	if req.CronExpression != nil {
+		cronExpression = *req.CronExpression
+	}
+	if req.Timezone != nil {
+		timezone = *req.Timezone
+	}
+	return checkConfigCost(db, &cloneConfig, cronExpression, timezone, req.CostOverride)
+}
+
+// checkUpdateCost runs the cost gate for an update request that changes the config's query,
+// datasource or parameters, scored at the schedule's (possibly updated) frequency
+func checkUpdateCost(db *gorm.DB, scheduleID int, req models.CompleteScheduleUpdateRequest) (*QueryCostEstimate, error) {
+	if req.Configs == nil {
+		return nil, nil
+	}
+
+	var schedule models.ReportSchedule
+	if err := db.First(&schedule, scheduleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("schedule not found")
+		}
+		return nil, fmt.Errorf("failed to find schedule: %w", err)
+	}
+	var current models.ReportConfig
+	if err := db.First(&current, schedule.ConfigID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find config: %w", err)
+	}
+
+	updated := current
+	updated.ReportQuery = req.Configs.ReportQuery
+	updated.DatasourceID = req.Configs.DatasourceID
+	if req.Configs.Parameters != nil {
+		var parameters models.Parameters
+		if err := json.Unmarshal(req.Configs.Parameters, &parameters); err != nil {
+			return nil, fmt.Errorf("invalid parameters JSON: %w", err)
+		}
+		updated.Parameters = parameters
+	}
+	if req.Configs.ParameterSchema != nil {
+		updated.ParameterSchema = req.Configs.ParameterSchema
+	}
+	if !costInputsChanged(&current, &updated) {
+		return nil, nil
+	}
+
+	cronExpression, timezone := schedule.CronExpression, schedule.Timezone
+	if req.CronExpression != nil {
+		cronExpression = *req.CronExpression
+	}
+	if req.Timezone != nil {
+		timezone = *req.Timezone
+	}
+	return checkConfigCost(db, &updated, cronExpression, timezone, req.CostOverride)
+}
+
+// cloneSource carries what a clone copies from its source beyond the create request
+type cloneSource struct {
+	scheduleID      int
+	configID        int
+	deliveryConfigs []models.DeliveryConfig // stored delivery_config of each delivery, by request index
+}
+
+// auditSummary is what the create audit of a clone records about its source; nil when not cloning
+func (source *cloneSource) auditSummary() map[string]interface{} {
+	if source == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"cloned_from": map[string]int{"schedule_id": source.scheduleID, "config_id": source.configID},
+	}
+}
+
+// CloneComplete copies a schedule with its config, deliveries and recipients in a single transaction.
+// Stored delivery credentials are copied as they are and never leave the service; the clone is
+// inactive unless req.IsActive says otherwise, so it does not run before it is edited.
+func (s *CompleteScheduleService) CloneComplete(sourceScheduleID int, req models.CompleteScheduleCloneRequest) (*models.CompleteScheduleResponse, error) {
+	costEstimate, err := checkCloneCost(config.DB, sourceScheduleID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var response *models.CompleteScheduleResponse
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var schedule models.ReportSchedule
+		if err := tx.First(&schedule, sourceScheduleID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("schedule not found")
 			}
+			return fmt.Errorf("failed to find schedule: %w", err)
+		}
 
-			// Create recipients for this delivery
-			recipientResponses := []models.RecipientResponseNested{}
-			for _, recipientReq := range deliveryReq.Recipients {
-				// Set default active
-				recipientActive := true
-				if recipientReq.IsActive != nil {
-					recipientActive = *recipientReq.IsActive
-				}
+		var sourceConfig models.ReportConfig
+		if err := tx.First(&sourceConfig, schedule.ConfigID).Error; err != nil {
+			return fmt.Errorf("failed to find config: %w", err)
+		}
 
-				recipientModel := models.ReportDeliveryRecipient{
-					DeliveryID:     deliveryModel.ID,
-					RecipientValue: recipientReq.RecipientValue,
-					IsActive:       recipientActive,
-					CreatedAt:      models.CustomTime{Time: now},
-					UpdatedAt:      models.CustomTime{Time: now},
-				}
+		var deliveries []models.ReportDelivery
+		if err := tx.Where("config_id = ?", sourceConfig.ID).Order("id").Find(&deliveries).Error; err != nil {
+			return fmt.Errorf("failed to fetch deliveries: %w", err)
+		}
 
-				if err := tx.Create(&recipientModel).Error; err != nil {
-					return fmt.Errorf("failed to create recipient '%s': %w", recipientReq.RecipientValue, err)
-				}
+		recipients, err := recipientsByDelivery(tx, deliveries)
+		if err != nil {
+			return err
+		}
 
-				recipientResponses = append(recipientResponses, models.RecipientResponseNested{
-					ID:             recipientModel.ID,
-					RecipientValue: recipientModel.RecipientValue,
-					IsActive:       recipientModel.IsActive,
+		source := &cloneSource{scheduleID: schedule.ID, configID: sourceConfig.ID}
+		deliveryRequests := make([]models.DeliveryWithRecipientsRequest, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			recipientRequests := make([]models.RecipientRequest, 0, len(recipients[delivery.ID]))
+			for _, recipient := range recipients[delivery.ID] {
+				recipientActive := recipient.IsActive
+				recipientRequests = append(recipientRequests, models.RecipientRequest{
+					RecipientType:   recipient.RecipientType,
+					RecipientValue:  recipient.RecipientValue,
+					RecipientConfig: recipient.RecipientConfig,
+					IsActive:        &recipientActive,
 				})
 			}
 
-			// Marshal delivery config back to json.RawMessage for response
-			// Mask sensitive fields (password, api_key, secret)
-			maskedConfig := maskSensitiveFields(deliveryModel.DeliveryConfig, deliveryModel.Method)
-			deliveryConfigJSON, _ := json.Marshal(maskedConfig)
-
-			deliveryResponses = append(deliveryResponses, models.DeliveryResponseNested{
-				ID:                   deliveryModel.ID,
-				DeliveryName:         deliveryModel.DeliveryName,
-				Method:               deliveryModel.Method,
-				MaxRetry:             deliveryModel.MaxRetry,
-				RetryIntervalMinutes: deliveryModel.RetryIntervalMinutes,
-				IsActive:             deliveryModel.IsActive,
-				DeliveryConfig:       deliveryConfigJSON,
-				Recipients:           recipientResponses,
+			maxRetry, retryInterval, isActive := delivery.MaxRetry, delivery.RetryIntervalMinutes, delivery.IsActive
+			deliveryRequests = append(deliveryRequests, models.DeliveryWithRecipientsRequest{
+				DeliveryName:         delivery.DeliveryName,
+				Method:               delivery.Method,
+				MaxRetry:             &maxRetry,
+				RetryIntervalMinutes: &retryInterval,
+				IsActive:             &isActive,
+				Recipients:           recipientRequests,
 			})
+			source.deliveryConfigs = append(source.deliveryConfigs, delivery.DeliveryConfig)
+		}
+
+		reportName := sourceConfig.ReportName
+		if req.ReportName != nil {
+			reportName = *req.ReportName
+			var count int64
+			if err := tx.Model(&models.ReportConfig{}).Where("report_name = ?", reportName).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return fmt.Errorf("report config with name '%s' already exists", reportName)
+			}
+		} else if reportName, err = uniqueReportName(tx, reportName, "copy"); err != nil {
+			return err
 		}
 
-		// Build response
-		// Marshal parameters back to json.RawMessage for response
-		parametersJSON, _ := json.Marshal(configModel.Parameters)
-
-		response = &models.CompleteScheduleResponse{
-			ScheduleID:     scheduleModel.ID,
-			ConfigID:       configModel.ID,
-			CronExpression: scheduleModel.CronExpression,
-			Timezone:       scheduleModel.Timezone,
-			IsActive:       scheduleModel.IsActive,
-			LastRunAt:      scheduleModel.LastRunAt,
-			NextRunAt:      scheduleModel.NextRunAt,
-			CreatedAt:      scheduleModel.CreatedAt,
-			UpdatedAt:      scheduleModel.UpdatedAt,
-			CreatedBy:      scheduleModel.CreatedBy,
-			UpdatedBy:      scheduleModel.UpdatedBy,
-			Config: models.ConfigResponseNested{
-				ID:             configModel.ID,
-				ReportName:     configModel.ReportName,
-				ReportQuery:    configModel.ReportQuery,
-				OutputFormat:   configModel.OutputFormat,
-				DatasourceID:   configModel.DatasourceID,
-				FileName:       configModel.FileName,
-				Parameters:     parametersJSON,
-				TimeoutSeconds: configModel.TimeoutSeconds,
-				MaxRows:        configModel.MaxRows,
-				IsActive:       configModel.IsActive,
-				Version:        configModel.Version,
-				Deliveries:     deliveryResponses,
+		parametersJSON, _ := json.Marshal(sourceConfig.Parameters)
+		timeoutSeconds, maxRows, misfireMaxRuns := sourceConfig.TimeoutSeconds, sourceConfig.MaxRows, schedule.MisfireMaxRuns
+		cloneReq := models.CompleteScheduleRequest{
+			CronExpression: schedule.CronExpression,
+			Timezone:       schedule.Timezone,
+			MisfirePolicy:  schedule.MisfirePolicy,
+			MisfireMaxRuns: &misfireMaxRuns,
+			CreatedBy:      req.CreatedBy,
+			UpdatedBy:      req.CreatedBy,
+			CostOverride:   req.CostOverride,
+			Configs: models.ConfigWithDeliveriesRequest{
+				ReportName:      reportName,
+				ReportQuery:     sourceConfig.ReportQuery,
+				OutputFormat:    sourceConfig.OutputFormat,
+				DatasourceID:    sourceConfig.DatasourceID,
+				FileName:        sourceConfig.FileName,
+				Parameters:      parametersJSON,
+				ParameterSchema: sourceConfig.ParameterSchema,
+				TimeoutSeconds:  &timeoutSeconds,
+				MaxRows:         &maxRows,
+				Deliveries:      deliveryRequests,
 			},
 		}
+		if req.CronExpression != nil {
+			cloneReq.CronExpression = *req.CronExpression
+		}
+		if req.Timezone != nil {
+			cloneReq.Timezone = *req.Timezone
+		}
+		if req.DatasourceID != nil {
+			cloneReq.Configs.DatasourceID = *req.DatasourceID
+		}
+		if req.IsActive != nil {
+			cloneReq.IsActive = *req.IsActive
+		}
+		if validation := utils.ValidateCronExpression(cloneReq.CronExpression, cloneReq.Timezone); !validation.Valid {
+			return fmt.Errorf("invalid cron expression: %s", strings.Join(validation.Errors, "; "))
+		}
 
-		return nil
-	})
+		response, err = s.createComplete(tx, cloneReq, source, costEstimate)
+		if err != nil {
+			return err
+		}
 
+		// Link the source to its clone; the clone's create audit links back via cloned_from
+		return writeConfigAudit(tx, configAuditEntry{
+			ConfigID:    sourceConfig.ID,
+			Action:      "clone",
+			Summary:     map[string]interface{}{"cloned_to": map[string]int{"schedule_id": response.ScheduleID, "config_id": response.ConfigID}},
+			PerformedBy: req.CreatedBy,
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -253,398 +534,592 @@ func (s *CompleteScheduleService) CreateComplete(req models.CompleteScheduleRequ
 	return response, nil
 }
 
+// recipientsByDelivery reads the recipients of deliveries in one query, grouped by delivery id
+func recipientsByDelivery(db *gorm.DB, deliveries []models.ReportDelivery) (map[int][]models.ReportDeliveryRecipient, error) {
+	byDelivery := make(map[int][]models.ReportDeliveryRecipient, len(deliveries))
+	if len(deliveries) == 0 {
+		return byDelivery, nil
+	}
+
+	deliveryIDs := make([]int, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		deliveryIDs = append(deliveryIDs, delivery.ID)
+	}
+	var recipients []models.ReportDeliveryRecipient
+	if err := db.Where("delivery_id IN ?", deliveryIDs).Order("id").Find(&recipients).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch recipients: %w", err)
+	}
+	for _, recipient := range recipients {
+		byDelivery[recipient.DeliveryID] = append(byDelivery[recipient.DeliveryID], recipient)
+	}
+	return byDelivery, nil
+}
+
+// uniqueReportName names a clone or import "<name> (<label>)", numbering it when that name is taken
+func uniqueReportName(tx *gorm.DB, name string, label string) (string, error) {
+	for n := 1; ; n++ {
+		candidate := fmt.Sprintf("%s (%s)", name, label)
+		if n > 1 {
+			candidate = fmt.Sprintf("%s (%s %d)", name, label, n)
+		}
+		var count int64
+		if err := tx.Model(&models.ReportConfig{}).Where("report_name = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+	}
+}
+
 // UpdateComplete updates a complete schedule with partial update support (Option B: Flexible Update)
 func (s *CompleteScheduleService) UpdateComplete(scheduleID int, req models.CompleteScheduleUpdateRequest) (*models.CompleteScheduleResponse, error) {
-	db := config.DB
+	// Heavy queries need cost_override; EXPLAIN runs before the transaction is opened
+	costEstimate, err := checkUpdateCost(config.DB, scheduleID, req)
+	if err != nil {
+		return nil, err
+	}
+
 	var response *models.CompleteScheduleResponse
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		response, err = s.updateComplete(tx, scheduleID, req, costEstimate)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
 
-	// Start transaction
-	err := db.Transaction(func(tx *gorm.DB) error {
-		now := time.Now()
+// updateComplete applies req to the schedule and its config, deliveries and recipients using tx.
+// costEstimate is what the cost gate returned for req before tx was opened; it is recorded in the audit trail.
+func (s *CompleteScheduleService) updateComplete(tx *gorm.DB, scheduleID int, req models.CompleteScheduleUpdateRequest, costEstimate *QueryCostEstimate) (*models.CompleteScheduleResponse, error) {
+	now := time.Now()
 
-		// Step 1: Find existing schedule
-		var schedule models.ReportSchedule
-		if err := tx.First(&schedule, scheduleID).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return errors.New("schedule not found")
-			}
-			return fmt.Errorf("failed to find schedule: %w", err)
+	// Step 1: Lock the schedule with its config, deliveries and recipients, and check If-Match
+	// against all of them, so no nested row can change between the check and the write
+	etag, err := completeScheduleETag(tx, scheduleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("schedule not found")
+		}
+		return nil, fmt.Errorf("failed to find schedule: %w", err)
+	}
+	if !utils.ETagMatches(req.IfMatch, etag) {
+		current, err := loadCompleteSchedule(tx, scheduleID)
+		if err != nil {
+			return nil, err
 		}
+		return nil, &PreconditionFailedError{ETag: etag, Current: current}
+	}
+
+	var schedule models.ReportSchedule
+	if err := tx.First(&schedule, scheduleID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find schedule: %w", err)
+	}
 
-		configID := schedule.ConfigID
+	configID := schedule.ConfigID
 
-		// Step 2: Update schedule fields (if provided)
-		scheduleUpdates := map[string]interface{}{}
+	// Step 2: Update schedule fields (if provided)
+	scheduleUpdates := map[string]interface{}{}
+	if req.CronExpression != nil {
+		scheduleUpdates["cron_expression"] = *req.CronExpression
+		// Recalculate next_run_at
+		nextRunAt, err := s.calculateNextRun(*req.CronExpression, schedule.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression: %w", err)
+		}
+		scheduleUpdates["next_run_at"] = nextRunAt
+	}
+	if req.Timezone != nil {
+		scheduleUpdates["timezone"] = *req.Timezone
+		// Recalculate next_run_at with new timezone
+		cronExpr := schedule.CronExpression
 		if req.CronExpression != nil {
-			scheduleUpdates["cron_expression"] = *req.CronExpression
-			// Recalculate next_run_at
-			nextRunAt, err := s.calculateNextRun(*req.CronExpression, schedule.Timezone)
-			if err != nil {
-				return fmt.Errorf("invalid cron expression: %w", err)
-			}
-			scheduleUpdates["next_run_at"] = nextRunAt
+			cronExpr = *req.CronExpression
 		}
-		if req.Timezone != nil {
-			scheduleUpdates["timezone"] = *req.Timezone
-			// Recalculate next_run_at with new timezone
-			cronExpr := schedule.CronExpression
-			if req.CronExpression != nil {
-				cronExpr = *req.CronExpression
-			}
-			nextRunAt, err := s.calculateNextRun(cronExpr, *req.Timezone)
-			if err != nil {
-				return fmt.Errorf("invalid timezone: %w", err)
-			}
-			scheduleUpdates["next_run_at"] = nextRunAt
+		nextRunAt, err := s.calculateNextRun(cronExpr, *req.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone: %w", err)
 		}
-		if req.IsActive != nil {
-			scheduleUpdates["is_active"] = *req.IsActive
+		scheduleUpdates["next_run_at"] = nextRunAt
+	}
+	if req.IsActive != nil {
+		scheduleUpdates["is_active"] = *req.IsActive
+	}
+	if req.LastRunAt != nil {
+		scheduleUpdates["last_run_at"] = req.LastRunAt
+	}
+	if req.MisfirePolicy != nil {
+		scheduleUpdates["misfire_policy"] = *req.MisfirePolicy
+	}
+	if req.MisfireMaxRuns != nil {
+		scheduleUpdates["misfire_max_runs"] = *req.MisfireMaxRuns
+	}
+	scheduleUpdates["updated_at"] = now
+	scheduleUpdates["updated_by"] = req.UpdatedBy
+	scheduleUpdates["version"] = gorm.Expr("version + 1")
+
+	if len(scheduleUpdates) > 0 {
+		if err := tx.Model(&schedule).Updates(scheduleUpdates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update schedule: %w", err)
+		}
+	}
+
+	// Step 3: Update config fields (if provided)
+	var config models.ReportConfig
+	if err := tx.First(&config, configID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find config: %w", err)
+	}
+
+	if req.Configs != nil {
+		// Store before state for audit (full config JSON); the reload below scans into config's maps
+		beforeValue, _ := json.Marshal(config)
+
+		var datasource models.DataSource
+		if err := tx.First(&datasource, req.Configs.DatasourceID).Error; err != nil {
+			return nil, fmt.Errorf("datasource id %d not found: %w", req.Configs.DatasourceID, err)
 		}
-		if req.LastRunAt != nil {
-			scheduleUpdates["last_run_at"] = req.LastRunAt
+		if err := CheckReportQuery(configID, req.Configs.ReportQuery, datasource.DbType); err != nil {
+			return nil, err
 		}
-		scheduleUpdates["updated_at"] = now
-		scheduleUpdates["updated_by"] = req.UpdatedBy
 
-		if len(scheduleUpdates) > 0 {
-			if err := tx.Model(&schedule).Updates(scheduleUpdates).Error; err != nil {
-				return fmt.Errorf("failed to update schedule: %w", err)
+		configUpdates := map[string]interface{}{}
+		configUpdates["report_name"] = req.Configs.ReportName
+		configUpdates["report_query"] = req.Configs.ReportQuery
+		configUpdates["output_format"] = req.Configs.OutputFormat
+		configUpdates["datasource_id"] = req.Configs.DatasourceID
+		if req.Configs.FileName != nil {
+			configUpdates["file_name"] = req.Configs.FileName
+		}
+		if req.Configs.Parameters != nil {
+			configUpdates["parameters"] = req.Configs.Parameters
+		}
+		if req.Configs.ParameterSchema != nil {
+			if err := ValidateParameterSchema(req.Configs.ParameterSchema); err != nil {
+				return nil, fmt.Errorf("invalid parameter schema: %w", err)
 			}
+			configUpdates["parameter_schema"] = req.Configs.ParameterSchema
 		}
-
-		// Step 3: Update config fields (if provided)
-		var config models.ReportConfig
-		if err := tx.First(&config, configID).Error; err != nil {
-			return fmt.Errorf("failed to find config: %w", err)
+		if req.Configs.TimeoutSeconds != nil {
+			configUpdates["timeout_seconds"] = req.Configs.TimeoutSeconds
+		}
+		if req.Configs.MaxRows != nil {
+			configUpdates["max_rows"] = req.Configs.MaxRows
 		}
+		configUpdates["updated_at"] = now
+		configUpdates["updated_by"] = req.UpdatedBy
+		configUpdates["version"] = gorm.Expr("version + 1")
 
-		if req.Configs != nil {
-			// Store before state for audit (full config JSON)
-			beforeValueJSON, _ := json.Marshal(config)
-			beforeValueStr := string(beforeValueJSON)
+		if err := tx.Model(&config).Updates(configUpdates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update config: %w", err)
+		}
 
-			configUpdates := map[string]interface{}{}
-			configUpdates["report_name"] = req.Configs.ReportName
-			configUpdates["report_query"] = req.Configs.ReportQuery
-			configUpdates["output_format"] = req.Configs.OutputFormat
-			configUpdates["datasource_id"] = req.Configs.DatasourceID
-			if req.Configs.FileName != nil {
-				configUpdates["file_name"] = req.Configs.FileName
-			}
-			if req.Configs.Parameters != nil {
-				configUpdates["parameters"] = req.Configs.Parameters
-			}
-			if req.Configs.TimeoutSeconds != nil {
-				configUpdates["timeout_seconds"] = req.Configs.TimeoutSeconds
-			}
-			if req.Configs.MaxRows != nil {
-				configUpdates["max_rows"] = req.Configs.MaxRows
-			}
-			configUpdates["updated_at"] = now
-			configUpdates["updated_by"] = req.UpdatedBy
-			configUpdates["version"] = gorm.Expr("version + 1")
+		// Reload config to get updated values for after state
+		tx.First(&config, configID)
 
-			if err := tx.Model(&config).Updates(configUpdates).Error; err != nil {
-				return fmt.Errorf("failed to update config: %w", err)
-			}
+		// Create audit trail for config update
+		if err := writeConfigAudit(tx, configAuditEntry{
+			ConfigID:    configID,
+			Action:      "update",
+			Before:      json.RawMessage(beforeValue),
+			After:       config,
+			Cost:        costEstimate,
+			PerformedBy: req.UpdatedBy,
+		}); err != nil {
+			return nil, err
+		}
 
-			// Reload config to get updated values for after state
-			tx.First(&config, configID)
-
-			// Store after state for audit (full config JSON)
-			afterValueJSON, _ := json.Marshal(config)
-			afterValueStr := string(afterValueJSON)
-
-			// Create audit trail for config update
-			auditUpdate := models.ReportConfigAudit{
-				ConfigID:    &configID,
-				Action:      "update",
-				BeforeValue: &beforeValueStr,
-				AfterValue:  &afterValueStr,
-				PerformedBy: req.UpdatedBy,
-				PerformedAt: now,
-			}
-			if err := tx.Create(&auditUpdate).Error; err != nil {
-				return fmt.Errorf("failed to create audit trail: %w", err)
-			}
+		if err := recordConfigVersion(&repository.ReportConfigVersionRepository{DB: tx}, &config, models.VersionChangeUpdate, nil, req.UpdatedBy); err != nil {
+			return nil, fmt.Errorf("failed to record config version: %w", err)
 		}
+	}
 
-		// Step 4: Handle deliveries (create/update/deactivate) - Option B: Flexible
-		deliveryResponses := []models.DeliveryResponseNested{}
-		if req.Configs != nil && len(req.Configs.Deliveries) > 0 {
-			// Get all existing delivery IDs from request
-			requestedDeliveryIDs := map[int]bool{}
-			for _, deliveryReq := range req.Configs.Deliveries {
-				if deliveryReq.ID != nil {
-					requestedDeliveryIDs[*deliveryReq.ID] = true
-				}
+	// Step 4: Handle deliveries (create/update/deactivate) - Option B: Flexible
+	deliveryResponses := []models.DeliveryResponseNested{}
+	if req.Configs != nil && len(req.Configs.Deliveries) > 0 {
+		// Get all existing delivery IDs from request
+		requestedDeliveryIDs := map[int]bool{}
+		for _, deliveryReq := range req.Configs.Deliveries {
+			if deliveryReq.ID != nil {
+				requestedDeliveryIDs[*deliveryReq.ID] = true
 			}
+		}
 
-			// Hard delete deliveries not in request
-			if err := tx.Where("config_id = ? AND id NOT IN ?", configID, getMapKeys(requestedDeliveryIDs)).
-				Delete(&models.ReportDelivery{}).Error; err != nil {
-				return fmt.Errorf("failed to delete removed deliveries: %w", err)
-			}
+		// Hard delete deliveries not in request
+		if err := tx.Where("config_id = ? AND id NOT IN ?", configID, getMapKeys(requestedDeliveryIDs)).
+			Delete(&models.ReportDelivery{}).Error; err != nil {
+			return nil, fmt.Errorf("failed to delete removed deliveries: %w", err)
+		}
 
-			// Process each delivery in request
-			for _, deliveryReq := range req.Configs.Deliveries {
-				var deliveryModel models.ReportDelivery
+		// Process each delivery in request
+		for _, deliveryReq := range req.Configs.Deliveries {
+			var deliveryModel models.ReportDelivery
 
-				if deliveryReq.ID != nil {
-					// UPDATE existing delivery
-					if err := tx.First(&deliveryModel, *deliveryReq.ID).Error; err != nil {
-						return fmt.Errorf("delivery id %d not found: %w", *deliveryReq.ID, err)
-					}
+			if deliveryReq.ID != nil {
+				// UPDATE existing delivery
+				if err := tx.First(&deliveryModel, *deliveryReq.ID).Error; err != nil {
+					return nil, fmt.Errorf("delivery id %d not found: %w", *deliveryReq.ID, err)
+				}
 
-					deliveryUpdates := map[string]interface{}{
-						"delivery_name": deliveryReq.DeliveryName,
-						"method":        deliveryReq.Method,
-						"updated_at":    now,
-						"updated_by":    req.UpdatedBy,
-					}
-					if deliveryReq.MaxRetry != nil {
-						deliveryUpdates["max_retry"] = deliveryReq.MaxRetry
-					}
-					if deliveryReq.RetryIntervalMinutes != nil {
-						deliveryUpdates["retry_interval_minutes"] = deliveryReq.RetryIntervalMinutes
-					}
-					if deliveryReq.IsActive != nil {
-						deliveryUpdates["is_active"] = deliveryReq.IsActive
+				deliveryUpdates := map[string]interface{}{
+					"delivery_name": deliveryReq.DeliveryName,
+					"method":        deliveryReq.Method,
+					"updated_at":    now,
+					"updated_by":    req.UpdatedBy,
+					"version":       gorm.Expr("version + 1"),
+				}
+				if deliveryReq.MaxRetry != nil {
+					deliveryUpdates["max_retry"] = deliveryReq.MaxRetry
+				}
+				if deliveryReq.RetryIntervalMinutes != nil {
+					deliveryUpdates["retry_interval_minutes"] = deliveryReq.RetryIntervalMinutes
+				}
+				if deliveryReq.IsActive != nil {
+					deliveryUpdates["is_active"] = deliveryReq.IsActive
+				}
+				if deliveryReq.DeliveryConfig != nil {
+					// Go through models.DeliveryConfig so secrets are encrypted and masked ones keep their stored value
+					var deliveryConfig models.DeliveryConfig
+					if err := json.Unmarshal(deliveryReq.DeliveryConfig, &deliveryConfig); err != nil {
+						return nil, fmt.Errorf("invalid delivery_config JSON: %w", err)
 					}
-					if deliveryReq.DeliveryConfig != nil {
-						deliveryUpdates["delivery_config"] = deliveryReq.DeliveryConfig
+					deliveryConfig = utils.RestoreMaskedSecrets(deliveryConfig, deliveryModel.DeliveryConfig)
+					if err := utils.ValidateSecretReferences(deliveryConfig); err != nil {
+						return nil, err
 					}
+					deliveryUpdates["delivery_config"] = deliveryConfig
+				}
 
-					if err := tx.Model(&deliveryModel).Updates(deliveryUpdates).Error; err != nil {
-						return fmt.Errorf("failed to update delivery %d: %w", *deliveryReq.ID, err)
-					}
-				} else {
-					// CREATE new delivery
-					// Parse delivery config
-					var deliveryConfig models.DeliveryConfig
-					if deliveryReq.DeliveryConfig != nil {
-						if err := json.Unmarshal(deliveryReq.DeliveryConfig, &deliveryConfig); err != nil {
-							return fmt.Errorf("invalid delivery_config JSON: %w", err)
-						}
+				if err := tx.Model(&deliveryModel).Updates(deliveryUpdates).Error; err != nil {
+					return nil, fmt.Errorf("failed to update delivery %d: %w", *deliveryReq.ID, err)
+				}
+			} else {
+				// CREATE new delivery
+				// Parse delivery config
+				var deliveryConfig models.DeliveryConfig
+				if deliveryReq.DeliveryConfig != nil {
+					if err := json.Unmarshal(deliveryReq.DeliveryConfig, &deliveryConfig); err != nil {
+						return nil, fmt.Errorf("invalid delivery_config JSON: %w", err)
 					}
+				}
+				if err := utils.ValidateSecretReferences(deliveryConfig); err != nil {
+					return nil, err
+				}
 
-					// Set defaults
-					maxRetry := 3
-					retryInterval := 5
-					isActive := true
-					if deliveryReq.MaxRetry != nil {
-						maxRetry = *deliveryReq.MaxRetry
-					}
-					if deliveryReq.RetryIntervalMinutes != nil {
-						retryInterval = *deliveryReq.RetryIntervalMinutes
-					}
-					if deliveryReq.IsActive != nil {
-						isActive = *deliveryReq.IsActive
-					}
+				// Set defaults
+				maxRetry := 3
+				retryInterval := 5
+				isActive := true
+				if deliveryReq.MaxRetry != nil {
+					maxRetry = *deliveryReq.MaxRetry
+				}
+				if deliveryReq.RetryIntervalMinutes != nil {
+					retryInterval = *deliveryReq.RetryIntervalMinutes
+				}
+				if deliveryReq.IsActive != nil {
+					isActive = *deliveryReq.IsActive
+				}
 
-					deliveryModel = models.ReportDelivery{
-						ConfigID:             configID,
-						DeliveryName:         deliveryReq.DeliveryName,
-						Method:               deliveryReq.Method,
-						MaxRetry:             maxRetry,
-						RetryIntervalMinutes: retryInterval,
-						IsActive:             isActive,
-						DeliveryConfig:       deliveryConfig,
-						CreatedAt:            models.CustomTime{Time: now},
-						UpdatedAt:            models.CustomTime{Time: now},
-						CreatedBy:            req.UpdatedBy,
-						UpdatedBy:            req.UpdatedBy,
-					}
+				deliveryModel = models.ReportDelivery{
+					ConfigID:             configID,
+					DeliveryName:         deliveryReq.DeliveryName,
+					Method:               deliveryReq.Method,
+					MaxRetry:             maxRetry,
+					RetryIntervalMinutes: retryInterval,
+					IsActive:             isActive,
+					DeliveryConfig:       deliveryConfig,
+					CreatedAt:            models.CustomTime{Time: now},
+					UpdatedAt:            models.CustomTime{Time: now},
+					CreatedBy:            req.UpdatedBy,
+					UpdatedBy:            req.UpdatedBy,
+					Version:              1,
+				}
 
-					if err := tx.Create(&deliveryModel).Error; err != nil {
-						return fmt.Errorf("failed to create delivery: %w", err)
-					}
+				if err := tx.Create(&deliveryModel).Error; err != nil {
+					return nil, fmt.Errorf("failed to create delivery: %w", err)
 				}
+			}
 
-				// Handle recipients for this delivery
-				recipientResponses := []models.RecipientResponseNested{}
-				if len(deliveryReq.Recipients) > 0 {
-					// Get requested recipient IDs
-					requestedRecipientIDs := map[int]bool{}
-					for _, recipientReq := range deliveryReq.Recipients {
-						if recipientReq.ID != nil {
-							requestedRecipientIDs[*recipientReq.ID] = true
-						}
+			// Handle recipients for this delivery
+			recipientResponses := []models.RecipientResponseNested{}
+			if len(deliveryReq.Recipients) > 0 {
+				// Get requested recipient IDs
+				requestedRecipientIDs := map[int]bool{}
+				for _, recipientReq := range deliveryReq.Recipients {
+					if recipientReq.ID != nil {
+						requestedRecipientIDs[*recipientReq.ID] = true
 					}
+				}
 
-					// Hard delete recipients not in request
-					if err := tx.Where("delivery_id = ? AND id NOT IN ?", deliveryModel.ID, getMapKeys(requestedRecipientIDs)).
-						Delete(&models.ReportDeliveryRecipient{}).Error; err != nil {
-						return fmt.Errorf("failed to delete removed recipients: %w", err)
-					}
+				// Hard delete recipients not in request
+				if err := tx.Where("delivery_id = ? AND id NOT IN ?", deliveryModel.ID, getMapKeys(requestedRecipientIDs)).
+					Delete(&models.ReportDeliveryRecipient{}).Error; err != nil {
+					return nil, fmt.Errorf("failed to delete removed recipients: %w", err)
+				}
+
+				// Process each recipient
+				for _, recipientReq := range deliveryReq.Recipients {
+					var recipientModel models.ReportDeliveryRecipient
 
-					// Process each recipient
-					for _, recipientReq := range deliveryReq.Recipients {
-						var recipientModel models.ReportDeliveryRecipient
-
-						if recipientReq.ID != nil {
-							// UPDATE existing recipient
-							if err := tx.First(&recipientModel, *recipientReq.ID).Error; err != nil {
-								return fmt.Errorf("recipient id %d not found: %w", *recipientReq.ID, err)
-							}
-
-							recipientUpdates := map[string]interface{}{
-								"recipient_value": recipientReq.RecipientValue,
-								"updated_at":      now,
-							}
-							if recipientReq.IsActive != nil {
-								recipientUpdates["is_active"] = recipientReq.IsActive
-							}
-
-							if err := tx.Model(&recipientModel).Updates(recipientUpdates).Error; err != nil {
-								return fmt.Errorf("failed to update recipient: %w", err)
-							}
-						} else {
-							// CREATE new recipient
-							recipientActive := true
-							if recipientReq.IsActive != nil {
-								recipientActive = *recipientReq.IsActive
-							}
-
-							recipientModel = models.ReportDeliveryRecipient{
-								DeliveryID:     deliveryModel.ID,
-								RecipientValue: recipientReq.RecipientValue,
-								IsActive:       recipientActive,
-								CreatedAt:      models.CustomTime{Time: now},
-								UpdatedAt:      models.CustomTime{Time: now},
-							}
-
-							if err := tx.Create(&recipientModel).Error; err != nil {
-								return fmt.Errorf("failed to create recipient: %w", err)
-							}
+					if recipientReq.ID != nil {
+						// UPDATE existing recipient
+						if err := tx.First(&recipientModel, *recipientReq.ID).Error; err != nil {
+							return nil, fmt.Errorf("recipient id %d not found: %w", *recipientReq.ID, err)
 						}
 
-						recipientResponses = append(recipientResponses, models.RecipientResponseNested{
-							ID:             recipientModel.ID,
-							RecipientValue: recipientModel.RecipientValue,
-							IsActive:       recipientModel.IsActive,
-						})
-					}
-				}
+						recipientUpdates := map[string]interface{}{
+							"recipient_value": recipientReq.RecipientValue,
+							"updated_at":      now,
+							"version":         gorm.Expr("version + 1"),
+						}
+						if recipientReq.IsActive != nil {
+							recipientUpdates["is_active"] = recipientReq.IsActive
+						}
+						if recipientReq.RecipientType != "" {
+							recipientUpdates["recipient_type"] = recipientReq.RecipientType
+						}
+						if recipientReq.RecipientConfig != nil {
+							recipientUpdates["recipient_config"] = recipientReq.RecipientConfig
+						}
 
-				// Marshal delivery config back to json.RawMessage for response
-				maskedConfig := maskSensitiveFields(deliveryModel.DeliveryConfig, deliveryModel.Method)
-				deliveryConfigJSON, _ := json.Marshal(maskedConfig)
-
-				deliveryResponses = append(deliveryResponses, models.DeliveryResponseNested{
-					ID:                   deliveryModel.ID,
-					DeliveryName:         deliveryModel.DeliveryName,
-					Method:               deliveryModel.Method,
-					MaxRetry:             deliveryModel.MaxRetry,
-					RetryIntervalMinutes: deliveryModel.RetryIntervalMinutes,
-					IsActive:             deliveryModel.IsActive,
-					DeliveryConfig:       deliveryConfigJSON,
-					Recipients:           recipientResponses,
-				})
-			}
-		} else {
-			// If no deliveries provided in update, fetch existing ones
-			var existingDeliveries []models.ReportDelivery
-			if err := tx.Where("config_id = ? AND is_active = ?", configID, true).Find(&existingDeliveries).Error; err != nil {
-				return fmt.Errorf("failed to fetch deliveries: %w", err)
-			}
+						if err := tx.Model(&recipientModel).Updates(recipientUpdates).Error; err != nil {
+							return nil, fmt.Errorf("failed to update recipient: %w", err)
+						}
+					} else {
+						// CREATE new recipient
+						recipientActive := true
+						if recipientReq.IsActive != nil {
+							recipientActive = *recipientReq.IsActive
+						}
 
-			for _, delivery := range existingDeliveries {
-				var recipients []models.ReportDeliveryRecipient
-				if err := tx.Where("delivery_id = ? AND is_active = ?", delivery.ID, true).Find(&recipients).Error; err != nil {
-					return fmt.Errorf("failed to fetch recipients: %w", err)
-				}
+						recipientModel = models.ReportDeliveryRecipient{
+							DeliveryID:      deliveryModel.ID,
+							RecipientType:   recipientReq.RecipientType,
+							RecipientValue:  recipientReq.RecipientValue,
+							RecipientConfig: recipientReq.RecipientConfig,
+							IsActive:        recipientActive,
+							CreatedAt:       models.CustomTime{Time: now},
+							UpdatedAt:       models.CustomTime{Time: now},
+							Version:         1,
+						}
+
+						if err := tx.Create(&recipientModel).Error; err != nil {
+							return nil, fmt.Errorf("failed to create recipient: %w", err)
+						}
+					}
 
-				recipientResponses := []models.RecipientResponseNested{}
-				for _, recipient := range recipients {
 					recipientResponses = append(recipientResponses, models.RecipientResponseNested{
-						ID:             recipient.ID,
-						RecipientValue: recipient.RecipientValue,
-						IsActive:       recipient.IsActive,
+						ID:             recipientModel.ID,
+						RecipientValue: recipientModel.RecipientValue,
+						IsActive:       recipientModel.IsActive,
 					})
 				}
-
-				// Marshal delivery config back to json.RawMessage for response
-				maskedConfig := maskSensitiveFields(delivery.DeliveryConfig, delivery.Method)
-				deliveryConfigJSON, _ := json.Marshal(maskedConfig)
-
-				deliveryResponses = append(deliveryResponses, models.DeliveryResponseNested{
-					ID:                   delivery.ID,
-					DeliveryName:         delivery.DeliveryName,
-					Method:               delivery.Method,
-					MaxRetry:             delivery.MaxRetry,
-					RetryIntervalMinutes: delivery.RetryIntervalMinutes,
-					IsActive:             delivery.IsActive,
-					DeliveryConfig:       deliveryConfigJSON,
-					Recipients:           recipientResponses,
-				})
 			}
-		}
-
-		// Reload updated models
-		tx.First(&schedule, scheduleID)
-		tx.First(&config, configID)
 
-		// Build response
-		// Marshal parameters back to json.RawMessage for response
-		parametersJSON, _ := json.Marshal(config.Parameters)
+			// Marshal delivery config back to json.RawMessage for response
+			maskedConfig := maskSensitiveFields(deliveryModel.DeliveryConfig, deliveryModel.Method)
+			deliveryConfigJSON, _ := json.Marshal(maskedConfig)
 
-		response = &models.CompleteScheduleResponse{
-			ScheduleID:     schedule.ID,
-			ConfigID:       config.ID,
-			CronExpression: schedule.CronExpression,
-			Timezone:       schedule.Timezone,
-			IsActive:       schedule.IsActive,
-			LastRunAt:      schedule.LastRunAt,
-			NextRunAt:      schedule.NextRunAt,
-			CreatedAt:      schedule.CreatedAt,
-			UpdatedAt:      schedule.UpdatedAt,
-			CreatedBy:      schedule.CreatedBy,
-			UpdatedBy:      schedule.UpdatedBy,
-			Config: models.ConfigResponseNested{
-				ID:             config.ID,
-				ReportName:     config.ReportName,
-				ReportQuery:    config.ReportQuery,
-				OutputFormat:   config.OutputFormat,
-				DatasourceID:   config.DatasourceID,
-				FileName:       config.FileName,
-				Parameters:     parametersJSON,
-				TimeoutSeconds: config.TimeoutSeconds,
-				MaxRows:        config.MaxRows,
-				IsActive:       config.IsActive,
-				Version:        config.Version,
-				Deliveries:     deliveryResponses,
-			},
+			deliveryResponses = append(deliveryResponses, models.DeliveryResponseNested{
+				ID:                   deliveryModel.ID,
+				DeliveryName:         deliveryModel.DeliveryName,
+				Method:               deliveryModel.Method,
+				MaxRetry:             deliveryModel.MaxRetry,
+				RetryIntervalMinutes: deliveryModel.RetryIntervalMinutes,
+				IsActive:             deliveryModel.IsActive,
+				DeliveryConfig:       deliveryConfigJSON,
+				Recipients:           recipientResponses,
+			})
+		}
+	} else {
+		// If no deliveries provided in update, fetch existing ones
+		existingResponses, err := loadDeliveryResponses(tx, configID)
+		if err != nil {
+			return nil, err
 		}
+		deliveryResponses = existingResponses
+	}
 
-		return nil
-	})
+	// Reload updated models
+	tx.First(&schedule, scheduleID)
+	tx.First(&config, configID)
 
+	response := newCompleteScheduleResponse(&schedule, &config, deliveryResponses)
+
+	// ETag of the state after this update, for the client's next If-Match
+	response.ETag, err = completeScheduleETag(tx, scheduleID)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to compute etag: %w", err)
 	}
 
 	return response, nil
 }
 
-// calculateNextRun calculates the next run time from cron expression and timezone
-func (s *CompleteScheduleService) calculateNextRun(cronExpression string, timezone string) (*models.CustomTime, error) {
-	// Parse timezone
-	loc, err := time.LoadLocation(timezone)
+// GetComplete retrieves a complete schedule with its active deliveries and recipients, and its ETag
+func (s *CompleteScheduleService) GetComplete(scheduleID int) (*models.CompleteScheduleResponse, error) {
+	response, err := loadCompleteSchedule(config.DB, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	response.ETag, err = completeScheduleETag(config.DB, scheduleID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid timezone '%s': %w", timezone, err)
+		return nil, err
+	}
+	return response, nil
+}
+
+// loadCompleteSchedule reads the stored complete schedule the way UpdateComplete returns it
+func loadCompleteSchedule(db *gorm.DB, scheduleID int) (*models.CompleteScheduleResponse, error) {
+	var schedule models.ReportSchedule
+	if err := db.First(&schedule, scheduleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("schedule not found")
+		}
+		return nil, fmt.Errorf("failed to find schedule: %w", err)
+	}
+
+	var config models.ReportConfig
+	if err := db.First(&config, schedule.ConfigID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find config: %w", err)
 	}
 
-	// Parse cron expression
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	schedule, err := parser.Parse(cronExpression)
+	deliveryResponses, err := loadDeliveryResponses(db, config.ID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid cron expression '%s': %w", cronExpression, err)
+		return nil, err
+	}
+
+	return newCompleteScheduleResponse(&schedule, &config, deliveryResponses), nil
+}
+
+// loadDeliveryResponses reads a config's active deliveries with their active recipients
+func loadDeliveryResponses(db *gorm.DB, configID int) ([]models.DeliveryResponseNested, error) {
+	var existingDeliveries []models.ReportDelivery
+	if err := db.Where("config_id = ? AND is_active = ?", configID, true).Find(&existingDeliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch deliveries: %w", err)
 	}
 
-	// Calculate next run from now
-	now := time.Now().In(loc)
-	nextRun := schedule.Next(now)
+	deliveryResponses := []models.DeliveryResponseNested{}
+	for _, delivery := range existingDeliveries {
+		var recipients []models.ReportDeliveryRecipient
+		if err := db.Where("delivery_id = ? AND is_active = ?", delivery.ID, true).Find(&recipients).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch recipients: %w", err)
+		}
+
+		recipientResponses := []models.RecipientResponseNested{}
+		for _, recipient := range recipients {
+			recipientResponses = append(recipientResponses, models.RecipientResponseNested{
+				ID:             recipient.ID,
+				RecipientValue: recipient.RecipientValue,
+				IsActive:       recipient.IsActive,
+			})
+		}
+
+		// Marshal delivery config back to json.RawMessage for response
+		maskedConfig := maskSensitiveFields(delivery.DeliveryConfig, delivery.Method)
+		deliveryConfigJSON, _ := json.Marshal(maskedConfig)
+
+		deliveryResponses = append(deliveryResponses, models.DeliveryResponseNested{
+			ID:                   delivery.ID,
+			DeliveryName:         delivery.DeliveryName,
+			Method:               delivery.Method,
+			MaxRetry:             delivery.MaxRetry,
+			RetryIntervalMinutes: delivery.RetryIntervalMinutes,
+			IsActive:             delivery.IsActive,
+			DeliveryConfig:       deliveryConfigJSON,
+			Recipients:           recipientResponses,
+		})
+	}
+	return deliveryResponses, nil
+}
+
+// newCompleteScheduleResponse builds the complete schedule response from its parts
+func newCompleteScheduleResponse(schedule *models.ReportSchedule, config *models.ReportConfig, deliveryResponses []models.DeliveryResponseNested) *models.CompleteScheduleResponse {
+	// Marshal parameters back to json.RawMessage for response
+	parametersJSON, _ := json.Marshal(config.Parameters)
+
+	return &models.CompleteScheduleResponse{
+		ScheduleID:     schedule.ID,
+		ConfigID:       config.ID,
+		CronExpression: schedule.CronExpression,
+		Timezone:       schedule.Timezone,
+		IsActive:       schedule.IsActive,
+		LastRunAt:      schedule.LastRunAt,
+		NextRunAt:      schedule.NextRunAt,
+		MisfirePolicy:  schedule.MisfirePolicy,
+		MisfireMaxRuns: schedule.MisfireMaxRuns,
+		CreatedAt:      schedule.CreatedAt,
+		UpdatedAt:      schedule.UpdatedAt,
+		CreatedBy:      schedule.CreatedBy,
+		UpdatedBy:      schedule.UpdatedBy,
+		Config: models.ConfigResponseNested{
+			ID:              config.ID,
+			ReportName:      config.ReportName,
+			ReportQuery:     config.ReportQuery,
+			OutputFormat:    config.OutputFormat,
+			DatasourceID:    config.DatasourceID,
+			FileName:        config.FileName,
+			Parameters:      parametersJSON,
+			ParameterSchema: config.ParameterSchema,
+			TimeoutSeconds:  config.TimeoutSeconds,
+			MaxRows:         config.MaxRows,
+			IsActive:        config.IsActive,
+			Version:         config.Version,
+			Deliveries:      deliveryResponses,
+		},
+	}
+}
+
+// completeScheduleETag locks the schedule, its config and every delivery and recipient of the config,
+// and hashes their versions into one ETag. Adding or removing a nested row changes it as well.
+func completeScheduleETag(tx *gorm.DB, scheduleID int) (string, error) {
+	forUpdate := clause.Locking{Strength: "UPDATE"}
+
+	var schedule models.ReportSchedule
+	if err := tx.Clauses(forUpdate).Select("id", "config_id", "version").First(&schedule, scheduleID).Error; err != nil {
+		return "", err
+	}
+	var config models.ReportConfig
+	if err := tx.Clauses(forUpdate).Select("id", "version").First(&config, schedule.ConfigID).Error; err != nil {
+		return "", err
+	}
+	tokens := []string{
+		fmt.Sprintf("schedule:%d:%d", schedule.ID, schedule.Version),
+		fmt.Sprintf("config:%d:%d", config.ID, config.Version),
+	}
+
+	var deliveries []models.ReportDelivery
+	if err := tx.Clauses(forUpdate).Select("id", "version").Where("config_id = ?", config.ID).Order("id").Find(&deliveries).Error; err != nil {
+		return "", err
+	}
+	if len(deliveries) == 0 {
+		return utils.CompositeETag(tokens), nil
+	}
+	deliveryIDs := make([]int, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		tokens = append(tokens, fmt.Sprintf("delivery:%d:%d", delivery.ID, delivery.Version))
+		deliveryIDs = append(deliveryIDs, delivery.ID)
+	}
+
+	var recipients []models.ReportDeliveryRecipient
+	if err := tx.Clauses(forUpdate).Select("id", "delivery_id", "version").Where("delivery_id IN ?", deliveryIDs).Order("id").Find(&recipients).Error; err != nil {
+		return "", err
+	}
+	for _, recipient := range recipients {
+		tokens = append(tokens, fmt.Sprintf("recipient:%d:%d:%d", recipient.ID, recipient.DeliveryID, recipient.Version))
+	}
+
+	return utils.CompositeETag(tokens), nil
+}
+
+// calculateNextRun calculates the next run time from cron expression and timezone
+func (s *CompleteScheduleService) calculateNextRun(cronExpression string, timezone string) (*models.CustomTime, error) {
+	nextRun, err := utils.CalculateNextRun(cronExpression, timezone, time.Now())
+	if err != nil {
+		return nil, err
+	}
 
 	return &models.CustomTime{Time: nextRun}, nil
 }
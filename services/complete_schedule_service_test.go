@@ -0,0 +1,228 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"scheduling-report/models"
+
+	"gorm.io/gorm"
+)
+
+// createTestSchedule creates "Sales export" on the seeded datasource with an sftp delivery holding a
+// password and an email delivery, each with recipients
+func createTestSchedule(t *testing.T, db *gorm.DB) *models.CompleteScheduleResponse {
+	t.Helper()
+
+	reportConfig := seedConfig(t, db)
+	// The seeded datasource cannot be reached, so the cost check needs an override
+	created, err := NewCompleteScheduleService().CreateComplete(models.CompleteScheduleRequest{
+		CronExpression: "0 6 * * *",
+		Timezone:       "UTC",
+		IsActive:       true,
+		CreatedBy:      "test",
+		CostOverride:   true,
+		Configs: models.ConfigWithDeliveriesRequest{
+			ReportName:   "Sales export",
+			ReportQuery:  reportConfig.ReportQuery,
+			OutputFormat: "csv",
+			DatasourceID: reportConfig.DatasourceID,
+			Deliveries: []models.DeliveryWithRecipientsRequest{
+				{
+					DeliveryName:   "Finance SFTP",
+					Method:         "sftp",
+					DeliveryConfig: json.RawMessage(`{"host":"sftp.example.com","username":"reports","password":"s3cret"}`),
+					Recipients:     []models.RecipientRequest{{RecipientType: "path", RecipientValue: "/finance/daily"}},
+				},
+				{
+					DeliveryName:   "Sales team",
+					Method:         "email",
+					DeliveryConfig: json.RawMessage(`{"subject":"Daily sales"}`),
+					Recipients: []models.RecipientRequest{
+						{RecipientType: "email", RecipientValue: "ana@example.com"},
+						{RecipientType: "email", RecipientValue: "ben@example.com"},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateComplete: %v", err)
+	}
+	return created
+}
+
+// loadDeliveryRecipients returns a config's deliveries and the recipient values of each, in id order
+func loadDeliveryRecipients(t *testing.T, db *gorm.DB, configID int) ([]models.ReportDelivery, map[string][]string) {
+	t.Helper()
+
+	var deliveries []models.ReportDelivery
+	if err := db.Where("config_id = ?", configID).Order("id").Find(&deliveries).Error; err != nil {
+		t.Fatalf("load deliveries: %v", err)
+	}
+	recipients := map[string][]string{}
+	for _, delivery := range deliveries {
+		var values []string
+		if err := db.Model(&models.ReportDeliveryRecipient{}).Where("delivery_id = ?", delivery.ID).
+			Order("id").Pluck("recipient_value", &values).Error; err != nil {
+			t.Fatalf("load recipients: %v", err)
+		}
+		recipients[delivery.DeliveryName] = values
+	}
+	return deliveries, recipients
+}
+
+func TestCloneComplete(t *testing.T) {
+	db := useTestDB(t)
+	source := createTestSchedule(t, db)
+	service := NewCompleteScheduleService()
+
+	clone, err := service.CloneComplete(source.ScheduleID, models.CompleteScheduleCloneRequest{CreatedBy: "test"})
+	if err != nil {
+		t.Fatalf("CloneComplete: %v", err)
+	}
+
+	if clone.ScheduleID == source.ScheduleID || clone.ConfigID == source.ConfigID {
+		t.Fatalf("clone reuses the source rows: %+v", clone)
+	}
+	if clone.Config.ReportName != "Sales export (copy)" {
+		t.Errorf("report_name = %q, want %q", clone.Config.ReportName, "Sales export (copy)")
+	}
+	if clone.IsActive {
+		t.Error("clone is active, want it inactive until edited")
+	}
+	if clone.CronExpression != "0 6 * * *" || clone.Config.ReportQuery != source.Config.ReportQuery {
+		t.Errorf("clone schedule = %q / %q, want the source's", clone.CronExpression, clone.Config.ReportQuery)
+	}
+
+	sourceDeliveries, sourceRecipients := loadDeliveryRecipients(t, db, source.ConfigID)
+	cloneDeliveries, cloneRecipients := loadDeliveryRecipients(t, db, clone.ConfigID)
+	if len(cloneDeliveries) != len(sourceDeliveries) {
+		t.Fatalf("%d deliveries cloned, want %d", len(cloneDeliveries), len(sourceDeliveries))
+	}
+	for name, values := range sourceRecipients {
+		if strings.Join(cloneRecipients[name], ",") != strings.Join(values, ",") {
+			t.Errorf("%s recipients = %v, want %v", name, cloneRecipients[name], values)
+		}
+	}
+	// Stored credentials are copied even though the response masks them
+	if password := cloneDeliveries[0].DeliveryConfig["password"]; password != "s3cret" {
+		t.Errorf("cloned password = %v, want the stored secret", password)
+	}
+
+	var cloneAudit models.ReportConfigAudit
+	if err := db.Where("config_id = ? AND action = ?", source.ConfigID, "clone").First(&cloneAudit).Error; err != nil {
+		t.Fatalf("load clone audit: %v", err)
+	}
+	if cloneAudit.ChangeSummary == nil || !strings.Contains(*cloneAudit.ChangeSummary, `"cloned_to"`) {
+		t.Errorf("clone audit change_summary = %v, want cloned_to", cloneAudit.ChangeSummary)
+	}
+	var createAudit models.ReportConfigAudit
+	if err := db.Where("config_id = ? AND action = ?", clone.ConfigID, "create").First(&createAudit).Error; err != nil {
+		t.Fatalf("load create audit: %v", err)
+	}
+	if createAudit.ChangeSummary == nil || !strings.Contains(*createAudit.ChangeSummary, `"cloned_from"`) {
+		t.Errorf("create audit change_summary = %v, want cloned_from", createAudit.ChangeSummary)
+	}
+
+	second, err := service.CloneComplete(source.ScheduleID, models.CompleteScheduleCloneRequest{CreatedBy: "test"})
+	if err != nil {
+		t.Fatalf("second CloneComplete: %v", err)
+	}
+	if second.Config.ReportName != "Sales export (copy 2)" {
+		t.Errorf("second clone report_name = %q, want %q", second.Config.ReportName, "Sales export (copy 2)")
+	}
+}
+
+func TestCloneCompleteRejects(t *testing.T) {
+	db := useTestDB(t)
+	source := createTestSchedule(t, db)
+
+	takenName := "Sales export"
+	unreachable := models.DataSource{
+		Name:          "unreachable",
+		DbType:        "mysql",
+		ConnectionURL: "report:report@tcp(127.0.0.1:1)/reports?timeout=1s",
+		IsActive:      true,
+		CreatedBy:     "test",
+		UpdatedBy:     "test",
+	}
+	if err := db.Create(&unreachable).Error; err != nil {
+		t.Fatalf("seed datasource: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		scheduleID int
+		req        models.CompleteScheduleCloneRequest
+		wantErr    string
+		wantHeavy  bool
+	}{
+		{name: "unknown schedule", scheduleID: source.ScheduleID + 100, wantErr: "schedule not found"},
+		{name: "taken report name", scheduleID: source.ScheduleID, req: models.CompleteScheduleCloneRequest{ReportName: &takenName},
+			wantErr: "report config with name 'Sales export' already exists"},
+		{name: "another datasource is cost checked", scheduleID: source.ScheduleID,
+			req: models.CompleteScheduleCloneRequest{DatasourceID: &unreachable.ID}, wantHeavy: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.CreatedBy = "test"
+			_, err := NewCompleteScheduleService().CloneComplete(tt.scheduleID, tt.req)
+
+			var heavyErr *HeavyQueryError
+			switch {
+			case tt.wantHeavy && !errors.As(err, &heavyErr):
+				t.Fatalf("CloneComplete = %v, want a HeavyQueryError", err)
+			case !tt.wantHeavy && (err == nil || err.Error() != tt.wantErr):
+				t.Fatalf("CloneComplete = %v, want %q", err, tt.wantErr)
+			}
+
+			var count int64
+			db.Model(&models.ReportConfig{}).Count(&count)
+			if count != 2 {
+				t.Errorf("%d configs, want only the seeded and source configs", count)
+			}
+		})
+	}
+}
+
+func TestUniqueReportName(t *testing.T) {
+	tests := []struct {
+		name  string
+		taken []string
+		label string
+		want  string
+	}{
+		{name: "free", label: "copy", want: "Sales (copy)"},
+		{name: "first taken", taken: []string{"Sales (copy)"}, label: "copy", want: "Sales (copy 2)"},
+		{name: "numbered taken", taken: []string{"Sales (copy)", "Sales (copy 2)"}, label: "copy", want: "Sales (copy 3)"},
+		{name: "gap reused", taken: []string{"Sales (copy)", "Sales (copy 3)"}, label: "copy", want: "Sales (copy 2)"},
+		{name: "other label", taken: []string{"Sales (copy)"}, label: "imported", want: "Sales (imported)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := useTestDB(t)
+			reportConfig := seedConfig(t, db)
+			for _, name := range tt.taken {
+				taken := reportConfig
+				taken.ID = 0
+				taken.ReportName = name
+				if err := db.Create(&taken).Error; err != nil {
+					t.Fatalf("seed config %q: %v", name, err)
+				}
+			}
+
+			got, err := uniqueReportName(db, "Sales", tt.label)
+			if err != nil {
+				t.Fatalf("uniqueReportName: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("uniqueReportName = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
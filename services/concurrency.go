@@ -0,0 +1,35 @@
+package services
+
+import (
+	"scheduling-report/models"
+	"scheduling-report/utils"
+)
+
+// PreconditionFailedError rejects a write whose If-Match no longer matches the stored entity.
+// Current is the entity as stored now and ETag its tag, so the client can merge and retry.
+type PreconditionFailedError struct {
+	ETag    string
+	Current interface{}
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return "resource was modified by another request; reload it and retry"
+}
+
+// checkIfMatch fails with the current state when ifMatch is set and does not match etag
+func checkIfMatch(ifMatch string, etag string, current interface{}) error {
+	if utils.ETagMatches(ifMatch, etag) {
+		return nil
+	}
+	return &PreconditionFailedError{ETag: etag, Current: current}
+}
+
+// versionConflictError reports a conditional update that lost the race against another write
+func versionConflictError(current interface{}, version int) error {
+	return &PreconditionFailedError{ETag: utils.VersionETag(version), Current: current}
+}
+
+// metadataConflictError reports a metadata update that lost the race against another write
+func metadataConflictError(current *models.ReportConfig) error {
+	return &PreconditionFailedError{ETag: utils.MetadataETag(current.MetadataVersion), Current: current}
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"scheduling-report/models"
+	"scheduling-report/utils"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// sqlDriverNames maps DataSource.DbType to the registered database/sql driver
+var sqlDriverNames = map[string]string{
+	"mysql":      "mysql",
+	"postgresql": "postgres",
+	"sqlserver":  "sqlserver",
+}
+
+// versionQueries returns the server version for each supported DbType
+var versionQueries = map[string]string{
+	"mysql":      "SELECT VERSION()",
+	"postgresql": "SHOW server_version",
+	"sqlserver":  "SELECT CAST(SERVERPROPERTY('ProductVersion') AS NVARCHAR(128))",
+}
+
+// Connection test error categories
+const (
+	ConnectionErrorUnsupported    = "unsupported_type"
+	ConnectionErrorInvalidConfig  = "invalid_config"
+	ConnectionErrorDNS            = "dns"
+	ConnectionErrorRefused        = "connection_refused"
+	ConnectionErrorTimeout        = "timeout"
+	ConnectionErrorAuthentication = "authentication"
+	ConnectionErrorDatabase       = "database_not_found"
+	ConnectionErrorTLS            = "tls"
+	ConnectionErrorUnknown        = "unknown"
+)
+
+// OpenDataSource opens a connection pool to a report datasource.
+// The caller owns the returned *sql.DB and must close it.
+func OpenDataSource(datasource *models.DataSource) (*sql.DB, error) {
+	driverName, ok := sqlDriverNames[datasource.DbType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported datasource type '%s'", datasource.DbType)
+	}
+
+	dsn, err := datasourceDSN(datasource)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open datasource: %w", err)
+	}
+
+	db.SetMaxOpenConns(2)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	return db, nil
+}
+
+// datasourceDSN resolves secret references in the datasource and returns the driver DSN.
+// A password in connection_config (literal or reference) overrides the one in the URL.
+func datasourceDSN(datasource *models.DataSource) (string, error) {
+	dsn, err := utils.ResolveSecretReference(string(datasource.ConnectionURL))
+	if err != nil {
+		return "", err
+	}
+	connectionConfig, err := utils.ResolveSecretReferences(datasource.ConnectionConfig)
+	if err != nil {
+		return "", err
+	}
+	password, _ := connectionConfig["password"].(string)
+
+	if datasource.DbType == "mysql" {
+		// go-sql-driver expects a DSN without scheme
+		dsn = strings.TrimPrefix(dsn, "mysql://")
+		if password == "" {
+			return dsn, nil
+		}
+		cfg, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return "", err
+		}
+		cfg.Passwd = password
+		return cfg.FormatDSN(), nil
+	}
+
+	if password == "" {
+		return dsn, nil
+	}
+	parsed, err := url.Parse(dsn)
+	if err != nil || parsed.User == nil {
+		return "", errors.New("connection_config password requires a connection URL with a user name")
+	}
+	parsed.User = url.UserPassword(parsed.User.Username(), password)
+	return parsed.String(), nil
+}
+
+// ConnectionTestResult is the outcome of probing a datasource
+type ConnectionTestResult struct {
+	Success       bool      `json:"success"`
+	DbType        string    `json:"db_type"`
+	LatencyMs     int64     `json:"latency_ms"`
+	ServerVersion string    `json:"server_version,omitempty"`
+	ErrorCategory string    `json:"error_category,omitempty"`
+	ErrorMessage  string    `json:"error_message,omitempty"`
+	TestedAt      time.Time `json:"tested_at"`
+}
+
+// TestDataSourceConnection opens a real connection and runs a version probe within timeout
+func TestDataSourceConnection(datasource *models.DataSource, timeout time.Duration) ConnectionTestResult {
+	result := ConnectionTestResult{
+		DbType:   datasource.DbType,
+		TestedAt: time.Now(),
+	}
+
+	fail := func(category string, err error) ConnectionTestResult {
+		result.ErrorCategory = category
+		result.ErrorMessage = err.Error()
+		return result
+	}
+
+	if _, ok := sqlDriverNames[datasource.DbType]; !ok {
+		return fail(ConnectionErrorUnsupported, fmt.Errorf("connection test is not supported for datasource type '%s'", datasource.DbType))
+	}
+
+	db, err := OpenDataSource(datasource)
+	if err != nil {
+		return fail(ConnectionErrorInvalidConfig, err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	var version string
+	err = db.QueryRowContext(ctx, versionQueries[datasource.DbType]).Scan(&version)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fail(ConnectionErrorTimeout, fmt.Errorf("no response within %s", timeout))
+		}
+		return fail(categorizeConnectionError(err), err)
+	}
+
+	result.Success = true
+	result.ServerVersion = version
+	return result
+}
+
+// categorizeConnectionError maps driver and network errors to a connection test category
+func categorizeConnectionError(err error) string {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1044, 1045, 1698:
+			return ConnectionErrorAuthentication
+		case 1049:
+			return ConnectionErrorDatabase
+		}
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "28000", "28P01":
+			return ConnectionErrorAuthentication
+		case "3D000":
+			return ConnectionErrorDatabase
+		}
+	}
+
+	var mssqlErr mssql.Error
+	if errors.As(err, &mssqlErr) {
+		switch mssqlErr.Number {
+		case 18456:
+			return ConnectionErrorAuthentication
+		case 4060:
+			return ConnectionErrorDatabase
+		}
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ConnectionErrorDNS
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ConnectionErrorRefused
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ConnectionErrorTimeout
+	}
+
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "tls") || strings.Contains(message, "ssl") || strings.Contains(message, "x509"):
+		return ConnectionErrorTLS
+	case strings.Contains(message, "invalid dsn") || strings.Contains(message, "missing") || strings.Contains(message, "parse"):
+		return ConnectionErrorInvalidConfig
+	case strings.Contains(message, "password") || strings.Contains(message, "login failed") || strings.Contains(message, "access denied"):
+		return ConnectionErrorAuthentication
+	}
+
+	return ConnectionErrorUnknown
+}
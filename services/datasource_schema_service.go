@@ -0,0 +1,408 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"scheduling-report/config"
+	"scheduling-report/models"
+	"scheduling-report/repositories"
+)
+
+// Default and maximum rows sampled for column statistics
+const (
+	DefaultStatisticsSampleSize = 1000
+	MaxStatisticsSampleSize     = 10000
+)
+
+// SchemaColumn is a column of an introspected table
+type SchemaColumn struct {
+	Name     string `json:"name"`
+	DataType string `json:"data_type"`
+	Nullable bool   `json:"nullable"`
+	Position int    `json:"position"`
+}
+
+// SchemaTable is a table or view of an introspected datasource
+type SchemaTable struct {
+	Schema string `json:"schema"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	// RowEstimate comes from the database's statistics, not a COUNT(*)
+	RowEstimate *int64         `json:"row_estimate"`
+	Columns     []SchemaColumn `json:"columns"`
+}
+
+// DatasourceSchema is the table and column catalog of a datasource
+type DatasourceSchema struct {
+	DatasourceID int           `json:"datasource_id"`
+	DbType       string        `json:"db_type"`
+	Schemas      []string      `json:"schemas"`
+	Tables       []SchemaTable `json:"tables"`
+	FetchedAt    time.Time     `json:"fetched_at"`
+	Cached       bool          `json:"cached"`
+}
+
+// ColumnStatistics summarises a column over a sample of rows
+type ColumnStatistics struct {
+	Name          string      `json:"name"`
+	DataType      string      `json:"data_type"`
+	NullCount     int64       `json:"null_count"`
+	NullFraction  float64     `json:"null_fraction"`
+	DistinctCount *int64      `json:"distinct_count"`
+	Min           interface{} `json:"min"`
+	Max           interface{} `json:"max"`
+}
+
+// TableStatistics holds sample statistics for every column of a table
+type TableStatistics struct {
+	DatasourceID int                `json:"datasource_id"`
+	Schema       string             `json:"schema"`
+	Table        string             `json:"table"`
+	RowEstimate  *int64             `json:"row_estimate"`
+	SampleSize   int                `json:"sample_size"`
+	SampledRows  int64              `json:"sampled_rows"`
+	Columns      []ColumnStatistics `json:"columns"`
+}
+
+// schemaQueries list every column with its table, one row per column, ordered by table and position.
+// Columns: schema, table, table type, row estimate, column, data type, is nullable, position.
+var schemaQueries = map[string]string{
+	"mysql": `SELECT c.TABLE_SCHEMA, c.TABLE_NAME, t.TABLE_TYPE, t.TABLE_ROWS,
+	c.COLUMN_NAME, c.DATA_TYPE, c.IS_NULLABLE, c.ORDINAL_POSITION
+FROM information_schema.COLUMNS c
+JOIN information_schema.TABLES t ON t.TABLE_SCHEMA = c.TABLE_SCHEMA AND t.TABLE_NAME = c.TABLE_NAME
+WHERE c.TABLE_SCHEMA = DATABASE()
+ORDER BY c.TABLE_SCHEMA, c.TABLE_NAME, c.ORDINAL_POSITION`,
+	"postgresql": `SELECT c.table_schema, c.table_name, t.table_type,
+	(SELECT pc.reltuples::bigint FROM pg_catalog.pg_class pc
+	 WHERE pc.oid = to_regclass(quote_ident(c.table_schema) || '.' || quote_ident(c.table_name))),
+	c.column_name, c.data_type, c.is_nullable, c.ordinal_position
+FROM information_schema.columns c
+JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+WHERE c.table_schema NOT IN ('pg_catalog', 'information_schema')
+ORDER BY c.table_schema, c.table_name, c.ordinal_position`,
+	"sqlserver": `SELECT c.TABLE_SCHEMA, c.TABLE_NAME, t.TABLE_TYPE,
+	(SELECT SUM(p.rows) FROM sys.partitions p
+	 WHERE p.object_id = OBJECT_ID(QUOTENAME(c.TABLE_SCHEMA) + '.' + QUOTENAME(c.TABLE_NAME)) AND p.index_id IN (0, 1)),
+	c.COLUMN_NAME, c.DATA_TYPE, c.IS_NULLABLE, c.ORDINAL_POSITION
+FROM INFORMATION_SCHEMA.COLUMNS c
+JOIN INFORMATION_SCHEMA.TABLES t ON t.TABLE_SCHEMA = c.TABLE_SCHEMA AND t.TABLE_NAME = c.TABLE_NAME
+ORDER BY c.TABLE_SCHEMA, c.TABLE_NAME, c.ORDINAL_POSITION`,
+}
+
+// orderedTypes support MIN/MAX and COUNT(DISTINCT) on every supported database
+var orderedTypes = map[string]bool{
+	"tinyint": true, "smallint": true, "mediumint": true, "int": true, "integer": true, "bigint": true,
+	"decimal": true, "numeric": true, "float": true, "double": true, "double precision": true, "real": true,
+	"money": true, "smallmoney": true,
+	"date": true, "time": true, "datetime": true, "datetime2": true, "smalldatetime": true, "datetimeoffset": true,
+	"timestamp": true, "timestamp without time zone": true, "timestamp with time zone": true, "year": true,
+	"char": true, "varchar": true, "nchar": true, "nvarchar": true, "character": true, "character varying": true,
+	"enum": true,
+}
+
+// distinctTypes support COUNT(DISTINCT) but not MIN/MAX everywhere
+var distinctTypes = map[string]bool{
+	"boolean": true, "bit": true, "uuid": true, "uniqueidentifier": true,
+}
+
+type schemaCacheEntry struct {
+	schema    *DatasourceSchema
+	expiresAt time.Time
+}
+
+// schemaCache is shared by every DatasourceSchemaService so updates can invalidate it
+var schemaCache = struct {
+	sync.Mutex
+	entries map[int]schemaCacheEntry
+}{entries: map[int]schemaCacheEntry{}}
+
+// InvalidateSchemaCache drops the cached schema of a datasource after its connection changes
+func InvalidateSchemaCache(datasourceID int) {
+	schemaCache.Lock()
+	defer schemaCache.Unlock()
+	delete(schemaCache.entries, datasourceID)
+}
+
+type DatasourceSchemaService struct {
+	repo *repository.DatasourceRepository
+}
+
+func NewDatasourceSchemaService() *DatasourceSchemaService {
+	return &DatasourceSchemaService{
+		repo: repository.NewDatasourceRepository(),
+	}
+}
+
+// GetSchema returns the datasource's tables and columns, from cache unless refresh is set
+func (s *DatasourceSchemaService) GetSchema(id int, refresh bool) (*DatasourceSchema, error) {
+	datasource, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, errors.New("datasource not found")
+	}
+	return s.schema(datasource, refresh)
+}
+
+func (s *DatasourceSchemaService) schema(datasource *models.DataSource, refresh bool) (*DatasourceSchema, error) {
+	if !refresh {
+		schemaCache.Lock()
+		entry, ok := schemaCache.entries[datasource.ID]
+		schemaCache.Unlock()
+		if ok && time.Now().Before(entry.expiresAt) {
+			cached := *entry.schema
+			cached.Cached = true
+			return &cached, nil
+		}
+	}
+
+	schema, err := introspectSchema(datasource)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaCache.Lock()
+	schemaCache.entries[datasource.ID] = schemaCacheEntry{
+		schema:    schema,
+		expiresAt: time.Now().Add(time.Duration(config.Config.SchemaCacheTTLSeconds) * time.Second),
+	}
+	schemaCache.Unlock()
+
+	return schema, nil
+}
+
+func introspectSchema(datasource *models.DataSource) (*DatasourceSchema, error) {
+	query, ok := schemaQueries[datasource.DbType]
+	if !ok {
+		return nil, fmt.Errorf("schema introspection is not supported for datasource type '%s'", datasource.DbType)
+	}
+
+	db, err := OpenDataSource(datasource)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), schemaQueryTimeout())
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	defer rows.Close()
+
+	schema := &DatasourceSchema{
+		DatasourceID: datasource.ID,
+		DbType:       datasource.DbType,
+		Schemas:      []string{},
+		Tables:       []SchemaTable{},
+		FetchedAt:    time.Now(),
+	}
+
+	for rows.Next() {
+		var (
+			schemaName, tableName, tableType string
+			rowEstimate                      sql.NullInt64
+			column                           SchemaColumn
+			nullable                         string
+		)
+		if err := rows.Scan(&schemaName, &tableName, &tableType, &rowEstimate, &column.Name, &column.DataType, &nullable, &column.Position); err != nil {
+			return nil, fmt.Errorf("failed to read schema: %w", err)
+		}
+		column.Nullable = strings.EqualFold(nullable, "YES")
+
+		last := len(schema.Tables) - 1
+		if last < 0 || schema.Tables[last].Schema != schemaName || schema.Tables[last].Name != tableName {
+			table := SchemaTable{Schema: schemaName, Name: tableName, Type: tableType, Columns: []SchemaColumn{}}
+			// PostgreSQL reports -1 for tables that were never analyzed
+			if rowEstimate.Valid && rowEstimate.Int64 >= 0 {
+				estimate := rowEstimate.Int64
+				table.RowEstimate = &estimate
+			}
+			schema.Tables = append(schema.Tables, table)
+			if len(schema.Schemas) == 0 || schema.Schemas[len(schema.Schemas)-1] != schemaName {
+				schema.Schemas = append(schema.Schemas, schemaName)
+			}
+			last++
+		}
+		schema.Tables[last].Columns = append(schema.Tables[last].Columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	return schema, nil
+}
+
+// GetTableStatistics samples up to sampleSize rows of a table and summarises each column.
+// table is "name" or "schema.name" and must exist in the datasource's schema.
+func (s *DatasourceSchemaService) GetTableStatistics(id int, table string, sampleSize int) (*TableStatistics, error) {
+	datasource, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, errors.New("datasource not found")
+	}
+
+	if sampleSize <= 0 {
+		sampleSize = DefaultStatisticsSampleSize
+	}
+	if sampleSize > MaxStatisticsSampleSize {
+		sampleSize = MaxStatisticsSampleSize
+	}
+
+	schema, err := s.schema(datasource, false)
+	if err != nil {
+		return nil, err
+	}
+	target, err := findSchemaTable(schema, table)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := OpenDataSource(datasource)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), schemaQueryTimeout())
+	defer cancel()
+
+	// Only identifiers taken from the introspected schema reach the SQL, always quoted
+	selects := []string{"COUNT(*)"}
+	sampleColumns := make([]string, len(target.Columns))
+	for i, column := range target.Columns {
+		quoted := quoteIdentifier(datasource.DbType, column.Name)
+		sampleColumns[i] = quoted
+		dataType := strings.ToLower(column.DataType)
+		selects = append(selects, "COUNT("+quoted+")")
+		if orderedTypes[dataType] || distinctTypes[dataType] {
+			selects = append(selects, "COUNT(DISTINCT "+quoted+")")
+		}
+		if orderedTypes[dataType] {
+			selects = append(selects, "MIN("+quoted+")", "MAX("+quoted+")")
+		}
+	}
+
+	from := quoteIdentifier(datasource.DbType, target.Schema) + "." + quoteIdentifier(datasource.DbType, target.Name)
+	var sample string
+	if datasource.DbType == "sqlserver" {
+		sample = fmt.Sprintf("SELECT TOP (%d) %s FROM %s", sampleSize, strings.Join(sampleColumns, ", "), from)
+	} else {
+		sample = fmt.Sprintf("SELECT %s FROM %s LIMIT %d", strings.Join(sampleColumns, ", "), from, sampleSize)
+	}
+	query := fmt.Sprintf("SELECT %s FROM (%s) sample_rows", strings.Join(selects, ", "), sample)
+
+	values := make([]interface{}, len(selects))
+	pointers := make([]interface{}, len(selects))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := db.QueryRowContext(ctx, query).Scan(pointers...); err != nil {
+		return nil, fmt.Errorf("failed to sample table: %w", err)
+	}
+
+	stats := &TableStatistics{
+		DatasourceID: datasource.ID,
+		Schema:       target.Schema,
+		Table:        target.Name,
+		RowEstimate:  target.RowEstimate,
+		SampleSize:   sampleSize,
+		SampledRows:  toInt64(values[0]),
+		Columns:      make([]ColumnStatistics, len(target.Columns)),
+	}
+
+	next := 1
+	for i, column := range target.Columns {
+		dataType := strings.ToLower(column.DataType)
+		columnStats := ColumnStatistics{Name: column.Name, DataType: column.DataType}
+
+		columnStats.NullCount = stats.SampledRows - toInt64(values[next])
+		next++
+		if stats.SampledRows > 0 {
+			columnStats.NullFraction = float64(columnStats.NullCount) / float64(stats.SampledRows)
+		}
+		if orderedTypes[dataType] || distinctTypes[dataType] {
+			distinct := toInt64(values[next])
+			columnStats.DistinctCount = &distinct
+			next++
+		}
+		if orderedTypes[dataType] {
+			columnStats.Min = statisticValue(values[next])
+			columnStats.Max = statisticValue(values[next+1])
+			next += 2
+		}
+		stats.Columns[i] = columnStats
+	}
+
+	return stats, nil
+}
+
+// findSchemaTable looks a table up by "name" or "schema.name"
+func findSchemaTable(schema *DatasourceSchema, table string) (*SchemaTable, error) {
+	schemaName, tableName, qualified := strings.Cut(table, ".")
+	if !qualified {
+		schemaName, tableName = "", table
+	}
+
+	var match *SchemaTable
+	for i := range schema.Tables {
+		candidate := &schema.Tables[i]
+		if candidate.Name != tableName || (qualified && candidate.Schema != schemaName) {
+			continue
+		}
+		if match != nil {
+			return nil, fmt.Errorf("table '%s' exists in several schemas, qualify it as schema.table", table)
+		}
+		match = candidate
+	}
+	if match == nil {
+		return nil, errors.New("table not found")
+	}
+	return match, nil
+}
+
+// quoteIdentifier quotes a table or column name for dbType
+func quoteIdentifier(dbType string, name string) string {
+	switch dbType {
+	case "mysql":
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	case "sqlserver":
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	default:
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+}
+
+func toInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case int64:
+		return v
+	case int32:
+		return int64(v)
+	case int:
+		return int64(v)
+	case []byte:
+		var n int64
+		fmt.Sscan(string(v), &n)
+		return n
+	}
+	return 0
+}
+
+// statisticValue makes driver values JSON friendly
+func statisticValue(value interface{}) interface{} {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return value
+}
+
+func schemaQueryTimeout() time.Duration {
+	return time.Duration(config.Config.SchemaQueryTimeoutSeconds) * time.Second
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"scheduling-report/config"
+	"scheduling-report/models"
+)
+
+var schemaOrdersTables = []string{
+	`CREATE TABLE orders (id INTEGER PRIMARY KEY, region VARCHAR NOT NULL, total DECIMAL, shipped_on DATE, paid BOOLEAN, note TEXT)`,
+	`INSERT INTO orders VALUES
+	(1, 'EU', 10.5, '2026-01-02', 1, NULL),
+	(2, 'US', 20, '2026-01-05', 0, 'rush'),
+	(3, 'EU', NULL, NULL, 1, NULL),
+	(4, 'APAC', 5.25, '2026-01-01', 0, NULL)`,
+	`CREATE VIEW eu_orders AS SELECT id, total FROM orders WHERE region = 'EU'`,
+}
+
+func useSchemaConfig() {
+	config.Config.SchemaCacheTTLSeconds = 60
+	config.Config.SchemaQueryTimeoutSeconds = 5
+}
+
+func TestGetSchemaGroupsColumnsAndCaches(t *testing.T) {
+	db := useTestDB(t)
+	useSchemaConfig()
+	datasource := seedSQLiteDatasource(t, db, schemaOrdersTables...)
+	service := NewDatasourceSchemaService()
+
+	schema, err := service.GetSchema(datasource.ID, false)
+	if err != nil {
+		t.Fatalf("GetSchema: %v", err)
+	}
+	if schema.Cached || schema.DbType != "sqlite" || len(schema.Schemas) != 1 || schema.Schemas[0] != "main" {
+		t.Fatalf("schema = cached %t, %s, schemas %v; want a fresh sqlite schema of main", schema.Cached, schema.DbType, schema.Schemas)
+	}
+	if len(schema.Tables) != 2 {
+		t.Fatalf("tables = %+v, want eu_orders and orders", schema.Tables)
+	}
+	view, orders := schema.Tables[0], schema.Tables[1]
+	if view.Name != "eu_orders" || view.Type != "VIEW" || len(view.Columns) != 2 {
+		t.Errorf("first table = %s %s with %d columns, want the eu_orders view with 2", view.Name, view.Type, len(view.Columns))
+	}
+	if orders.Name != "orders" || orders.Type != "BASE TABLE" || len(orders.Columns) != 6 {
+		t.Fatalf("second table = %s %s with %d columns, want the orders table with 6", orders.Name, orders.Type, len(orders.Columns))
+	}
+	if region := orders.Columns[1]; region != (SchemaColumn{Name: "region", DataType: "varchar", Nullable: false, Position: 2}) {
+		t.Errorf("region column = %+v", region)
+	}
+	if total := orders.Columns[2]; !total.Nullable || total.Position != 3 {
+		t.Errorf("total column = %+v, want nullable at position 3", total)
+	}
+
+	// A table added later only shows up on refresh or after invalidation
+	reportsDB, err := OpenDataSource(&datasource)
+	if err != nil {
+		t.Fatalf("OpenDataSource: %v", err)
+	}
+	defer reportsDB.Close()
+	if _, err := reportsDB.Exec("CREATE TABLE refunds (id INTEGER)"); err != nil {
+		t.Fatalf("create refunds: %v", err)
+	}
+
+	cached, err := service.GetSchema(datasource.ID, false)
+	if err != nil {
+		t.Fatalf("GetSchema: %v", err)
+	}
+	if !cached.Cached || len(cached.Tables) != 2 {
+		t.Errorf("second read = cached %t with %d tables, want the cached 2", cached.Cached, len(cached.Tables))
+	}
+	refreshed, err := service.GetSchema(datasource.ID, true)
+	if err != nil {
+		t.Fatalf("GetSchema(refresh): %v", err)
+	}
+	if refreshed.Cached || len(refreshed.Tables) != 3 {
+		t.Errorf("refresh = cached %t with %d tables, want a fresh read of 3", refreshed.Cached, len(refreshed.Tables))
+	}
+
+	if _, err := reportsDB.Exec("DROP TABLE refunds"); err != nil {
+		t.Fatalf("drop refunds: %v", err)
+	}
+	InvalidateSchemaCache(datasource.ID)
+	invalidated, err := service.GetSchema(datasource.ID, false)
+	if err != nil {
+		t.Fatalf("GetSchema: %v", err)
+	}
+	if invalidated.Cached || len(invalidated.Tables) != 2 {
+		t.Errorf("after invalidation = cached %t with %d tables, want a fresh read of 2", invalidated.Cached, len(invalidated.Tables))
+	}
+}
+
+func TestGetSchemaErrors(t *testing.T) {
+	db := useTestDB(t)
+	useSchemaConfig()
+
+	oracle := models.DataSource{Name: "warehouse", DbType: "oracle", ConnectionURL: "oracle://report@db/warehouse", IsActive: true, CreatedBy: "test", UpdatedBy: "test"}
+	if err := db.Create(&oracle).Error; err != nil {
+		t.Fatalf("seed datasource: %v", err)
+	}
+	InvalidateSchemaCache(oracle.ID)
+
+	if _, err := NewDatasourceSchemaService().GetSchema(oracle.ID, false); err == nil || !strings.Contains(err.Error(), "not supported for datasource type 'oracle'") {
+		t.Errorf("GetSchema(oracle) error = %v, want unsupported type", err)
+	}
+	if _, err := NewDatasourceSchemaService().GetSchema(oracle.ID+1, false); err == nil || err.Error() != "datasource not found" {
+		t.Errorf("GetSchema(missing) error = %v, want datasource not found", err)
+	}
+}
+
+func TestGetTableStatistics(t *testing.T) {
+	db := useTestDB(t)
+	useSchemaConfig()
+	datasource := seedSQLiteDatasource(t, db, schemaOrdersTables...)
+	service := NewDatasourceSchemaService()
+
+	stats, err := service.GetTableStatistics(datasource.ID, "main.orders", 0)
+	if err != nil {
+		t.Fatalf("GetTableStatistics: %v", err)
+	}
+	if stats.SampleSize != DefaultStatisticsSampleSize || stats.SampledRows != 4 || len(stats.Columns) != 6 {
+		t.Fatalf("stats = sample %d, %d rows, %d columns; want %d, 4, 6", stats.SampleSize, stats.SampledRows, len(stats.Columns), DefaultStatisticsSampleSize)
+	}
+
+	tests := []struct {
+		name     string
+		nulls    int64
+		distinct string
+		min, max string
+	}{
+		{name: "id", nulls: 0, distinct: "4", min: "1", max: "4"},
+		{name: "region", nulls: 0, distinct: "3", min: "APAC", max: "US"},
+		{name: "total", nulls: 1, distinct: "3", min: "5.25", max: "20"},
+		{name: "shipped_on", nulls: 1, distinct: "3", min: "2026-01-01", max: "2026-01-05"},
+		// Booleans only get a distinct count, other types only nulls
+		{name: "paid", nulls: 0, distinct: "2", min: "<nil>", max: "<nil>"},
+		{name: "note", nulls: 3, distinct: "<nil>", min: "<nil>", max: "<nil>"},
+	}
+	for i, tt := range tests {
+		column := stats.Columns[i]
+		distinct := "<nil>"
+		if column.DistinctCount != nil {
+			distinct = fmt.Sprint(*column.DistinctCount)
+		}
+		if column.Name != tt.name || column.NullCount != tt.nulls || distinct != tt.distinct ||
+			fmt.Sprint(column.Min) != tt.min || fmt.Sprint(column.Max) != tt.max {
+			t.Errorf("column %d = %s nulls %d distinct %s min %v max %v; want %s nulls %d distinct %s min %s max %s",
+				i, column.Name, column.NullCount, distinct, column.Min, column.Max, tt.name, tt.nulls, tt.distinct, tt.min, tt.max)
+		}
+	}
+	if fraction := stats.Columns[5].NullFraction; fraction != 0.75 {
+		t.Errorf("note null fraction = %v, want 0.75", fraction)
+	}
+
+	sampled, err := service.GetTableStatistics(datasource.ID, "orders", 2)
+	if err != nil {
+		t.Fatalf("GetTableStatistics(sample 2): %v", err)
+	}
+	if sampled.SampleSize != 2 || sampled.SampledRows != 2 {
+		t.Errorf("sample = %d of %d rows, want 2 of 2", sampled.SampledRows, sampled.SampleSize)
+	}
+	capped, err := service.GetTableStatistics(datasource.ID, "orders", MaxStatisticsSampleSize+1)
+	if err != nil {
+		t.Fatalf("GetTableStatistics(over max): %v", err)
+	}
+	if capped.SampleSize != MaxStatisticsSampleSize {
+		t.Errorf("sample size = %d, want it capped at %d", capped.SampleSize, MaxStatisticsSampleSize)
+	}
+
+	if _, err := service.GetTableStatistics(datasource.ID, "orders; DROP TABLE orders", 0); err == nil || err.Error() != "table not found" {
+		t.Errorf("GetTableStatistics(injected name) error = %v, want table not found", err)
+	}
+}
+
+func TestFindSchemaTable(t *testing.T) {
+	schema := &DatasourceSchema{Tables: []SchemaTable{
+		{Schema: "sales", Name: "orders"},
+		{Schema: "archive", Name: "orders"},
+		{Schema: "sales", Name: "refunds"},
+	}}
+
+	tests := []struct {
+		table      string
+		wantSchema string
+		wantErr    string
+	}{
+		{table: "refunds", wantSchema: "sales"},
+		{table: "archive.orders", wantSchema: "archive"},
+		{table: "orders", wantErr: "exists in several schemas"},
+		{table: "archive.refunds", wantErr: "table not found"},
+		{table: "customers", wantErr: "table not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.table, func(t *testing.T) {
+			table, err := findSchemaTable(schema, tt.table)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("findSchemaTable error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("findSchemaTable: %v", err)
+			}
+			if table.Schema != tt.wantSchema {
+				t.Errorf("schema = %s, want %s", table.Schema, tt.wantSchema)
+			}
+		})
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		dbType string
+		name   string
+		want   string
+	}{
+		{dbType: "mysql", name: "order`s", want: "`order``s`"},
+		{dbType: "postgresql", name: `order"s`, want: `"order""s"`},
+		{dbType: "sqlserver", name: "order]s", want: "[order]]s]"},
+	}
+
+	for _, tt := range tests {
+		if got := quoteIdentifier(tt.dbType, tt.name); got != tt.want {
+			t.Errorf("quoteIdentifier(%s, %q) = %s, want %s", tt.dbType, tt.name, got, tt.want)
+		}
+	}
+}
@@ -3,8 +3,11 @@ package services
 import (
 	"errors"
 	"fmt"
+	"scheduling-report/config"
 	"scheduling-report/models"
 	"scheduling-report/repositories"
+	"scheduling-report/utils"
+	"time"
 )
 
 type DatasourceService struct {
@@ -17,9 +20,13 @@ func NewDatasourceService() *DatasourceService {
 	}
 }
 
-// GetAll retrieves all datasources with optional filter
-func (s *DatasourceService) GetAll(isActive *bool) ([]models.DataSource, error) {
-	return s.repo.GetAll(isActive)
+// List retrieves one page of datasources, filtered and sorted by the list query parameters
+func (s *DatasourceService) List(params map[string]string) ([]models.DataSource, *repository.PageMeta, error) {
+	q, err := repository.ParseListQuery(params, repository.DatasourceListSpec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.repo.List(q)
 }
 
 // GetByID retrieves a single datasource by ID
@@ -37,6 +44,7 @@ type CreateDatasourceInput struct {
 	ConnectionURL    string                    `json:"connection_url" validate:"required"`
 	DbType           string                    `json:"db_type" validate:"required,oneof=mysql postgresql oracle sqlserver mongodb bigquery snowflake"`
 	ConnectionConfig models.ConnectionConfig   `json:"connection_config"`
+	TestConnection   bool                      `json:"test_connection"` // Probe the connection and refuse to save if it fails
 	CreatedBy        string                    `json:"created_by" validate:"required"`
 }
 
@@ -53,7 +61,7 @@ func (s *DatasourceService) Create(input CreateDatasourceInput) (*models.DataSou
 
 	datasource := &models.DataSource{
 		Name:             input.Name,
-		ConnectionURL:    input.ConnectionURL,
+		ConnectionURL:    models.EncryptedString(input.ConnectionURL),
 		DbType:           input.DbType,
 		ConnectionConfig: input.ConnectionConfig,
 		IsActive:         true,
@@ -61,6 +69,16 @@ func (s *DatasourceService) Create(input CreateDatasourceInput) (*models.DataSou
 		UpdatedBy:        input.CreatedBy,
 	}
 
+	if err := validateDatasourceSecretReferences(datasource); err != nil {
+		return nil, err
+	}
+
+	if input.TestConnection {
+		if result := s.testConnection(datasource); !result.Success {
+			return nil, &ConnectionTestError{Result: result}
+		}
+	}
+
 	if err := s.repo.Create(datasource); err != nil {
 		return nil, err
 	}
@@ -74,6 +92,7 @@ type UpdateDatasourceInput struct {
 	ConnectionURL    string                  `json:"connection_url" validate:"required"`
 	DbType           string                  `json:"db_type" validate:"required,oneof=mysql postgresql oracle sqlserver mongodb bigquery snowflake"`
 	ConnectionConfig models.ConnectionConfig `json:"connection_config"`
+	TestConnection   bool                    `json:"test_connection"` // Probe the connection and refuse to save if it fails
 	UpdatedBy        string                  `json:"updated_by" validate:"required"`
 }
 
@@ -96,16 +115,29 @@ func (s *DatasourceService) Update(id int, input UpdateDatasourceInput) (*models
 		}
 	}
 
-	// Update fields
+	// Update fields; secrets echoed back masked from a read keep their stored value
 	datasource.Name = input.Name
-	datasource.ConnectionURL = input.ConnectionURL
+	if input.ConnectionURL != utils.MaskConnectionURL(string(datasource.ConnectionURL)) {
+		datasource.ConnectionURL = models.EncryptedString(input.ConnectionURL)
+	}
 	datasource.DbType = input.DbType
-	datasource.ConnectionConfig = input.ConnectionConfig
+	datasource.ConnectionConfig = utils.RestoreMaskedSecrets(input.ConnectionConfig, datasource.ConnectionConfig)
 	datasource.UpdatedBy = input.UpdatedBy
 
+	if err := validateDatasourceSecretReferences(datasource); err != nil {
+		return nil, err
+	}
+
+	if input.TestConnection {
+		if result := s.testConnection(datasource); !result.Success {
+			return nil, &ConnectionTestError{Result: result}
+		}
+	}
+
 	if err := s.repo.Update(datasource); err != nil {
 		return nil, err
 	}
+	InvalidateSchemaCache(id)
 
 	return datasource, nil
 }
@@ -118,5 +150,42 @@ func (s *DatasourceService) Delete(id int) error {
 		return errors.New("datasource not found")
 	}
 
-	return s.repo.Delete(id)
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+	InvalidateSchemaCache(id)
+	return nil
+}
+
+// validateDatasourceSecretReferences checks that env://, file:// and vault:// references resolve
+func validateDatasourceSecretReferences(datasource *models.DataSource) error {
+	if _, err := utils.ResolveSecretReference(string(datasource.ConnectionURL)); err != nil {
+		return err
+	}
+	return utils.ValidateSecretReferences(datasource.ConnectionConfig)
+}
+
+// ConnectionTestError is returned when a test-before-save connection test fails
+type ConnectionTestError struct {
+	Result ConnectionTestResult
+}
+
+func (e *ConnectionTestError) Error() string {
+	return fmt.Sprintf("connection test failed (%s): %s", e.Result.ErrorCategory, e.Result.ErrorMessage)
+}
+
+// TestConnection probes a saved datasource
+func (s *DatasourceService) TestConnection(id int) (*ConnectionTestResult, error) {
+	datasource, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, errors.New("datasource not found")
+	}
+
+	result := s.testConnection(datasource)
+	return &result, nil
+}
+
+func (s *DatasourceService) testConnection(datasource *models.DataSource) ConnectionTestResult {
+	timeout := time.Duration(config.Config.DatasourceTestTimeoutSeconds) * time.Second
+	return TestDataSourceConnection(datasource, timeout)
 }
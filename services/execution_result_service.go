@@ -0,0 +1,249 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"scheduling-report/config"
+	"scheduling-report/models"
+	"scheduling-report/repositories"
+
+	"github.com/IBM/sarama"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// Execution statuses
+const (
+	ExecutionStatusQueued    = "queued"
+	ExecutionStatusRunning   = "running"
+	ExecutionStatusCompleted = "completed"
+	ExecutionStatusFailed    = "failed"
+	ExecutionStatusCancelled = "cancelled"
+)
+
+// ExecutionResultTopic returns the topic workers publish execution outcomes to
+func ExecutionResultTopic() string {
+	return viper.GetString("KAFKA_TOPIC_EXECUTION_RESULTS")
+}
+
+// ExecutionResultConsumerGroup returns the consumer group used to read execution results
+func ExecutionResultConsumerGroup() string {
+	if group := viper.GetString("KAFKA_CONSUMER_GROUP_RESULTS"); group != "" {
+		return group
+	}
+	return "scheduling-report-api-results"
+}
+
+// ExecutionResult represents the message payload a worker publishes for an execution
+type ExecutionResult struct {
+	ExecutionID          string           `json:"execution_id"`
+	Status               string           `json:"status"` // running, completed, failed, cancelled
+	CompletedAt          string           `json:"completed_at,omitempty"`
+	QueryExecutionTimeMs *int             `json:"query_execution_time_ms,omitempty"`
+	RowsReturned         *int             `json:"rows_returned,omitempty"`
+	FileGeneratedPath    *string          `json:"file_generated_path,omitempty"`
+	FileSizeBytes        *int64           `json:"file_size_bytes,omitempty"`
+	ErrorMessage         *string          `json:"error_message,omitempty"`
+	Deliveries           []DeliveryResult `json:"deliveries,omitempty"`
+}
+
+// DeliveryResult is the outcome of one delivery attempt for an execution
+type DeliveryResult struct {
+	DeliveryID       int                    `json:"delivery_id"`
+	Status           string                 `json:"status"` // pending, success, failed, retry
+	SentAt           string                 `json:"sent_at,omitempty"`
+	CompletedAt      string                 `json:"completed_at,omitempty"`
+	RecipientCount   int                    `json:"recipient_count"`
+	SuccessCount     int                    `json:"success_count"`
+	FailureCount     int                    `json:"failure_count"`
+	RetryCount       int                    `json:"retry_count"`
+	ErrorMessage     *string                `json:"error_message,omitempty"`
+	DeliveryDetails  map[string]interface{} `json:"delivery_details,omitempty"`
+	FileSizeBytes    *int64                 `json:"file_size_bytes,omitempty"`
+	ProcessingTimeMs *int                   `json:"processing_time_ms,omitempty"`
+}
+
+// allowedFromStatuses lists the statuses an execution may move out of for each reported status
+var allowedFromStatuses = map[string][]string{
+	ExecutionStatusRunning:   {ExecutionStatusQueued},
+	ExecutionStatusCompleted: {ExecutionStatusQueued, ExecutionStatusRunning},
+	ExecutionStatusFailed:    {ExecutionStatusQueued, ExecutionStatusRunning},
+	ExecutionStatusCancelled: {ExecutionStatusQueued, ExecutionStatusRunning},
+}
+
+var validDeliveryStatuses = map[string]bool{
+	"pending": true,
+	"success": true,
+	"failed":  true,
+	"retry":   true,
+}
+
+// ExecutionResultService applies worker results to report_executions, report_delivery_logs and report_schedules
+type ExecutionResultService struct{}
+
+func NewExecutionResultService() *ExecutionResultService {
+	return &ExecutionResultService{}
+}
+
+// HandleMessage is the Kafka handler for the execution-results topic.
+// Malformed payloads are logged and dropped; database errors are returned for retry.
+func (s *ExecutionResultService) HandleMessage(message *sarama.ConsumerMessage) error {
+	var result ExecutionResult
+	if err := json.Unmarshal(message.Value, &result); err != nil {
+		log.Error().Err(err).Int64("offset", message.Offset).Msg("Invalid execution result payload, skipping")
+		return nil
+	}
+
+	if err := s.ApplyResult(result); err != nil {
+		if _, invalid := err.(invalidResultError); invalid {
+			log.Error().Err(err).Str("execution_id", result.ExecutionID).Msg("Invalid execution result, skipping")
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// invalidResultError marks results that can never be applied, so retrying is pointless
+type invalidResultError struct {
+	reason string
+}
+
+func (e invalidResultError) Error() string {
+	return e.reason
+}
+
+// ApplyResult records an execution result idempotently in a single transaction:
+// status changes only move forward, each delivery attempt is logged once and the
+// schedule's last_run_at is advanced only when the execution completed successfully.
+func (s *ExecutionResultService) ApplyResult(result ExecutionResult) error {
+	fromStatuses, ok := allowedFromStatuses[result.Status]
+	if !ok {
+		return invalidResultError{reason: fmt.Sprintf("unknown execution status '%s'", result.Status)}
+	}
+	if result.ExecutionID == "" {
+		return invalidResultError{reason: "execution_id is required"}
+	}
+	for _, delivery := range result.Deliveries {
+		if !validDeliveryStatuses[delivery.Status] {
+			return invalidResultError{reason: fmt.Sprintf("unknown delivery status '%s' for delivery %d", delivery.Status, delivery.DeliveryID)}
+		}
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		executionRepo := &repository.ReportExecutionRepository{DB: tx}
+		deliveryLogRepo := &repository.ReportDeliveryLogRepository{DB: tx}
+		scheduleRepo := &repository.ReportScheduleRepository{DB: tx}
+
+		execution, err := executionRepo.GetByID(result.ExecutionID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return invalidResultError{reason: "execution not found"}
+			}
+			return err
+		}
+
+		// Step 1: Move the execution forward (no-op for duplicates and stale results)
+		updates := map[string]interface{}{
+			"status": result.Status,
+		}
+		if result.Status != ExecutionStatusRunning {
+			completedAt := parseResultTime(result.CompletedAt, time.Now())
+			updates["completed_at"] = completedAt
+		}
+		if result.QueryExecutionTimeMs != nil {
+			updates["query_execution_time_ms"] = *result.QueryExecutionTimeMs
+		}
+		if result.RowsReturned != nil {
+			updates["rows_returned"] = *result.RowsReturned
+		}
+		if result.FileGeneratedPath != nil {
+			updates["file_generated_path"] = *result.FileGeneratedPath
+		}
+		if result.FileSizeBytes != nil {
+			updates["file_size_bytes"] = *result.FileSizeBytes
+		}
+		if result.ErrorMessage != nil {
+			updates["error_message"] = *result.ErrorMessage
+		}
+
+		changed, err := executionRepo.TransitionStatus(execution.ID, fromStatuses, updates)
+		if err != nil {
+			return fmt.Errorf("failed to update execution: %w", err)
+		}
+		if !changed {
+			log.Info().
+				Str("execution_id", execution.ID).
+				Str("current_status", execution.Status).
+				Str("reported_status", result.Status).
+				Msg("Execution result already applied or stale, skipping status update")
+		}
+
+		// Step 2: Append delivery logs, once per delivery attempt
+		for _, delivery := range result.Deliveries {
+			exists, err := deliveryLogRepo.Exists(execution.ID, delivery.DeliveryID, delivery.RetryCount)
+			if err != nil {
+				return fmt.Errorf("failed to check delivery log: %w", err)
+			}
+			if exists {
+				continue
+			}
+
+			configID := execution.ConfigID
+			deliveryID := delivery.DeliveryID
+			deliveryLog := &models.ReportDeliveryLog{
+				ConfigID:         &configID,
+				DeliveryID:       &deliveryID,
+				ScheduleID:       execution.ScheduleID,
+				ExecutionID:      execution.ID,
+				Status:           delivery.Status,
+				SentAt:           parseResultTime(delivery.SentAt, time.Now()),
+				RecipientCount:   delivery.RecipientCount,
+				SuccessCount:     delivery.SuccessCount,
+				FailureCount:     delivery.FailureCount,
+				RetryCount:       delivery.RetryCount,
+				ErrorMessage:     delivery.ErrorMessage,
+				DeliveryDetails:  delivery.DeliveryDetails,
+				FileSizeBytes:    delivery.FileSizeBytes,
+				ProcessingTimeMs: delivery.ProcessingTimeMs,
+			}
+			if delivery.CompletedAt != "" {
+				completedAt := parseResultTime(delivery.CompletedAt, time.Now())
+				deliveryLog.CompletedAt = &completedAt
+			}
+
+			if err := deliveryLogRepo.Create(deliveryLog); err != nil {
+				return fmt.Errorf("failed to create delivery log: %w", err)
+			}
+		}
+
+		// Step 3: Advance the schedule only for a successful run
+		if changed && result.Status == ExecutionStatusCompleted && execution.ScheduleID != nil {
+			lastRunAt := execution.StartedAt
+			if scheduledAt, ok := execution.ExecutionContext["scheduled_at"].(string); ok {
+				lastRunAt = parseResultTime(scheduledAt, lastRunAt)
+			}
+			if err := scheduleRepo.AdvanceLastRunAt(*execution.ScheduleID, lastRunAt); err != nil {
+				return fmt.Errorf("failed to advance schedule: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// parseResultTime parses an RFC3339 timestamp, falling back when it is empty or invalid
+func parseResultTime(value string, fallback time.Time) time.Time {
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"scheduling-report/models"
+
+	"github.com/IBM/sarama"
+	"gorm.io/gorm"
+)
+
+// fakeConsumerGroup is a single-partition broker stand-in for NewKafkaConsumerFromGroup
+type fakeConsumerGroup struct {
+	sarama.ConsumerGroup
+
+	messages chan *sarama.ConsumerMessage
+	errors   chan error
+
+	mu     sync.Mutex
+	marked []int64
+}
+
+func newFakeConsumerGroup() *fakeConsumerGroup {
+	return &fakeConsumerGroup{
+		messages: make(chan *sarama.ConsumerMessage, 100),
+		errors:   make(chan error),
+	}
+}
+
+func (g *fakeConsumerGroup) Consume(ctx context.Context, topics []string, handler sarama.ConsumerGroupHandler) error {
+	session := &fakeSession{ctx: ctx, group: g}
+	if err := handler.Setup(session); err != nil {
+		return err
+	}
+	err := handler.ConsumeClaim(session, &fakeClaim{topic: topics[0], messages: g.messages})
+	if cleanupErr := handler.Cleanup(session); err == nil {
+		err = cleanupErr
+	}
+	return err
+}
+
+func (g *fakeConsumerGroup) Errors() <-chan error {
+	return g.errors
+}
+
+func (g *fakeConsumerGroup) Close() error {
+	close(g.errors)
+	return nil
+}
+
+// publish appends a JSON message to the partition
+func (g *fakeConsumerGroup) publish(t *testing.T, topic string, value interface{}) {
+	t.Helper()
+
+	payload, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("marshal message: %v", err)
+	}
+	g.mu.Lock()
+	offset := int64(len(g.messages)) + int64(len(g.marked))
+	g.mu.Unlock()
+	g.messages <- &sarama.ConsumerMessage{Topic: topic, Value: payload, Offset: offset}
+}
+
+// waitForMarked blocks until n messages have been committed
+func (g *fakeConsumerGroup) waitForMarked(t *testing.T, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		g.mu.Lock()
+		done := len(g.marked) >= n
+		g.mu.Unlock()
+		if done {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d messages to be committed", n)
+}
+
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+
+	ctx   context.Context
+	group *fakeConsumerGroup
+}
+
+func (s *fakeSession) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeSession) MarkMessage(message *sarama.ConsumerMessage, metadata string) {
+	s.group.mu.Lock()
+	defer s.group.mu.Unlock()
+	s.group.marked = append(s.group.marked, message.Offset)
+}
+
+type fakeClaim struct {
+	sarama.ConsumerGroupClaim
+
+	topic    string
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *fakeClaim) Topic() string {
+	return c.topic
+}
+
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage {
+	return c.messages
+}
+
+// seedScheduledExecution inserts a schedule and one execution queued for it
+func seedScheduledExecution(t *testing.T, db *gorm.DB, id string, scheduledAt time.Time) (models.ReportSchedule, models.ReportExecution) {
+	t.Helper()
+
+	reportConfig := seedConfig(t, db)
+	schedule := models.ReportSchedule{
+		ConfigID:       reportConfig.ID,
+		CronExpression: "0 * * * *",
+		Timezone:       "UTC",
+		IsActive:       true,
+		NextRunAt:      &models.CustomTime{Time: scheduledAt.Add(time.Hour)},
+		MisfirePolicy:  models.MisfirePolicyRunOnce,
+		MisfireMaxRuns: 10,
+		CreatedBy:      "test",
+		UpdatedBy:      "test",
+		Version:        1,
+	}
+	if err := db.Create(&schedule).Error; err != nil {
+		t.Fatalf("seed schedule: %v", err)
+	}
+
+	execution := models.ReportExecution{
+		ID:               id,
+		ConfigID:         reportConfig.ID,
+		ScheduleID:       &schedule.ID,
+		Status:           ExecutionStatusQueued,
+		StartedAt:        time.Now().UTC(),
+		ExecutedBy:       "scheduler",
+		ExecutionContext: models.ExecutionContext{"scheduled_at": scheduledAt.Format(time.RFC3339)},
+	}
+	if err := db.Create(&execution).Error; err != nil {
+		t.Fatalf("seed execution: %v", err)
+	}
+
+	return schedule, execution
+}
+
+func startResultConsumer(t *testing.T) *fakeConsumerGroup {
+	t.Helper()
+
+	group := newFakeConsumerGroup()
+	consumer := NewKafkaConsumerFromGroup("execution-results", group, []string{"report-execution-results"}, NewExecutionResultService().HandleMessage)
+	consumer.Start()
+	t.Cleanup(func() { consumer.Stop() })
+
+	return group
+}
+
+func loadExecutionState(t *testing.T, db *gorm.DB, executionID string, scheduleID int) (models.ReportExecution, models.ReportSchedule, int64) {
+	t.Helper()
+
+	var execution models.ReportExecution
+	if err := db.First(&execution, "id = ?", executionID).Error; err != nil {
+		t.Fatalf("load execution: %v", err)
+	}
+	var schedule models.ReportSchedule
+	if err := db.First(&schedule, scheduleID).Error; err != nil {
+		t.Fatalf("load schedule: %v", err)
+	}
+	var logs int64
+	if err := db.Model(&models.ReportDeliveryLog{}).Where("execution_id = ?", executionID).Count(&logs).Error; err != nil {
+		t.Fatalf("count delivery logs: %v", err)
+	}
+	return execution, schedule, logs
+}
+
+func TestExecutionResultsThroughConsumer(t *testing.T) {
+	scheduledAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	rows := 42
+	delivery := DeliveryResult{DeliveryID: 7, Status: "success", RecipientCount: 2, SuccessCount: 2}
+	running := ExecutionResult{ExecutionID: "exec-1", Status: ExecutionStatusRunning}
+	completed := ExecutionResult{
+		ExecutionID:  "exec-1",
+		Status:       ExecutionStatusCompleted,
+		CompletedAt:  "2026-03-01T09:05:00Z",
+		RowsReturned: &rows,
+		Deliveries:   []DeliveryResult{delivery},
+	}
+	failed := ExecutionResult{ExecutionID: "exec-1", Status: ExecutionStatusFailed}
+
+	tests := []struct {
+		name          string
+		results       []ExecutionResult
+		wantStatus    string
+		wantLogs      int64
+		wantLastRunAt bool
+	}{
+		{
+			name:       "running does not advance last_run_at",
+			results:    []ExecutionResult{running},
+			wantStatus: ExecutionStatusRunning,
+		},
+		{
+			name:          "completion advances last_run_at to the scheduled time",
+			results:       []ExecutionResult{running, completed},
+			wantStatus:    ExecutionStatusCompleted,
+			wantLogs:      1,
+			wantLastRunAt: true,
+		},
+		{
+			name:          "duplicate completion is applied once",
+			results:       []ExecutionResult{running, completed, completed, completed},
+			wantStatus:    ExecutionStatusCompleted,
+			wantLogs:      1,
+			wantLastRunAt: true,
+		},
+		{
+			name:          "running after completion is stale and ignored",
+			results:       []ExecutionResult{completed, running},
+			wantStatus:    ExecutionStatusCompleted,
+			wantLogs:      1,
+			wantLastRunAt: true,
+		},
+		{
+			name:          "failure after completion does not regress the execution",
+			results:       []ExecutionResult{completed, failed},
+			wantStatus:    ExecutionStatusCompleted,
+			wantLogs:      1,
+			wantLastRunAt: true,
+		},
+		{
+			name:       "failed run leaves last_run_at alone",
+			results:    []ExecutionResult{running, failed},
+			wantStatus: ExecutionStatusFailed,
+		},
+		{
+			name: "each delivery retry is logged once",
+			results: []ExecutionResult{
+				{ExecutionID: "exec-1", Status: ExecutionStatusRunning, Deliveries: []DeliveryResult{{DeliveryID: 7, Status: "retry", RetryCount: 0}}},
+				{ExecutionID: "exec-1", Status: ExecutionStatusRunning, Deliveries: []DeliveryResult{{DeliveryID: 7, Status: "retry", RetryCount: 0}}},
+				{ExecutionID: "exec-1", Status: ExecutionStatusCompleted, Deliveries: []DeliveryResult{{DeliveryID: 7, Status: "retry", RetryCount: 0}, {DeliveryID: 7, Status: "success", RetryCount: 1}}},
+			},
+			wantStatus:    ExecutionStatusCompleted,
+			wantLogs:      2,
+			wantLastRunAt: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := useTestDB(t)
+			schedule, execution := seedScheduledExecution(t, db, "exec-1", scheduledAt)
+			group := startResultConsumer(t)
+
+			for _, result := range tt.results {
+				group.publish(t, "report-execution-results", result)
+			}
+			group.waitForMarked(t, len(tt.results))
+
+			stored, storedSchedule, logs := loadExecutionState(t, db, execution.ID, schedule.ID)
+			if stored.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", stored.Status, tt.wantStatus)
+			}
+			if logs != tt.wantLogs {
+				t.Errorf("delivery logs = %d, want %d", logs, tt.wantLogs)
+			}
+
+			if tt.wantLastRunAt {
+				if storedSchedule.LastRunAt == nil || !storedSchedule.LastRunAt.Equal(scheduledAt) {
+					t.Errorf("last_run_at = %v, want %v", storedSchedule.LastRunAt, scheduledAt)
+				}
+			} else if storedSchedule.LastRunAt != nil && !storedSchedule.LastRunAt.IsZero() {
+				t.Errorf("last_run_at = %v, want unset", storedSchedule.LastRunAt)
+			}
+		})
+	}
+}
+
+func TestExecutionResultConsumerSkipsInvalidResults(t *testing.T) {
+	db := useTestDB(t)
+	schedule, execution := seedScheduledExecution(t, db, "exec-1", time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC))
+	group := startResultConsumer(t)
+
+	// Unknown executions, unknown statuses and malformed payloads are committed without retrying
+	group.publish(t, "report-execution-results", ExecutionResult{ExecutionID: "missing", Status: ExecutionStatusCompleted})
+	group.publish(t, "report-execution-results", ExecutionResult{ExecutionID: "exec-1", Status: "exploded"})
+	group.publish(t, "report-execution-results", "not an object")
+	group.publish(t, "report-execution-results", ExecutionResult{ExecutionID: "exec-1", Status: ExecutionStatusCompleted})
+	group.waitForMarked(t, 4)
+
+	stored, _, _ := loadExecutionState(t, db, execution.ID, schedule.ID)
+	if stored.Status != ExecutionStatusCompleted {
+		t.Errorf("status = %s, want %s", stored.Status, ExecutionStatusCompleted)
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/rs/zerolog/log"
+)
+
+// KafkaMessageHandler processes one consumed message. Returned errors are retried with backoff
+// before the message is skipped, so handlers must be idempotent.
+type KafkaMessageHandler func(message *sarama.ConsumerMessage) error
+
+// KafkaConsumer runs a consumer group loop for a set of topics
+type KafkaConsumer struct {
+	name       string
+	group      sarama.ConsumerGroup
+	topics     []string
+	handler    KafkaMessageHandler
+	maxRetries int
+
+	cancel context.CancelFunc
+	doneCh chan struct{}
+}
+
+// NewKafkaConsumer connects a consumer group to the configured brokers
+func NewKafkaConsumer(name string, groupID string, topics []string, handler KafkaMessageHandler) (*KafkaConsumer, error) {
+	config, err := newKafkaConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	config.Consumer.Return.Errors = true
+
+	group, err := sarama.NewConsumerGroup(kafkaBrokers(), groupID, config)
+	if err != nil {
+		log.Error().Err(err).Str("consumer", name).Msg("Failed to create Kafka consumer group")
+		return nil, err
+	}
+
+	return NewKafkaConsumerFromGroup(name, group, topics, handler), nil
+}
+
+// NewKafkaConsumerFromGroup wraps an existing consumer group, e.g. a local broker stand-in
+func NewKafkaConsumerFromGroup(name string, group sarama.ConsumerGroup, topics []string, handler KafkaMessageHandler) *KafkaConsumer {
+	return &KafkaConsumer{
+		name:       name,
+		group:      group,
+		topics:     topics,
+		handler:    handler,
+		maxRetries: 5,
+		doneCh:     make(chan struct{}),
+	}
+}
+
+// Start consumes in a background goroutine, rejoining the group after every rebalance
+func (c *KafkaConsumer) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	go func() {
+		for err := range c.group.Errors() {
+			log.Error().Err(err).Str("consumer", c.name).Msg("Kafka consumer error")
+		}
+	}()
+
+	go func() {
+		defer close(c.doneCh)
+
+		for {
+			if err := c.group.Consume(ctx, c.topics, c); err != nil {
+				if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+					return
+				}
+				log.Error().Err(err).Str("consumer", c.name).Msg("Kafka consume failed, rejoining")
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(5 * time.Second):
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+
+	log.Info().Str("consumer", c.name).Strs("topics", c.topics).Msg("Kafka consumer started")
+}
+
+// Stop leaves the group after the in-flight message and closes the connection
+func (c *KafkaConsumer) Stop() error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.doneCh
+	}
+	err := c.group.Close()
+	log.Info().Str("consumer", c.name).Msg("Kafka consumer stopped")
+	return err
+}
+
+// Setup implements sarama.ConsumerGroupHandler
+func (c *KafkaConsumer) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// Cleanup implements sarama.ConsumerGroupHandler
+func (c *KafkaConsumer) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim implements sarama.ConsumerGroupHandler
+func (c *KafkaConsumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for {
+		select {
+		case <-session.Context().Done():
+			return nil
+		case message, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			// Leave the offset uncommitted when shutdown interrupts retries, so the message is redelivered
+			if !c.handle(session.Context(), message) {
+				return nil
+			}
+			session.MarkMessage(message, "")
+		}
+	}
+}
+
+// handle runs the handler with exponential backoff; a message that keeps failing is logged and skipped.
+// Returns false when the context was cancelled before the message was settled.
+func (c *KafkaConsumer) handle(ctx context.Context, message *sarama.ConsumerMessage) bool {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		err := c.handler(message)
+		if err == nil {
+			return true
+		}
+
+		logEvent := log.Error().
+			Err(err).
+			Str("consumer", c.name).
+			Str("topic", message.Topic).
+			Int32("partition", message.Partition).
+			Int64("offset", message.Offset).
+			Int("attempt", attempt)
+
+		if attempt > c.maxRetries {
+			logEvent.Msg("Kafka message failed permanently, skipping")
+			return true
+		}
+		logEvent.Msg("Kafka message handling failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -3,7 +3,6 @@ package services
 import (
 	"crypto/sha256"
 	"crypto/tls"
-	"encoding/json"
 	"fmt"
 	"hash"
 	"strings"
@@ -48,10 +47,13 @@ func (x *XDGSCRAMClient) Done() bool {
 	return x.ClientConversation.Done()
 }
 
-// InitKafkaProducer initializes the Kafka producer with configurable security
-func InitKafkaProducer() error {
-	brokers := strings.Split(viper.GetString("KAFKA_BOOTSTRAP_SERVERS"), ",")
-	topic := viper.GetString("KAFKA_TOPIC_EXECUTION_REQUESTS")
+// kafkaBrokers returns the configured bootstrap servers
+func kafkaBrokers() []string {
+	return strings.Split(viper.GetString("KAFKA_BOOTSTRAP_SERVERS"), ",")
+}
+
+// newKafkaConfig builds a sarama config with the configured security protocol applied
+func newKafkaConfig(client string) (*sarama.Config, error) {
 	securityProtocol := viper.GetString("KAFKA_SECURITY_PROTOCOL")
 
 	// Default to SASL_SSL if not specified
@@ -60,9 +62,6 @@ func InitKafkaProducer() error {
 	}
 
 	config := sarama.NewConfig()
-	config.Producer.Return.Successes = true
-	config.Producer.RequiredAcks = sarama.WaitForAll
-	config.Producer.Retry.Max = 5
 
 	// Configure security based on protocol
 	switch strings.ToUpper(securityProtocol) {
@@ -77,20 +76,35 @@ func InitKafkaProducer() error {
 		config.Net.TLS.Config = &tls.Config{
 			InsecureSkipVerify: true, // Skip certificate verification for Aiven cloud
 		}
-		log.Info().Msg("Kafka producer configured with SASL_SSL security")
+		log.Info().Str("client", client).Msg("Kafka client configured with SASL_SSL security")
 
 	case "PLAINTEXT":
 		// PLAINTEXT configuration for local Kafka
 		config.Net.SASL.Enable = false
 		config.Net.TLS.Enable = false
-		log.Info().Msg("Kafka producer configured with PLAINTEXT security")
+		log.Info().Str("client", client).Msg("Kafka client configured with PLAINTEXT security")
 
 	default:
 		log.Error().Str("protocol", securityProtocol).Msg("Unsupported Kafka security protocol")
-		return fmt.Errorf("unsupported Kafka security protocol: %s", securityProtocol)
+		return nil, fmt.Errorf("unsupported Kafka security protocol: %s", securityProtocol)
 	}
 
-	producer, err := sarama.NewSyncProducer(brokers, config)
+	return config, nil
+}
+
+// InitKafkaProducer initializes the Kafka producer with configurable security
+func InitKafkaProducer() error {
+	topic := ExecutionRequestTopic()
+
+	config, err := newKafkaConfig("producer")
+	if err != nil {
+		return err
+	}
+	config.Producer.Return.Successes = true
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Retry.Max = 5
+
+	producer, err := sarama.NewSyncProducer(kafkaBrokers(), config)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create Kafka producer")
 		return err
@@ -110,6 +124,11 @@ func GetKafkaProducer() *KafkaProducer {
 	return kafkaProducerInstance
 }
 
+// ExecutionRequestTopic returns the topic report execution requests are published to
+func ExecutionRequestTopic() string {
+	return viper.GetString("KAFKA_TOPIC_EXECUTION_REQUESTS")
+}
+
 // ExecutionRequest represents the message payload for report execution
 type ExecutionRequest struct {
 	ExecutionID string `json:"execution_id"`
@@ -117,36 +136,38 @@ type ExecutionRequest struct {
 	ScheduleID  *int   `json:"schedule_id"`
 	ExecutedBy  string `json:"executed_by"`
 	QueuedAt    string `json:"queued_at"`
+	// ScheduledAt is the cron occurrence this execution covers (RFC3339), empty for manual runs
+	ScheduledAt string `json:"scheduled_at,omitempty"`
+	// LastRunAt is the start of the covered window (RFC3339); pass both to utils.CalculateTimeRange
+	LastRunAt string `json:"last_run_at,omitempty"`
+	// Parameters are per-run overrides of the config's parameter values
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
 }
 
-// ProduceExecutionRequest sends a report execution request to Kafka
-func (kp *KafkaProducer) ProduceExecutionRequest(req ExecutionRequest) error {
-	messageJSON, err := json.Marshal(req)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to marshal execution request")
-		return err
-	}
-
+// Produce sends a raw message to the given topic
+func (kp *KafkaProducer) Produce(topic string, key string, payload []byte) error {
 	msg := &sarama.ProducerMessage{
-		Topic: kp.topic,
-		Key:   sarama.StringEncoder(req.ExecutionID),
-		Value: sarama.ByteEncoder(messageJSON),
+		Topic: topic,
+		Key:   sarama.StringEncoder(key),
+		Value: sarama.ByteEncoder(payload),
 	}
 
 	partition, offset, err := kp.producer.SendMessage(msg)
 	if err != nil {
 		log.Error().
 			Err(err).
-			Str("execution_id", req.ExecutionID).
+			Str("topic", topic).
+			Str("key", key).
 			Msg("Failed to produce message to Kafka")
 		return err
 	}
 
 	log.Info().
-		Str("execution_id", req.ExecutionID).
+		Str("topic", topic).
+		Str("key", key).
 		Int32("partition", partition).
 		Int64("offset", offset).
-		Msg("Successfully produced execution request to Kafka")
+		Msg("Successfully produced message to Kafka")
 
 	return nil
 }
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"scheduling-report/config"
+	"scheduling-report/models"
+	"scheduling-report/repositories"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// outboxWakeup lets writers nudge the relay right after committing a message instead of waiting for the next poll
+var outboxWakeup = make(chan struct{}, 1)
+
+// notifyOutboxRelay triggers an immediate relay pass without blocking
+func notifyOutboxRelay() {
+	select {
+	case outboxWakeup <- struct{}{}:
+	default:
+	}
+}
+
+// OutboxRelay publishes pending outbox messages to Kafka and marks them sent,
+// retrying failed publishes with exponential backoff. Messages are claimed under a short lease
+// and published outside any transaction, so no row lock is held while Kafka is slow.
+type OutboxRelay struct {
+	repo         *repository.ExecutionOutboxRepository
+	pollInterval time.Duration
+	batchSize    int
+	retryBase    time.Duration
+	retryMax     time.Duration
+	claimLease   time.Duration
+	claimant     string
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+func NewOutboxRelay() *OutboxRelay {
+	hostname, _ := os.Hostname()
+
+	return &OutboxRelay{
+		repo:         repository.NewExecutionOutboxRepository(),
+		pollInterval: time.Duration(config.Config.OutboxPollIntervalSeconds) * time.Second,
+		batchSize:    config.Config.OutboxBatchSize,
+		retryBase:    time.Duration(config.Config.OutboxRetryBaseSeconds) * time.Second,
+		retryMax:     time.Duration(config.Config.OutboxRetryMaxSeconds) * time.Second,
+		claimLease:   time.Duration(config.Config.OutboxClaimLeaseSeconds) * time.Second,
+		claimant:     fmt.Sprintf("%s/%s", hostname, uuid.New().String()),
+		stopCh:       make(chan struct{}),
+		doneCh:       make(chan struct{}),
+	}
+}
+
+// Start runs the relay loop in a background goroutine
+func (r *OutboxRelay) Start() {
+	log.Info().
+		Dur("poll_interval", r.pollInterval).
+		Int("batch_size", r.batchSize).
+		Dur("claim_lease", r.claimLease).
+		Str("claimant", r.claimant).
+		Msg("Outbox relay started")
+
+	go func() {
+		defer close(r.doneCh)
+
+		ticker := time.NewTicker(r.pollInterval)
+		defer ticker.Stop()
+
+		r.relayPending()
+		for {
+			select {
+			case <-r.stopCh:
+				return
+			case <-ticker.C:
+				r.relayPending()
+			case <-outboxWakeup:
+				r.relayPending()
+			}
+		}
+	}()
+}
+
+// Stop signals the relay loop to exit and waits for the current pass to finish
+func (r *OutboxRelay) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopCh)
+	})
+	<-r.doneCh
+	log.Info().Msg("Outbox relay stopped")
+}
+
+// relayPending publishes due messages batch by batch until none are left.
+// Each batch is claimed and committed first; every message is then published and
+// marked sent or failed in its own short update.
+func (r *OutboxRelay) relayPending() {
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		default:
+		}
+
+		now := time.Now()
+		messages, err := r.repo.ClaimPending(now, r.batchSize, r.claimant, now.Add(r.claimLease))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to claim outbox messages")
+			return
+		}
+
+		for _, message := range messages {
+			if publishErr := r.publish(message); publishErr != nil {
+				if err := r.repo.MarkFailed(message.ID, r.claimant, r.nextAttemptAt(message.Attempts+1), publishErr.Error()); err != nil {
+					log.Error().Err(err).Int64("outbox_id", message.ID).Msg("Failed to record outbox publish failure")
+				}
+				continue
+			}
+
+			if err := r.repo.MarkSent(message.ID); err != nil {
+				// The claim lease runs out and the message is published again; consumers dedupe by execution_id
+				log.Error().Err(err).Int64("outbox_id", message.ID).Msg("Failed to mark outbox message sent")
+			}
+		}
+
+		if len(messages) < r.batchSize {
+			return
+		}
+	}
+}
+
+// publish sends one outbox message through the shared Kafka producer
+func (r *OutboxRelay) publish(message models.ExecutionOutbox) error {
+	kafkaProducer := GetKafkaProducer()
+	if kafkaProducer == nil {
+		return errors.New("kafka producer not initialized")
+	}
+
+	if err := kafkaProducer.Produce(message.Topic, message.MessageKey, []byte(message.Payload)); err != nil {
+		log.Warn().
+			Err(err).
+			Int64("outbox_id", message.ID).
+			Str("execution_id", message.ExecutionID).
+			Int("attempt", message.Attempts+1).
+			Msg("Outbox publish failed, will retry")
+		return err
+	}
+
+	return nil
+}
+
+// nextAttemptAt returns an exponential backoff with jitter, capped at retryMax
+func (r *OutboxRelay) nextAttemptAt(attempts int) time.Time {
+	delay := r.retryMax
+	if attempts < 32 {
+		if backoff := r.retryBase << uint(attempts-1); backoff > 0 && backoff < r.retryMax {
+			delay = backoff
+		}
+	}
+
+	// Up to 20% jitter so a broker outage does not end in a synchronized retry storm
+	jitter := time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	return time.Now().Add(delay + jitter)
+}
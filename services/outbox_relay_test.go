@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"scheduling-report/config"
+	"scheduling-report/models"
+
+	"github.com/IBM/sarama/mocks"
+	"gorm.io/gorm"
+)
+
+// useMockProducer swaps the shared Kafka producer for a sarama mock for the duration of the test
+func useMockProducer(t *testing.T) *mocks.SyncProducer {
+	t.Helper()
+
+	mock := mocks.NewSyncProducer(t, nil)
+	previous := kafkaProducerInstance
+	kafkaProducerInstance = &KafkaProducer{producer: mock}
+	t.Cleanup(func() {
+		kafkaProducerInstance = previous
+		mock.Close()
+	})
+
+	return mock
+}
+
+func newTestOutboxRelay() *OutboxRelay {
+	config.Config.OutboxBatchSize = 10
+	config.Config.OutboxRetryBaseSeconds = 5
+	config.Config.OutboxRetryMaxSeconds = 300
+	config.Config.OutboxClaimLeaseSeconds = 60
+	return NewOutboxRelay()
+}
+
+func seedOutbox(t *testing.T, db *gorm.DB, executionIDs ...string) {
+	t.Helper()
+
+	for _, executionID := range executionIDs {
+		if err := db.Create(&models.ExecutionOutbox{
+			ExecutionID:   executionID,
+			Topic:         "report-execution-requests",
+			MessageKey:    executionID,
+			Payload:       `{"execution_id":"` + executionID + `"}`,
+			Status:        models.OutboxStatusPending,
+			NextAttemptAt: time.Now().Add(-time.Second),
+		}).Error; err != nil {
+			t.Fatalf("seed outbox: %v", err)
+		}
+	}
+}
+
+func loadOutbox(t *testing.T, db *gorm.DB) map[string]models.ExecutionOutbox {
+	t.Helper()
+
+	var messages []models.ExecutionOutbox
+	if err := db.Find(&messages).Error; err != nil {
+		t.Fatalf("load outbox: %v", err)
+	}
+	byExecution := map[string]models.ExecutionOutbox{}
+	for _, message := range messages {
+		byExecution[message.ExecutionID] = message
+	}
+	return byExecution
+}
+
+func TestOutboxRelayMarksEachMessageSentOrFailed(t *testing.T) {
+	db := useTestDB(t)
+	producer := useMockProducer(t)
+	relay := newTestOutboxRelay()
+	seedOutbox(t, db, "exec-1", "exec-2", "exec-3")
+
+	// Publishing happens outside any transaction: the outbox table stays writable while Kafka is called
+	writable := func(val []byte) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		return db.WithContext(ctx).Exec("UPDATE report_execution_outbox SET attempts = attempts WHERE id = 0").Error
+	}
+	producer.ExpectSendMessageWithCheckerFunctionAndSucceed(writable)
+	producer.ExpectSendMessageWithCheckerFunctionAndFail(writable, errors.New("broker unavailable"))
+	producer.ExpectSendMessageWithCheckerFunctionAndSucceed(writable)
+
+	before := time.Now()
+	relay.relayPending()
+
+	messages := loadOutbox(t, db)
+	for _, id := range []string{"exec-1", "exec-3"} {
+		message := messages[id]
+		if message.Status != models.OutboxStatusSent || message.SentAt == nil || message.Attempts != 1 || message.ClaimedBy != nil {
+			t.Errorf("%s = %+v, want sent after one attempt and no claim", id, message)
+		}
+	}
+
+	failed := messages["exec-2"]
+	if failed.Status != models.OutboxStatusPending || failed.Attempts != 1 || failed.ClaimedBy != nil {
+		t.Errorf("exec-2 = %+v, want pending after one failed attempt and no claim", failed)
+	}
+	if failed.LastError == nil || *failed.LastError != "broker unavailable" {
+		t.Errorf("exec-2 last_error = %v, want the publish error", failed.LastError)
+	}
+	if !failed.NextAttemptAt.After(before.Add(4 * time.Second)) {
+		t.Errorf("exec-2 next_attempt_at = %v, want a backoff of at least the retry base", failed.NextAttemptAt)
+	}
+
+	// Nothing is due before the backoff passes, so a second pass publishes nothing
+	relay.relayPending()
+}
+
+func TestOutboxClaimKeepsOtherRelaysOffUntilTheLeaseEnds(t *testing.T) {
+	db := useTestDB(t)
+	first := newTestOutboxRelay()
+	second := newTestOutboxRelay()
+	seedOutbox(t, db, "exec-1", "exec-2")
+
+	now := time.Now()
+	claimed, err := first.repo.ClaimPending(now, 10, first.claimant, now.Add(time.Minute))
+	if err != nil || len(claimed) != 2 {
+		t.Fatalf("first claim = %d messages, %v; want 2", len(claimed), err)
+	}
+
+	claimed, err = second.repo.ClaimPending(now, 10, second.claimant, now.Add(time.Minute))
+	if err != nil || len(claimed) != 0 {
+		t.Fatalf("second claim during the lease = %d messages, %v; want 0", len(claimed), err)
+	}
+
+	// The first relay died; once its lease is over the messages are claimed again
+	later := now.Add(2 * time.Minute)
+	claimed, err = second.repo.ClaimPending(later, 10, second.claimant, later.Add(time.Minute))
+	if err != nil || len(claimed) != 2 {
+		t.Fatalf("claim after the lease = %d messages, %v; want 2", len(claimed), err)
+	}
+
+	// A late failure report from the first relay does not push back messages it no longer owns
+	if err := first.repo.MarkFailed(claimed[0].ID, first.claimant, later.Add(time.Hour), "late"); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+	message := loadOutbox(t, db)[claimed[0].ExecutionID]
+	if message.ClaimedBy == nil || *message.ClaimedBy != second.claimant || message.Attempts != 0 {
+		t.Errorf("message = %+v, want still claimed by the second relay", message)
+	}
+}
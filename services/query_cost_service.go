@@ -0,0 +1,411 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"scheduling-report/config"
+	"scheduling-report/models"
+	"scheduling-report/repositories"
+	"scheduling-report/utils"
+
+	"github.com/rs/zerolog/log"
+)
+
+// explainTimeout bounds the EXPLAIN round trip; the query itself is not executed
+const explainTimeout = 15 * time.Second
+
+// QueryCostEstimate is what the datasource's planner expects a report query to cost
+type QueryCostEstimate struct {
+	DbType string `json:"db_type"`
+	// EstimatedRows is the planner's estimate of rows read per run, summed over table accesses
+	EstimatedRows  int64    `json:"estimated_rows"`
+	PlannerCost    float64  `json:"planner_cost"`
+	FullScans      []string `json:"full_scans"`
+	MissingIndexes []string `json:"missing_indexes"`
+	// ExecutionsPerDay comes from the config's schedules, at least 1 for unscheduled configs
+	ExecutionsPerDay float64 `json:"executions_per_day"`
+	// LoadScore is thousands of rows read per day, weighted up for full scans and missing indexes
+	LoadScore        float64 `json:"load_score"`
+	Threshold        float64 `json:"threshold"`
+	RequiresOverride bool    `json:"requires_override"`
+	// Skipped says why the planner could not be asked; such a query is treated as heavy
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// HeavyQueryError is returned when a config's load score is above the threshold, or could not be
+// estimated, without cost_override
+type HeavyQueryError struct {
+	Estimate *QueryCostEstimate
+}
+
+func (e *HeavyQueryError) Error() string {
+	if e.Estimate.Skipped != "" {
+		return fmt.Sprintf("%s; set cost_override to save it anyway", e.Estimate.Skipped)
+	}
+	return fmt.Sprintf("query load score %.0f exceeds the threshold of %.0f; set cost_override to save it anyway",
+		e.Estimate.LoadScore, e.Estimate.Threshold)
+}
+
+// CostEstimateInput estimates a saved config, a draft, or a saved config with draft edits
+type CostEstimateInput struct {
+	ConfigID        *int                   `json:"config_id"`
+	DatasourceID    int                    `json:"datasource_id"`
+	ReportQuery     string                 `json:"report_query"`
+	ParameterSchema models.ParameterSchema `json:"parameter_schema"`
+	Parameters      map[string]interface{} `json:"parameters"`
+	// CronExpression replaces the saved config's schedules for the executions-per-day figure
+	CronExpression string `json:"cron_expression"`
+	Timezone       string `json:"timezone"`
+}
+
+type QueryCostService struct {
+	configRepo     *repository.ReportConfigRepository
+	datasourceRepo *repository.DatasourceRepository
+	scheduleRepo   *repository.ReportScheduleRepository
+}
+
+func NewQueryCostService() *QueryCostService {
+	return &QueryCostService{
+		configRepo:     repository.NewReportConfigRepository(),
+		datasourceRepo: repository.NewDatasourceRepository(),
+		scheduleRepo:   repository.NewReportScheduleRepository(),
+	}
+}
+
+// Estimate runs EXPLAIN for the requested config and scores it against the threshold
+func (s *QueryCostService) Estimate(input CostEstimateInput) (*QueryCostEstimate, error) {
+	reportConfig := &models.ReportConfig{}
+	if input.ConfigID != nil {
+		saved, err := s.configRepo.GetByID(*input.ConfigID)
+		if err != nil {
+			return nil, errors.New("report config not found")
+		}
+		reportConfig = saved
+	}
+	if input.DatasourceID != 0 {
+		reportConfig.DatasourceID = input.DatasourceID
+	}
+	if input.ReportQuery != "" {
+		reportConfig.ReportQuery = input.ReportQuery
+	}
+	if input.ParameterSchema != nil {
+		reportConfig.ParameterSchema = input.ParameterSchema
+	}
+	if reportConfig.ReportQuery == "" || reportConfig.DatasourceID == 0 {
+		return nil, errors.New("config_id or datasource_id and report_query are required")
+	}
+
+	datasource, err := s.datasourceRepo.GetByID(reportConfig.DatasourceID)
+	if err != nil {
+		return nil, errors.New("datasource not found")
+	}
+
+	var executionsPerDay float64
+	if input.CronExpression != "" {
+		validation := utils.ValidateCronExpression(input.CronExpression, input.Timezone)
+		if !validation.Valid {
+			return nil, errors.New("invalid cron expression")
+		}
+		executionsPerDay = validation.ExecutionsPerDay
+	} else if reportConfig.ID != 0 {
+		executionsPerDay = s.ScheduledExecutionsPerDay(reportConfig.ID)
+	}
+
+	return estimateQueryCost(reportConfig, datasource, input.Parameters, input.CronExpression, input.Timezone, executionsPerDay)
+}
+
+// ScheduledExecutionsPerDay sums the daily runs of a config's active schedules
+func (s *QueryCostService) ScheduledExecutionsPerDay(configID int) float64 {
+	schedules, err := s.scheduleRepo.GetByConfigID(configID)
+	if err != nil {
+		return 0
+	}
+	var total float64
+	for _, schedule := range schedules {
+		if schedule.IsActive {
+			total += utils.ValidateCronExpression(schedule.CronExpression, schedule.Timezone).ExecutionsPerDay
+		}
+	}
+	return total
+}
+
+// CheckQueryCost is the save-time gate. It returns the estimate when the config is above the
+// threshold but override is set, so the caller can record the override in the audit trail.
+// The gate fails closed: a query the planner could not estimate also needs override, and the
+// returned estimate then carries the reason the check was skipped. Datasource types with no
+// EXPLAIN support are not gated; their estimate is returned as a warning, without override.
+func CheckQueryCost(reportConfig *models.ReportConfig, datasource *models.DataSource, cronExpression string, timezone string, executionsPerDay float64, override bool) (*QueryCostEstimate, error) {
+	if _, ok := queryExplainers[datasource.DbType]; !ok {
+		log.Warn().Int("config_id", reportConfig.ID).Str("db_type", datasource.DbType).Msg("Query cost estimation is not supported for this datasource type, saving without a cost check")
+		return &QueryCostEstimate{
+			DbType:         datasource.DbType,
+			FullScans:      []string{},
+			MissingIndexes: []string{},
+			Threshold:      config.Config.QueryCostMaxLoadScore,
+			Skipped:        fmt.Sprintf("cost check skipped: cost estimation is not supported for datasource type '%s'", datasource.DbType),
+		}, nil
+	}
+
+	estimate, err := estimateQueryCost(reportConfig, datasource, nil, cronExpression, timezone, executionsPerDay)
+	if err != nil {
+		skipped := &QueryCostEstimate{
+			DbType:           datasource.DbType,
+			FullScans:        []string{},
+			MissingIndexes:   []string{},
+			Threshold:        config.Config.QueryCostMaxLoadScore,
+			RequiresOverride: true,
+			Skipped:          "cost check skipped: " + err.Error(),
+		}
+		if !override {
+			return nil, &HeavyQueryError{Estimate: skipped}
+		}
+		log.Warn().Err(err).Int("config_id", reportConfig.ID).Msg("Query cost estimation failed, saving with cost_override")
+		return skipped, nil
+	}
+	if !estimate.RequiresOverride {
+		return nil, nil
+	}
+	if !override {
+		return nil, &HeavyQueryError{Estimate: estimate}
+	}
+	return estimate, nil
+}
+
+// costInputsChanged reports whether a save changes what the cost gate scores: the query, the
+// datasource it runs on, or the parameters (and parameter defaults) it is rendered with.
+// Saves that leave them alone, such as a rename or a cron change, skip the gate.
+func costInputsChanged(before *models.ReportConfig, after *models.ReportConfig) bool {
+	if before.ReportQuery != after.ReportQuery || before.DatasourceID != after.DatasourceID {
+		return true
+	}
+	beforeParameters, _ := json.Marshal(before.Parameters)
+	afterParameters, _ := json.Marshal(after.Parameters)
+	beforeSchema, _ := json.Marshal(before.ParameterSchema)
+	afterSchema, _ := json.Marshal(after.ParameterSchema)
+	return string(beforeParameters) != string(afterParameters) || string(beforeSchema) != string(afterSchema)
+}
+
+// auditKey is the change_summary key an estimate returned by CheckQueryCost is recorded under
+func (e *QueryCostEstimate) auditKey() string {
+	if e.RequiresOverride {
+		return "cost_override"
+	}
+	return "cost_check"
+}
+
+// CostOverrideSummary is the audit change_summary recorded when CheckQueryCost let a config through
+// with an estimate: under cost_override for a heavy query, or cost_check for a skipped check
+func CostOverrideSummary(estimate *QueryCostEstimate) *string {
+	return configAuditEntry{Cost: estimate}.changeSummary()
+}
+
+func estimateQueryCost(reportConfig *models.ReportConfig, datasource *models.DataSource, overrides map[string]interface{}, cronExpression string, timezone string, executionsPerDay float64) (*QueryCostEstimate, error) {
+	parameters, err := ResolveReportParameters(reportConfig.ParameterSchema, reportConfig.Parameters, overrides)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+	query, err := utils.RenderReportQuery(reportConfig.ReportQuery, datasource.DbType, parameters, ParameterTypes(reportConfig.ParameterSchema),
+		utils.NewVariableContext(nil, cronExpression, timezone, time.Now()))
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := OpenDataSource(datasource)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), explainTimeout)
+	defer cancel()
+
+	estimate := &QueryCostEstimate{DbType: datasource.DbType, FullScans: []string{}, MissingIndexes: []string{}}
+	explain, ok := queryExplainers[datasource.DbType]
+	if !ok {
+		return nil, fmt.Errorf("cost estimation is not supported for datasource type '%s'", datasource.DbType)
+	}
+	if err := explain(ctx, db, query, estimate); err != nil {
+		return nil, err
+	}
+
+	// Unscheduled configs still run on demand
+	estimate.ExecutionsPerDay = math.Max(executionsPerDay, 1)
+	multiplier := 1 + 0.5*float64(len(estimate.FullScans)) + float64(len(estimate.MissingIndexes))
+	estimate.LoadScore = math.Round(float64(estimate.EstimatedRows) * estimate.ExecutionsPerDay * multiplier / 1000)
+	estimate.Threshold = config.Config.QueryCostMaxLoadScore
+	estimate.RequiresOverride = estimate.LoadScore > estimate.Threshold
+
+	return estimate, nil
+}
+
+// queryExplainers read the planner's estimate for each DbType that supports cost estimation
+var queryExplainers = map[string]func(ctx context.Context, db *sql.DB, query *utils.RenderedQuery, estimate *QueryCostEstimate) error{
+	"mysql":      explainMySQL,
+	"postgresql": explainPostgres,
+	"sqlserver":  explainSQLServer,
+}
+
+// explainMySQL reads EXPLAIN FORMAT=JSON: every object with an access_type is a table access
+func explainMySQL(ctx context.Context, db *sql.DB, query *utils.RenderedQuery, estimate *QueryCostEstimate) error {
+	var plan string
+	if err := db.QueryRowContext(ctx, "EXPLAIN FORMAT=JSON "+query.SQL, query.Args...).Scan(&plan); err != nil {
+		return fmt.Errorf("EXPLAIN failed: %w", err)
+	}
+	var root interface{}
+	if err := json.Unmarshal([]byte(plan), &root); err != nil {
+		return fmt.Errorf("unreadable EXPLAIN output: %w", err)
+	}
+
+	walkJSON(root, func(node map[string]interface{}) {
+		if costInfo, ok := node["cost_info"].(map[string]interface{}); ok && estimate.PlannerCost == 0 {
+			estimate.PlannerCost = jsonNumber(costInfo["query_cost"])
+		}
+		accessType, ok := node["access_type"].(string)
+		if !ok {
+			return
+		}
+		table, _ := node["table_name"].(string)
+		estimate.EstimatedRows += int64(jsonNumber(node["rows_examined_per_scan"]))
+		if accessType == "ALL" {
+			estimate.FullScans = append(estimate.FullScans, table)
+			if node["possible_keys"] == nil {
+				estimate.MissingIndexes = append(estimate.MissingIndexes, table)
+			}
+		}
+	})
+	return nil
+}
+
+// explainPostgres reads EXPLAIN (FORMAT JSON); sequential scans with a filter had no usable index
+func explainPostgres(ctx context.Context, db *sql.DB, query *utils.RenderedQuery, estimate *QueryCostEstimate) error {
+	var plan string
+	if err := db.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+query.SQL, query.Args...).Scan(&plan); err != nil {
+		return fmt.Errorf("EXPLAIN failed: %w", err)
+	}
+	var root []struct {
+		Plan map[string]interface{} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(plan), &root); err != nil || len(root) == 0 {
+		return fmt.Errorf("unreadable EXPLAIN output: %v", err)
+	}
+	estimate.PlannerCost = jsonNumber(root[0].Plan["Total Cost"])
+
+	walkJSON(root[0].Plan, func(node map[string]interface{}) {
+		nodeType, _ := node["Node Type"].(string)
+		if !strings.HasSuffix(nodeType, "Scan") {
+			return
+		}
+		estimate.EstimatedRows += int64(jsonNumber(node["Plan Rows"]))
+		if nodeType == "Seq Scan" {
+			table, _ := node["Relation Name"].(string)
+			estimate.FullScans = append(estimate.FullScans, table)
+			if _, filtered := node["Filter"]; filtered {
+				estimate.MissingIndexes = append(estimate.MissingIndexes, table)
+			}
+		}
+	})
+	return nil
+}
+
+// explainSQLServer reads the estimated XML showplan on a pinned connection
+func explainSQLServer(ctx context.Context, db *sql.DB, query *utils.RenderedQuery, estimate *QueryCostEstimate) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SET SHOWPLAN_XML ON"); err != nil {
+		return fmt.Errorf("EXPLAIN failed: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SET SHOWPLAN_XML OFF")
+
+	var plan string
+	if err := conn.QueryRowContext(ctx, query.SQL, query.Args...).Scan(&plan); err != nil {
+		return fmt.Errorf("EXPLAIN failed: %w", err)
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(plan))
+	fullScan := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("unreadable showplan: %w", err)
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		attrs := map[string]string{}
+		for _, attr := range element.Attr {
+			attrs[attr.Name.Local] = attr.Value
+		}
+
+		switch element.Name.Local {
+		case "StmtSimple":
+			if estimate.PlannerCost == 0 {
+				estimate.PlannerCost, _ = strconv.ParseFloat(attrs["StatementSubTreeCost"], 64)
+			}
+		case "RelOp":
+			physicalOp := attrs["PhysicalOp"]
+			if !strings.HasSuffix(physicalOp, "Scan") && !strings.HasSuffix(physicalOp, "Seek") {
+				continue
+			}
+			rows := attrs["EstimatedRowsRead"]
+			if rows == "" {
+				rows = attrs["EstimateRows"]
+			}
+			read, _ := strconv.ParseFloat(rows, 64)
+			estimate.EstimatedRows += int64(read)
+			fullScan = physicalOp == "Table Scan" || physicalOp == "Clustered Index Scan"
+		case "Object":
+			if fullScan {
+				estimate.FullScans = append(estimate.FullScans, strings.Trim(attrs["Table"], "[]"))
+				fullScan = false
+			}
+		case "MissingIndex":
+			estimate.MissingIndexes = append(estimate.MissingIndexes, strings.Trim(attrs["Table"], "[]"))
+		}
+	}
+	return nil
+}
+
+// walkJSON calls visit for every object in a decoded JSON document
+func walkJSON(value interface{}, visit func(map[string]interface{})) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		visit(v)
+		for _, child := range v {
+			walkJSON(child, visit)
+		}
+	case []interface{}:
+		for _, child := range v {
+			walkJSON(child, visit)
+		}
+	}
+}
+
+// jsonNumber reads planner numbers, which MySQL sometimes reports as strings
+func jsonNumber(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return 0
+}
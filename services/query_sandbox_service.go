@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"scheduling-report/config"
+	"scheduling-report/models"
+	"scheduling-report/repositories"
+	"scheduling-report/utils"
+)
+
+// sensitiveColumnPatterns mask sandbox result columns whose name contains any of them
+var sensitiveColumnPatterns = []string{
+	"password", "passwd", "secret", "token", "api_key", "private_key",
+	"ssn", "social_security", "credit_card", "card_number", "cvv", "iban",
+}
+
+// SandboxInput runs a saved config, a draft, or a saved config with draft edits.
+// Draft fields override the saved config's values when both are given.
+type SandboxInput struct {
+	ConfigID        *int                   `json:"config_id"`
+	DatasourceID    int                    `json:"datasource_id"`
+	ReportQuery     string                 `json:"report_query"`
+	ParameterSchema models.ParameterSchema `json:"parameter_schema"`
+	// Parameters are per-run overrides of the config's parameter values
+	Parameters map[string]interface{} `json:"parameters"`
+	// ExecutionTime is the run time template variables are evaluated for; defaults to now
+	ExecutionTime  string `json:"execution_time"`
+	LastRunAt      string `json:"last_run_at"`
+	CronExpression string `json:"cron_expression"`
+	Timezone       string `json:"timezone"`
+	// MaxRows and TimeoutSeconds may only lower the sandbox caps
+	MaxRows        int      `json:"max_rows" validate:"min=0"`
+	TimeoutSeconds int      `json:"timeout_seconds" validate:"min=0"`
+	MaskedColumns  []string `json:"masked_columns"`
+}
+
+// SandboxColumn describes a result column
+type SandboxColumn struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Masked bool   `json:"masked"`
+}
+
+// SandboxResult is what a sandbox run returned
+type SandboxResult struct {
+	ExecutionTime string          `json:"execution_time"`
+	RenderedSQL   string          `json:"rendered_sql"`
+	QueryArgs     []interface{}   `json:"query_args"`
+	Columns       []SandboxColumn `json:"columns"`
+	Rows          [][]interface{} `json:"rows"`
+	RowCount      int             `json:"row_count"`
+	Truncated     bool            `json:"truncated"`
+	MaxRows       int             `json:"max_rows"`
+	QueryTimeMs   int64           `json:"query_time_ms"`
+}
+
+type QuerySandboxService struct {
+	configRepo     *repository.ReportConfigRepository
+	datasourceRepo *repository.DatasourceRepository
+}
+
+func NewQuerySandboxService() *QuerySandboxService {
+	return &QuerySandboxService{
+		configRepo:     repository.NewReportConfigRepository(),
+		datasourceRepo: repository.NewDatasourceRepository(),
+	}
+}
+
+// Run renders the query for the requested execution time and runs it inside a
+// read-only transaction that is always rolled back, within the sandbox row and time caps.
+func (s *QuerySandboxService) Run(input SandboxInput) (*SandboxResult, error) {
+	reportConfig := &models.ReportConfig{}
+	if input.ConfigID != nil {
+		saved, err := s.configRepo.GetByID(*input.ConfigID)
+		if err != nil {
+			return nil, errors.New("report config not found")
+		}
+		reportConfig = saved
+	}
+	if input.DatasourceID != 0 {
+		reportConfig.DatasourceID = input.DatasourceID
+	}
+	if input.ReportQuery != "" {
+		reportConfig.ReportQuery = input.ReportQuery
+	}
+	if input.ParameterSchema != nil {
+		if err := ValidateParameterSchema(input.ParameterSchema); err != nil {
+			return nil, fmt.Errorf("invalid parameter schema: %w", err)
+		}
+		reportConfig.ParameterSchema = input.ParameterSchema
+	}
+	if reportConfig.ReportQuery == "" || reportConfig.DatasourceID == 0 {
+		return nil, errors.New("config_id or datasource_id and report_query are required")
+	}
+
+	datasource, err := s.datasourceRepo.GetByID(reportConfig.DatasourceID)
+	if err != nil {
+		return nil, errors.New("datasource not found")
+	}
+
+	configID := 0
+	if input.ConfigID != nil && input.ReportQuery == "" {
+		configID = *input.ConfigID
+	}
+	if err := CheckReportQuery(configID, reportConfig.ReportQuery, datasource.DbType); err != nil {
+		return nil, err
+	}
+
+	variableContext, executionTime, err := sandboxVariableContext(input)
+	if err != nil {
+		return nil, err
+	}
+	parameters, err := ResolveReportParameters(reportConfig.ParameterSchema, reportConfig.Parameters, input.Parameters)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+	query, err := utils.RenderReportQuery(reportConfig.ReportQuery, datasource.DbType, parameters, ParameterTypes(reportConfig.ParameterSchema), variableContext)
+	if err != nil {
+		return nil, err
+	}
+
+	maxRows := capSandboxLimit(input.MaxRows, config.Config.SandboxMaxRows)
+	timeoutSeconds := capSandboxLimit(input.TimeoutSeconds, config.Config.SandboxTimeoutSeconds)
+
+	db, err := OpenDataSource(datasource)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSeconds)*time.Second)
+	defer cancel()
+
+	queryStart := time.Now()
+	queryResult, err := runReadOnlyQuery(ctx, db, datasource.DbType, query.SQL, query.Args, maxRows, true)
+	queryTimeMs := time.Since(queryStart).Milliseconds()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("query exceeded sandbox timeout of %d seconds", timeoutSeconds)
+		}
+		return nil, err
+	}
+
+	result := &SandboxResult{
+		ExecutionTime: executionTime.Format(time.RFC3339),
+		RenderedSQL:   query.SQL,
+		QueryArgs:     query.Args,
+		Columns:       make([]SandboxColumn, len(queryResult.Columns)),
+		Rows:          [][]interface{}{},
+		RowCount:      len(queryResult.Rows),
+		Truncated:     queryResult.Truncated,
+		MaxRows:       maxRows,
+		QueryTimeMs:   queryTimeMs,
+	}
+
+	for i, name := range queryResult.Columns {
+		result.Columns[i] = SandboxColumn{
+			Name:   name,
+			Type:   queryResult.ColumnTypes[i],
+			Masked: isSensitiveColumn(name, input.MaskedColumns),
+		}
+	}
+	for _, row := range queryResult.Rows {
+		for i, value := range row {
+			if result.Columns[i].Masked && value != nil {
+				row[i] = utils.MaskedSecret
+				continue
+			}
+			row[i] = typedSandboxValue(value, result.Columns[i].Type)
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	return result, nil
+}
+
+// sandboxVariableContext evaluates template variables at the requested execution time
+func sandboxVariableContext(input SandboxInput) (utils.VariableContext, time.Time, error) {
+	location, err := utils.LoadTimezone(input.Timezone)
+	if err != nil {
+		return utils.VariableContext{}, time.Time{}, err
+	}
+
+	executionTime := time.Now()
+	if input.ExecutionTime != "" {
+		executionTime, err = parseSandboxTime(input.ExecutionTime, location)
+		if err != nil {
+			return utils.VariableContext{}, time.Time{}, fmt.Errorf("invalid execution_time: %w", err)
+		}
+	}
+
+	var lastRunAt *time.Time
+	if input.LastRunAt != "" {
+		parsed, err := parseSandboxTime(input.LastRunAt, location)
+		if err != nil {
+			return utils.VariableContext{}, time.Time{}, fmt.Errorf("invalid last_run_at: %w", err)
+		}
+		lastRunAt = &parsed
+	}
+
+	if input.CronExpression != "" {
+		if validation := utils.ValidateCronExpression(input.CronExpression, input.Timezone); !validation.Valid {
+			return utils.VariableContext{}, time.Time{}, errors.New("invalid cron expression")
+		}
+	}
+
+	return utils.NewVariableContext(lastRunAt, input.CronExpression, input.Timezone, executionTime), executionTime, nil
+}
+
+// parseSandboxTime accepts RFC3339 or a wall clock time in the request's timezone
+func parseSandboxTime(value string, location *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("'%s' is not RFC3339 or YYYY-MM-DD[ HH:MM:SS]", value)
+}
+
+// capSandboxLimit returns requested when it is set and below the configured cap
+func capSandboxLimit(requested int, limit int) int {
+	if requested > 0 && requested < limit {
+		return requested
+	}
+	return limit
+}
+
+func isSensitiveColumn(name string, extra []string) bool {
+	lower := strings.ToLower(name)
+	for _, column := range extra {
+		if strings.EqualFold(column, name) {
+			return true
+		}
+	}
+	for _, pattern := range append(sensitiveColumnPatterns, config.Config.SandboxMaskedColumns...) {
+		if strings.Contains(lower, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// typedSandboxValue turns numeric strings (MySQL's text protocol) back into JSON numbers
+func typedSandboxValue(value interface{}, databaseType string) interface{} {
+	text, ok := value.(string)
+	if !ok {
+		return value
+	}
+	switch strings.ToUpper(databaseType) {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "BIGINT", "INT2", "INT4", "INT8",
+		"UNSIGNED TINYINT", "UNSIGNED SMALLINT", "UNSIGNED MEDIUMINT", "UNSIGNED INT", "UNSIGNED BIGINT":
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n
+		}
+	case "FLOAT", "DOUBLE", "FLOAT4", "FLOAT8", "REAL":
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
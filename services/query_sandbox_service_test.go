@@ -0,0 +1,144 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"scheduling-report/config"
+	"scheduling-report/utils"
+)
+
+// sandboxSalesTable holds ten sales rows, ids 1 to 10
+var sandboxSalesTable = []string{
+	`CREATE TABLE sales (id INTEGER PRIMARY KEY, region VARCHAR(10), total REAL, customer_token VARCHAR(20))`,
+	`WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 10)
+	INSERT INTO sales SELECT i, CASE i % 2 WHEN 0 THEN 'EU' ELSE 'US' END, i * 1.5, 'tok-' || i FROM n`,
+}
+
+func TestSandboxRun(t *testing.T) {
+	db := useTestDB(t)
+	datasource := seedSQLiteDatasource(t, db, sandboxSalesTable...)
+	config.Config.SandboxMaxRows = 5
+	config.Config.SandboxTimeoutSeconds = 1
+
+	tests := []struct {
+		name          string
+		query         string
+		maxRows       int
+		timeout       int
+		wantRows      int
+		wantMaxRows   int
+		wantTruncated bool
+		wantErr       string
+		wantGuard     bool
+	}{
+		{name: "under the cap", query: "SELECT id, region FROM sales WHERE id <= 2", wantRows: 2, wantMaxRows: 5},
+		{name: "configured cap truncates", query: "SELECT id FROM sales", wantRows: 5, wantMaxRows: 5, wantTruncated: true},
+		{name: "request lowers the cap", query: "SELECT id FROM sales", maxRows: 3, wantRows: 3, wantMaxRows: 3, wantTruncated: true},
+		{name: "request cannot raise the cap", query: "SELECT id FROM sales", maxRows: 100, wantRows: 5, wantMaxRows: 5, wantTruncated: true},
+		{name: "delete rejected by the guard", query: "DELETE FROM sales", wantGuard: true},
+		{name: "update rejected by the guard", query: "UPDATE sales SET total = 0", wantGuard: true},
+		{name: "stacked write rejected by the guard", query: "SELECT id FROM sales; DROP TABLE sales", wantGuard: true},
+		{
+			name:    "configured timeout stops a runaway query",
+			query:   "SELECT SLEEP_MS(1500) AS slow",
+			wantErr: "query exceeded sandbox timeout of 1 seconds",
+		},
+		{
+			name:    "request cannot raise the timeout",
+			query:   "SELECT SLEEP_MS(1500) AS slow",
+			timeout: 60,
+			wantErr: "query exceeded sandbox timeout of 1 seconds",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			result, err := NewQuerySandboxService().Run(SandboxInput{
+				DatasourceID:   datasource.ID,
+				ReportQuery:    tt.query,
+				MaxRows:        tt.maxRows,
+				TimeoutSeconds: tt.timeout,
+			})
+
+			if tt.wantGuard {
+				var guardErr *utils.SQLGuardError
+				if !errors.As(err, &guardErr) {
+					t.Fatalf("Run = %v, want a SQLGuardError", err)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				if elapsed := time.Since(start); elapsed > 5*time.Second {
+					t.Errorf("Run took %s, want it stopped once the 1s timeout passed", elapsed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if result.RowCount != tt.wantRows || len(result.Rows) != tt.wantRows || result.MaxRows != tt.wantMaxRows || result.Truncated != tt.wantTruncated {
+				t.Errorf("rows = %d (%d returned), max_rows = %d, truncated = %t; want %d, %d, %t",
+					result.RowCount, len(result.Rows), result.MaxRows, result.Truncated, tt.wantRows, tt.wantMaxRows, tt.wantTruncated)
+			}
+		})
+	}
+
+	var count int64
+	reportsDB, err := OpenDataSource(&datasource)
+	if err != nil {
+		t.Fatalf("OpenDataSource: %v", err)
+	}
+	defer reportsDB.Close()
+	if err := reportsDB.QueryRow("SELECT COUNT(*) FROM sales").Scan(&count); err != nil {
+		t.Fatalf("count sales: %v", err)
+	}
+	if count != 10 {
+		t.Errorf("sales has %d rows after the sandbox runs, want all 10 kept", count)
+	}
+}
+
+func TestSandboxRunMasksSensitiveColumns(t *testing.T) {
+	db := useTestDB(t)
+	datasource := seedSQLiteDatasource(t, db, sandboxSalesTable...)
+	config.Config.SandboxMaxRows = 5
+	config.Config.SandboxTimeoutSeconds = 1
+
+	result, err := NewQuerySandboxService().Run(SandboxInput{
+		DatasourceID:  datasource.ID,
+		ReportQuery:   "SELECT id, region, customer_token FROM sales WHERE id = 1",
+		MaskedColumns: []string{"REGION"},
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	masked := map[string]bool{}
+	for _, column := range result.Columns {
+		masked[column.Name] = column.Masked
+	}
+	if masked["id"] || !masked["region"] || !masked["customer_token"] {
+		t.Errorf("masked columns = %v, want region (requested) and customer_token (pattern) only", masked)
+	}
+	if want := []interface{}{int64(1), utils.MaskedSecret, utils.MaskedSecret}; len(result.Rows) != 1 || !equalRow(result.Rows[0], want) {
+		t.Errorf("rows = %#v, want [%#v]", result.Rows, want)
+	}
+}
+
+func equalRow(got []interface{}, want []interface{}) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
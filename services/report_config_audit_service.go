@@ -3,9 +3,12 @@ package services
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"scheduling-report/models"
 	"scheduling-report/repositories"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type ReportConfigAuditService struct {
@@ -18,23 +21,13 @@ func NewReportConfigAuditService() *ReportConfigAuditService {
 	}
 }
 
-// GetAll retrieves all audit records with optional filters
-func (s *ReportConfigAuditService) GetAll(configID *int, action *string, performedBy *string) ([]models.ReportConfigAudit, error) {
-	// Validate action enum if provided
-	if action != nil {
-		validActions := map[string]bool{
-			"create":     true,
-			"update":     true,
-			"delete":     true,
-			"activate":   true,
-			"deactivate": true,
-		}
-		if !validActions[*action] {
-			return nil, errors.New("invalid action value. Must be one of: create, update, delete, activate, deactivate")
-		}
+// List retrieves one page of audit records, filtered and sorted by the list query parameters
+func (s *ReportConfigAuditService) List(params map[string]string) ([]models.ReportConfigAudit, *repository.PageMeta, error) {
+	q, err := repository.ParseListQuery(params, repository.AuditListSpec)
+	if err != nil {
+		return nil, nil, err
 	}
-
-	return s.repo.GetAll(configID, action, performedBy)
+	return s.repo.List(q)
 }
 
 // GetByID retrieves a single audit record
@@ -55,6 +48,21 @@ func (s *ReportConfigAuditService) CreateAuditLog(
 	performedBy string,
 	sessionID *string,
 	ipAddress *string,
+) error {
+	return s.CreateAuditLogWithSummary(configID, action, beforeValue, afterValue, nil, performedBy, sessionID, ipAddress)
+}
+
+// CreateAuditLogWithSummary is CreateAuditLog with a change_summary JSON document,
+// e.g. the cost estimate of a heavy query saved with cost_override
+func (s *ReportConfigAuditService) CreateAuditLogWithSummary(
+	configID *int,
+	action string,
+	beforeValue interface{},
+	afterValue interface{},
+	changeSummary *string,
+	performedBy string,
+	sessionID *string,
+	ipAddress *string,
 ) error {
 	// Convert before/after values to JSON strings
 	var beforeJSON, afterJSON *string
@@ -76,19 +84,80 @@ func (s *ReportConfigAuditService) CreateAuditLog(
 	}
 
 	audit := &models.ReportConfigAudit{
-		ConfigID:    configID,
-		Action:      action,
-		BeforeValue: beforeJSON,
-		AfterValue:  afterJSON,
-		PerformedBy: performedBy,
-		PerformedAt: time.Now(),
-		SessionID:   sessionID,
-		IPAddress:   ipAddress,
+		ConfigID:      configID,
+		Action:        action,
+		BeforeValue:   beforeJSON,
+		AfterValue:    afterJSON,
+		ChangeSummary: changeSummary,
+		PerformedBy:   performedBy,
+		PerformedAt:   time.Now(),
+		SessionID:     sessionID,
+		IPAddress:     ipAddress,
 	}
 
 	return s.repo.Create(audit)
 }
 
+// configAuditEntry is one row of a config's audit trail, written by writeConfigAudit
+type configAuditEntry struct {
+	ConfigID  int
+	Action    string
+	FieldName string      // set when the row records a single field
+	Before    interface{} // stored as JSON
+	After     interface{} // stored as JSON
+	// Summary and Cost make up the change_summary document; Cost is the estimate CheckQueryCost
+	// let the save through with, recorded under cost_override or cost_check
+	Summary     map[string]interface{}
+	Cost        *QueryCostEstimate
+	PerformedBy string
+}
+
+// changeSummary is the change_summary JSON document of the entry, nil when there is nothing to record
+func (e configAuditEntry) changeSummary() *string {
+	summary := make(map[string]interface{}, len(e.Summary)+1)
+	for key, value := range e.Summary {
+		summary[key] = value
+	}
+	if e.Cost != nil {
+		summary[e.Cost.auditKey()] = e.Cost
+	}
+	if len(summary) == 0 {
+		return nil
+	}
+	summaryJSON, _ := json.Marshal(summary)
+	summaryStr := string(summaryJSON)
+	return &summaryStr
+}
+
+// writeConfigAudit records entry with db, so the row commits or rolls back with the caller's transaction
+func writeConfigAudit(db *gorm.DB, entry configAuditEntry) error {
+	audit := &models.ReportConfigAudit{
+		ConfigID:      &entry.ConfigID,
+		Action:        entry.Action,
+		ChangeSummary: entry.changeSummary(),
+		PerformedBy:   entry.PerformedBy,
+		PerformedAt:   time.Now(),
+	}
+	if entry.FieldName != "" {
+		audit.FieldName = &entry.FieldName
+	}
+	if entry.Before != nil {
+		beforeJSON, _ := json.Marshal(entry.Before)
+		beforeStr := string(beforeJSON)
+		audit.BeforeValue = &beforeStr
+	}
+	if entry.After != nil {
+		afterJSON, _ := json.Marshal(entry.After)
+		afterStr := string(afterJSON)
+		audit.AfterValue = &afterStr
+	}
+
+	if err := db.Create(audit).Error; err != nil {
+		return fmt.Errorf("failed to create audit trail: %w", err)
+	}
+	return nil
+}
+
 // CreateAuditLogWithFieldChange creates audit log for specific field changes
 func (s *ReportConfigAuditService) CreateAuditLogWithFieldChange(
 	configID *int,
@@ -5,12 +5,19 @@ import (
 	"fmt"
 	"scheduling-report/models"
 	"scheduling-report/repositories"
+	"scheduling-report/utils"
+	"strings"
+
+	"gorm.io/gorm"
 )
 
 type ReportConfigService struct {
 	repo            *repository.ReportConfigRepository
 	datasourceRepo  *repository.DatasourceRepository
 	auditService    *ReportConfigAuditService
+	costService     *QueryCostService
+	folderRepo      *repository.ReportFolderRepository
+	tagRepo         *repository.ReportTagRepository
 }
 
 func NewReportConfigService() *ReportConfigService {
@@ -18,12 +25,20 @@ func NewReportConfigService() *ReportConfigService {
 		repo:           repository.NewReportConfigRepository(),
 		datasourceRepo: repository.NewDatasourceRepository(),
 		auditService:   NewReportConfigAuditService(),
+		costService:    NewQueryCostService(),
+		folderRepo:     repository.NewReportFolderRepository(),
+		tagRepo:        repository.NewReportTagRepository(),
 	}
 }
 
-// GetAll retrieves all report configs with optional filters
-func (s *ReportConfigService) GetAll(isActive *bool, datasourceID *int) ([]models.ReportConfig, error) {
-	return s.repo.GetAll(isActive, datasourceID)
+// List retrieves one page of report configs, filtered and sorted by the list query parameters
+// and narrowed by tags, folder path and owner
+func (s *ReportConfigService) List(params map[string]string, catalog models.ConfigCatalogFilters) ([]models.ReportConfig, *repository.PageMeta, error) {
+	q, err := repository.ParseListQuery(params, repository.ReportConfigListSpec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.repo.List(q, catalog)
 }
 
 // GetByID retrieves a single report config by ID
@@ -42,8 +57,14 @@ type CreateReportConfigInput struct {
 	OutputFormat   string             `json:"output_format" validate:"required,oneof=csv xlsx pdf json"`
 	DatasourceID   int                `json:"datasource_id" validate:"required"`
 	Parameters     models.Parameters  `json:"parameters"`
+	ParameterSchema models.ParameterSchema `json:"parameter_schema"`
 	TimeoutSeconds int                `json:"timeout_seconds" validate:"min=1,max=3600"`
 	MaxRows        int                `json:"max_rows" validate:"min=1,max=1000000"`
+	CostOverride   bool               `json:"cost_override"` // Save even if the query's load score is above the threshold
+	FolderID       *int               `json:"folder_id"`
+	OwnerTeam      *string            `json:"owner_team" validate:"omitempty,max=100"`
+	Owners         []string           `json:"owners" validate:"omitempty,dive,required,max=100"`
+	Tags           []string           `json:"tags"` // Names of existing tags
 	CreatedBy      string             `json:"created_by" validate:"required"`
 	IPAddress      *string            `json:"-"` // For audit
 	SessionID      *string            `json:"-"` // For audit
@@ -69,6 +90,22 @@ func (s *ReportConfigService) Create(input CreateReportConfigInput) (*models.Rep
 		return nil, fmt.Errorf("report config with name '%s' already exists", input.ReportName)
 	}
 
+	if err := ValidateParameterSchema(input.ParameterSchema); err != nil {
+		return nil, fmt.Errorf("invalid parameter schema: %w", err)
+	}
+
+	if err := CheckReportQuery(0, input.ReportQuery, datasource.DbType); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkFolder(input.FolderID); err != nil {
+		return nil, err
+	}
+	tagIDs, err := resolveTagNames(s.tagRepo, input.Tags)
+	if err != nil {
+		return nil, err
+	}
+
 	// Set defaults if not provided
 	if input.TimeoutSeconds == 0 {
 		input.TimeoutSeconds = 300
@@ -83,24 +120,48 @@ func (s *ReportConfigService) Create(input CreateReportConfigInput) (*models.Rep
 		OutputFormat:   input.OutputFormat,
 		DatasourceID:   input.DatasourceID,
 		Parameters:     input.Parameters,
+		ParameterSchema: input.ParameterSchema,
 		TimeoutSeconds: input.TimeoutSeconds,
 		MaxRows:        input.MaxRows,
 		IsActive:       true,
 		CreatedBy:      input.CreatedBy,
 		UpdatedBy:      input.CreatedBy,
 		Version:        1,
+		FolderID:       input.FolderID,
+		OwnerTeam:      input.OwnerTeam,
+		Owners:         normalizeOwners(input.Owners),
+		Tags:           normalizeTagNames(input.Tags),
+	}
+
+	// New configs have no schedules yet, so they are scored at one run per day
+	costEstimate, err := CheckQueryCost(config, datasource, "", "", 0, input.CostOverride)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.repo.Create(config); err != nil {
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := (&repository.ReportConfigRepository{DB: tx}).Create(config); err != nil {
+			return err
+		}
+		if err := (&repository.ReportTagRepository{DB: tx}).SetConfigTags(config.ID, tagIDsOf(config.Tags, tagIDs)); err != nil {
+			return err
+		}
+		if err := recordConfigVersion(&repository.ReportConfigVersionRepository{DB: tx}, config, models.VersionChangeCreate, nil, input.CreatedBy); err != nil {
+			return fmt.Errorf("failed to record config version: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	// ✅ AUTO-CREATE AUDIT LOG
-	s.auditService.CreateAuditLog(
+	s.auditService.CreateAuditLogWithSummary(
 		&config.ID,
 		"create",
 		nil,           // before_value (null for create)
 		config,        // after_value (full config)
+		CostOverrideSummary(costEstimate), // change_summary (heavy query saved with cost_override)
 		input.CreatedBy,
 		input.SessionID,
 		input.IPAddress,
@@ -116,9 +177,12 @@ type UpdateReportConfigInput struct {
 	OutputFormat   string            `json:"output_format" validate:"required,oneof=csv xlsx pdf json"`
 	DatasourceID   int               `json:"datasource_id" validate:"required"`
 	Parameters     models.Parameters `json:"parameters"`
+	ParameterSchema models.ParameterSchema `json:"parameter_schema"`
 	TimeoutSeconds int               `json:"timeout_seconds" validate:"min=1,max=3600"`
 	MaxRows        int               `json:"max_rows" validate:"min=1,max=1000000"`
+	CostOverride   bool              `json:"cost_override"` // Save even if the query's load score is above the threshold
 	UpdatedBy      string            `json:"updated_by" validate:"required"`
+	IfMatch        string            `json:"-"` // ETag the client last read; empty skips the check
 	IPAddress      *string           `json:"-"` // For audit
 	SessionID      *string           `json:"-"` // For audit
 }
@@ -131,6 +195,10 @@ func (s *ReportConfigService) Update(id int, input UpdateReportConfigInput) (*mo
 		return nil, errors.New("report config not found")
 	}
 
+	if err := checkIfMatch(input.IfMatch, utils.VersionETag(existingConfig.Version), existingConfig); err != nil {
+		return nil, err
+	}
+
 	// Validate datasource exists and is active
 	datasource, err := s.datasourceRepo.GetByID(input.DatasourceID)
 	if err != nil {
@@ -151,29 +219,139 @@ func (s *ReportConfigService) Update(id int, input UpdateReportConfigInput) (*mo
 		}
 	}
 
+	if err := ValidateParameterSchema(input.ParameterSchema); err != nil {
+		return nil, fmt.Errorf("invalid parameter schema: %w", err)
+	}
+
+	if err := CheckReportQuery(id, input.ReportQuery, datasource.DbType); err != nil {
+		return nil, err
+	}
+
 	// Update fields
+	previousConfig := *existingConfig
 	existingConfig.ReportName = input.ReportName
 	existingConfig.ReportQuery = input.ReportQuery
 	existingConfig.OutputFormat = input.OutputFormat
 	existingConfig.DatasourceID = input.DatasourceID
 	existingConfig.Parameters = input.Parameters
+	existingConfig.ParameterSchema = input.ParameterSchema
 	existingConfig.TimeoutSeconds = input.TimeoutSeconds
 	existingConfig.MaxRows = input.MaxRows
 	existingConfig.UpdatedBy = input.UpdatedBy
 
-	if err := s.repo.Update(existingConfig); err != nil {
-		return nil, err
+	// A rename or format change leaves the query's cost as it was
+	var costEstimate *QueryCostEstimate
+	if costInputsChanged(&previousConfig, existingConfig) {
+		costEstimate, err = CheckQueryCost(existingConfig, datasource, "", "", s.costService.ScheduledExecutionsPerDay(id), input.CostOverride)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Reload to get incremented version
-	updatedConfig, _ := s.repo.GetByID(id)
+	// The version snapshot is written with the update, so every version has one
+	var updatedConfig *models.ReportConfig
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		txRepo := &repository.ReportConfigRepository{DB: tx}
+		if err := txRepo.Update(existingConfig); err != nil {
+			return err
+		}
+
+		// Reload to get incremented version
+		updatedConfig, err = txRepo.GetByID(id)
+		if err != nil {
+			return err
+		}
+
+		if err := recordConfigVersion(&repository.ReportConfigVersionRepository{DB: tx}, updatedConfig, models.VersionChangeUpdate, nil, input.UpdatedBy); err != nil {
+			return fmt.Errorf("failed to record config version: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			if current, err := s.repo.GetByID(id); err == nil {
+				return nil, versionConflictError(current, current.Version)
+			}
+		}
+		return nil, err
+	}
 
 	// ✅ AUTO-CREATE AUDIT LOG
-	s.auditService.CreateAuditLog(
+	s.auditService.CreateAuditLogWithSummary(
 		&updatedConfig.ID,
 		"update",
 		existingConfig,    // before_value (old config before update)
 		updatedConfig,     // after_value (new config after update)
+		CostOverrideSummary(costEstimate), // change_summary (heavy query saved with cost_override)
+		input.UpdatedBy,
+		input.SessionID,
+		input.IPAddress,
+	)
+
+	return updatedConfig, nil
+}
+
+// ConfigMetadataInput defines input structure for replacing the folder, ownership and tags of a config
+type ConfigMetadataInput struct {
+	FolderID  *int     `json:"folder_id"`
+	OwnerTeam *string  `json:"owner_team" validate:"omitempty,max=100"`
+	Owners    []string `json:"owners" validate:"omitempty,dive,required,max=100"`
+	Tags      []string `json:"tags"` // Names of existing tags
+	UpdatedBy string   `json:"-"`
+	IfMatch   string   `json:"-"` // If-Match header; the change is rejected when it no longer matches the metadata
+	IPAddress *string  `json:"-"` // For audit
+	SessionID *string  `json:"-"` // For audit
+}
+
+// UpdateMetadata replaces the folder, ownership and tags of a config with audit logging.
+// Catalog metadata is not part of the config version; it has its own metadata version and ETag.
+func (s *ReportConfigService) UpdateMetadata(id int, input ConfigMetadataInput) (*models.ReportConfig, error) {
+	existingConfig, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, errors.New("report config not found")
+	}
+	if err := checkIfMatch(input.IfMatch, utils.MetadataETag(existingConfig.MetadataVersion), existingConfig); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkFolder(input.FolderID); err != nil {
+		return nil, err
+	}
+	tagIDs, err := resolveTagNames(s.tagRepo, input.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *existingConfig
+	updated.FolderID = input.FolderID
+	updated.OwnerTeam = input.OwnerTeam
+	updated.Owners = normalizeOwners(input.Owners)
+	updated.Tags = normalizeTagNames(input.Tags)
+	updated.UpdatedBy = input.UpdatedBy
+
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := (&repository.ReportConfigRepository{DB: tx}).UpdateMetadata(&updated); err != nil {
+			return err
+		}
+		return (&repository.ReportTagRepository{DB: tx}).SetConfigTags(id, tagIDsOf(updated.Tags, tagIDs))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			if current, err := s.repo.GetByID(id); err == nil {
+				return nil, metadataConflictError(current)
+			}
+		}
+		return nil, err
+	}
+
+	updatedConfig, _ := s.repo.GetByID(id)
+
+	// ✅ AUTO-CREATE AUDIT LOG
+	s.auditService.CreateAuditLog(
+		&id,
+		"update",
+		existingConfig, // before_value (config before the metadata change)
+		updatedConfig,  // after_value (config after the metadata change)
 		input.UpdatedBy,
 		input.SessionID,
 		input.IPAddress,
@@ -182,6 +360,44 @@ func (s *ReportConfigService) Update(id int, input UpdateReportConfigInput) (*mo
 	return updatedConfig, nil
 }
 
+// checkFolder checks that a config's folder, if any, exists
+func (s *ReportConfigService) checkFolder(folderID *int) error {
+	if folderID == nil {
+		return nil
+	}
+	if _, err := s.folderRepo.GetByID(*folderID); err != nil {
+		return errors.New("folder not found")
+	}
+	return nil
+}
+
+// normalizeOwners trims and de-duplicates owners, keeping their order
+func normalizeOwners(owners []string) models.StringList {
+	if owners == nil {
+		return nil
+	}
+	seen := make(map[string]bool, len(owners))
+	normalized := models.StringList{}
+	for _, owner := range owners {
+		owner = strings.TrimSpace(owner)
+		if owner == "" || seen[owner] {
+			continue
+		}
+		seen[owner] = true
+		normalized = append(normalized, owner)
+	}
+	return normalized
+}
+
+// tagIDsOf maps tag names to their IDs, as resolved by resolveTagNames
+func tagIDsOf(names []string, ids map[string]int) []int {
+	tagIDs := make([]int, 0, len(names))
+	for _, name := range names {
+		tagIDs = append(tagIDs, ids[name])
+	}
+	return tagIDs
+}
+
 // Delete performs soft delete with audit logging
 func (s *ReportConfigService) Delete(id int, deletedBy string, sessionID *string, ipAddress *string) error {
 	// Get existing config for audit
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"scheduling-report/models"
+	"scheduling-report/repositories"
+
+	"gorm.io/gorm"
+)
+
+type ReportConfigVersionService struct {
+	repo           *repository.ReportConfigVersionRepository
+	configRepo     *repository.ReportConfigRepository
+	datasourceRepo *repository.DatasourceRepository
+	auditService   *ReportConfigAuditService
+	costService    *QueryCostService
+}
+
+func NewReportConfigVersionService() *ReportConfigVersionService {
+	return &ReportConfigVersionService{
+		repo:           repository.NewReportConfigVersionRepository(),
+		configRepo:     repository.NewReportConfigRepository(),
+		datasourceRepo: repository.NewDatasourceRepository(),
+		auditService:   NewReportConfigAuditService(),
+		costService:    NewQueryCostService(),
+	}
+}
+
+// recordConfigVersion snapshots config at its current version.
+// Pass a tx-scoped repo to make the snapshot part of the same transaction.
+func recordConfigVersion(repo *repository.ReportConfigVersionRepository, config *models.ReportConfig, changeType string, restoredFrom *int, createdBy string) error {
+	return repo.Create(&models.ReportConfigVersion{
+		ConfigID:     config.ID,
+		Version:      config.Version,
+		Snapshot:     models.NewConfigSnapshot(config),
+		ChangeType:   changeType,
+		RestoredFrom: restoredFrom,
+		CreatedBy:    createdBy,
+	})
+}
+
+// ListVersions retrieves the version history of a config, newest first
+func (s *ReportConfigVersionService) ListVersions(configID int) ([]models.ReportConfigVersion, error) {
+	if _, err := s.configRepo.GetByID(configID); err != nil {
+		return nil, errors.New("report config not found")
+	}
+	return s.repo.GetByConfigID(configID)
+}
+
+// GetVersion retrieves the config as it was at one version
+func (s *ReportConfigVersionService) GetVersion(configID int, version int) (*models.ReportConfigVersion, error) {
+	if _, err := s.configRepo.GetByID(configID); err != nil {
+		return nil, errors.New("report config not found")
+	}
+	configVersion, err := s.repo.GetByVersion(configID, version)
+	if err != nil {
+		return nil, errors.New("version not found")
+	}
+	return configVersion, nil
+}
+
+// VersionFieldChange is one snapshot field that differs between two versions
+type VersionFieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// VersionDiff lists the fields that changed from one version to another
+type VersionDiff struct {
+	ConfigID    int                  `json:"config_id"`
+	FromVersion int                  `json:"from_version"`
+	ToVersion   int                  `json:"to_version"`
+	Changes     []VersionFieldChange `json:"changes"`
+}
+
+// DiffVersions compares the snapshots of two versions field by field
+func (s *ReportConfigVersionService) DiffVersions(configID int, fromVersion int, toVersion int) (*VersionDiff, error) {
+	from, err := s.GetVersion(configID, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.GetVersion(configID, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	fromFields, err := snapshotFields(from.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := snapshotFields(to.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, 0, len(fromFields))
+	for field := range fromFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	diff := &VersionDiff{ConfigID: configID, FromVersion: fromVersion, ToVersion: toVersion, Changes: []VersionFieldChange{}}
+	for _, field := range fields {
+		if !reflect.DeepEqual(fromFields[field], toFields[field]) {
+			diff.Changes = append(diff.Changes, VersionFieldChange{Field: field, From: fromFields[field], To: toFields[field]})
+		}
+	}
+	return diff, nil
+}
+
+// snapshotFields flattens a snapshot to its JSON fields so versions compare the way they are stored
+func snapshotFields(snapshot models.ConfigSnapshot) (map[string]interface{}, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// RestoreVersionInput defines input structure for restoring a config version
+type RestoreVersionInput struct {
+	CostOverride bool    `json:"cost_override"` // Restore even if the query's load score is above the threshold
+	RestoredBy   string  `json:"-"`
+	IPAddress    *string `json:"-"` // For audit
+	SessionID    *string `json:"-"` // For audit
+}
+
+// RestoreVersion copies a version's snapshot onto the config as a new version.
+// The restored content goes through the same checks as an update.
+func (s *ReportConfigVersionService) RestoreVersion(configID int, version int, input RestoreVersionInput) (*models.ReportConfig, error) {
+	existingConfig, err := s.configRepo.GetByID(configID)
+	if err != nil {
+		return nil, errors.New("report config not found")
+	}
+	configVersion, err := s.repo.GetByVersion(configID, version)
+	if err != nil {
+		return nil, errors.New("version not found")
+	}
+	snapshot := configVersion.Snapshot
+
+	datasource, err := s.datasourceRepo.GetByID(snapshot.DatasourceID)
+	if err != nil {
+		return nil, errors.New("datasource not found")
+	}
+	if !datasource.IsActive {
+		return nil, errors.New("datasource is not active")
+	}
+
+	if existingConfig.ReportName != snapshot.ReportName {
+		exists, err := s.configRepo.CheckNameExists(snapshot.ReportName, configID)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, fmt.Errorf("report config with name '%s' already exists", snapshot.ReportName)
+		}
+	}
+
+	if err := ValidateParameterSchema(snapshot.ParameterSchema); err != nil {
+		return nil, fmt.Errorf("invalid parameter schema: %w", err)
+	}
+
+	if err := CheckReportQuery(configID, snapshot.ReportQuery, datasource.DbType); err != nil {
+		return nil, err
+	}
+
+	restoredConfig := *existingConfig
+	restoredConfig.ReportName = snapshot.ReportName
+	restoredConfig.ReportQuery = snapshot.ReportQuery
+	restoredConfig.OutputFormat = snapshot.OutputFormat
+	restoredConfig.DatasourceID = snapshot.DatasourceID
+	restoredConfig.FileName = snapshot.FileName
+	restoredConfig.Parameters = snapshot.Parameters
+	restoredConfig.ParameterSchema = snapshot.ParameterSchema
+	restoredConfig.TimeoutSeconds = snapshot.TimeoutSeconds
+	restoredConfig.MaxRows = snapshot.MaxRows
+	restoredConfig.UpdatedBy = input.RestoredBy
+
+	// Restoring a version that only differs in name, format or file name leaves the query's cost as it was
+	var costEstimate *QueryCostEstimate
+	if costInputsChanged(existingConfig, &restoredConfig) {
+		costEstimate, err = CheckQueryCost(&restoredConfig, datasource, "", "", s.costService.ScheduledExecutionsPerDay(configID), input.CostOverride)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The version snapshot is written with the rollback, so every version has one
+	var updatedConfig *models.ReportConfig
+	err = s.configRepo.DB.Transaction(func(tx *gorm.DB) error {
+		txRepo := &repository.ReportConfigRepository{DB: tx}
+		if err := txRepo.Update(&restoredConfig); err != nil {
+			return err
+		}
+
+		// Reload to get incremented version
+		updatedConfig, err = txRepo.GetByID(configID)
+		if err != nil {
+			return err
+		}
+
+		if err := recordConfigVersion(&repository.ReportConfigVersionRepository{DB: tx}, updatedConfig, models.VersionChangeRollback, &version, input.RestoredBy); err != nil {
+			return fmt.Errorf("failed to record config version: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			if current, err := s.configRepo.GetByID(configID); err == nil {
+				return nil, versionConflictError(current, current.Version)
+			}
+		}
+		return nil, err
+	}
+
+	summary := configAuditEntry{Summary: map[string]interface{}{"restored_from_version": version}, Cost: costEstimate}
+
+	s.auditService.CreateAuditLogWithSummary(
+		&configID,
+		"rollback",
+		existingConfig,
+		updatedConfig,
+		summary.changeSummary(),
+		input.RestoredBy,
+		input.SessionID,
+		input.IPAddress,
+	)
+
+	return updatedConfig, nil
+}
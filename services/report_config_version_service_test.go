@@ -0,0 +1,96 @@
+package services
+
+import (
+	"testing"
+
+	"scheduling-report/models"
+
+	"gorm.io/gorm"
+)
+
+// loadVersions returns the recorded version numbers of a config, oldest first
+func loadVersions(t *testing.T, db *gorm.DB, configID int) []int {
+	t.Helper()
+
+	var versions []int
+	if err := db.Model(&models.ReportConfigVersion{}).Where("config_id = ?", configID).
+		Order("version").Pluck("version", &versions).Error; err != nil {
+		t.Fatalf("load versions: %v", err)
+	}
+	return versions
+}
+
+func TestConfigVersionsRecordedWithEachWrite(t *testing.T) {
+	db := useTestDB(t)
+	reportConfig := seedConfig(t, db)
+	if err := db.Delete(&reportConfig).Error; err != nil {
+		t.Fatalf("clear seeded config: %v", err)
+	}
+
+	// The seeded datasource cannot be reached, so the cost check needs an override
+	configService := NewReportConfigService()
+	created, err := configService.Create(CreateReportConfigInput{
+		ReportName:   "Daily sales",
+		ReportQuery:  "SELECT id, total FROM sales",
+		OutputFormat: "csv",
+		DatasourceID: reportConfig.DatasourceID,
+		CostOverride: true,
+		CreatedBy:    "test",
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if got := loadVersions(t, db, created.ID); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("versions after create = %v, want [1]", got)
+	}
+
+	update := UpdateReportConfigInput{
+		ReportName:   "Daily sales",
+		ReportQuery:  "SELECT id, total, region FROM sales",
+		OutputFormat: "csv",
+		DatasourceID: reportConfig.DatasourceID,
+		CostOverride: true,
+		UpdatedBy:    "test",
+	}
+	updated, err := configService.Update(created.ID, update)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.Version != 2 {
+		t.Fatalf("version after update = %d, want 2", updated.Version)
+	}
+
+	restored, err := NewReportConfigVersionService().RestoreVersion(created.ID, 1, RestoreVersionInput{CostOverride: true, RestoredBy: "test"})
+	if err != nil {
+		t.Fatalf("RestoreVersion: %v", err)
+	}
+	if restored.Version != 3 || restored.ReportQuery != created.ReportQuery {
+		t.Fatalf("restored config = version %d %q, want version 3 %q", restored.Version, restored.ReportQuery, created.ReportQuery)
+	}
+	if got := loadVersions(t, db, created.ID); len(got) != 3 {
+		t.Fatalf("versions after restore = %v, want [1 2 3]", got)
+	}
+
+	// A snapshot that cannot be written rolls the update back with it
+	if err := db.Create(&models.ReportConfigVersion{
+		ConfigID:   created.ID,
+		Version:    4,
+		Snapshot:   models.NewConfigSnapshot(restored),
+		ChangeType: models.VersionChangeUpdate,
+		CreatedBy:  "test",
+	}).Error; err != nil {
+		t.Fatalf("seed conflicting version: %v", err)
+	}
+	update.ReportQuery = "SELECT id FROM sales"
+	if _, err := configService.Update(created.ID, update); err == nil {
+		t.Fatal("Update succeeded although its version snapshot could not be recorded")
+	}
+
+	var stored models.ReportConfig
+	if err := db.First(&stored, created.ID).Error; err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if stored.Version != 3 || stored.ReportQuery != created.ReportQuery {
+		t.Errorf("config after failed update = version %d %q, want version 3 %q", stored.Version, stored.ReportQuery, created.ReportQuery)
+	}
+}
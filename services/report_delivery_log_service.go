@@ -14,8 +14,13 @@ func NewReportDeliveryLogService() *ReportDeliveryLogService {
 	return &ReportDeliveryLogService{repo: repository.NewReportDeliveryLogRepository()}
 }
 
-func (s *ReportDeliveryLogService) GetAll(status *string, limit int) ([]models.ReportDeliveryLog, error) {
-	return s.repo.GetAll(status, limit)
+// List retrieves one page of delivery logs, filtered and sorted by the list query parameters
+func (s *ReportDeliveryLogService) List(params map[string]string) ([]models.ReportDeliveryLog, *repository.PageMeta, error) {
+	q, err := repository.ParseListQuery(params, repository.DeliveryLogListSpec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.repo.List(q)
 }
 
 func (s *ReportDeliveryLogService) GetByID(id int64) (*models.ReportDeliveryLog, error) {
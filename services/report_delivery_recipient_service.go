@@ -4,6 +4,7 @@ import (
 	"errors"
 	"scheduling-report/models"
 	"scheduling-report/repositories"
+	"scheduling-report/utils"
 )
 
 type ReportDeliveryRecipientService struct {
@@ -27,10 +28,16 @@ type UpdateRecipientInput struct {
 	RecipientType   string                 `json:"recipient_type" validate:"required"`
 	RecipientValue  string                 `json:"recipient_value" validate:"required"`
 	RecipientConfig map[string]interface{} `json:"recipient_config"`
+	IfMatch         string                 `json:"-"` // ETag the client last read; empty skips the check
 }
 
-func (s *ReportDeliveryRecipientService) GetAll(isActive *bool) ([]models.ReportDeliveryRecipient, error) {
-	return s.repo.GetAll(isActive)
+// List retrieves one page of recipients, filtered and sorted by the list query parameters
+func (s *ReportDeliveryRecipientService) List(params map[string]string) ([]models.ReportDeliveryRecipient, *repository.PageMeta, error) {
+	q, err := repository.ParseListQuery(params, repository.RecipientListSpec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.repo.List(q)
 }
 
 func (s *ReportDeliveryRecipientService) GetByID(id int) (*models.ReportDeliveryRecipient, error) {
@@ -60,6 +67,7 @@ func (s *ReportDeliveryRecipientService) Create(input CreateRecipientInput) (*mo
 		RecipientValue:  input.RecipientValue,
 		RecipientConfig: input.RecipientConfig,
 		IsActive:        true,
+		Version:         1,
 	}
 
 	if err := s.repo.Create(recipient); err != nil {
@@ -75,11 +83,20 @@ func (s *ReportDeliveryRecipientService) Update(id int, input UpdateRecipientInp
 		return nil, errors.New("recipient not found")
 	}
 
+	if err := checkIfMatch(input.IfMatch, utils.VersionETag(existingRecipient.Version), existingRecipient); err != nil {
+		return nil, err
+	}
+
 	existingRecipient.RecipientType = input.RecipientType
 	existingRecipient.RecipientValue = input.RecipientValue
 	existingRecipient.RecipientConfig = input.RecipientConfig
 
 	if err := s.repo.Update(existingRecipient); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			if current, err := s.repo.GetByID(id); err == nil {
+				return nil, versionConflictError(current, current.Version)
+			}
+		}
 		return nil, err
 	}
 
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"scheduling-report/models"
 	"scheduling-report/repositories"
+	"scheduling-report/utils"
 )
 
 type ReportDeliveryService struct {
@@ -38,12 +39,18 @@ type UpdateDeliveryInput struct {
 	MaxRetry             int                    `json:"max_retry" validate:"min=0,max=10"`
 	RetryIntervalMinutes int                    `json:"retry_interval_minutes" validate:"min=1,max=60"`
 	UpdatedBy            string                 `json:"updated_by"`
+	IfMatch              string                 `json:"-"` // ETag the client last read; empty skips the check
 	SessionID            *string                `json:"session_id"`
 	IPAddress            *string                `json:"ip_address"`
 }
 
-func (s *ReportDeliveryService) GetAll(isActive *bool) ([]models.ReportDelivery, error) {
-	return s.repo.GetAll(isActive)
+// List retrieves one page of deliveries, filtered and sorted by the list query parameters
+func (s *ReportDeliveryService) List(params map[string]string) ([]models.ReportDelivery, *repository.PageMeta, error) {
+	q, err := repository.ParseListQuery(params, repository.DeliveryListSpec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.repo.List(q)
 }
 
 func (s *ReportDeliveryService) GetByID(id int) (*models.ReportDelivery, error) {
@@ -67,6 +74,10 @@ func (s *ReportDeliveryService) Create(input CreateDeliveryInput) (*models.Repor
 		return nil, errors.New("report config not found")
 	}
 
+	if err := utils.ValidateSecretReferences(input.DeliveryConfig); err != nil {
+		return nil, err
+	}
+
 	delivery := &models.ReportDelivery{
 		ConfigID:             input.ConfigID,
 		DeliveryName:         input.DeliveryName,
@@ -77,6 +88,7 @@ func (s *ReportDeliveryService) Create(input CreateDeliveryInput) (*models.Repor
 		IsActive:             true,
 		CreatedBy:            input.CreatedBy,
 		UpdatedBy:            input.CreatedBy,
+		Version:              1,
 	}
 
 	if err := s.repo.Create(delivery); err != nil {
@@ -95,15 +107,27 @@ func (s *ReportDeliveryService) Update(id int, input UpdateDeliveryInput) (*mode
 		return nil, errors.New("delivery not found")
 	}
 
+	if err := checkIfMatch(input.IfMatch, utils.VersionETag(existingDelivery.Version), existingDelivery); err != nil {
+		return nil, err
+	}
+
 	beforeJSON, _ := json.Marshal(existingDelivery)
 	existingDelivery.DeliveryName = input.DeliveryName
 	existingDelivery.Method = input.Method
-	existingDelivery.DeliveryConfig = input.DeliveryConfig
+	existingDelivery.DeliveryConfig = utils.RestoreMaskedSecrets(input.DeliveryConfig, existingDelivery.DeliveryConfig)
+	if err := utils.ValidateSecretReferences(existingDelivery.DeliveryConfig); err != nil {
+		return nil, err
+	}
 	existingDelivery.MaxRetry = input.MaxRetry
 	existingDelivery.RetryIntervalMinutes = input.RetryIntervalMinutes
 	existingDelivery.UpdatedBy = input.UpdatedBy
 
 	if err := s.repo.Update(existingDelivery); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			if current, err := s.repo.GetByID(id); err == nil {
+				return nil, versionConflictError(current, current.Version)
+			}
+		}
 		return nil, err
 	}
 
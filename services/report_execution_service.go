@@ -1,29 +1,34 @@
 package services
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
+	"scheduling-report/config"
 	"scheduling-report/models"
 	"scheduling-report/repositories"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type ReportExecutionService struct {
-	repo         *repository.ReportExecutionRepository
-	configRepo   *repository.ReportConfigRepository
-	scheduleRepo *repository.ReportScheduleRepository
+	repo *repository.ReportExecutionRepository
 }
 
 func NewReportExecutionService() *ReportExecutionService {
 	return &ReportExecutionService{
-		repo:         repository.NewReportExecutionRepository(),
-		configRepo:   repository.NewReportConfigRepository(),
-		scheduleRepo: repository.NewReportScheduleRepository(),
+		repo: repository.NewReportExecutionRepository(),
 	}
 }
 
-func (s *ReportExecutionService) GetAll(status *string, limit int) ([]models.ReportExecution, error) {
-	return s.repo.GetAll(status, limit)
+// List retrieves one page of executions, filtered and sorted by the list query parameters
+func (s *ReportExecutionService) List(params map[string]string) ([]models.ReportExecution, *repository.PageMeta, error) {
+	q, err := repository.ParseListQuery(params, repository.ExecutionListSpec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.repo.List(q)
 }
 
 func (s *ReportExecutionService) GetByID(id string) (*models.ReportExecution, error) {
@@ -38,22 +43,88 @@ func (s *ReportExecutionService) GetByConfigID(configID int, limit int) ([]model
 	return s.repo.GetByConfigID(configID, limit)
 }
 
-// ExecuteAsync creates a queued execution and sends it to Kafka
-func (s *ReportExecutionService) ExecuteAsync(configID int, scheduleID *int, executedBy string) (*models.ReportExecution, error) {
+// ExecuteAsyncInput defines input structure for queuing an execution
+type ExecuteAsyncInput struct {
+	ConfigID   int
+	ScheduleID *int
+	ExecutedBy string
+	// ScheduledAt is the cron occurrence being run; nil for manual runs.
+	// Replayed (misfired) runs carry the original time so the worker rebuilds the missed window.
+	ScheduledAt *time.Time
+	// LastRunAt is the start of the window covered by this run; nil lets the worker derive it from the cron.
+	LastRunAt *time.Time
+	// Parameters override the config's parameter values for this run only
+	Parameters map[string]interface{}
+}
+
+// RejectedExecutionError marks input that can never be queued as given (missing config or schedule,
+// invalid parameters), as opposed to a failure writing the rows. Error() is the plain message.
+type RejectedExecutionError struct {
+	err error
+}
+
+func (e *RejectedExecutionError) Error() string {
+	return e.err.Error()
+}
+
+func (e *RejectedExecutionError) Unwrap() error {
+	return e.err
+}
+
+// ExecuteAsync creates a queued execution and enqueues its Kafka message through the outbox
+func (s *ReportExecutionService) ExecuteAsync(input ExecuteAsyncInput) (*models.ReportExecution, error) {
+	var execution *models.ReportExecution
+
+	// Write execution and its Kafka message atomically; the outbox relay publishes it
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		execution, err = s.ExecuteAsyncTx(tx, input)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notifyOutboxRelay()
+
+	return execution, nil
+}
+
+// ExecuteAsyncTx is ExecuteAsync inside the caller's transaction, so the execution commits or
+// rolls back together with the caller's own writes (the dispatcher's schedule claim).
+// The caller must call notifyOutboxRelay once the transaction has committed.
+func (s *ReportExecutionService) ExecuteAsyncTx(tx *gorm.DB, input ExecuteAsyncInput) (*models.ReportExecution, error) {
+	configID := input.ConfigID
+	scheduleID := input.ScheduleID
+	executedBy := input.ExecutedBy
+
 	// 1. Validate config exists
-	_, err := s.configRepo.GetByID(configID)
+	configRepo := &repository.ReportConfigRepository{DB: tx}
+	reportConfig, err := configRepo.GetByID(configID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &RejectedExecutionError{errors.New("report config not found")}
+	}
 	if err != nil {
 		return nil, errors.New("report config not found")
 	}
 
+	// Overrides are checked now so a bad value fails the request instead of the run
+	if _, err := ResolveReportParameters(reportConfig.ParameterSchema, reportConfig.Parameters, input.Parameters); err != nil {
+		return nil, &RejectedExecutionError{fmt.Errorf("invalid parameters: %w", err)}
+	}
+
 	// 2. Validate schedule if provided
 	if scheduleID != nil {
-		schedule, err := s.scheduleRepo.GetByID(*scheduleID)
+		scheduleRepo := &repository.ReportScheduleRepository{DB: tx}
+		schedule, err := scheduleRepo.GetByID(*scheduleID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &RejectedExecutionError{errors.New("schedule not found")}
+		}
 		if err != nil {
 			return nil, errors.New("schedule not found")
 		}
 		if schedule.ConfigID != configID {
-			return nil, errors.New("schedule does not belong to the specified config")
+			return nil, &RejectedExecutionError{errors.New("schedule does not belong to the specified config")}
 		}
 	}
 
@@ -61,25 +132,7 @@ func (s *ReportExecutionService) ExecuteAsync(configID int, scheduleID *int, exe
 	executionID := uuid.New().String()
 	now := time.Now()
 
-	execution := &models.ReportExecution{
-		ID:         executionID,
-		ConfigID:   configID,
-		ScheduleID: scheduleID,
-		Status:     "queued",
-		StartedAt:  now,
-		ExecutedBy: executedBy,
-	}
-
-	if err := s.repo.Create(execution); err != nil {
-		return nil, errors.New("failed to create execution record")
-	}
-
-	// 4. Produce message to Kafka
-	kafkaProducer := GetKafkaProducer()
-	if kafkaProducer == nil {
-		return nil, errors.New("kafka producer not initialized")
-	}
-
+	executionContext := models.ExecutionContext{}
 	executionReq := ExecutionRequest{
 		ExecutionID: executionID,
 		ConfigID:    configID,
@@ -87,9 +140,50 @@ func (s *ReportExecutionService) ExecuteAsync(configID int, scheduleID *int, exe
 		ExecutedBy:  executedBy,
 		QueuedAt:    now.Format(time.RFC3339),
 	}
+	if input.ScheduledAt != nil {
+		executionReq.ScheduledAt = input.ScheduledAt.Format(time.RFC3339)
+		executionContext["scheduled_at"] = executionReq.ScheduledAt
+	}
+	if input.LastRunAt != nil {
+		executionReq.LastRunAt = input.LastRunAt.Format(time.RFC3339)
+		executionContext["last_run_at"] = executionReq.LastRunAt
+	}
+	if len(input.Parameters) > 0 {
+		executionReq.Parameters = input.Parameters
+		executionContext["parameters"] = input.Parameters
+	}
+
+	execution := &models.ReportExecution{
+		ID:               executionID,
+		ConfigID:         configID,
+		ScheduleID:       scheduleID,
+		Status:           "queued",
+		StartedAt:        now,
+		ExecutedBy:       executedBy,
+		ExecutionContext: executionContext,
+	}
+
+	messageJSON, err := json.Marshal(executionReq)
+	if err != nil {
+		return nil, errors.New("failed to build execution request")
+	}
+
+	// 4. Write execution and its Kafka message with the caller's transaction
+	executionRepo := &repository.ReportExecutionRepository{DB: tx}
+	if err := executionRepo.Create(execution); err != nil {
+		return nil, errors.New("failed to create execution record")
+	}
 
-	if err := kafkaProducer.ProduceExecutionRequest(executionReq); err != nil {
-		return nil, errors.New("failed to produce message to kafka")
+	outboxRepo := &repository.ExecutionOutboxRepository{DB: tx}
+	if err := outboxRepo.Create(&models.ExecutionOutbox{
+		ExecutionID:   executionID,
+		Topic:         ExecutionRequestTopic(),
+		MessageKey:    executionID,
+		Payload:       string(messageJSON),
+		Status:        models.OutboxStatusPending,
+		NextAttemptAt: now,
+	}); err != nil {
+		return nil, errors.New("failed to enqueue execution request")
 	}
 
 	// 5. Return execution with queued status
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"scheduling-report/models"
+)
+
+func TestExecuteAsyncRejectsInvalidInput(t *testing.T) {
+	db := useTestDB(t)
+	reportConfig := seedConfig(t, db)
+	missingSchedule := 999
+
+	tests := []struct {
+		name  string
+		input ExecuteAsyncInput
+	}{
+		{name: "unknown config", input: ExecuteAsyncInput{ConfigID: reportConfig.ID + 1}},
+		{name: "unknown parameter", input: ExecuteAsyncInput{ConfigID: reportConfig.ID, Parameters: map[string]interface{}{"region": "EU"}}},
+		{name: "unknown schedule", input: ExecuteAsyncInput{ConfigID: reportConfig.ID, ScheduleID: &missingSchedule}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input.ExecutedBy = "test"
+			_, err := NewReportExecutionService().ExecuteAsync(tt.input)
+			var rejected *RejectedExecutionError
+			if !errors.As(err, &rejected) {
+				t.Fatalf("ExecuteAsync = %v, want a RejectedExecutionError", err)
+			}
+		})
+	}
+
+	var count int64
+	db.Model(&models.ReportExecution{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d executions queued, want none", count)
+	}
+}
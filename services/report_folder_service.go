@@ -0,0 +1,158 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"scheduling-report/models"
+	"scheduling-report/repositories"
+)
+
+type ReportFolderService struct {
+	repo         *repository.ReportFolderRepository
+	auditService *ReportConfigAuditService
+}
+
+func NewReportFolderService() *ReportFolderService {
+	return &ReportFolderService{
+		repo:         repository.NewReportFolderRepository(),
+		auditService: NewReportConfigAuditService(),
+	}
+}
+
+// FolderInput defines input structure for creating or updating a folder; without ParentID it is a root folder
+type FolderInput struct {
+	Name        string  `json:"name" validate:"required,max=100,excludes=/"`
+	ParentID    *int    `json:"parent_id"`
+	Description *string `json:"description"`
+	PerformedBy string  `json:"-"`
+	IPAddress   *string `json:"-"` // For audit
+	SessionID   *string `json:"-"` // For audit
+}
+
+// List retrieves one page of folders, filtered and sorted by the list query parameters
+func (s *ReportFolderService) List(params map[string]string) ([]models.ReportFolder, *repository.PageMeta, error) {
+	q, err := repository.ParseListQuery(params, repository.ReportFolderListSpec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.repo.List(q)
+}
+
+// GetByID retrieves a single folder
+func (s *ReportFolderService) GetByID(id int) (*models.ReportFolder, error) {
+	folder, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, errors.New("folder not found")
+	}
+	return folder, nil
+}
+
+// folderPath is the path of a folder named name under parentID
+func (s *ReportFolderService) folderPath(name string, parentID *int) (string, error) {
+	if parentID == nil {
+		return name, nil
+	}
+	parent, err := s.repo.GetByID(*parentID)
+	if err != nil {
+		return "", errors.New("parent folder not found")
+	}
+	return parent.Path + "/" + name, nil
+}
+
+// Create creates a new folder with audit logging
+func (s *ReportFolderService) Create(input FolderInput) (*models.ReportFolder, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	path, err := s.folderPath(input.Name, input.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := s.repo.CheckPathExists(path, 0)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("folder '%s' already exists", path)
+	}
+
+	folder := &models.ReportFolder{
+		Name:        input.Name,
+		ParentID:    input.ParentID,
+		Path:        path,
+		Description: input.Description,
+		CreatedBy:   input.PerformedBy,
+		UpdatedBy:   input.PerformedBy,
+	}
+	if err := s.repo.Create(folder); err != nil {
+		return nil, err
+	}
+
+	s.auditService.CreateAuditLogWithSummary(nil, "create", nil, folder, catalogSummary("folder"), input.PerformedBy, input.SessionID, input.IPAddress)
+
+	return folder, nil
+}
+
+// Update renames or moves a folder with audit logging; its subfolders move along
+func (s *ReportFolderService) Update(id int, input FolderInput) (*models.ReportFolder, error) {
+	existing, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, errors.New("folder not found")
+	}
+	before := *existing
+
+	input.Name = strings.TrimSpace(input.Name)
+	path, err := s.folderPath(input.Name, input.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	if input.ParentID != nil && (*input.ParentID == id || strings.HasPrefix(path, existing.Path+"/")) {
+		return nil, errors.New("folder cannot be moved into itself or one of its subfolders")
+	}
+	if path != existing.Path {
+		exists, err := s.repo.CheckPathExists(path, id)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, fmt.Errorf("folder '%s' already exists", path)
+		}
+	}
+
+	existing.Name = input.Name
+	existing.ParentID = input.ParentID
+	existing.Path = path
+	existing.Description = input.Description
+	existing.UpdatedBy = input.PerformedBy
+	if err := s.repo.Update(existing, before.Path); err != nil {
+		return nil, err
+	}
+
+	s.auditService.CreateAuditLogWithSummary(nil, "update", before, existing, catalogSummary("folder"), input.PerformedBy, input.SessionID, input.IPAddress)
+
+	return existing, nil
+}
+
+// Delete deletes an empty folder with audit logging
+func (s *ReportFolderService) Delete(id int, deletedBy string, sessionID *string, ipAddress *string) error {
+	existing, err := s.repo.GetByID(id)
+	if err != nil {
+		return errors.New("folder not found")
+	}
+
+	folders, configs, err := s.repo.CountChildren(id)
+	if err != nil {
+		return err
+	}
+	if folders > 0 || configs > 0 {
+		return fmt.Errorf("folder is not empty: it has %d subfolders and %d report configs", folders, configs)
+	}
+
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	s.auditService.CreateAuditLogWithSummary(nil, "delete", existing, nil, catalogSummary("folder"), deletedBy, sessionID, ipAddress)
+
+	return nil
+}
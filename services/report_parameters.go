@@ -0,0 +1,163 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+
+	"scheduling-report/models"
+	"scheduling-report/utils"
+)
+
+var parameterNamePattern = regexp.MustCompile(`^\w+$`)
+
+// scalarParameterTypes maps declared scalar types to the query template types that check them
+var scalarParameterTypes = map[string]utils.QueryParamType{
+	models.ParameterTypeDate:     utils.QueryParamDate,
+	models.ParameterTypeDatetime: utils.QueryParamDatetime,
+	models.ParameterTypeInt:      utils.QueryParamInt,
+	models.ParameterTypeString:   utils.QueryParamString,
+}
+
+// ValidateParameterSchema checks parameter definitions before a config is saved
+func ValidateParameterSchema(schema models.ParameterSchema) error {
+	seen := map[string]bool{}
+	for _, definition := range schema {
+		if !parameterNamePattern.MatchString(definition.Name) {
+			return fmt.Errorf("invalid parameter name '%s'", definition.Name)
+		}
+		if seen[definition.Name] {
+			return fmt.Errorf("duplicate parameter '%s'", definition.Name)
+		}
+		seen[definition.Name] = true
+
+		if utils.IsTemplateVariable(definition.Name) {
+			return fmt.Errorf("parameter '%s' conflicts with a built-in template variable", definition.Name)
+		}
+
+		switch definition.Type {
+		case models.ParameterTypeEnum:
+			if len(definition.Options) == 0 {
+				return fmt.Errorf("enum parameter '%s' requires options", definition.Name)
+			}
+		case models.ParameterTypeList:
+			if definition.ItemType != "" {
+				if _, ok := scalarParameterTypes[definition.ItemType]; !ok {
+					return fmt.Errorf("list parameter '%s' has invalid item_type '%s'", definition.Name, definition.ItemType)
+				}
+			}
+		default:
+			if _, ok := scalarParameterTypes[definition.Type]; !ok {
+				return fmt.Errorf("parameter '%s' has invalid type '%s'", definition.Name, definition.Type)
+			}
+		}
+
+		if definition.Default != nil {
+			if _, err := coerceParameter(definition, definition.Default); err != nil {
+				return fmt.Errorf("invalid default: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveReportParameters merges a run's parameter values in precedence order
+// overrides > config values > schema defaults and checks them against the schema.
+// Config values without a definition pass through untyped for older configs.
+func ResolveReportParameters(schema models.ParameterSchema, configValues models.Parameters, overrides map[string]interface{}) (map[string]interface{}, error) {
+	definitions := map[string]models.ParameterDefinition{}
+	for _, definition := range schema {
+		definitions[definition.Name] = definition
+	}
+
+	for name := range overrides {
+		if _, declared := definitions[name]; !declared {
+			return nil, fmt.Errorf("unknown parameter '%s'", name)
+		}
+	}
+
+	resolved := map[string]interface{}{}
+	for name, value := range configValues {
+		if _, declared := definitions[name]; !declared {
+			resolved[name] = value
+		}
+	}
+
+	for _, definition := range schema {
+		value := definition.Default
+		if configValue, ok := configValues[definition.Name]; ok && configValue != nil {
+			value = configValue
+		}
+		if override, ok := overrides[definition.Name]; ok && override != nil {
+			value = override
+		}
+
+		if value == nil {
+			if definition.Required {
+				return nil, fmt.Errorf("parameter '%s' is required", definition.Name)
+			}
+			continue
+		}
+
+		coerced, err := coerceParameter(definition, value)
+		if err != nil {
+			return nil, err
+		}
+		resolved[definition.Name] = coerced
+	}
+
+	return resolved, nil
+}
+
+// ParameterTypes returns the query template types for a schema, used when rendering
+func ParameterTypes(schema models.ParameterSchema) map[string]utils.QueryParamType {
+	types := map[string]utils.QueryParamType{}
+	for _, definition := range schema {
+		switch definition.Type {
+		case models.ParameterTypeEnum:
+			types[definition.Name] = utils.QueryParamEnum
+		case models.ParameterTypeList:
+			types[definition.Name] = utils.QueryParamList
+		default:
+			types[definition.Name] = scalarParameterTypes[definition.Type]
+		}
+	}
+	return types
+}
+
+// coerceParameter checks one value against its definition and converts it for binding
+func coerceParameter(definition models.ParameterDefinition, value interface{}) (interface{}, error) {
+	switch definition.Type {
+	case models.ParameterTypeEnum:
+		for _, option := range definition.Options {
+			if fmt.Sprint(option) == fmt.Sprint(value) {
+				return option, nil
+			}
+		}
+		return nil, fmt.Errorf("parameter '%s' must be one of %v", definition.Name, definition.Options)
+
+	case models.ParameterTypeList:
+		list, err := utils.CoerceQueryParam(definition.Name, utils.QueryParamList, value)
+		if err != nil {
+			return nil, err
+		}
+		itemType := utils.QueryParamString
+		if definition.ItemType != "" {
+			itemType = scalarParameterTypes[definition.ItemType]
+		}
+		items := list.([]interface{})
+		coerced := make([]interface{}, len(items))
+		for i, item := range items {
+			if coerced[i], err = utils.CoerceQueryParam(definition.Name, itemType, item); err != nil {
+				return nil, fmt.Errorf("parameter '%s' items must be of type %s", definition.Name, itemType)
+			}
+		}
+		return coerced, nil
+
+	default:
+		paramType, ok := scalarParameterTypes[definition.Type]
+		if !ok {
+			return nil, fmt.Errorf("parameter '%s' has invalid type '%s'", definition.Name, definition.Type)
+		}
+		return utils.CoerceQueryParam(definition.Name, paramType, value)
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// QueryResult holds the rows a report query returned, in column order
+type QueryResult struct {
+	Columns []string
+	// ColumnTypes are the database type names of Columns, as reported by the driver
+	ColumnTypes []string
+	Rows        [][]interface{}
+	// Truncated is set when rows beyond the cap were discarded
+	Truncated bool
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// RunReportQuery executes a report query in a read-only transaction and reads at most maxRows rows.
+// Exceeding maxRows is an error rather than a silent truncation; ctx carries the timeout.
+func RunReportQuery(ctx context.Context, db *sql.DB, dbType string, query string, args []interface{}, maxRows int) (*QueryResult, error) {
+	return runReadOnlyQuery(ctx, db, dbType, query, args, maxRows, false)
+}
+
+// runReadOnlyQuery runs the query in a transaction that is rolled back whatever it did.
+// SQL Server has no read-only transaction mode, so there the rollback is the only guard.
+func runReadOnlyQuery(ctx context.Context, db *sql.DB, dbType string, query string, args []interface{}, maxRows int, truncate bool) (*QueryResult, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: dbType != "sqlserver"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start read-only transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	return runQuery(ctx, tx, query, args, maxRows, truncate)
+}
+
+// runQuery reads at most maxRows rows; past the cap it either fails or, with truncate, stops reading
+func runQuery(ctx context.Context, q queryer, query string, args []interface{}, maxRows int, truncate bool) (*QueryResult, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+
+	result := &QueryResult{Columns: columns, ColumnTypes: make([]string, len(columns))}
+	if columnTypes, err := rows.ColumnTypes(); err == nil {
+		for i, columnType := range columnTypes {
+			result.ColumnTypes[i] = columnType.DatabaseTypeName()
+		}
+	}
+
+	for rows.Next() {
+		if maxRows > 0 && len(result.Rows) >= maxRows {
+			if truncate {
+				result.Truncated = true
+				break
+			}
+			return nil, fmt.Errorf("query returned more than max_rows (%d) rows", maxRows)
+		}
+
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		for i, value := range values {
+			values[i] = normalizeQueryValue(value)
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return result, nil
+}
+
+// normalizeQueryValue converts driver values into types every writer can format
+func normalizeQueryValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05")
+	default:
+		return v
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"scheduling-report/models"
 	"scheduling-report/repositories"
+	"scheduling-report/utils"
+	"time"
 
 	"github.com/robfig/cron/v3"
 )
@@ -25,6 +27,8 @@ type CreateScheduleInput struct {
 	ConfigID       int     `json:"config_id" validate:"required"`
 	CronExpression string  `json:"cron_expression" validate:"required,min=9"`
 	Timezone       string  `json:"timezone" validate:"required"`
+	MisfirePolicy  string  `json:"misfire_policy" validate:"omitempty,oneof=skip run_once run_all"`
+	MisfireMaxRuns int     `json:"misfire_max_runs" validate:"omitempty,min=1,max=1000"`
 	CreatedBy      string  `json:"created_by"`
 	SessionID      *string `json:"session_id"`
 	IPAddress      *string `json:"ip_address"`
@@ -33,14 +37,21 @@ type CreateScheduleInput struct {
 type UpdateScheduleInput struct {
 	CronExpression string  `json:"cron_expression" validate:"required,min=9"`
 	Timezone       string  `json:"timezone" validate:"required"`
+	MisfirePolicy  string  `json:"misfire_policy" validate:"omitempty,oneof=skip run_once run_all"`
+	MisfireMaxRuns int     `json:"misfire_max_runs" validate:"omitempty,min=1,max=1000"`
 	UpdatedBy      string  `json:"updated_by"`
+	IfMatch        string  `json:"-"` // ETag the client last read; empty skips the check
 	SessionID      *string `json:"session_id"`
 	IPAddress      *string `json:"ip_address"`
 }
 
-// GetAll retrieves all schedules
-func (s *ReportScheduleService) GetAll(isActive *bool) ([]models.ReportSchedule, error) {
-	return s.repo.GetAll(isActive)
+// List retrieves one page of schedules, filtered and sorted by the list query parameters
+func (s *ReportScheduleService) List(params map[string]string) ([]models.ReportSchedule, *repository.PageMeta, error) {
+	q, err := repository.ParseListQuery(params, repository.ScheduleListSpec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.repo.List(q)
 }
 
 // GetByID retrieves a schedule by ID
@@ -83,13 +94,31 @@ func (s *ReportScheduleService) Create(input CreateScheduleInput) (*models.Repor
 		return nil, errors.New("report config not found")
 	}
 
+	// Calculate first run in the schedule's timezone
+	nextRunAt, err := utils.CalculateNextRun(input.CronExpression, input.Timezone, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// Set misfire defaults if not provided
+	if input.MisfirePolicy == "" {
+		input.MisfirePolicy = models.MisfirePolicyRunOnce
+	}
+	if input.MisfireMaxRuns == 0 {
+		input.MisfireMaxRuns = 10
+	}
+
 	schedule := &models.ReportSchedule{
 		ConfigID:       input.ConfigID,
 		CronExpression: input.CronExpression,
 		Timezone:       input.Timezone,
 		IsActive:       true,
+		NextRunAt:      &models.CustomTime{Time: nextRunAt},
+		MisfirePolicy:  input.MisfirePolicy,
+		MisfireMaxRuns: input.MisfireMaxRuns,
 		CreatedBy:      input.CreatedBy,
 		UpdatedBy:      input.CreatedBy,
+		Version:        1,
 	}
 
 	if err := s.repo.Create(schedule); err != nil {
@@ -124,16 +153,38 @@ func (s *ReportScheduleService) Update(id int, input UpdateScheduleInput) (*mode
 		return nil, errors.New("schedule not found")
 	}
 
+	if err := checkIfMatch(input.IfMatch, utils.VersionETag(existingSchedule.Version), existingSchedule); err != nil {
+		return nil, err
+	}
+
 	// Capture before state
 	beforeJSON, _ := json.Marshal(existingSchedule)
 	beforeValue := string(beforeJSON)
 
+	// Recalculate next run in the schedule's timezone
+	nextRunAt, err := utils.CalculateNextRun(input.CronExpression, input.Timezone, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	// Update fields
 	existingSchedule.CronExpression = input.CronExpression
 	existingSchedule.Timezone = input.Timezone
+	existingSchedule.NextRunAt = &models.CustomTime{Time: nextRunAt}
+	if input.MisfirePolicy != "" {
+		existingSchedule.MisfirePolicy = input.MisfirePolicy
+	}
+	if input.MisfireMaxRuns != 0 {
+		existingSchedule.MisfireMaxRuns = input.MisfireMaxRuns
+	}
 	existingSchedule.UpdatedBy = input.UpdatedBy
 
 	if err := s.repo.Update(existingSchedule); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			if current, err := s.repo.GetByID(id); err == nil {
+				return nil, versionConflictError(current, current.Version)
+			}
+		}
 		return nil, err
 	}
 
@@ -184,16 +235,25 @@ func (s *ReportScheduleService) Delete(id int, deletedBy string, sessionID *stri
 	return nil
 }
 
-// GetSchedulesWithDetails retrieves schedules with full config and delivery details
-func (s *ReportScheduleService) GetSchedulesWithDetails(filters models.ScheduleDetailFilters) ([]models.ScheduleDetail, string, string) {
-	details, err := s.repo.GetSchedulesWithDetails(filters)
+// GetSchedulesWithDetails retrieves one page of schedules with full config and delivery details
+func (s *ReportScheduleService) GetSchedulesWithDetails(filters models.ScheduleDetailFilters, params map[string]string) ([]models.ScheduleDetail, *repository.PageMeta, string, error) {
+	q, err := repository.ParseListQuery(params, repository.ScheduleListSpec)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	details, meta, err := s.repo.GetSchedulesWithDetails(filters, q)
 	if err != nil {
-		return nil, "50003100", "Failed to retrieve schedule details"
+		var queryErr *repository.ListQueryError
+		if errors.As(err, &queryErr) {
+			return nil, nil, "", err
+		}
+		return nil, nil, "", errors.New("failed to retrieve schedule details")
 	}
 
 	if len(details) == 0 {
-		return []models.ScheduleDetail{}, "20003100", "No schedules found matching the filters"
+		return []models.ScheduleDetail{}, meta, "No schedules found matching the filters", nil
 	}
 
-	return details, "20003100", "Schedule details retrieved successfully"
+	return details, meta, "Schedule details retrieved successfully", nil
 }
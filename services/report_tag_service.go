@@ -0,0 +1,311 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"scheduling-report/models"
+	"scheduling-report/repositories"
+
+	"gorm.io/gorm"
+)
+
+type ReportTagService struct {
+	repo         *repository.ReportTagRepository
+	configRepo   *repository.ReportConfigRepository
+	auditService *ReportConfigAuditService
+}
+
+func NewReportTagService() *ReportTagService {
+	return &ReportTagService{
+		repo:         repository.NewReportTagRepository(),
+		configRepo:   repository.NewReportConfigRepository(),
+		auditService: NewReportConfigAuditService(),
+	}
+}
+
+// TagInput defines input structure for creating or updating a tag
+type TagInput struct {
+	Name        string  `json:"name" validate:"required,max=50,excludesall=0x2C"`
+	Color       *string `json:"color" validate:"omitempty,max=20"`
+	Description *string `json:"description"`
+	PerformedBy string  `json:"-"`
+	IPAddress   *string `json:"-"` // For audit
+	SessionID   *string `json:"-"` // For audit
+}
+
+// catalogSummary is the change_summary of audits on folders and tags, which belong to no config
+func catalogSummary(entity string) *string {
+	summary, _ := json.Marshal(map[string]interface{}{"entity": entity})
+	value := string(summary)
+	return &value
+}
+
+// List retrieves one page of tags, filtered and sorted by the list query parameters
+func (s *ReportTagService) List(params map[string]string) ([]models.ReportTag, *repository.PageMeta, error) {
+	q, err := repository.ParseListQuery(params, repository.ReportTagListSpec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.repo.List(q)
+}
+
+// GetByID retrieves a single tag
+func (s *ReportTagService) GetByID(id int) (*models.ReportTag, error) {
+	tag, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, errors.New("tag not found")
+	}
+	return tag, nil
+}
+
+// Create creates a new tag with audit logging
+func (s *ReportTagService) Create(input TagInput) (*models.ReportTag, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	exists, err := s.repo.CheckNameExists(input.Name, 0)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("tag '%s' already exists", input.Name)
+	}
+
+	tag := &models.ReportTag{
+		Name:        input.Name,
+		Color:       input.Color,
+		Description: input.Description,
+		CreatedBy:   input.PerformedBy,
+		UpdatedBy:   input.PerformedBy,
+	}
+	if err := s.repo.Create(tag); err != nil {
+		return nil, err
+	}
+
+	s.auditService.CreateAuditLogWithSummary(nil, "create", nil, tag, catalogSummary("tag"), input.PerformedBy, input.SessionID, input.IPAddress)
+
+	return tag, nil
+}
+
+// Update renames or re-describes a tag with audit logging; tagged configs follow the rename
+func (s *ReportTagService) Update(id int, input TagInput) (*models.ReportTag, error) {
+	existing, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, errors.New("tag not found")
+	}
+	before := *existing
+
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name != existing.Name {
+		exists, err := s.repo.CheckNameExists(input.Name, id)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, fmt.Errorf("tag '%s' already exists", input.Name)
+		}
+	}
+
+	existing.Name = input.Name
+	existing.Color = input.Color
+	existing.Description = input.Description
+	existing.UpdatedBy = input.PerformedBy
+	if err := s.repo.Update(existing); err != nil {
+		return nil, err
+	}
+
+	s.auditService.CreateAuditLogWithSummary(nil, "update", before, existing, catalogSummary("tag"), input.PerformedBy, input.SessionID, input.IPAddress)
+
+	return existing, nil
+}
+
+// Delete removes a tag from every config and deletes it, auditing the tag and each untagged config
+func (s *ReportTagService) Delete(id int, deletedBy string, sessionID *string, ipAddress *string) error {
+	existing, err := s.repo.GetByID(id)
+	if err != nil {
+		return errors.New("tag not found")
+	}
+
+	configIDs, err := s.repo.GetConfigIDs(id)
+	if err != nil {
+		return err
+	}
+	before, err := s.repo.GetNamesByConfigIDs(configIDs)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	s.auditService.CreateAuditLogWithSummary(nil, "delete", existing, nil, catalogSummary("tag"), deletedBy, sessionID, ipAddress)
+	for _, configID := range configIDs {
+		s.auditTags(configID, before[configID], removeTagNames(before[configID], []string{existing.Name}), deletedBy, sessionID, ipAddress)
+	}
+
+	return nil
+}
+
+// BulkRetag sets, adds or removes tags on many configs at once, in one transaction.
+// Every config whose tags change gets its own audit record.
+func (s *ReportTagService) BulkRetag(req models.BulkRetagRequest, sessionID *string, ipAddress *string) ([]models.BulkRetagResult, error) {
+	if req.Set != nil && (len(req.Add) > 0 || len(req.Remove) > 0) {
+		return nil, errors.New("set cannot be combined with add or remove")
+	}
+	if req.Set == nil && len(req.Add) == 0 && len(req.Remove) == 0 {
+		return nil, errors.New("nothing to change: give set, or add and/or remove")
+	}
+
+	// Every named tag must exist
+	var names []string
+	if req.Set != nil {
+		names = append(names, *req.Set...)
+	}
+	names = append(names, req.Add...)
+	names = append(names, req.Remove...)
+	tagIDs, err := resolveTagNames(s.repo, names)
+	if err != nil {
+		return nil, err
+	}
+
+	configIDs := uniqueInts(req.ConfigIDs)
+	count, err := s.configRepo.CountByIDs(configIDs)
+	if err != nil {
+		return nil, err
+	}
+	if int(count) != len(configIDs) {
+		return nil, errors.New("one or more report configs not found")
+	}
+
+	before, err := s.repo.GetNamesByConfigIDs(configIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.BulkRetagResult, 0, len(configIDs))
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		txTagRepo := &repository.ReportTagRepository{DB: tx}
+		var changed []int
+		for _, configID := range configIDs {
+			current := before[configID]
+			var after []string
+			if req.Set != nil {
+				after = normalizeTagNames(*req.Set)
+			} else {
+				after = removeTagNames(normalizeTagNames(append(append([]string{}, current...), req.Add...)), req.Remove)
+			}
+
+			result := models.BulkRetagResult{ConfigID: configID, Before: nonNilTags(current), After: after, Changed: !equalTags(current, after)}
+			if result.Changed {
+				if err := txTagRepo.SetConfigTags(configID, tagIDsOf(after, tagIDs)); err != nil {
+					return err
+				}
+				changed = append(changed, configID)
+			}
+			results = append(results, result)
+		}
+		return (&repository.ReportConfigRepository{DB: tx}).BumpMetadataVersions(changed)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		if result.Changed {
+			s.auditTags(result.ConfigID, result.Before, result.After, req.PerformedBy, sessionID, ipAddress)
+		}
+	}
+
+	return results, nil
+}
+
+// auditTags records a change of a config's tags as an update of its tags field
+func (s *ReportTagService) auditTags(configID int, before []string, after []string, performedBy string, sessionID *string, ipAddress *string) {
+	beforeJSON, _ := json.Marshal(nonNilTags(before))
+	afterJSON, _ := json.Marshal(nonNilTags(after))
+	s.auditService.CreateAuditLogWithFieldChange(&configID, "update", "tags", string(beforeJSON), string(afterJSON), performedBy, sessionID, ipAddress)
+}
+
+// resolveTagNames looks up tags by name, returning their IDs by name; every name must exist
+func resolveTagNames(repo *repository.ReportTagRepository, names []string) (map[string]int, error) {
+	names = normalizeTagNames(names)
+	tags, err := repo.GetByNames(names)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]int, len(tags))
+	for _, tag := range tags {
+		ids[tag.Name] = tag.ID
+	}
+	for _, name := range names {
+		if _, ok := ids[name]; !ok {
+			return nil, fmt.Errorf("tag '%s' not found", name)
+		}
+	}
+	return ids, nil
+}
+
+// normalizeTagNames trims, de-duplicates and sorts tag names
+func normalizeTagNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	normalized := []string{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		normalized = append(normalized, name)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+func removeTagNames(names []string, remove []string) []string {
+	removed := make(map[string]bool, len(remove))
+	for _, name := range remove {
+		removed[strings.TrimSpace(name)] = true
+	}
+	kept := []string{}
+	for _, name := range names {
+		if !removed[name] {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
+
+func equalTags(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func nonNilTags(names []string) []string {
+	if names == nil {
+		return []string{}
+	}
+	return names
+}
+
+func uniqueInts(values []int) []int {
+	seen := make(map[int]bool, len(values))
+	unique := make([]int, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"scheduling-report/config"
+	"scheduling-report/models"
+	"scheduling-report/repositories"
+	"scheduling-report/utils"
+
+	"github.com/IBM/sarama"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultReportTimeoutSeconds = 300
+	resultApplyAttempts         = 3
+)
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// WorkerConsumerGroup returns the consumer group report workers share
+func WorkerConsumerGroup() string {
+	if group := viper.GetString("KAFKA_CONSUMER_GROUP_WORKER"); group != "" {
+		return group
+	}
+	return "scheduling-report-worker"
+}
+
+// ReportWorker consumes execution requests, runs the report query against its
+// datasource and writes the output file. Results are applied directly through
+// ExecutionResultService since the worker shares the API database.
+type ReportWorker struct {
+	executionRepo  *repository.ReportExecutionRepository
+	configRepo     *repository.ReportConfigRepository
+	datasourceRepo *repository.DatasourceRepository
+	scheduleRepo   *repository.ReportScheduleRepository
+	resultService  *ExecutionResultService
+	outputDir      string
+}
+
+func NewReportWorker() *ReportWorker {
+	return &ReportWorker{
+		executionRepo:  repository.NewReportExecutionRepository(),
+		configRepo:     repository.NewReportConfigRepository(),
+		datasourceRepo: repository.NewDatasourceRepository(),
+		scheduleRepo:   repository.NewReportScheduleRepository(),
+		resultService:  NewExecutionResultService(),
+		outputDir:      config.Config.WorkerOutputDir,
+	}
+}
+
+// HandleMessage is the Kafka handler for the execution-requests topic
+func (w *ReportWorker) HandleMessage(message *sarama.ConsumerMessage) error {
+	var request ExecutionRequest
+	if err := json.Unmarshal(message.Value, &request); err != nil {
+		log.Error().Err(err).Int64("offset", message.Offset).Msg("Invalid execution request payload, skipping")
+		return nil
+	}
+
+	// Claim the execution; redelivered requests find it already running or finished
+	claimed, err := w.executionRepo.TransitionStatus(request.ExecutionID, []string{ExecutionStatusQueued}, map[string]interface{}{
+		"status":     ExecutionStatusRunning,
+		"started_at": time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to claim execution: %w", err)
+	}
+	if !claimed {
+		log.Info().Str("execution_id", request.ExecutionID).Msg("Execution is no longer queued, skipping")
+		return nil
+	}
+
+	log.Info().
+		Str("execution_id", request.ExecutionID).
+		Int("config_id", request.ConfigID).
+		Msg("Running report execution")
+
+	result := w.run(request)
+
+	// The execution is already claimed, so a redelivery cannot retry this step
+	for attempt := 1; ; attempt++ {
+		err := w.resultService.ApplyResult(result)
+		if err == nil {
+			break
+		}
+		if attempt >= resultApplyAttempts {
+			log.Error().Err(err).Str("execution_id", request.ExecutionID).Msg("Failed to record execution result")
+			return nil
+		}
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+
+	log.Info().
+		Str("execution_id", request.ExecutionID).
+		Str("status", result.Status).
+		Msg("Report execution finished")
+
+	return nil
+}
+
+// run executes the report and describes the outcome as an ExecutionResult
+func (w *ReportWorker) run(request ExecutionRequest) ExecutionResult {
+	result := ExecutionResult{ExecutionID: request.ExecutionID}
+
+	fail := func(err error) ExecutionResult {
+		message := err.Error()
+		result.Status = ExecutionStatusFailed
+		result.ErrorMessage = &message
+		result.CompletedAt = time.Now().Format(time.RFC3339)
+		return result
+	}
+
+	// Step 1: Load config and datasource
+	reportConfig, err := w.configRepo.GetByID(request.ConfigID)
+	if err != nil {
+		return fail(fmt.Errorf("report config not found"))
+	}
+	if !reportConfig.IsActive {
+		return fail(fmt.Errorf("report config is inactive"))
+	}
+
+	datasource, err := w.datasourceRepo.GetByID(reportConfig.DatasourceID)
+	if err != nil {
+		return fail(fmt.Errorf("datasource not found"))
+	}
+	if !datasource.IsActive {
+		return fail(fmt.Errorf("datasource is inactive"))
+	}
+
+	// Step 2: Re-check the query (configs saved before the guard existed) and render it for the covered time window
+	if err := CheckReportQuery(reportConfig.ID, reportConfig.ReportQuery, datasource.DbType); err != nil {
+		return fail(err)
+	}
+	variableContext, err := w.variableContext(request)
+	if err != nil {
+		return fail(err)
+	}
+	parameters, err := ResolveReportParameters(reportConfig.ParameterSchema, reportConfig.Parameters, request.Parameters)
+	if err != nil {
+		return fail(err)
+	}
+	query, err := utils.RenderReportQuery(reportConfig.ReportQuery, datasource.DbType, parameters, ParameterTypes(reportConfig.ParameterSchema), variableContext)
+	if err != nil {
+		return fail(err)
+	}
+
+	// Step 3: Run the query within the config's timeout and row limit
+	db, err := OpenDataSource(datasource)
+	if err != nil {
+		return fail(err)
+	}
+	defer db.Close()
+
+	timeoutSeconds := reportConfig.TimeoutSeconds
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = defaultReportTimeoutSeconds
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSeconds)*time.Second)
+	defer cancel()
+
+	queryStart := time.Now()
+	queryResult, err := RunReportQuery(ctx, db, datasource.DbType, query.SQL, query.Args, reportConfig.MaxRows)
+	queryTimeMs := int(time.Since(queryStart).Milliseconds())
+	result.QueryExecutionTimeMs = &queryTimeMs
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fail(fmt.Errorf("query exceeded timeout of %d seconds", timeoutSeconds))
+		}
+		return fail(err)
+	}
+
+	rowsReturned := len(queryResult.Rows)
+	result.RowsReturned = &rowsReturned
+
+	// Step 4: Write the output file
+	outputPath := w.outputPath(reportConfig, request.ExecutionID)
+	fileSize, err := WriteReportFile(outputPath, reportConfig.OutputFormat, queryResult)
+	if err != nil {
+		return fail(fmt.Errorf("failed to write report file: %w", err))
+	}
+
+	result.Status = ExecutionStatusCompleted
+	result.FileGeneratedPath = &outputPath
+	result.FileSizeBytes = &fileSize
+	result.CompletedAt = time.Now().Format(time.RFC3339)
+	return result
+}
+
+// variableContext builds the time window template variables are evaluated for
+func (w *ReportWorker) variableContext(request ExecutionRequest) (utils.VariableContext, error) {
+	executionTime := time.Now()
+	if request.ScheduledAt != "" {
+		scheduledAt, err := time.Parse(time.RFC3339, request.ScheduledAt)
+		if err != nil {
+			return utils.VariableContext{}, fmt.Errorf("invalid scheduled_at: %w", err)
+		}
+		executionTime = scheduledAt
+	}
+
+	var lastRunAt *time.Time
+	if request.LastRunAt != "" {
+		parsed, err := time.Parse(time.RFC3339, request.LastRunAt)
+		if err != nil {
+			return utils.VariableContext{}, fmt.Errorf("invalid last_run_at: %w", err)
+		}
+		lastRunAt = &parsed
+	}
+
+	// Manual runs have no schedule and evaluate in UTC
+	cronExpr, timezone := "", ""
+	if request.ScheduleID != nil {
+		schedule, err := w.scheduleRepo.GetByID(*request.ScheduleID)
+		if err != nil {
+			return utils.VariableContext{}, fmt.Errorf("schedule not found")
+		}
+		cronExpr, timezone = schedule.CronExpression, schedule.Timezone
+	}
+
+	return utils.NewVariableContext(lastRunAt, cronExpr, timezone, executionTime), nil
+}
+
+// outputPath returns <output_dir>/<config_id>/<execution_id>_<file_name>.<format>
+func (w *ReportWorker) outputPath(reportConfig *models.ReportConfig, executionID string) string {
+	name := reportConfig.ReportName
+	if reportConfig.FileName != nil && *reportConfig.FileName != "" {
+		name = *reportConfig.FileName
+	}
+	name = strings.TrimSuffix(name, "."+reportConfig.OutputFormat)
+	name = strings.Trim(unsafeFileNameChars.ReplaceAllString(name, "_"), "_")
+	if name == "" {
+		name = "report"
+	}
+
+	fileName := fmt.Sprintf("%s_%s.%s", executionID, name, reportConfig.OutputFormat)
+	return filepath.Join(w.outputDir, strconv.Itoa(reportConfig.ID), fileName)
+}
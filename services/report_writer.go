@@ -0,0 +1,136 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Report output formats
+const (
+	OutputFormatCSV  = "csv"
+	OutputFormatXLSX = "xlsx"
+	OutputFormatJSON = "json"
+)
+
+// WriteReportFile writes a query result to path in the given format and returns the file size
+func WriteReportFile(path string, format string, result *QueryResult) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	var err error
+	switch format {
+	case OutputFormatCSV:
+		err = writeCSV(path, result)
+	case OutputFormatXLSX:
+		err = writeXLSX(path, result)
+	case OutputFormatJSON:
+		err = writeJSON(path, result)
+	default:
+		return 0, fmt.Errorf("unsupported output format '%s'", format)
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func writeCSV(path string, result *QueryResult) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(result.Columns); err != nil {
+		return err
+	}
+
+	record := make([]string, len(result.Columns))
+	for _, row := range result.Rows {
+		for i, value := range row {
+			if value == nil {
+				record[i] = ""
+			} else {
+				record[i] = fmt.Sprintf("%v", value)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func writeXLSX(path string, result *QueryResult) error {
+	workbook := excelize.NewFile()
+	defer workbook.Close()
+
+	sheet := workbook.GetSheetName(0)
+	stream, err := workbook.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+
+	header := make([]interface{}, len(result.Columns))
+	for i, column := range result.Columns {
+		header[i] = column
+	}
+	if err := stream.SetRow("A1", header); err != nil {
+		return err
+	}
+
+	for i, row := range result.Rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		if err := stream.SetRow(cell, row); err != nil {
+			return err
+		}
+	}
+
+	if err := stream.Flush(); err != nil {
+		return err
+	}
+	return workbook.SaveAs(path)
+}
+
+func writeJSON(path string, result *QueryResult) error {
+	records := make([]map[string]interface{}, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		record := make(map[string]interface{}, len(result.Columns))
+		for i, column := range result.Columns {
+			record[column] = row[i]
+		}
+		records = append(records, record)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	if err := json.NewEncoder(file).Encode(records); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,665 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"scheduling-report/config"
+	"scheduling-report/models"
+	"scheduling-report/repositories"
+	"scheduling-report/utils"
+
+	"gorm.io/gorm"
+)
+
+type ScheduleBundleService struct {
+	completeService *CompleteScheduleService
+}
+
+func NewScheduleBundleService() *ScheduleBundleService {
+	return &ScheduleBundleService{
+		completeService: NewCompleteScheduleService(),
+	}
+}
+
+// secretPlaceholderPattern matches a whole "${secret:<name>}" placeholder value
+var secretPlaceholderPattern = regexp.MustCompile(`^\$\{secret:([^}]+)\}$`)
+
+// errImportDryRun rolls back a dry-run import after every schedule went through
+var errImportDryRun = errors.New("dry run")
+
+// BundleError rejects a bundle that cannot be imported as it is.
+// Secrets lists the placeholders that the import request did not supply.
+type BundleError struct {
+	Reason  string
+	Secrets []string
+}
+
+func (e *BundleError) Error() string {
+	return e.Reason
+}
+
+// ExportSchedules builds a bundle of the given schedules, or of every schedule when ids is empty
+func (s *ScheduleBundleService) ExportSchedules(ids []int, exportedBy string) (*models.ScheduleBundle, error) {
+	var schedules []models.ReportSchedule
+	query := config.DB.Order("id")
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	if err := query.Find(&schedules).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch schedules: %w", err)
+	}
+	if len(schedules) < len(ids) {
+		found := map[int]bool{}
+		for _, schedule := range schedules {
+			found[schedule.ID] = true
+		}
+		for _, id := range ids {
+			if !found[id] {
+				return nil, fmt.Errorf("schedule %d not found", id)
+			}
+		}
+	}
+
+	bundle := &models.ScheduleBundle{
+		Kind:          models.BundleKind,
+		FormatVersion: models.BundleFormatVersion,
+		ExportedAt:    time.Now().UTC(),
+		ExportedBy:    exportedBy,
+		Schedules:     make([]models.BundleSchedule, 0, len(schedules)),
+	}
+	requiredSecrets := map[string]bool{}
+	for i := range schedules {
+		bundleSchedule, err := exportSchedule(config.DB, &schedules[i], requiredSecrets)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Schedules = append(bundle.Schedules, *bundleSchedule)
+	}
+
+	for name := range requiredSecrets {
+		bundle.RequiredSecrets = append(bundle.RequiredSecrets, name)
+	}
+	sort.Strings(bundle.RequiredSecrets)
+
+	return bundle, nil
+}
+
+// ExportSchedule builds a bundle of one schedule
+func (s *ScheduleBundleService) ExportSchedule(scheduleID int, exportedBy string) (*models.ScheduleBundle, error) {
+	var count int64
+	if err := config.DB.Model(&models.ReportSchedule{}).Where("id = ?", scheduleID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, errors.New("schedule not found")
+	}
+	return s.ExportSchedules([]int{scheduleID}, exportedBy)
+}
+
+// exportSchedule converts a schedule with its config, deliveries and recipients to its bundle form,
+// adding the placeholders it emits to requiredSecrets
+func exportSchedule(db *gorm.DB, schedule *models.ReportSchedule, requiredSecrets map[string]bool) (*models.BundleSchedule, error) {
+	var reportConfig models.ReportConfig
+	if err := db.First(&reportConfig, schedule.ConfigID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find config: %w", err)
+	}
+
+	var datasource models.DataSource
+	if err := db.First(&datasource, reportConfig.DatasourceID).Error; err != nil {
+		return nil, fmt.Errorf("datasource id %d not found: %w", reportConfig.DatasourceID, err)
+	}
+
+	var folderPath string
+	if reportConfig.FolderID != nil {
+		folder, err := (&repository.ReportFolderRepository{DB: db}).GetByID(*reportConfig.FolderID)
+		if err != nil {
+			return nil, fmt.Errorf("folder id %d not found: %w", *reportConfig.FolderID, err)
+		}
+		folderPath = folder.Path
+	}
+	tagNames, err := (&repository.ReportTagRepository{DB: db}).GetNamesByConfigIDs([]int{reportConfig.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tags: %w", err)
+	}
+
+	var deliveries []models.ReportDelivery
+	if err := db.Where("config_id = ?", reportConfig.ID).Order("id").Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch deliveries: %w", err)
+	}
+
+	recipients, err := recipientsByDelivery(db, deliveries)
+	if err != nil {
+		return nil, err
+	}
+
+	bundleDeliveries := make([]models.BundleDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		bundleRecipients := make([]models.BundleRecipient, 0, len(recipients[delivery.ID]))
+		for _, recipient := range recipients[delivery.ID] {
+			bundleRecipients = append(bundleRecipients, models.BundleRecipient{
+				RecipientType:   recipient.RecipientType,
+				RecipientValue:  recipient.RecipientValue,
+				RecipientConfig: recipient.RecipientConfig,
+				IsActive:        recipient.IsActive,
+			})
+		}
+
+		placeholderPrefix := reportConfig.ReportName + "/" + delivery.DeliveryName + "/"
+		bundleDeliveries = append(bundleDeliveries, models.BundleDelivery{
+			DeliveryName:         delivery.DeliveryName,
+			Method:               delivery.Method,
+			MaxRetry:             delivery.MaxRetry,
+			RetryIntervalMinutes: delivery.RetryIntervalMinutes,
+			IsActive:             delivery.IsActive,
+			DeliveryConfig:       exportSecretFields(delivery.DeliveryConfig, placeholderPrefix, requiredSecrets),
+			Recipients:           bundleRecipients,
+		})
+	}
+
+	return &models.BundleSchedule{
+		CronExpression: schedule.CronExpression,
+		Timezone:       schedule.Timezone,
+		IsActive:       schedule.IsActive,
+		MisfirePolicy:  schedule.MisfirePolicy,
+		MisfireMaxRuns: schedule.MisfireMaxRuns,
+		Config: models.BundleConfig{
+			ReportName:      reportConfig.ReportName,
+			ReportQuery:     reportConfig.ReportQuery,
+			OutputFormat:    reportConfig.OutputFormat,
+			Datasource:      datasource.Name,
+			FileName:        reportConfig.FileName,
+			Parameters:      reportConfig.Parameters,
+			ParameterSchema: reportConfig.ParameterSchema,
+			TimeoutSeconds:  reportConfig.TimeoutSeconds,
+			MaxRows:         reportConfig.MaxRows,
+			Folder:          folderPath,
+			OwnerTeam:       reportConfig.OwnerTeam,
+			Owners:          reportConfig.Owners,
+			Tags:            tagNames[reportConfig.ID],
+			Deliveries:      bundleDeliveries,
+		},
+	}, nil
+}
+
+// exportSecretFields replaces every literal secret in a delivery config with a placeholder named
+// after its path. Secret references are kept, they only say where the secret lives.
+func exportSecretFields(deliveryConfig map[string]interface{}, prefix string, requiredSecrets map[string]bool) map[string]interface{} {
+	if deliveryConfig == nil {
+		return nil
+	}
+
+	result := make(map[string]interface{}, len(deliveryConfig))
+	for key, value := range deliveryConfig {
+		switch v := value.(type) {
+		case string:
+			if utils.IsSecretField(key) && v != "" && !utils.IsSecretReference(v) {
+				name := prefix + key
+				requiredSecrets[name] = true
+				result[key] = "${secret:" + name + "}"
+				continue
+			}
+			result[key] = v
+		case map[string]interface{}:
+			result[key] = exportSecretFields(v, prefix+key+".", requiredSecrets)
+		default:
+			result[key] = v
+		}
+	}
+	return result
+}
+
+// importSecretFields puts the secrets the import supplies in place of their placeholders.
+// A placeholder it cannot fill keeps the stored value of the delivery being overwritten (stored is
+// nil when there is none) by becoming MaskedSecret; otherwise its name is added to missing.
+func importSecretFields(deliveryConfig map[string]interface{}, secrets map[string]string, stored map[string]interface{}, missing map[string]bool) map[string]interface{} {
+	if deliveryConfig == nil {
+		return nil
+	}
+
+	result := make(map[string]interface{}, len(deliveryConfig))
+	for key, value := range deliveryConfig {
+		switch v := value.(type) {
+		case string:
+			match := secretPlaceholderPattern.FindStringSubmatch(v)
+			if match == nil {
+				result[key] = v
+				continue
+			}
+			if secret, ok := secrets[match[1]]; ok {
+				result[key] = secret
+			} else if storedValue, ok := stored[key].(string); ok && storedValue != "" {
+				result[key] = utils.MaskedSecret
+			} else {
+				missing[match[1]] = true
+				result[key] = v
+			}
+		case map[string]interface{}:
+			nested, _ := stored[key].(map[string]interface{})
+			result[key] = importSecretFields(v, secrets, nested, missing)
+		default:
+			result[key] = v
+		}
+	}
+	return result
+}
+
+// ImportSchedules creates or overwrites the bundled schedules in a single transaction.
+// A dry run goes through every check and write, reports what would happen and rolls back.
+func (s *ScheduleBundleService) ImportSchedules(req models.ScheduleImportRequest) ([]models.ScheduleImportResult, error) {
+	bundle := req.Bundle
+	if bundle.Kind != models.BundleKind {
+		return nil, &BundleError{Reason: fmt.Sprintf("unsupported bundle kind '%s', expected '%s'", bundle.Kind, models.BundleKind)}
+	}
+	if bundle.FormatVersion < 1 || bundle.FormatVersion > models.BundleFormatVersion {
+		return nil, &BundleError{Reason: fmt.Sprintf("unsupported bundle format_version %d, this server reads up to %d", bundle.FormatVersion, models.BundleFormatVersion)}
+	}
+	if len(bundle.Schedules) == 0 {
+		return nil, &BundleError{Reason: "bundle has no schedules"}
+	}
+
+	strategy := req.ConflictStrategy
+	if strategy == "" {
+		strategy = models.ImportConflictSkip
+	}
+
+	// Heavy queries need cost_override; EXPLAIN runs before the transaction is opened
+	costEstimates, err := checkImportCosts(config.DB, bundle.Schedules, strategy, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []models.ScheduleImportResult
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		results = make([]models.ScheduleImportResult, 0, len(bundle.Schedules))
+		missing := map[string]bool{}
+		for i, bundleSchedule := range bundle.Schedules {
+			result, err := s.importSchedule(tx, bundleSchedule, strategy, req, missing, costEstimates[i])
+			if err != nil {
+				return fmt.Errorf("%s: %w", bundleSchedule.Config.ReportName, err)
+			}
+			results = append(results, *result)
+		}
+
+		if len(missing) > 0 {
+			names := make([]string, 0, len(missing))
+			for name := range missing {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return &BundleError{Reason: "bundle needs secrets that were not supplied: " + strings.Join(names, ", "), Secrets: names}
+		}
+
+		if req.DryRun {
+			return errImportDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errImportDryRun) {
+		for i := range results {
+			if results[i].Action == "created" || results[i].Action == "renamed" {
+				results[i].ScheduleID, results[i].ConfigID = 0, 0
+			}
+		}
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// checkImportCosts runs the cost gate for every bundled schedule that will be created or will change
+// an existing config's query, by bundle index. Schedules whose datasource is missing are left to
+// importSchedule to report.
+func checkImportCosts(db *gorm.DB, bundleSchedules []models.BundleSchedule, strategy string, req models.ScheduleImportRequest) ([]*QueryCostEstimate, error) {
+	costEstimates := make([]*QueryCostEstimate, len(bundleSchedules))
+	for i, bundleSchedule := range bundleSchedules {
+		datasourceName := bundleSchedule.Config.Datasource
+		if mapped, ok := req.DatasourceMap[datasourceName]; ok {
+			datasourceName = mapped
+		}
+		var datasources []models.DataSource
+		if err := db.Where("name = ?", datasourceName).Limit(1).Find(&datasources).Error; err != nil {
+			return nil, err
+		}
+		if len(datasources) == 0 {
+			continue
+		}
+
+		var existing []models.ReportConfig
+		if err := db.Where("report_name = ?", bundleSchedule.Config.ReportName).Order("id").Limit(1).Find(&existing).Error; err != nil {
+			return nil, err
+		}
+		var overwritten *models.ReportConfig
+		if len(existing) > 0 {
+			switch strategy {
+			case models.ImportConflictSkip:
+				continue
+			case models.ImportConflictOverwrite:
+				overwritten = &existing[0]
+			}
+		}
+
+		costEstimate, err := checkBundleCost(db, bundleSchedule, datasources[0].ID, overwritten, req.CostOverride)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", bundleSchedule.Config.ReportName, err)
+		}
+		costEstimates[i] = costEstimate
+	}
+	return costEstimates, nil
+}
+
+// checkBundleCost runs the cost gate for a bundled schedule saved on datasourceID. existing is the
+// config it overwrites, nil when it is created; an overwrite that keeps the query is not checked.
+func checkBundleCost(db *gorm.DB, bundleSchedule models.BundleSchedule, datasourceID int, existing *models.ReportConfig, override bool) (*QueryCostEstimate, error) {
+	bundleConfig := bundleSchedule.Config
+	reportConfig := models.ReportConfig{
+		ReportQuery:     bundleConfig.ReportQuery,
+		DatasourceID:    datasourceID,
+		Parameters:      bundleConfig.Parameters,
+		ParameterSchema: bundleConfig.ParameterSchema,
+	}
+	if existing != nil {
+		// Like updateComplete, an overwrite keeps the stored parameters the bundle leaves out
+		reportConfig.ID = existing.ID
+		if bundleConfig.Parameters == nil {
+			reportConfig.Parameters = existing.Parameters
+		}
+		if bundleConfig.ParameterSchema == nil {
+			reportConfig.ParameterSchema = existing.ParameterSchema
+		}
+		if !costInputsChanged(existing, &reportConfig) {
+			return nil, nil
+		}
+	}
+	return checkConfigCost(db, &reportConfig, bundleSchedule.CronExpression, bundleSchedule.Timezone, override)
+}
+
+// importSchedule applies the conflict strategy to one bundled schedule using tx.
+// costEstimate is what checkImportCosts returned for it.
+func (s *ScheduleBundleService) importSchedule(tx *gorm.DB, bundleSchedule models.BundleSchedule, strategy string, req models.ScheduleImportRequest, missing map[string]bool, costEstimate *QueryCostEstimate) (*models.ScheduleImportResult, error) {
+	bundleConfig := bundleSchedule.Config
+
+	datasourceName := bundleConfig.Datasource
+	if mapped, ok := req.DatasourceMap[datasourceName]; ok {
+		datasourceName = mapped
+	}
+	var datasource models.DataSource
+	if err := tx.Where("name = ?", datasourceName).First(&datasource).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &BundleError{Reason: fmt.Sprintf("datasource '%s' not found; map it to a local datasource with datasource_map", datasourceName)}
+		}
+		return nil, err
+	}
+
+	result := &models.ScheduleImportResult{ReportName: bundleConfig.ReportName, Datasource: datasource.Name}
+
+	var existing []models.ReportConfig
+	if err := tx.Where("report_name = ?", bundleConfig.ReportName).Order("id").Limit(1).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+
+	reportName := bundleConfig.ReportName
+	if len(existing) > 0 {
+		switch strategy {
+		case models.ImportConflictSkip:
+			result.Action = "skipped"
+			result.ConfigID = existing[0].ID
+			var schedule models.ReportSchedule
+			if err := tx.Where("config_id = ?", existing[0].ID).Order("id").Limit(1).Find(&schedule).Error; err != nil {
+				return nil, err
+			}
+			result.ScheduleID = schedule.ID
+			return result, nil
+		case models.ImportConflictOverwrite:
+			return s.overwriteSchedule(tx, &existing[0], bundleSchedule, datasource.ID, req, missing, result, costEstimate)
+		case models.ImportConflictRename:
+			var err error
+			if reportName, err = uniqueReportName(tx, reportName, "imported"); err != nil {
+				return nil, err
+			}
+			result.Action = "renamed"
+			result.ImportedAs = reportName
+		}
+	} else {
+		result.Action = "created"
+	}
+
+	response, err := s.createSchedule(tx, bundleSchedule, reportName, datasource.ID, req, missing, costEstimate)
+	if err != nil {
+		return nil, err
+	}
+	result.ScheduleID = response.ScheduleID
+	result.ConfigID = response.ConfigID
+	return result, nil
+}
+
+// createSchedule creates a bundled schedule under reportName with its config, deliveries and recipients
+func (s *ScheduleBundleService) createSchedule(tx *gorm.DB, bundleSchedule models.BundleSchedule, reportName string, datasourceID int, req models.ScheduleImportRequest, missing map[string]bool, costEstimate *QueryCostEstimate) (*models.CompleteScheduleResponse, error) {
+	createReq := models.CompleteScheduleRequest{
+		CronExpression: bundleSchedule.CronExpression,
+		Timezone:       bundleSchedule.Timezone,
+		IsActive:       bundleSchedule.IsActive,
+		MisfirePolicy:  bundleSchedule.MisfirePolicy,
+		CreatedBy:      req.ImportedBy,
+		UpdatedBy:      req.ImportedBy,
+		CostOverride:   req.CostOverride,
+	}
+	if bundleSchedule.MisfireMaxRuns > 0 {
+		createReq.MisfireMaxRuns = &bundleSchedule.MisfireMaxRuns
+	}
+	configReq, err := bundleConfigRequest(bundleSchedule.Config, reportName, datasourceID, req.Secrets, nil, missing)
+	if err != nil {
+		return nil, err
+	}
+	createReq.Configs = *configReq
+	if err := utils.ValidateStruct(createReq); err != nil {
+		return nil, &BundleError{Reason: "invalid schedule: " + err.Error()}
+	}
+
+	response, err := s.completeService.createComplete(tx, createReq, nil, costEstimate)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyBundleCatalog(tx, response.ConfigID, bundleSchedule.Config, req.ImportedBy); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// overwriteSchedule replaces an existing config, its first schedule and its deliveries with a bundled schedule.
+// Deliveries are matched by name so placeholders without a supplied secret keep the stored value.
+func (s *ScheduleBundleService) overwriteSchedule(tx *gorm.DB, existing *models.ReportConfig, bundleSchedule models.BundleSchedule, datasourceID int, req models.ScheduleImportRequest, missing map[string]bool, result *models.ScheduleImportResult, costEstimate *QueryCostEstimate) (*models.ScheduleImportResult, error) {
+	var schedule models.ReportSchedule
+	if err := tx.Where("config_id = ?", existing.ID).Order("id").First(&schedule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &BundleError{Reason: fmt.Sprintf("report config '%s' has no schedule to overwrite", existing.ReportName)}
+		}
+		return nil, err
+	}
+
+	var deliveries []models.ReportDelivery
+	if err := tx.Where("config_id = ?", existing.ID).Order("id").Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch deliveries: %w", err)
+	}
+	existingDeliveries := map[string]*models.ReportDelivery{}
+	for i := range deliveries {
+		if _, ok := existingDeliveries[deliveries[i].DeliveryName]; !ok {
+			existingDeliveries[deliveries[i].DeliveryName] = &deliveries[i]
+		}
+	}
+
+	configReq, err := bundleConfigRequest(bundleSchedule.Config, bundleSchedule.Config.ReportName, datasourceID, req.Secrets, existingDeliveries, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	updateReq := models.CompleteScheduleUpdateRequest{
+		CronExpression: &bundleSchedule.CronExpression,
+		Timezone:       &bundleSchedule.Timezone,
+		IsActive:       &bundleSchedule.IsActive,
+		UpdatedBy:      req.ImportedBy,
+		CostOverride:   req.CostOverride,
+		Configs:        configReq,
+	}
+	if bundleSchedule.MisfirePolicy != "" {
+		updateReq.MisfirePolicy = &bundleSchedule.MisfirePolicy
+	}
+	if bundleSchedule.MisfireMaxRuns > 0 {
+		updateReq.MisfireMaxRuns = &bundleSchedule.MisfireMaxRuns
+	}
+	if err := utils.ValidateStruct(updateReq); err != nil {
+		return nil, &BundleError{Reason: "invalid schedule: " + err.Error()}
+	}
+
+	if _, err := s.completeService.updateComplete(tx, schedule.ID, updateReq, costEstimate); err != nil {
+		return nil, err
+	}
+	if err := applyBundleCatalog(tx, existing.ID, bundleSchedule.Config, req.ImportedBy); err != nil {
+		return nil, err
+	}
+	result.Action = "overwritten"
+	result.ScheduleID = schedule.ID
+	result.ConfigID = existing.ID
+	return result, nil
+}
+
+// applyBundleCatalog sets the folder, ownership and tags of a config to the bundled ones using tx.
+// A change bumps the metadata version and is audited like an UpdateMetadata.
+func applyBundleCatalog(tx *gorm.DB, configID int, bundleConfig models.BundleConfig, performedBy string) error {
+	configRepo := &repository.ReportConfigRepository{DB: tx}
+	tagRepo := &repository.ReportTagRepository{DB: tx}
+
+	var folderID *int
+	if path := strings.Trim(bundleConfig.Folder, "/"); path != "" {
+		folder, err := (&repository.ReportFolderRepository{DB: tx}).GetByPath(path)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &BundleError{Reason: fmt.Sprintf("folder '%s' not found", path)}
+			}
+			return err
+		}
+		folderID = &folder.ID
+	}
+
+	tagNames := normalizeTagNames(bundleConfig.Tags)
+	tags, err := tagRepo.GetByNames(tagNames)
+	if err != nil {
+		return err
+	}
+	tagIDs := make(map[string]int, len(tags))
+	for _, tag := range tags {
+		tagIDs[tag.Name] = tag.ID
+	}
+	for _, name := range tagNames {
+		if _, ok := tagIDs[name]; !ok {
+			return &BundleError{Reason: fmt.Sprintf("tag '%s' not found", name)}
+		}
+	}
+
+	existing, err := configRepo.GetByID(configID)
+	if err != nil {
+		return err
+	}
+	owners := normalizeOwners(bundleConfig.Owners)
+	if reflect.DeepEqual(existing.FolderID, folderID) && reflect.DeepEqual(existing.OwnerTeam, bundleConfig.OwnerTeam) &&
+		equalTags(existing.Owners, owners) && equalTags(existing.Tags, tagNames) {
+		return nil
+	}
+
+	updated := *existing
+	updated.FolderID = folderID
+	updated.OwnerTeam = bundleConfig.OwnerTeam
+	updated.Owners = owners
+	updated.UpdatedBy = performedBy
+	if err := configRepo.UpdateMetadata(&updated); err != nil {
+		return err
+	}
+	if err := tagRepo.SetConfigTags(configID, tagIDsOf(tagNames, tagIDs)); err != nil {
+		return err
+	}
+
+	after, err := configRepo.GetByID(configID)
+	if err != nil {
+		return err
+	}
+	return writeConfigAudit(tx, configAuditEntry{ConfigID: configID, Action: "update", Before: existing, After: after, PerformedBy: performedBy})
+}
+
+// bundleConfigRequest converts a bundled config to the request the complete schedule service takes.
+// existingDeliveries is set when overwriting: deliveries with a matching name are updated in place.
+func bundleConfigRequest(bundleConfig models.BundleConfig, reportName string, datasourceID int, secrets map[string]string, existingDeliveries map[string]*models.ReportDelivery, missing map[string]bool) (*models.ConfigWithDeliveriesRequest, error) {
+	var parametersJSON json.RawMessage
+	if bundleConfig.Parameters != nil {
+		var err error
+		if parametersJSON, err = json.Marshal(bundleConfig.Parameters); err != nil {
+			return nil, fmt.Errorf("invalid parameters: %w", err)
+		}
+	}
+
+	timeoutSeconds, maxRows := bundleConfig.TimeoutSeconds, bundleConfig.MaxRows
+	configReq := &models.ConfigWithDeliveriesRequest{
+		ReportName:      reportName,
+		ReportQuery:     bundleConfig.ReportQuery,
+		OutputFormat:    bundleConfig.OutputFormat,
+		DatasourceID:    datasourceID,
+		FileName:        bundleConfig.FileName,
+		Parameters:      parametersJSON,
+		ParameterSchema: bundleConfig.ParameterSchema,
+		Deliveries:      make([]models.DeliveryWithRecipientsRequest, 0, len(bundleConfig.Deliveries)),
+	}
+	if timeoutSeconds > 0 {
+		configReq.TimeoutSeconds = &timeoutSeconds
+	}
+	if maxRows > 0 {
+		configReq.MaxRows = &maxRows
+	}
+
+	for _, bundleDelivery := range bundleConfig.Deliveries {
+		deliveryReq := models.DeliveryWithRecipientsRequest{
+			DeliveryName: bundleDelivery.DeliveryName,
+			Method:       bundleDelivery.Method,
+			Recipients:   make([]models.RecipientRequest, 0, len(bundleDelivery.Recipients)),
+		}
+		maxRetry, retryInterval, isActive := bundleDelivery.MaxRetry, bundleDelivery.RetryIntervalMinutes, bundleDelivery.IsActive
+		deliveryReq.MaxRetry = &maxRetry
+		deliveryReq.RetryIntervalMinutes = &retryInterval
+		deliveryReq.IsActive = &isActive
+
+		var stored map[string]interface{}
+		if existingDelivery, ok := existingDeliveries[bundleDelivery.DeliveryName]; ok {
+			deliveryReq.ID = &existingDelivery.ID
+			stored = existingDelivery.DeliveryConfig
+			delete(existingDeliveries, bundleDelivery.DeliveryName)
+		}
+		if bundleDelivery.DeliveryConfig != nil {
+			deliveryConfigJSON, err := json.Marshal(importSecretFields(bundleDelivery.DeliveryConfig, secrets, stored, missing))
+			if err != nil {
+				return nil, fmt.Errorf("invalid delivery_config: %w", err)
+			}
+			deliveryReq.DeliveryConfig = deliveryConfigJSON
+		}
+
+		for _, bundleRecipient := range bundleDelivery.Recipients {
+			recipientActive := bundleRecipient.IsActive
+			deliveryReq.Recipients = append(deliveryReq.Recipients, models.RecipientRequest{
+				RecipientType:   bundleRecipient.RecipientType,
+				RecipientValue:  bundleRecipient.RecipientValue,
+				RecipientConfig: bundleRecipient.RecipientConfig,
+				IsActive:        &recipientActive,
+			})
+		}
+
+		configReq.Deliveries = append(configReq.Deliveries, deliveryReq)
+	}
+
+	return configReq, nil
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"scheduling-report/models"
+	"scheduling-report/utils"
+)
+
+func TestSecretPlaceholderRoundTrip(t *testing.T) {
+	deliveryConfig := map[string]interface{}{
+		"host":     "sftp.example.com",
+		"password": "s3cret",
+		"api_key":  "env://REPORTS_API_KEY", // a reference only says where the secret lives
+		"auth":     map[string]interface{}{"token": "t0ken", "scheme": "bearer"},
+		"port":     float64(22),
+	}
+
+	requiredSecrets := map[string]bool{}
+	exported := exportSecretFields(deliveryConfig, "Sales/SFTP/", requiredSecrets)
+
+	wantExported := map[string]interface{}{
+		"host":     "sftp.example.com",
+		"password": "${secret:Sales/SFTP/password}",
+		"api_key":  "env://REPORTS_API_KEY",
+		"auth":     map[string]interface{}{"token": "${secret:Sales/SFTP/auth.token}", "scheme": "bearer"},
+		"port":     float64(22),
+	}
+	if !reflect.DeepEqual(exported, wantExported) {
+		t.Fatalf("exported = %v, want %v", exported, wantExported)
+	}
+	if want := map[string]bool{"Sales/SFTP/password": true, "Sales/SFTP/auth.token": true}; !reflect.DeepEqual(requiredSecrets, want) {
+		t.Errorf("required secrets = %v, want %v", requiredSecrets, want)
+	}
+
+	tests := []struct {
+		name        string
+		secrets     map[string]string
+		stored      map[string]interface{}
+		want        map[string]interface{}
+		wantMissing []string
+	}{
+		{
+			name:    "supplied secrets fill the placeholders",
+			secrets: map[string]string{"Sales/SFTP/password": "n3w", "Sales/SFTP/auth.token": "vault://kv/sftp#token"},
+			want: map[string]interface{}{
+				"host": "sftp.example.com", "password": "n3w", "api_key": "env://REPORTS_API_KEY",
+				"auth": map[string]interface{}{"token": "vault://kv/sftp#token", "scheme": "bearer"}, "port": float64(22),
+			},
+		},
+		{
+			name:   "an overwrite keeps the stored secrets",
+			stored: deliveryConfig,
+			want: map[string]interface{}{
+				"host": "sftp.example.com", "password": utils.MaskedSecret, "api_key": "env://REPORTS_API_KEY",
+				"auth": map[string]interface{}{"token": utils.MaskedSecret, "scheme": "bearer"}, "port": float64(22),
+			},
+		},
+		{
+			name:        "unfilled placeholders are missing",
+			secrets:     map[string]string{"Sales/SFTP/password": "n3w"},
+			want:        map[string]interface{}{"host": "sftp.example.com", "password": "n3w", "api_key": "env://REPORTS_API_KEY", "auth": map[string]interface{}{"token": "${secret:Sales/SFTP/auth.token}", "scheme": "bearer"}, "port": float64(22)},
+			wantMissing: []string{"Sales/SFTP/auth.token"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			missing := map[string]bool{}
+			got := importSecretFields(exported, tt.secrets, tt.stored, missing)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("imported = %v, want %v", got, tt.want)
+			}
+			var gotMissing []string
+			for name := range missing {
+				gotMissing = append(gotMissing, name)
+			}
+			if !reflect.DeepEqual(gotMissing, tt.wantMissing) {
+				t.Errorf("missing = %v, want %v", gotMissing, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestExportImportKeepsSecretsOutOfTheBundle(t *testing.T) {
+	db := useTestDB(t)
+	source := createTestSchedule(t, db)
+	service := NewScheduleBundleService()
+
+	bundle, err := service.ExportSchedule(source.ScheduleID, "test")
+	if err != nil {
+		t.Fatalf("ExportSchedule: %v", err)
+	}
+	bundleJSON, _ := json.Marshal(bundle)
+	if strings.Contains(string(bundleJSON), "s3cret") {
+		t.Fatalf("bundle carries the stored password: %s", bundleJSON)
+	}
+	const placeholder = "Sales export/Finance SFTP/password"
+	if !reflect.DeepEqual(bundle.RequiredSecrets, []string{placeholder}) {
+		t.Errorf("required_secrets = %v, want [%s]", bundle.RequiredSecrets, placeholder)
+	}
+	if recipients := bundle.Schedules[0].Config.Deliveries[1].Recipients; len(recipients) != 2 || recipients[1].RecipientValue != "ben@example.com" {
+		t.Errorf("Sales team recipients = %+v, want both, in order", recipients)
+	}
+
+	// A copy needs the secret supplied; there is no stored value to fall back on
+	_, err = service.ImportSchedules(models.ScheduleImportRequest{Bundle: *bundle, ConflictStrategy: models.ImportConflictRename, CostOverride: true, ImportedBy: "test"})
+	var bundleErr *BundleError
+	if !errors.As(err, &bundleErr) || !reflect.DeepEqual(bundleErr.Secrets, []string{placeholder}) {
+		t.Fatalf("import without secrets = %v, want a BundleError naming %s", err, placeholder)
+	}
+
+	results, err := service.ImportSchedules(models.ScheduleImportRequest{
+		Bundle:           *bundle,
+		ConflictStrategy: models.ImportConflictRename,
+		Secrets:          map[string]string{placeholder: "n3w"},
+		CostOverride:     true,
+		ImportedBy:       "test",
+	})
+	if err != nil {
+		t.Fatalf("import with secrets: %v", err)
+	}
+	if results[0].Action != "renamed" || results[0].ImportedAs != "Sales export (imported)" {
+		t.Fatalf("result = %+v, want renamed to Sales export (imported)", results[0])
+	}
+	imported, importedRecipients := loadDeliveryRecipients(t, db, results[0].ConfigID)
+	if password := imported[0].DeliveryConfig["password"]; password != "n3w" {
+		t.Errorf("imported password = %v, want the supplied secret", password)
+	}
+	if got := strings.Join(importedRecipients["Sales team"], ","); got != "ana@example.com,ben@example.com" {
+		t.Errorf("imported Sales team recipients = %s", got)
+	}
+
+	// Overwriting the source without the secret keeps its stored password
+	results, err = service.ImportSchedules(models.ScheduleImportRequest{Bundle: *bundle, ConflictStrategy: models.ImportConflictOverwrite, ImportedBy: "test"})
+	if err != nil {
+		t.Fatalf("overwrite without secrets: %v", err)
+	}
+	if results[0].Action != "overwritten" || results[0].ConfigID != source.ConfigID {
+		t.Fatalf("result = %+v, want the source overwritten", results[0])
+	}
+	overwritten, _ := loadDeliveryRecipients(t, db, source.ConfigID)
+	if password := overwritten[0].DeliveryConfig["password"]; password != "s3cret" {
+		t.Errorf("overwritten password = %v, want the stored secret kept", password)
+	}
+}
+
+func TestExportImportCarriesCatalog(t *testing.T) {
+	db := useTestDB(t)
+	source := createTestSchedule(t, db)
+	folder := models.ReportFolder{Name: "monthly", Path: "finance/monthly", CreatedBy: "test", UpdatedBy: "test"}
+	if err := db.Create(&folder).Error; err != nil {
+		t.Fatalf("seed folder: %v", err)
+	}
+	for _, name := range []string{"finance", "monthly"} {
+		if err := db.Create(&models.ReportTag{Name: name, CreatedBy: "test", UpdatedBy: "test"}).Error; err != nil {
+			t.Fatalf("seed tag: %v", err)
+		}
+	}
+	team := "finance-ops"
+	if _, err := NewReportConfigService().UpdateMetadata(source.ConfigID, ConfigMetadataInput{
+		FolderID: &folder.ID, OwnerTeam: &team, Owners: []string{"ana"}, Tags: []string{"monthly", "finance"}, UpdatedBy: "test",
+	}); err != nil {
+		t.Fatalf("UpdateMetadata: %v", err)
+	}
+	service := NewScheduleBundleService()
+
+	bundle, err := service.ExportSchedule(source.ScheduleID, "test")
+	if err != nil {
+		t.Fatalf("ExportSchedule: %v", err)
+	}
+	exported := bundle.Schedules[0].Config
+	if exported.Folder != "finance/monthly" || exported.OwnerTeam == nil || *exported.OwnerTeam != team ||
+		!reflect.DeepEqual(exported.Owners, []string{"ana"}) || !reflect.DeepEqual(exported.Tags, []string{"finance", "monthly"}) {
+		t.Fatalf("exported catalog = %q %v %v %v, want finance/monthly, finance-ops, [ana], [finance monthly]",
+			exported.Folder, exported.OwnerTeam, exported.Owners, exported.Tags)
+	}
+
+	secrets := map[string]string{"Sales export/Finance SFTP/password": "n3w"}
+	results, err := service.ImportSchedules(models.ScheduleImportRequest{Bundle: *bundle, ConflictStrategy: models.ImportConflictRename, Secrets: secrets, CostOverride: true, ImportedBy: "test"})
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	imported, err := NewReportConfigService().GetByID(results[0].ConfigID)
+	if err != nil {
+		t.Fatalf("load imported config: %v", err)
+	}
+	if imported.FolderID == nil || *imported.FolderID != folder.ID || imported.OwnerTeam == nil || *imported.OwnerTeam != team ||
+		!reflect.DeepEqual([]string(imported.Owners), []string{"ana"}) || !reflect.DeepEqual(imported.Tags, []string{"finance", "monthly"}) {
+		t.Errorf("imported catalog = %v %v %v %v, want the exported one", imported.FolderID, imported.OwnerTeam, imported.Owners, imported.Tags)
+	}
+
+	// Folders and tags are matched by name and must exist, like datasources
+	unknownTag := *bundle
+	unknownTag.Schedules = []models.BundleSchedule{bundle.Schedules[0]}
+	unknownTag.Schedules[0].Config.Tags = []string{"hr"}
+	_, err = service.ImportSchedules(models.ScheduleImportRequest{Bundle: unknownTag, ConflictStrategy: models.ImportConflictRename, Secrets: secrets, CostOverride: true, ImportedBy: "test"})
+	var bundleErr *BundleError
+	if !errors.As(err, &bundleErr) || bundleErr.Reason != "tag 'hr' not found" {
+		t.Errorf("import with an unknown tag = %v, want a BundleError", err)
+	}
+
+	// The bundle holds the whole catalog metadata, so overwriting with none clears it
+	uncatalogued := *bundle
+	uncatalogued.Schedules = []models.BundleSchedule{bundle.Schedules[0]}
+	uncatalogued.Schedules[0].Config.Folder = ""
+	uncatalogued.Schedules[0].Config.OwnerTeam = nil
+	uncatalogued.Schedules[0].Config.Owners = nil
+	uncatalogued.Schedules[0].Config.Tags = nil
+	if _, err := service.ImportSchedules(models.ScheduleImportRequest{Bundle: uncatalogued, ConflictStrategy: models.ImportConflictOverwrite, ImportedBy: "test"}); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	overwritten, err := NewReportConfigService().GetByID(source.ConfigID)
+	if err != nil {
+		t.Fatalf("load overwritten config: %v", err)
+	}
+	if overwritten.FolderID != nil || overwritten.OwnerTeam != nil || len(overwritten.Owners) != 0 || len(overwritten.Tags) != 0 {
+		t.Errorf("overwritten catalog = %v %v %v %v, want it cleared", overwritten.FolderID, overwritten.OwnerTeam, overwritten.Owners, overwritten.Tags)
+	}
+	if overwritten.MetadataVersion != 3 {
+		t.Errorf("metadata_version = %d, want 3 after the metadata update and the overwrite", overwritten.MetadataVersion)
+	}
+}
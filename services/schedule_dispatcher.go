@@ -0,0 +1,254 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"scheduling-report/config"
+	"scheduling-report/models"
+	"scheduling-report/repositories"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// SchedulerLeaseName is the lease row shared by every replica's dispatcher
+const SchedulerLeaseName = "report-schedule-dispatcher"
+
+// ScheduleDispatcher polls due report schedules and queues their executions.
+// Only the replica holding the scheduler lease polls; each due run is additionally
+// claimed with SKIP LOCKED so a run is never queued twice during a leader handover.
+type ScheduleDispatcher struct {
+	scheduleRepo     *repository.ReportScheduleRepository
+	leaseRepo        *repository.SchedulerLeaseRepository
+	executionService *ReportExecutionService
+	auditService     *ReportConfigAuditService
+	pollInterval     time.Duration
+	batchSize        int
+	leaseTTLSeconds  int
+	misfireThreshold time.Duration
+	holderID         string
+	isLeader         atomic.Bool
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+func NewScheduleDispatcher() *ScheduleDispatcher {
+	hostname, _ := os.Hostname()
+
+	return &ScheduleDispatcher{
+		scheduleRepo:     repository.NewReportScheduleRepository(),
+		leaseRepo:        repository.NewSchedulerLeaseRepository(),
+		executionService: NewReportExecutionService(),
+		auditService:     NewReportConfigAuditService(),
+		pollInterval:     time.Duration(config.Config.SchedulerPollIntervalSeconds) * time.Second,
+		batchSize:        config.Config.SchedulerBatchSize,
+		leaseTTLSeconds:  config.Config.SchedulerLeaseTTLSeconds,
+		misfireThreshold: time.Duration(config.Config.SchedulerMisfireThresholdSeconds) * time.Second,
+		// Unique per instance so several dispatchers in one process compete like separate pods
+		holderID: fmt.Sprintf("%s/%s", hostname, uuid.New().String()),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// HolderID identifies this dispatcher in the scheduler lease
+func (d *ScheduleDispatcher) HolderID() string {
+	return d.holderID
+}
+
+// IsLeader reports whether this dispatcher held the lease on its last poll
+func (d *ScheduleDispatcher) IsLeader() bool {
+	return d.isLeader.Load()
+}
+
+// Start runs the polling loop in a background goroutine
+func (d *ScheduleDispatcher) Start() {
+	log.Info().
+		Str("holder_id", d.holderID).
+		Dur("poll_interval", d.pollInterval).
+		Int("batch_size", d.batchSize).
+		Int("lease_ttl_seconds", d.leaseTTLSeconds).
+		Dur("misfire_threshold", d.misfireThreshold).
+		Msg("Schedule dispatcher started")
+
+	go func() {
+		defer close(d.doneCh)
+
+		ticker := time.NewTicker(d.pollInterval)
+		defer ticker.Stop()
+
+		// Dispatch immediately on startup, then on every tick
+		d.tick()
+		for {
+			select {
+			case <-d.stopCh:
+				return
+			case <-ticker.C:
+				d.tick()
+			}
+		}
+	}()
+}
+
+// Stop signals the polling loop to exit, waits for the current tick to finish
+// and releases the lease so another replica can take over without waiting for expiry
+func (d *ScheduleDispatcher) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stopCh)
+	})
+	<-d.doneCh
+
+	if d.isLeader.Load() {
+		if err := d.leaseRepo.Release(SchedulerLeaseName, d.holderID); err != nil {
+			log.Error().Err(err).Str("holder_id", d.holderID).Msg("Failed to release scheduler lease")
+		}
+		d.isLeader.Store(false)
+	}
+
+	log.Info().Str("holder_id", d.holderID).Msg("Schedule dispatcher stopped")
+}
+
+// tick renews or acquires the lease and dispatches due schedules when leading
+func (d *ScheduleDispatcher) tick() {
+	leader, err := d.leaseRepo.TryAcquire(SchedulerLeaseName, d.holderID, d.leaseTTLSeconds)
+	if err != nil {
+		log.Error().Err(err).Str("holder_id", d.holderID).Msg("Failed to acquire scheduler lease")
+		leader = false
+	}
+
+	if wasLeader := d.isLeader.Swap(leader); wasLeader != leader {
+		log.Info().
+			Str("holder_id", d.holderID).
+			Bool("leader", leader).
+			Msg("Scheduler leadership changed")
+	}
+
+	if leader {
+		d.dispatchDue()
+	}
+}
+
+// dispatchDue claims every schedule whose next_run_at has passed, applies its misfire policy
+// and queues an execution for each resulting run. The first poll after startup replays
+// whatever was missed while the service was down. Executions are written in the claim's
+// transaction, so a schedule only advances once its runs are durably queued.
+func (d *ScheduleDispatcher) dispatchDue() {
+	now := time.Now()
+	plans := map[int]*schedulePlan{}
+	queued := map[int][]*models.ReportExecution{}
+	reasons := map[int]error{}
+
+	claimed, deactivated, err := d.scheduleRepo.ClaimDueSchedules(now, d.batchSize, func(tx *gorm.DB, schedule models.ReportSchedule) (*time.Time, time.Time, error) {
+		// Resolve the plan before anything is queued so a broken cron/timezone never fires on every poll
+		plan, err := planDueSchedule(schedule, now, d.misfireThreshold)
+		if err != nil {
+			reasons[schedule.ID] = err
+			return nil, time.Time{}, fmt.Errorf("%w: %v", repository.ErrScheduleRejected, err)
+		}
+
+		scheduleID := schedule.ID
+		var executions []*models.ReportExecution
+		for _, run := range plan.Runs {
+			scheduledAt := run.ScheduledAt
+
+			execution, err := d.executionService.ExecuteAsyncTx(tx, ExecuteAsyncInput{
+				ConfigID:    schedule.ConfigID,
+				ScheduleID:  &scheduleID,
+				ExecutedBy:  "scheduler",
+				ScheduledAt: &scheduledAt,
+				LastRunAt:   run.LastRunAt,
+			})
+			if err != nil {
+				var rejected *RejectedExecutionError
+				if errors.As(err, &rejected) {
+					reasons[schedule.ID] = err
+					return nil, time.Time{}, fmt.Errorf("%w: %v", repository.ErrScheduleRejected, err)
+				}
+
+				// Nothing of this schedule is committed; it stays due and is retried on the next poll
+				log.Error().
+					Err(err).
+					Int("schedule_id", schedule.ID).
+					Int("config_id", schedule.ConfigID).
+					Time("scheduled_at", scheduledAt).
+					Msg("Failed to queue scheduled execution")
+				return nil, time.Time{}, err
+			}
+			executions = append(executions, execution)
+		}
+
+		plans[schedule.ID] = plan
+		queued[schedule.ID] = executions
+		return plan.LastRunAt, plan.NextRunAt, nil
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to claim due schedules")
+		return
+	}
+
+	for _, schedule := range deactivated {
+		d.recordDeactivation(schedule, reasons[schedule.ID])
+	}
+
+	if len(claimed) > 0 {
+		notifyOutboxRelay()
+	}
+
+	for _, schedule := range claimed {
+		plan := plans[schedule.ID]
+
+		if plan.Misfired {
+			log.Warn().
+				Int("schedule_id", schedule.ID).
+				Str("misfire_policy", schedule.MisfirePolicy).
+				Int("missed_runs", plan.Missed).
+				Int("replayed_runs", len(plan.Runs)).
+				Msg("Schedule misfire detected")
+		}
+
+		for _, execution := range queued[schedule.ID] {
+			log.Info().
+				Int("schedule_id", schedule.ID).
+				Int("config_id", schedule.ConfigID).
+				Str("execution_id", execution.ID).
+				Interface("scheduled_at", execution.ExecutionContext["scheduled_at"]).
+				Msg("Scheduled execution queued")
+		}
+	}
+}
+
+// recordDeactivation logs and audits a schedule the dispatcher switched off because its runs can never be queued
+func (d *ScheduleDispatcher) recordDeactivation(schedule models.ReportSchedule, reason error) {
+	reasonText := "unknown"
+	if reason != nil {
+		reasonText = reason.Error()
+	}
+
+	log.Error().
+		Int("schedule_id", schedule.ID).
+		Int("config_id", schedule.ConfigID).
+		Str("reason", reasonText).
+		Msg("Schedule deactivated: its runs cannot be queued")
+
+	after := schedule
+	after.IsActive = false
+	summary, _ := json.Marshal(map[string]interface{}{
+		"entity":      "schedule",
+		"schedule_id": schedule.ID,
+		"reason":      reasonText,
+	})
+	changeSummary := string(summary)
+
+	if err := d.auditService.CreateAuditLogWithSummary(&schedule.ConfigID, "deactivate", schedule, after, &changeSummary, "scheduler", nil, nil); err != nil {
+		log.Error().Err(err).Int("schedule_id", schedule.ID).Msg("Failed to audit schedule deactivation")
+	}
+}
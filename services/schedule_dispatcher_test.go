@@ -0,0 +1,294 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"scheduling-report/config"
+	"scheduling-report/models"
+	"scheduling-report/utils"
+
+	"gorm.io/gorm"
+)
+
+const testDispatchers = 5
+
+// newTestDispatchers builds n dispatchers that share config.DB, like n replicas of the API
+func newTestDispatchers(n int, leaseTTLSeconds int) []*ScheduleDispatcher {
+	config.Config.SchedulerPollIntervalSeconds = 1
+	config.Config.SchedulerBatchSize = 100
+	config.Config.SchedulerLeaseTTLSeconds = leaseTTLSeconds
+	config.Config.SchedulerMisfireThresholdSeconds = 3600
+
+	dispatchers := make([]*ScheduleDispatcher, n)
+	for i := range dispatchers {
+		dispatchers[i] = NewScheduleDispatcher()
+	}
+	return dispatchers
+}
+
+// tickAll runs one poll on every dispatcher concurrently
+func tickAll(dispatchers []*ScheduleDispatcher) {
+	var wg sync.WaitGroup
+	for _, d := range dispatchers {
+		wg.Add(1)
+		go func(d *ScheduleDispatcher) {
+			defer wg.Done()
+			d.tick()
+		}(d)
+	}
+	wg.Wait()
+}
+
+// leaders returns the dispatchers that believe they hold the lease
+func leaders(dispatchers []*ScheduleDispatcher) []*ScheduleDispatcher {
+	var result []*ScheduleDispatcher
+	for _, d := range dispatchers {
+		if d.IsLeader() {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+func assertSingleLeader(t *testing.T, db *gorm.DB, dispatchers []*ScheduleDispatcher) *ScheduleDispatcher {
+	t.Helper()
+
+	current := leaders(dispatchers)
+	if len(current) != 1 {
+		t.Fatalf("got %d leaders, want exactly 1", len(current))
+	}
+
+	var lease models.SchedulerLease
+	if err := db.Where("name = ?", SchedulerLeaseName).First(&lease).Error; err != nil {
+		t.Fatalf("read lease: %v", err)
+	}
+	if lease.HolderID != current[0].HolderID() {
+		t.Fatalf("lease held by %q, but leader is %q", lease.HolderID, current[0].HolderID())
+	}
+
+	return current[0]
+}
+
+func TestDispatcherLeaseElectsOneLeaderAtATime(t *testing.T) {
+	db := useTestDB(t)
+	dispatchers := newTestDispatchers(testDispatchers, 60)
+
+	tickAll(dispatchers)
+	leader := assertSingleLeader(t, db, dispatchers)
+
+	// Renewals keep the same leader
+	for i := 0; i < 3; i++ {
+		tickAll(dispatchers)
+		if got := assertSingleLeader(t, db, dispatchers); got != leader {
+			t.Fatalf("leader changed from %s to %s while the lease was renewed", leader.HolderID(), got.HolderID())
+		}
+	}
+}
+
+func TestDispatcherLeaseTakeoverAfterRelease(t *testing.T) {
+	db := useTestDB(t)
+	dispatchers := newTestDispatchers(testDispatchers, 60)
+
+	tickAll(dispatchers)
+	leader := assertSingleLeader(t, db, dispatchers)
+
+	if err := leader.leaseRepo.Release(SchedulerLeaseName, leader.HolderID()); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+
+	var others []*ScheduleDispatcher
+	for _, d := range dispatchers {
+		if d != leader {
+			others = append(others, d)
+		}
+	}
+	tickAll(others)
+	leader.isLeader.Store(false)
+
+	if got := assertSingleLeader(t, db, dispatchers); got == leader {
+		t.Fatal("released lease was not taken over by another dispatcher")
+	}
+}
+
+func TestDispatcherLeaseTakeoverAfterExpiry(t *testing.T) {
+	db := useTestDB(t)
+	dispatchers := newTestDispatchers(testDispatchers, 1)
+
+	tickAll(dispatchers)
+	leader := assertSingleLeader(t, db, dispatchers)
+
+	// The leader stops renewing (crashed); the others only win once the TTL has passed
+	var others []*ScheduleDispatcher
+	for _, d := range dispatchers {
+		if d != leader {
+			others = append(others, d)
+		}
+	}
+	leader.isLeader.Store(false)
+
+	tickAll(others)
+	if got := leaders(others); len(got) != 0 {
+		t.Fatalf("%d dispatchers took over an unexpired lease", len(got))
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+
+	tickAll(others)
+	if got := assertSingleLeader(t, db, dispatchers); got == leader {
+		t.Fatal("expired lease was not taken over by another dispatcher")
+	}
+}
+
+func TestDispatchersQueueEachDueRunExactlyOnce(t *testing.T) {
+	db := useTestDB(t)
+	reportConfig := seedConfig(t, db)
+	dispatchers := newTestDispatchers(testDispatchers, 60)
+
+	start := time.Now().UTC()
+	firstRun := start.Truncate(time.Minute).Add(-3 * time.Minute)
+
+	const scheduleCount = 20
+	for i := 0; i < scheduleCount; i++ {
+		policy := models.MisfirePolicyRunAll
+		if i%2 == 1 {
+			policy = models.MisfirePolicyRunOnce
+		}
+		schedule := models.ReportSchedule{
+			ConfigID:       reportConfig.ID,
+			CronExpression: "* * * * *",
+			Timezone:       "UTC",
+			IsActive:       true,
+			NextRunAt:      &models.CustomTime{Time: firstRun},
+			MisfirePolicy:  policy,
+			MisfireMaxRuns: 10,
+			CreatedBy:      "test",
+			UpdatedBy:      "test",
+			Version:        1,
+		}
+		if err := db.Create(&schedule).Error; err != nil {
+			t.Fatalf("seed schedule: %v", err)
+		}
+	}
+
+	// Every dispatcher polls as if it were the leader, the worst case of an overlapping handover
+	for round := 0; round < 3; round++ {
+		var wg sync.WaitGroup
+		for _, d := range dispatchers {
+			wg.Add(1)
+			go func(d *ScheduleDispatcher) {
+				defer wg.Done()
+				d.dispatchDue()
+			}(d)
+		}
+		wg.Wait()
+	}
+
+	var schedules []models.ReportSchedule
+	if err := db.Order("id").Find(&schedules).Error; err != nil {
+		t.Fatalf("load schedules: %v", err)
+	}
+
+	var executions []models.ReportExecution
+	if err := db.Find(&executions).Error; err != nil {
+		t.Fatalf("load executions: %v", err)
+	}
+
+	var outboxCount int64
+	if err := db.Model(&models.ExecutionOutbox{}).Count(&outboxCount).Error; err != nil {
+		t.Fatalf("count outbox: %v", err)
+	}
+	if int(outboxCount) != len(executions) {
+		t.Errorf("got %d outbox rows for %d executions, want one each", outboxCount, len(executions))
+	}
+
+	runs := map[int]map[string]int{}
+	for _, execution := range executions {
+		if execution.ScheduleID == nil {
+			t.Fatalf("execution %s has no schedule", execution.ID)
+		}
+		scheduledAt, err := time.Parse(time.RFC3339, fmt.Sprint(execution.ExecutionContext["scheduled_at"]))
+		if err != nil {
+			t.Fatalf("execution %s scheduled_at: %v", execution.ID, err)
+		}
+		if runs[*execution.ScheduleID] == nil {
+			runs[*execution.ScheduleID] = map[string]int{}
+		}
+		runs[*execution.ScheduleID][scheduledAt.UTC().Format(time.RFC3339)]++
+	}
+
+	due, _, err := utils.CalculateRunsBetween("* * * * *", "UTC", firstRun, start, 0)
+	if err != nil {
+		t.Fatalf("CalculateRunsBetween: %v", err)
+	}
+
+	for _, schedule := range schedules {
+		for scheduledAt, count := range runs[schedule.ID] {
+			if count != 1 {
+				t.Errorf("schedule %d run %s queued %d times", schedule.ID, scheduledAt, count)
+			}
+		}
+
+		if schedule.MisfirePolicy == models.MisfirePolicyRunAll {
+			for _, run := range due {
+				if runs[schedule.ID][run.UTC().Format(time.RFC3339)] != 1 {
+					t.Errorf("schedule %d run %s was not queued exactly once", schedule.ID, run.Format(time.RFC3339))
+				}
+			}
+		} else if len(runs[schedule.ID]) != 1 {
+			t.Errorf("run_once schedule %d queued %d runs, want 1", schedule.ID, len(runs[schedule.ID]))
+		}
+
+		if schedule.NextRunAt == nil || !schedule.NextRunAt.After(start) {
+			t.Errorf("schedule %d next_run_at = %v, want after %v", schedule.ID, schedule.NextRunAt, start)
+		}
+	}
+}
+
+func TestDispatcherDeactivatesUnrunnableSchedule(t *testing.T) {
+	db := useTestDB(t)
+	reportConfig := seedConfig(t, db)
+	dispatcher := newTestDispatchers(1, 60)[0]
+
+	schedule := models.ReportSchedule{
+		ConfigID:       reportConfig.ID,
+		CronExpression: "not a cron",
+		Timezone:       "UTC",
+		IsActive:       true,
+		NextRunAt:      &models.CustomTime{Time: time.Now().UTC().Add(-time.Minute)},
+		MisfirePolicy:  models.MisfirePolicyRunOnce,
+		MisfireMaxRuns: 10,
+		CreatedBy:      "test",
+		UpdatedBy:      "test",
+		Version:        1,
+	}
+	if err := db.Create(&schedule).Error; err != nil {
+		t.Fatalf("seed schedule: %v", err)
+	}
+
+	dispatcher.dispatchDue()
+
+	var stored models.ReportSchedule
+	if err := db.First(&stored, schedule.ID).Error; err != nil {
+		t.Fatalf("load schedule: %v", err)
+	}
+	if stored.IsActive {
+		t.Error("schedule with a broken cron is still active")
+	}
+
+	var audits []models.ReportConfigAudit
+	if err := db.Where("config_id = ? AND action = ?", reportConfig.ID, "deactivate").Find(&audits).Error; err != nil {
+		t.Fatalf("load audits: %v", err)
+	}
+	if len(audits) != 1 || audits[0].PerformedBy != "scheduler" || audits[0].ChangeSummary == nil {
+		t.Fatalf("got audits %+v, want one scheduler deactivation with a reason", audits)
+	}
+
+	var executions int64
+	db.Model(&models.ReportExecution{}).Count(&executions)
+	if executions != 0 {
+		t.Errorf("got %d executions for an unrunnable schedule", executions)
+	}
+}
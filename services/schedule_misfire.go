@@ -0,0 +1,91 @@
+package services
+
+import (
+	"time"
+
+	"scheduling-report/models"
+	"scheduling-report/utils"
+)
+
+// scheduledRun is one occurrence the dispatcher queues for a schedule
+type scheduledRun struct {
+	ScheduledAt time.Time
+	// LastRunAt is the start of the window this run covers; nil lets the worker derive it from the cron
+	LastRunAt *time.Time
+}
+
+// schedulePlan describes what the dispatcher does with one due schedule
+type schedulePlan struct {
+	Runs []scheduledRun
+	// LastRunAt is only moved by the dispatcher for skipped runs; successful runs
+	// advance it when their result comes back (see ExecutionResultService)
+	LastRunAt *time.Time
+	NextRunAt time.Time
+	Missed    int  // overdue occurrences found between next_run_at and now
+	Misfired  bool // true when the misfire policy was applied
+}
+
+// planDueSchedule lists the occurrences between the schedule's next_run_at and now and applies
+// its misfire policy. A single occurrence younger than misfireThreshold is an ordinary on-time run.
+func planDueSchedule(schedule models.ReportSchedule, now time.Time, misfireThreshold time.Duration) (*schedulePlan, error) {
+	nextRunAt, err := utils.CalculateNextRun(schedule.CronExpression, schedule.Timezone, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only run_all needs more than the latest occurrence
+	keep := 1
+	if schedule.MisfirePolicy == models.MisfirePolicyRunAll {
+		keep = schedule.MisfireMaxRuns
+		if keep <= 0 {
+			keep = 1
+		}
+	}
+
+	runs, total, err := utils.CalculateRunsBetween(schedule.CronExpression, schedule.Timezone, schedule.NextRunAt.Time, now, keep)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &schedulePlan{NextRunAt: nextRunAt, Missed: total}
+	if total == 0 {
+		return plan, nil
+	}
+
+	var previousRunAt *time.Time
+	if schedule.LastRunAt != nil && !schedule.LastRunAt.IsZero() {
+		lastRunAt := schedule.LastRunAt.Time
+		previousRunAt = &lastRunAt
+	}
+
+	latest := runs[len(runs)-1]
+
+	// On time: exactly one occurrence, fired within the threshold
+	if total == 1 && now.Sub(latest) <= misfireThreshold {
+		plan.Missed = 0
+		plan.Runs = []scheduledRun{{ScheduledAt: latest, LastRunAt: previousRunAt}}
+		return plan, nil
+	}
+
+	plan.Misfired = true
+	switch schedule.MisfirePolicy {
+	case models.MisfirePolicySkip:
+		// last_run_at still moves forward so the next window does not swallow the skipped period
+		plan.LastRunAt = &latest
+
+	case models.MisfirePolicyRunAll:
+		// Runs beyond the cap were dropped, so the first kept run only covers its own cron window
+		if total > len(runs) {
+			previousRunAt = nil
+		}
+		for i := range runs {
+			plan.Runs = append(plan.Runs, scheduledRun{ScheduledAt: runs[i], LastRunAt: previousRunAt})
+			previousRunAt = &runs[i]
+		}
+
+	default: // models.MisfirePolicyRunOnce
+		plan.Runs = []scheduledRun{{ScheduledAt: latest, LastRunAt: previousRunAt}}
+	}
+
+	return plan, nil
+}
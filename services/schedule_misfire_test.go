@@ -0,0 +1,182 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"scheduling-report/models"
+)
+
+func TestPlanDueSchedule(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 3, 1, hour, minute, 0, 0, time.UTC)
+	}
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	// Hourly schedule evaluated at 12:10; the next run after now is always 13:00
+	now := at(12, 10)
+
+	tests := []struct {
+		name        string
+		policy      string
+		maxRuns     int
+		nextRunAt   time.Time
+		lastRunAt   *time.Time
+		threshold   time.Duration
+		wantRuns    []scheduledRun
+		wantLastRun *time.Time
+		wantMissed  int
+		wantMisfire bool
+	}{
+		{
+			name:      "not due yet",
+			policy:    models.MisfirePolicyRunOnce,
+			nextRunAt: at(13, 0),
+			lastRunAt: ptr(at(12, 0)),
+			threshold: 30 * time.Minute,
+		},
+		{
+			name:      "on time within the threshold",
+			policy:    models.MisfirePolicyRunOnce,
+			nextRunAt: at(12, 0),
+			lastRunAt: ptr(at(11, 0)),
+			threshold: 30 * time.Minute,
+			wantRuns:  []scheduledRun{{ScheduledAt: at(12, 0), LastRunAt: ptr(at(11, 0))}},
+		},
+		{
+			name:      "first run has no window start",
+			policy:    models.MisfirePolicyRunAll,
+			maxRuns:   10,
+			nextRunAt: at(12, 0),
+			threshold: 30 * time.Minute,
+			wantRuns:  []scheduledRun{{ScheduledAt: at(12, 0)}},
+		},
+		{
+			name:        "one occurrence older than the threshold is a misfire",
+			policy:      models.MisfirePolicyRunOnce,
+			nextRunAt:   at(12, 0),
+			lastRunAt:   ptr(at(11, 0)),
+			threshold:   5 * time.Minute,
+			wantRuns:    []scheduledRun{{ScheduledAt: at(12, 0), LastRunAt: ptr(at(11, 0))}},
+			wantMissed:  1,
+			wantMisfire: true,
+		},
+		{
+			name:        "skip queues nothing and moves last_run_at to the latest occurrence",
+			policy:      models.MisfirePolicySkip,
+			nextRunAt:   at(9, 0),
+			lastRunAt:   ptr(at(8, 0)),
+			threshold:   30 * time.Minute,
+			wantLastRun: ptr(at(12, 0)),
+			wantMissed:  4,
+			wantMisfire: true,
+		},
+		{
+			name:        "run_once queues the latest occurrence covering the whole gap",
+			policy:      models.MisfirePolicyRunOnce,
+			nextRunAt:   at(9, 0),
+			lastRunAt:   ptr(at(8, 0)),
+			threshold:   30 * time.Minute,
+			wantRuns:    []scheduledRun{{ScheduledAt: at(12, 0), LastRunAt: ptr(at(8, 0))}},
+			wantMissed:  4,
+			wantMisfire: true,
+		},
+		{
+			name:        "run_once without last_run_at",
+			policy:      models.MisfirePolicyRunOnce,
+			nextRunAt:   at(9, 0),
+			threshold:   30 * time.Minute,
+			wantRuns:    []scheduledRun{{ScheduledAt: at(12, 0)}},
+			wantMissed:  4,
+			wantMisfire: true,
+		},
+		{
+			name:      "run_all queues every occurrence with chained windows",
+			policy:    models.MisfirePolicyRunAll,
+			maxRuns:   10,
+			nextRunAt: at(9, 0),
+			lastRunAt: ptr(at(8, 0)),
+			threshold: 30 * time.Minute,
+			wantRuns: []scheduledRun{
+				{ScheduledAt: at(9, 0), LastRunAt: ptr(at(8, 0))},
+				{ScheduledAt: at(10, 0), LastRunAt: ptr(at(9, 0))},
+				{ScheduledAt: at(11, 0), LastRunAt: ptr(at(10, 0))},
+				{ScheduledAt: at(12, 0), LastRunAt: ptr(at(11, 0))},
+			},
+			wantMissed:  4,
+			wantMisfire: true,
+		},
+		{
+			name:      "run_all keeps the most recent misfire_max_runs",
+			policy:    models.MisfirePolicyRunAll,
+			maxRuns:   2,
+			nextRunAt: at(9, 0),
+			lastRunAt: ptr(at(8, 0)),
+			threshold: 30 * time.Minute,
+			// The first kept run only covers its own cron window, not the dropped ones
+			wantRuns: []scheduledRun{
+				{ScheduledAt: at(11, 0)},
+				{ScheduledAt: at(12, 0), LastRunAt: ptr(at(11, 0))},
+			},
+			wantMissed:  4,
+			wantMisfire: true,
+		},
+		{
+			name:        "run_all with no cap set keeps one run",
+			policy:      models.MisfirePolicyRunAll,
+			nextRunAt:   at(9, 0),
+			lastRunAt:   ptr(at(8, 0)),
+			threshold:   30 * time.Minute,
+			wantRuns:    []scheduledRun{{ScheduledAt: at(12, 0)}},
+			wantMissed:  4,
+			wantMisfire: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule := models.ReportSchedule{
+				CronExpression: "0 * * * *",
+				Timezone:       "UTC",
+				NextRunAt:      &models.CustomTime{Time: tt.nextRunAt},
+				MisfirePolicy:  tt.policy,
+				MisfireMaxRuns: tt.maxRuns,
+			}
+			if tt.lastRunAt != nil {
+				schedule.LastRunAt = &models.CustomTime{Time: *tt.lastRunAt}
+			}
+
+			plan, err := planDueSchedule(schedule, now, tt.threshold)
+			if err != nil {
+				t.Fatalf("planDueSchedule: %v", err)
+			}
+
+			if !plan.NextRunAt.Equal(at(13, 0)) {
+				t.Errorf("next_run_at = %s, want %s", plan.NextRunAt, at(13, 0))
+			}
+			if plan.Missed != tt.wantMissed || plan.Misfired != tt.wantMisfire {
+				t.Errorf("missed = %d misfired = %t, want %d %t", plan.Missed, plan.Misfired, tt.wantMissed, tt.wantMisfire)
+			}
+			if !sameTime(plan.LastRunAt, tt.wantLastRun) {
+				t.Errorf("last_run_at = %v, want %v", plan.LastRunAt, tt.wantLastRun)
+			}
+
+			if len(plan.Runs) != len(tt.wantRuns) {
+				t.Fatalf("runs = %+v, want %+v", plan.Runs, tt.wantRuns)
+			}
+			for i, want := range tt.wantRuns {
+				got := plan.Runs[i]
+				if !got.ScheduledAt.Equal(want.ScheduledAt) || !sameTime(got.LastRunAt, want.LastRunAt) {
+					t.Errorf("run %d = %s from %v, want %s from %v", i, got.ScheduledAt, got.LastRunAt, want.ScheduledAt, want.LastRunAt)
+				}
+			}
+		})
+	}
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,575 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"scheduling-report/config"
+	"scheduling-report/models"
+	"scheduling-report/utils"
+
+	"gorm.io/gorm"
+)
+
+type ScheduleSyncService struct {
+	bundleService *ScheduleBundleService
+}
+
+func NewScheduleSyncService() *ScheduleSyncService {
+	return &ScheduleSyncService{
+		bundleService: NewScheduleBundleService(),
+	}
+}
+
+// SyncOptions controls how a manifest directory is applied
+type SyncOptions struct {
+	DryRun       bool   // Plan and check every change, then roll back
+	Prune        bool   // Deactivate managed configs that no manifest declares any more
+	Actor        string // performed_by of the audit entries
+	CostOverride bool   // Save even if a query's load score is above the threshold
+}
+
+// slugPattern is the shape of manifest slugs: lowercase words joined by dashes or underscores
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// errSyncDryRun rolls back a dry-run sync after the whole plan went through
+var errSyncDryRun = errors.New("dry run")
+
+// LoadManifests reads every .yaml, .yml and .json file under dir as a schedule manifest, ordered by slug
+func LoadManifests(dir string) ([]models.ScheduleManifest, error) {
+	var manifests []models.ScheduleManifest
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".yaml" && ext != ".yml" && ext != ".json" {
+			return nil
+		}
+
+		manifest, err := loadManifest(path, ext)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if other, ok := files[manifest.Slug]; ok {
+			return fmt.Errorf("%s: slug '%s' is already used by %s", path, manifest.Slug, other)
+		}
+		files[manifest.Slug] = path
+		manifests = append(manifests, *manifest)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].Slug < manifests[j].Slug })
+	return manifests, nil
+}
+
+// loadManifest parses one manifest file, fills in defaults and checks it holds no literal secrets
+func loadManifest(path string, ext string) (*models.ScheduleManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if ext != ".json" {
+		if data, err = utils.YAMLToJSON(data); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	applyManifestDefaults(raw)
+	data, _ = json.Marshal(raw)
+
+	var manifest models.ScheduleManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.Slug == "" {
+		manifest.Slug = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if !slugPattern.MatchString(manifest.Slug) {
+		return nil, fmt.Errorf("invalid slug '%s': use lowercase letters, digits, '-' and '_'", manifest.Slug)
+	}
+
+	for _, delivery := range manifest.Config.Deliveries {
+		if key := literalSecretField(delivery.DeliveryConfig); key != "" {
+			return nil, fmt.Errorf("delivery '%s' has a literal secret in '%s'; use a secret reference or a ${secret:...} placeholder", delivery.DeliveryName, key)
+		}
+	}
+
+	return &manifest, nil
+}
+
+// applyManifestDefaults fills in the settings a manifest may omit, with the same defaults as the API
+func applyManifestDefaults(raw map[string]interface{}) {
+	setDefault(raw, "is_active", true)
+	setDefault(raw, "timezone", "UTC")
+	setDefault(raw, "misfire_policy", models.MisfirePolicyRunOnce)
+	setDefault(raw, "misfire_max_runs", 10)
+
+	configRaw, _ := raw["config"].(map[string]interface{})
+	if configRaw == nil {
+		return
+	}
+	setDefault(configRaw, "timeout_seconds", 300)
+	setDefault(configRaw, "max_rows", 10000)
+
+	deliveries, _ := configRaw["deliveries"].([]interface{})
+	for _, item := range deliveries {
+		delivery, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		setDefault(delivery, "is_active", true)
+		setDefault(delivery, "max_retry", 3)
+		setDefault(delivery, "retry_interval_minutes", 5)
+		setDefault(delivery, "delivery_config", map[string]interface{}{})
+
+		recipients, _ := delivery["recipients"].([]interface{})
+		for _, item := range recipients {
+			if recipient, ok := item.(map[string]interface{}); ok {
+				setDefault(recipient, "is_active", true)
+				setDefault(recipient, "recipient_type", "email")
+			}
+		}
+	}
+}
+
+func setDefault(raw map[string]interface{}, key string, value interface{}) {
+	if _, ok := raw[key]; !ok {
+		raw[key] = value
+	}
+}
+
+// literalSecretField returns the first secret field holding a value rather than a reference or placeholder
+func literalSecretField(deliveryConfig map[string]interface{}) string {
+	for key, value := range deliveryConfig {
+		switch v := value.(type) {
+		case string:
+			if utils.IsSecretField(key) && v != "" && !utils.IsSecretReference(v) && !secretPlaceholderPattern.MatchString(v) {
+				return key
+			}
+		case map[string]interface{}:
+			if nested := literalSecretField(v); nested != "" {
+				return key + "." + nested
+			}
+		}
+	}
+	return ""
+}
+
+// scheduleState is the comparable form of a bundled schedule: deliveries ordered by name, recipients by
+// type and value, tags and owners as they are stored, and secret placeholders reduced to one token
+// since their names do not matter
+func scheduleState(bundleSchedule models.BundleSchedule) (map[string]interface{}, error) {
+	normalized := bundleSchedule
+	normalized.Config.Folder = strings.Trim(bundleSchedule.Config.Folder, "/")
+	normalized.Config.Owners = normalizeOwners(bundleSchedule.Config.Owners)
+	normalized.Config.Tags = normalizeTagNames(bundleSchedule.Config.Tags)
+	normalized.Config.Deliveries = make([]models.BundleDelivery, len(bundleSchedule.Config.Deliveries))
+	for i, delivery := range bundleSchedule.Config.Deliveries {
+		delivery.DeliveryConfig = genericPlaceholders(delivery.DeliveryConfig)
+		delivery.Recipients = append([]models.BundleRecipient(nil), delivery.Recipients...)
+		sort.SliceStable(delivery.Recipients, func(a, b int) bool {
+			if delivery.Recipients[a].RecipientType != delivery.Recipients[b].RecipientType {
+				return delivery.Recipients[a].RecipientType < delivery.Recipients[b].RecipientType
+			}
+			return delivery.Recipients[a].RecipientValue < delivery.Recipients[b].RecipientValue
+		})
+		normalized.Config.Deliveries[i] = delivery
+	}
+	sort.SliceStable(normalized.Config.Deliveries, func(a, b int) bool {
+		return normalized.Config.Deliveries[a].DeliveryName < normalized.Config.Deliveries[b].DeliveryName
+	})
+
+	// Round-trip through JSON so numbers and empty values compare the same whichever side they came from
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, err
+	}
+	var state map[string]interface{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func genericPlaceholders(deliveryConfig map[string]interface{}) map[string]interface{} {
+	if deliveryConfig == nil {
+		return map[string]interface{}{}
+	}
+	result := make(map[string]interface{}, len(deliveryConfig))
+	for key, value := range deliveryConfig {
+		switch v := value.(type) {
+		case string:
+			if secretPlaceholderPattern.MatchString(v) {
+				result[key] = "${secret}"
+				continue
+			}
+			result[key] = v
+		case map[string]interface{}:
+			result[key] = genericPlaceholders(v)
+		default:
+			result[key] = v
+		}
+	}
+	return result
+}
+
+// stateChecksum hashes a schedule state; encoding/json sorts map keys, so equal states hash the same
+func stateChecksum(state map[string]interface{}) string {
+	data, _ := json.Marshal(state)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// stateChanges lists the schedule fields, and config fields as config.<field>, that differ between two states
+func stateChanges(current map[string]interface{}, desired map[string]interface{}) []string {
+	changes := diffKeys(current, desired, "", "config")
+	currentConfig, _ := current["config"].(map[string]interface{})
+	desiredConfig, _ := desired["config"].(map[string]interface{})
+	return append(changes, diffKeys(currentConfig, desiredConfig, "config.", "")...)
+}
+
+func diffKeys(current map[string]interface{}, desired map[string]interface{}, prefix string, skip string) []string {
+	keys := map[string]bool{}
+	for key := range current {
+		keys[key] = true
+	}
+	for key := range desired {
+		keys[key] = true
+	}
+
+	var changes []string
+	for key := range keys {
+		if key != skip && !reflect.DeepEqual(current[key], desired[key]) {
+			changes = append(changes, prefix+key)
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// currentState loads the first schedule of a config and its comparable state
+func currentState(tx *gorm.DB, configID int) (*models.ReportSchedule, map[string]interface{}, error) {
+	var schedule models.ReportSchedule
+	if err := tx.Where("config_id = ?", configID).Order("id").First(&schedule).Error; err != nil {
+		return nil, nil, err
+	}
+	bundleSchedule, err := exportSchedule(tx, &schedule, map[string]bool{})
+	if err != nil {
+		return nil, nil, err
+	}
+	state, err := scheduleState(*bundleSchedule)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &schedule, state, nil
+}
+
+// SyncManifests applies a manifest directory: it creates configs for new slugs, updates the ones whose
+// state differs from their manifest and, with Prune, deactivates managed configs with no manifest.
+// Everything runs in one transaction through the complete schedule service, so each change is audited.
+func (s *ScheduleSyncService) SyncManifests(dir string, opts SyncOptions) ([]models.SyncPlanItem, error) {
+	manifests, err := LoadManifests(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Heavy queries need cost_override; EXPLAIN runs before the transaction is opened
+	costEstimates, err := checkManifestCosts(config.DB, manifests, opts.CostOverride)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan []models.SyncPlanItem
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		plan = make([]models.SyncPlanItem, 0, len(manifests))
+		declared := map[string]bool{}
+		missing := map[string]bool{}
+		for i, manifest := range manifests {
+			declared[manifest.Slug] = true
+			item, err := s.syncManifest(tx, manifest, opts, missing, costEstimates[i])
+			if err != nil {
+				return fmt.Errorf("%s: %w", manifest.Slug, err)
+			}
+			plan = append(plan, *item)
+		}
+
+		if len(missing) > 0 {
+			names := make([]string, 0, len(missing))
+			for name := range missing {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return &BundleError{Reason: "manifests use placeholders with no stored secret to keep: " + strings.Join(names, ", "), Secrets: names}
+		}
+
+		if opts.Prune {
+			pruned, err := s.pruneUndeclared(tx, declared, opts.Actor)
+			if err != nil {
+				return err
+			}
+			plan = append(plan, pruned...)
+		}
+
+		if opts.DryRun {
+			return errSyncDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errSyncDryRun) {
+		for i := range plan {
+			if plan[i].Action == models.SyncActionCreate {
+				plan[i].ConfigID, plan[i].ScheduleID = 0, 0
+			}
+		}
+		return plan, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// checkManifestCosts runs the cost gate for every manifest that creates a config or changes a managed
+// config's query, by manifest index. Manifests whose datasource is missing are left to syncManifest to report.
+func checkManifestCosts(db *gorm.DB, manifests []models.ScheduleManifest, override bool) ([]*QueryCostEstimate, error) {
+	costEstimates := make([]*QueryCostEstimate, len(manifests))
+	for i, manifest := range manifests {
+		var datasources []models.DataSource
+		if err := db.Where("name = ?", manifest.Config.Datasource).Limit(1).Find(&datasources).Error; err != nil {
+			return nil, err
+		}
+		if len(datasources) == 0 {
+			continue
+		}
+
+		var existing []models.ReportConfig
+		if err := db.Where("slug = ?", manifest.Slug).Limit(1).Find(&existing).Error; err != nil {
+			return nil, err
+		}
+		var managed *models.ReportConfig
+		if len(existing) > 0 {
+			managed = &existing[0]
+		}
+
+		costEstimate, err := checkBundleCost(db, manifest.BundleSchedule, datasources[0].ID, managed, override)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", manifest.Slug, err)
+		}
+		costEstimates[i] = costEstimate
+	}
+	return costEstimates, nil
+}
+
+// syncManifest plans and applies one manifest using tx.
+// costEstimate is what checkManifestCosts returned for it.
+func (s *ScheduleSyncService) syncManifest(tx *gorm.DB, manifest models.ScheduleManifest, opts SyncOptions, missing map[string]bool, costEstimate *QueryCostEstimate) (*models.SyncPlanItem, error) {
+	item := &models.SyncPlanItem{Slug: manifest.Slug, ReportName: manifest.Config.ReportName}
+
+	var datasource models.DataSource
+	if err := tx.Where("name = ?", manifest.Config.Datasource).First(&datasource).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &BundleError{Reason: fmt.Sprintf("datasource '%s' not found", manifest.Config.Datasource)}
+		}
+		return nil, err
+	}
+
+	desired, err := scheduleState(manifest.BundleSchedule)
+	if err != nil {
+		return nil, err
+	}
+	req := models.ScheduleImportRequest{ImportedBy: opts.Actor, CostOverride: opts.CostOverride}
+
+	var existing []models.ReportConfig
+	if err := tx.Where("slug = ?", manifest.Slug).Limit(1).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		if err := checkReportNameFree(tx, manifest.Config.ReportName, 0); err != nil {
+			return nil, err
+		}
+		response, err := s.bundleService.createSchedule(tx, manifest.BundleSchedule, manifest.Config.ReportName, datasource.ID, req, missing, costEstimate)
+		if err != nil {
+			return nil, err
+		}
+		item.Action = models.SyncActionCreate
+		item.ConfigID, item.ScheduleID = response.ConfigID, response.ScheduleID
+		return item, markManaged(tx, response.ConfigID, manifest.Slug)
+	}
+
+	reportConfig := &existing[0]
+	schedule, current, err := currentState(tx, reportConfig.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &BundleError{Reason: fmt.Sprintf("report config '%s' has no schedule to sync", reportConfig.ReportName)}
+		}
+		return nil, err
+	}
+	item.ConfigID, item.ScheduleID = reportConfig.ID, schedule.ID
+	item.Drifted = reportConfig.ManagedChecksum != nil && *reportConfig.ManagedChecksum != stateChecksum(current)
+
+	item.Changes = stateChanges(current, desired)
+	if !reportConfig.IsActive {
+		item.Changes = append(item.Changes, "config.is_active")
+	}
+	if len(item.Changes) == 0 {
+		item.Action = models.SyncActionNoop
+		if item.Drifted {
+			// Edited outside sync to what the manifest now says; take that state as applied
+			return item, markManaged(tx, reportConfig.ID, manifest.Slug)
+		}
+		return item, nil
+	}
+
+	if manifest.Config.ReportName != reportConfig.ReportName {
+		if err := checkReportNameFree(tx, manifest.Config.ReportName, reportConfig.ID); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := s.bundleService.overwriteSchedule(tx, reportConfig, manifest.BundleSchedule, datasource.ID, req, missing, &models.ScheduleImportResult{}, costEstimate); err != nil {
+		return nil, err
+	}
+	if !reportConfig.IsActive {
+		if err := setConfigActive(tx, reportConfig, true, opts.Actor); err != nil {
+			return nil, err
+		}
+	}
+	item.Action = models.SyncActionUpdate
+	return item, markManaged(tx, reportConfig.ID, manifest.Slug)
+}
+
+// checkReportNameFree fails when another config than exceptID already uses name
+func checkReportNameFree(tx *gorm.DB, name string, exceptID int) error {
+	var count int64
+	if err := tx.Model(&models.ReportConfig{}).Where("report_name = ? AND id <> ?", name, exceptID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("report config with name '%s' already exists", name)
+	}
+	return nil
+}
+
+// markManaged records a config as owned by sync under slug, with its state as now stored as the applied one
+func markManaged(tx *gorm.DB, configID int, slug string) error {
+	_, state, err := currentState(tx, configID)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&models.ReportConfig{}).Where("id = ?", configID).Updates(map[string]interface{}{
+		"managed_by":       models.ManagedBySync,
+		"slug":             slug,
+		"managed_checksum": stateChecksum(state),
+	}).Error
+}
+
+// setConfigActive activates or deactivates a config with its schedules, audited like a toggle
+func setConfigActive(tx *gorm.DB, reportConfig *models.ReportConfig, isActive bool, performedBy string) error {
+	now := time.Now()
+	// Activation is not versioned content (see ToggleActive), so the config version stays put
+	if err := tx.Model(reportConfig).Updates(map[string]interface{}{
+		"is_active":  isActive,
+		"updated_by": performedBy,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update config: %w", err)
+	}
+	if !isActive {
+		if err := tx.Model(&models.ReportSchedule{}).Where("config_id = ? AND is_active = ?", reportConfig.ID, true).Updates(map[string]interface{}{
+			"is_active":  false,
+			"updated_at": now,
+			"updated_by": performedBy,
+			"version":    gorm.Expr("version + 1"),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to deactivate schedules: %w", err)
+		}
+	}
+
+	action := "deactivate"
+	if isActive {
+		action = "activate"
+	}
+	return writeConfigAudit(tx, configAuditEntry{
+		ConfigID:    reportConfig.ID,
+		Action:      action,
+		FieldName:   "is_active",
+		Before:      !isActive,
+		After:       isActive,
+		PerformedBy: performedBy,
+	})
+}
+
+// pruneUndeclared deactivates the active managed configs whose slug no manifest declares
+func (s *ScheduleSyncService) pruneUndeclared(tx *gorm.DB, declared map[string]bool, actor string) ([]models.SyncPlanItem, error) {
+	var managed []models.ReportConfig
+	if err := tx.Where("managed_by = ? AND is_active = ?", models.ManagedBySync, true).Order("slug").Find(&managed).Error; err != nil {
+		return nil, err
+	}
+
+	var items []models.SyncPlanItem
+	for i := range managed {
+		reportConfig := &managed[i]
+		if reportConfig.Slug == nil || declared[*reportConfig.Slug] {
+			continue
+		}
+		if err := setConfigActive(tx, reportConfig, false, actor); err != nil {
+			return nil, fmt.Errorf("%s: %w", *reportConfig.Slug, err)
+		}
+		items = append(items, models.SyncPlanItem{
+			Slug:       *reportConfig.Slug,
+			ReportName: reportConfig.ReportName,
+			Action:     models.SyncActionDeactivate,
+			Changes:    []string{"config.is_active"},
+			ConfigID:   reportConfig.ID,
+		})
+	}
+	return items, nil
+}
+
+// GetDrift lists the managed configs whose stored state no longer matches what sync last applied
+func (s *ScheduleSyncService) GetDrift() ([]models.ManagedConfigDrift, error) {
+	var managed []models.ReportConfig
+	if err := config.DB.Where("managed_by IS NOT NULL").Order("slug").Find(&managed).Error; err != nil {
+		return nil, err
+	}
+
+	drift := []models.ManagedConfigDrift{}
+	for _, reportConfig := range managed {
+		if reportConfig.ManagedChecksum == nil || reportConfig.Slug == nil {
+			continue
+		}
+		_, state, err := currentState(config.DB, reportConfig.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if state != nil && stateChecksum(state) == *reportConfig.ManagedChecksum {
+			continue
+		}
+		drift = append(drift, models.ManagedConfigDrift{
+			ConfigID:   reportConfig.ID,
+			Slug:       *reportConfig.Slug,
+			ReportName: reportConfig.ReportName,
+			ManagedBy:  *reportConfig.ManagedBy,
+		})
+	}
+	return drift, nil
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"scheduling-report/models"
+
+	"gorm.io/gorm"
+)
+
+// writeManifest writes a manifest for slug reading from the seeded datasource
+func writeManifest(t *testing.T, dir string, slug string, reportName string) {
+	t.Helper()
+
+	manifest := fmt.Sprintf(`{
+  "cron_expression": "0 6 * * *",
+  "config": {
+    "report_name": %q,
+    "report_query": "SELECT id, total FROM sales",
+    "output_format": "csv",
+    "datasource": "reports",
+    "deliveries": [
+      {"delivery_name": "Sales team", "method": "email", "recipients": [{"recipient_value": "ana@example.com"}]}
+    ]
+  }
+}`, reportName)
+	if err := os.WriteFile(filepath.Join(dir, slug+".json"), []byte(manifest), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+}
+
+// loadManagedConfig returns the config sync manages under slug
+func loadManagedConfig(t *testing.T, db *gorm.DB, slug string) models.ReportConfig {
+	t.Helper()
+
+	var reportConfig models.ReportConfig
+	if err := db.Where("slug = ?", slug).First(&reportConfig).Error; err != nil {
+		t.Fatalf("load config %s: %v", slug, err)
+	}
+	return reportConfig
+}
+
+func TestSyncActivationKeepsConfigVersion(t *testing.T) {
+	db := useTestDB(t)
+	seedConfig(t, db)
+	dir := t.TempDir()
+	writeManifest(t, dir, "daily-sales", "Sales export")
+
+	// The seeded datasource cannot be reached, so the cost check needs an override
+	service := NewScheduleSyncService()
+	if _, err := service.SyncManifests(dir, SyncOptions{Actor: "sync", CostOverride: true}); err != nil {
+		t.Fatalf("SyncManifests: %v", err)
+	}
+	created := loadManagedConfig(t, db, "daily-sales")
+
+	if err := os.Remove(filepath.Join(dir, "daily-sales.json")); err != nil {
+		t.Fatalf("remove manifest: %v", err)
+	}
+	plan, err := service.SyncManifests(dir, SyncOptions{Actor: "sync", Prune: true})
+	if err != nil {
+		t.Fatalf("SyncManifests with prune: %v", err)
+	}
+	if len(plan) != 1 || plan[0].Action != models.SyncActionDeactivate {
+		t.Fatalf("plan = %+v, want daily-sales deactivated", plan)
+	}
+
+	pruned := loadManagedConfig(t, db, "daily-sales")
+	if pruned.IsActive || pruned.Version != created.Version {
+		t.Errorf("pruned config is_active = %t, version = %d; want inactive at version %d", pruned.IsActive, pruned.Version, created.Version)
+	}
+	var activeSchedules int64
+	db.Model(&models.ReportSchedule{}).Where("config_id = ? AND is_active = ?", created.ID, true).Count(&activeSchedules)
+	if activeSchedules != 0 {
+		t.Errorf("%d schedules still active, want them deactivated with the config", activeSchedules)
+	}
+	var audit models.ReportConfigAudit
+	if err := db.Where("config_id = ? AND action = ?", created.ID, "deactivate").First(&audit).Error; err != nil {
+		t.Fatalf("load deactivate audit: %v", err)
+	}
+	if audit.FieldName == nil || *audit.FieldName != "is_active" || *audit.BeforeValue != "true" || *audit.AfterValue != "false" {
+		t.Errorf("deactivate audit = %+v, want is_active true -> false", audit)
+	}
+
+	if err := setConfigActive(db, &pruned, true, "sync"); err != nil {
+		t.Fatalf("setConfigActive: %v", err)
+	}
+	if reactivated := loadManagedConfig(t, db, "daily-sales"); !reactivated.IsActive || reactivated.Version != created.Version {
+		t.Errorf("reactivated config is_active = %t, version = %d; want active at version %d", reactivated.IsActive, reactivated.Version, created.Version)
+	}
+}
+
+func TestSyncDryRunRollsBack(t *testing.T) {
+	db := useTestDB(t)
+	seedConfig(t, db)
+	dir := t.TempDir()
+	writeManifest(t, dir, "daily-sales", "Sales export")
+
+	service := NewScheduleSyncService()
+	if _, err := service.SyncManifests(dir, SyncOptions{Actor: "sync", CostOverride: true}); err != nil {
+		t.Fatalf("SyncManifests: %v", err)
+	}
+
+	// Replace daily-sales with weekly-sales: a dry run plans the create and the prune, then rolls both back
+	if err := os.Remove(filepath.Join(dir, "daily-sales.json")); err != nil {
+		t.Fatalf("remove manifest: %v", err)
+	}
+	writeManifest(t, dir, "weekly-sales", "Weekly sales export")
+
+	var configsBefore, auditsBefore int64
+	db.Model(&models.ReportConfig{}).Count(&configsBefore)
+	db.Model(&models.ReportConfigAudit{}).Count(&auditsBefore)
+
+	plan, err := service.SyncManifests(dir, SyncOptions{Actor: "sync", Prune: true, DryRun: true, CostOverride: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(plan) != 2 {
+		t.Fatalf("plan = %+v, want weekly-sales created and daily-sales deactivated", plan)
+	}
+	if plan[0].Slug != "weekly-sales" || plan[0].Action != models.SyncActionCreate || plan[0].ConfigID != 0 || plan[0].ScheduleID != 0 {
+		t.Errorf("plan[0] = %+v, want weekly-sales created, without the rolled back ids", plan[0])
+	}
+	if plan[1].Slug != "daily-sales" || plan[1].Action != models.SyncActionDeactivate {
+		t.Errorf("plan[1] = %+v, want daily-sales deactivated", plan[1])
+	}
+
+	var configsAfter, auditsAfter int64
+	db.Model(&models.ReportConfig{}).Count(&configsAfter)
+	db.Model(&models.ReportConfigAudit{}).Count(&auditsAfter)
+	if configsAfter != configsBefore || auditsAfter != auditsBefore {
+		t.Errorf("configs %d -> %d, audits %d -> %d; want the dry run rolled back", configsBefore, configsAfter, auditsBefore, auditsAfter)
+	}
+	if daily := loadManagedConfig(t, db, "daily-sales"); !daily.IsActive {
+		t.Error("daily-sales deactivated by a dry run")
+	}
+}
+
+func TestSyncAppliesCatalog(t *testing.T) {
+	db := useTestDB(t)
+	seedConfig(t, db)
+	for _, name := range []string{"finance", "monthly"} {
+		if err := db.Create(&models.ReportTag{Name: name, CreatedBy: "test", UpdatedBy: "test"}).Error; err != nil {
+			t.Fatalf("seed tag: %v", err)
+		}
+	}
+	dir := t.TempDir()
+	manifest := `{
+  "cron_expression": "0 6 * * *",
+  "config": {
+    "report_name": "Sales export",
+    "report_query": "SELECT id, total FROM sales",
+    "output_format": "csv",
+    "datasource": "reports",
+    "owner_team": "sales-ops",
+    "owners": ["ana"],
+    "tags": ["monthly", "finance"],
+    "deliveries": [
+      {"delivery_name": "Sales team", "method": "email", "recipients": [{"recipient_value": "ana@example.com"}]}
+    ]
+  }
+}`
+	if err := os.WriteFile(filepath.Join(dir, "daily-sales.json"), []byte(manifest), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+
+	service := NewScheduleSyncService()
+	if _, err := service.SyncManifests(dir, SyncOptions{Actor: "sync", CostOverride: true}); err != nil {
+		t.Fatalf("SyncManifests: %v", err)
+	}
+	created, err := NewReportConfigService().GetByID(loadManagedConfig(t, db, "daily-sales").ID)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if created.OwnerTeam == nil || *created.OwnerTeam != "sales-ops" || !reflect.DeepEqual(created.Tags, []string{"finance", "monthly"}) {
+		t.Fatalf("synced catalog = %v %v, want sales-ops [finance monthly]", created.OwnerTeam, created.Tags)
+	}
+
+	// Tags in another order are the same state
+	plan, err := service.SyncManifests(dir, SyncOptions{Actor: "sync"})
+	if err != nil {
+		t.Fatalf("second SyncManifests: %v", err)
+	}
+	if plan[0].Action != models.SyncActionNoop {
+		t.Errorf("second sync plan = %+v, want noop", plan[0])
+	}
+
+	// A metadata edit outside sync is drift, and sync puts the manifest's metadata back
+	if _, err := NewReportConfigService().UpdateMetadata(created.ID, ConfigMetadataInput{Tags: []string{"finance"}, UpdatedBy: "editor"}); err != nil {
+		t.Fatalf("UpdateMetadata: %v", err)
+	}
+	plan, err = service.SyncManifests(dir, SyncOptions{Actor: "sync"})
+	if err != nil {
+		t.Fatalf("third SyncManifests: %v", err)
+	}
+	if !plan[0].Drifted || !reflect.DeepEqual(plan[0].Changes, []string{"config.owner_team", "config.owners", "config.tags"}) {
+		t.Errorf("plan = %+v, want the drifted owner_team, owners and tags restored", plan[0])
+	}
+	restored, _ := NewReportConfigService().GetByID(created.ID)
+	if !reflect.DeepEqual(restored.Tags, []string{"finance", "monthly"}) {
+		t.Errorf("tags after sync = %v, want [finance monthly]", restored.Tags)
+	}
+}
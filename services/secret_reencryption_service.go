@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"scheduling-report/config"
+	"scheduling-report/repositories"
+	"scheduling-report/utils"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// ReencryptionSummary reports how many rows were rewritten under the active key
+type ReencryptionSummary struct {
+	ActiveKeyID string `json:"active_key_id"`
+	Datasources int    `json:"datasources"`
+	Deliveries  int    `json:"deliveries"`
+}
+
+type SecretReencryptionService struct{}
+
+func NewSecretReencryptionService() *SecretReencryptionService {
+	return &SecretReencryptionService{}
+}
+
+// ReencryptAll decrypts every stored credential with whichever key wrote it and saves it
+// again under the active key. Run it after adding a new active key; once it completes the
+// old key can be removed from ENCRYPTION_KEYS. Plaintext rows from before encryption was
+// enabled are encrypted as well.
+func (s *SecretReencryptionService) ReencryptAll() (*ReencryptionSummary, error) {
+	if !utils.SecretEncryptionEnabled() {
+		return nil, errors.New("no encryption keys configured")
+	}
+
+	summary := &ReencryptionSummary{ActiveKeyID: utils.ActiveSecretKeyID()}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		datasourceRepo := &repository.DatasourceRepository{DB: tx}
+		deliveryRepo := &repository.ReportDeliveryRepository{DB: tx}
+
+		datasources, err := datasourceRepo.GetAll(nil)
+		if err != nil {
+			return fmt.Errorf("failed to load datasources: %w", err)
+		}
+		for i := range datasources {
+			if err := datasourceRepo.UpdateSecrets(&datasources[i]); err != nil {
+				return fmt.Errorf("failed to re-encrypt datasource %d: %w", datasources[i].ID, err)
+			}
+			summary.Datasources++
+		}
+
+		deliveries, err := deliveryRepo.GetAll(nil)
+		if err != nil {
+			return fmt.Errorf("failed to load deliveries: %w", err)
+		}
+		for i := range deliveries {
+			if err := deliveryRepo.UpdateSecrets(&deliveries[i]); err != nil {
+				return fmt.Errorf("failed to re-encrypt delivery %d: %w", deliveries[i].ID, err)
+			}
+			summary.Deliveries++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("active_key_id", summary.ActiveKeyID).
+		Int("datasources", summary.Datasources).
+		Int("deliveries", summary.Deliveries).
+		Msg("Re-encrypted stored credentials")
+
+	return summary, nil
+}
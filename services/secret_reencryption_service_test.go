@@ -0,0 +1,93 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"scheduling-report/models"
+	"scheduling-report/utils"
+)
+
+func TestReencryptAllRewritesOldKeyRows(t *testing.T) {
+	db := useTestDB(t)
+	key1 := "k1:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 32)))
+	key2 := "k2:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("b", 32)))
+	t.Cleanup(func() { _ = utils.InitSecretKeys("", "") })
+
+	// One row from before encryption was enabled, the rest written under k1
+	legacy := models.DataSource{Name: "legacy", DbType: "mysql", ConnectionURL: "report:plain@tcp(db:3306)/legacy", IsActive: true, CreatedBy: "test", UpdatedBy: "test"}
+	if err := db.Create(&legacy).Error; err != nil {
+		t.Fatalf("seed legacy datasource: %v", err)
+	}
+	if err := utils.InitSecretKeys(key1, "k1"); err != nil {
+		t.Fatalf("InitSecretKeys: %v", err)
+	}
+	reportConfig := seedConfig(t, db)
+	delivery := models.ReportDelivery{
+		ConfigID:       reportConfig.ID,
+		DeliveryName:   "Mail",
+		Method:         "email",
+		DeliveryConfig: models.DeliveryConfig{"host": "smtp.example.com", "smtp_password": "s3cret"},
+		IsActive:       true,
+		CreatedBy:      "test",
+		UpdatedBy:      "test",
+		Version:        1,
+	}
+	if err := db.Create(&delivery).Error; err != nil {
+		t.Fatalf("seed delivery: %v", err)
+	}
+
+	// k2 becomes active; k1 is still loaded to read the old rows
+	if err := utils.InitSecretKeys(key1+","+key2, "k2"); err != nil {
+		t.Fatalf("InitSecretKeys: %v", err)
+	}
+	summary, err := NewSecretReencryptionService().ReencryptAll()
+	if err != nil {
+		t.Fatalf("ReencryptAll: %v", err)
+	}
+	if summary.ActiveKeyID != "k2" || summary.Datasources != 2 || summary.Deliveries != 1 {
+		t.Errorf("summary = %+v, want k2 with 2 datasources and 1 delivery", summary)
+	}
+
+	var connectionURLs []string
+	if err := db.Table("report_datasources").Pluck("connection_url", &connectionURLs).Error; err != nil {
+		t.Fatalf("load connection urls: %v", err)
+	}
+	for _, stored := range connectionURLs {
+		if utils.SecretKeyID(stored) != "k2" {
+			t.Errorf("connection_url %.20q... not written with k2", stored)
+		}
+	}
+	var deliveryConfig string
+	if err := db.Table("report_deliveries").Where("id = ?", delivery.ID).Pluck("delivery_config", &deliveryConfig).Error; err != nil {
+		t.Fatalf("load delivery config: %v", err)
+	}
+	var columns map[string]interface{}
+	if err := json.Unmarshal([]byte(deliveryConfig), &columns); err != nil {
+		t.Fatalf("unmarshal delivery config: %v", err)
+	}
+	if password, _ := columns["smtp_password"].(string); utils.SecretKeyID(password) != "k2" {
+		t.Errorf("smtp_password %.20q... not written with k2", password)
+	}
+
+	// With k1 retired every row still reads back
+	if err := utils.InitSecretKeys(key2, "k2"); err != nil {
+		t.Fatalf("InitSecretKeys: %v", err)
+	}
+	var reloaded models.DataSource
+	if err := db.First(&reloaded, legacy.ID).Error; err != nil {
+		t.Fatalf("reload datasource: %v", err)
+	}
+	if reloaded.ConnectionURL != legacy.ConnectionURL {
+		t.Errorf("connection_url = %q, want %q", reloaded.ConnectionURL, legacy.ConnectionURL)
+	}
+	var reloadedDelivery models.ReportDelivery
+	if err := db.First(&reloadedDelivery, delivery.ID).Error; err != nil {
+		t.Fatalf("reload delivery: %v", err)
+	}
+	if reloadedDelivery.DeliveryConfig["smtp_password"] != "s3cret" {
+		t.Errorf("smtp_password = %v, want it decrypted", reloadedDelivery.DeliveryConfig["smtp_password"])
+	}
+}
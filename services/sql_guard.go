@@ -0,0 +1,17 @@
+package services
+
+import (
+	"scheduling-report/config"
+	"scheduling-report/utils"
+)
+
+// CheckReportQuery rejects report queries that are not a single read-only statement for
+// dbType. configID is 0 for configs not saved yet; saved configs listed in
+// SQL_GUARD_EXEMPT_CONFIG_IDS skip the check, and SQL_GUARD_ALLOWLIST lets specific
+// keywords or functions through for every config.
+func CheckReportQuery(configID int, query string, dbType string) error {
+	if configID != 0 && config.Config.SQLGuardExemptConfigIDs[configID] {
+		return nil
+	}
+	return utils.AnalyzeReadOnlyQuery(query, dbType, config.Config.SQLGuardAllowlist)
+}
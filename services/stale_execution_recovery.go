@@ -0,0 +1,194 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"scheduling-report/config"
+	"scheduling-report/models"
+	"scheduling-report/repositories"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// requeueCountKey is the execution_context entry counting how often recovery re-queued an execution
+const requeueCountKey = "requeue_count"
+
+// StaleExecutionRecovery reclaims executions a worker claimed and never finished (it crashed or was
+// killed mid-run). An execution is stale once started_at plus its config's timeout_seconds plus a
+// grace period has passed; it is queued again, or failed once it has been re-queued maxRequeues times.
+// Every worker replica runs it; the status transitions make a concurrent sweep a no-op.
+type StaleExecutionRecovery struct {
+	executionRepo *repository.ReportExecutionRepository
+	resultService *ExecutionResultService
+	grace         time.Duration
+	interval      time.Duration
+	maxRequeues   int
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+func NewStaleExecutionRecovery() *StaleExecutionRecovery {
+	return &StaleExecutionRecovery{
+		executionRepo: repository.NewReportExecutionRepository(),
+		resultService: NewExecutionResultService(),
+		grace:         time.Duration(config.Config.WorkerStaleGraceSeconds) * time.Second,
+		interval:      time.Duration(config.Config.WorkerRecoveryIntervalSeconds) * time.Second,
+		maxRequeues:   config.Config.WorkerMaxRequeues,
+		stopCh:        make(chan struct{}),
+		doneCh:        make(chan struct{}),
+	}
+}
+
+// Start sweeps for stale executions in a background goroutine
+func (r *StaleExecutionRecovery) Start() {
+	log.Info().
+		Dur("interval", r.interval).
+		Dur("grace", r.grace).
+		Int("max_requeues", r.maxRequeues).
+		Msg("Stale execution recovery started")
+
+	go func() {
+		defer close(r.doneCh)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		r.recoverStale(time.Now())
+		for {
+			select {
+			case <-r.stopCh:
+				return
+			case <-ticker.C:
+				r.recoverStale(time.Now())
+			}
+		}
+	}()
+}
+
+// Stop signals the sweep loop to exit and waits for the current sweep to finish
+func (r *StaleExecutionRecovery) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopCh)
+	})
+	<-r.doneCh
+	log.Info().Msg("Stale execution recovery stopped")
+}
+
+// recoverStale re-queues or fails every execution that is stale at now
+func (r *StaleExecutionRecovery) recoverStale(now time.Time) {
+	stale, err := r.executionRepo.FindStaleRunning(now, defaultReportTimeoutSeconds, r.grace)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to find stale executions")
+		return
+	}
+
+	for _, execution := range stale {
+		requeues := requeueCount(execution.ExecutionContext)
+
+		if requeues < r.maxRequeues {
+			requeued, err := r.requeue(execution, requeues+1)
+			if err == nil {
+				if requeued {
+					log.Warn().
+						Str("execution_id", execution.ID).
+						Time("started_at", execution.StartedAt).
+						Int("requeue_count", requeues+1).
+						Msg("Stale execution queued again")
+				}
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Error().Err(err).Str("execution_id", execution.ID).Msg("Failed to requeue stale execution")
+				continue
+			}
+			// No request message left to send again; fail it instead
+		}
+
+		message := fmt.Sprintf("execution stopped responding: still running %s after it started, past its timeout and a %s grace period",
+			now.Sub(execution.StartedAt).Round(time.Second), r.grace)
+		if err := r.resultService.ApplyResult(ExecutionResult{
+			ExecutionID:  execution.ID,
+			Status:       ExecutionStatusFailed,
+			CompletedAt:  now.Format(time.RFC3339),
+			ErrorMessage: &message,
+		}); err != nil {
+			log.Error().Err(err).Str("execution_id", execution.ID).Msg("Failed to fail stale execution")
+			continue
+		}
+		log.Warn().
+			Str("execution_id", execution.ID).
+			Time("started_at", execution.StartedAt).
+			Int("requeue_count", requeues).
+			Msg("Stale execution failed")
+	}
+}
+
+// requeue moves a running execution back to queued and writes its request message to the outbox again.
+// Returns false when the execution left running meanwhile, and gorm.ErrRecordNotFound when it has no
+// request message to resend.
+func (r *StaleExecutionRecovery) requeue(execution models.ReportExecution, count int) (bool, error) {
+	requeued := false
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		executionRepo := &repository.ReportExecutionRepository{DB: tx}
+		outboxRepo := &repository.ExecutionOutboxRepository{DB: tx}
+
+		request, err := outboxRepo.GetLatestByExecutionID(execution.ID)
+		if err != nil {
+			return err
+		}
+
+		executionContext := models.ExecutionContext{}
+		for key, value := range execution.ExecutionContext {
+			executionContext[key] = value
+		}
+		executionContext[requeueCountKey] = count
+
+		changed, err := executionRepo.TransitionStatus(execution.ID, []string{ExecutionStatusRunning}, map[string]interface{}{
+			"status":            ExecutionStatusQueued,
+			"execution_context": executionContext,
+		})
+		if err != nil || !changed {
+			return err
+		}
+
+		if err := outboxRepo.Create(&models.ExecutionOutbox{
+			ExecutionID:   execution.ID,
+			Topic:         request.Topic,
+			MessageKey:    request.MessageKey,
+			Payload:       request.Payload,
+			Status:        models.OutboxStatusPending,
+			NextAttemptAt: time.Now(),
+		}); err != nil {
+			return err
+		}
+
+		requeued = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if requeued {
+		notifyOutboxRelay()
+	}
+	return requeued, nil
+}
+
+// requeueCount reads how often an execution has been re-queued from its context
+func requeueCount(executionContext models.ExecutionContext) int {
+	switch count := executionContext[requeueCountKey].(type) {
+	case float64:
+		return int(count)
+	case int:
+		return count
+	}
+	return 0
+}
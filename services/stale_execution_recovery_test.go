@@ -0,0 +1,98 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"scheduling-report/config"
+	"scheduling-report/models"
+)
+
+func TestStaleExecutionRecovery(t *testing.T) {
+	db := useTestDB(t)
+	reportConfig := seedConfig(t, db)
+	if err := db.Model(&reportConfig).Update("timeout_seconds", 600).Error; err != nil {
+		t.Fatalf("set timeout: %v", err)
+	}
+
+	config.Config.WorkerStaleGraceSeconds = 300
+	config.Config.WorkerRecoveryIntervalSeconds = 60
+	config.Config.WorkerMaxRequeues = 1
+	recovery := NewStaleExecutionRecovery()
+
+	now := time.Now().UTC()
+	seed := func(id string, startedAgo time.Duration, requeues int, withRequest bool) {
+		executionContext := models.ExecutionContext{}
+		if requeues > 0 {
+			executionContext[requeueCountKey] = requeues
+		}
+		if err := db.Create(&models.ReportExecution{
+			ID:               id,
+			ConfigID:         reportConfig.ID,
+			Status:           ExecutionStatusRunning,
+			StartedAt:        now.Add(-startedAgo),
+			ExecutedBy:       "scheduler",
+			ExecutionContext: executionContext,
+		}).Error; err != nil {
+			t.Fatalf("seed execution: %v", err)
+		}
+		if withRequest {
+			seedOutbox(t, db, id)
+			if err := db.Model(&models.ExecutionOutbox{}).Where("execution_id = ?", id).
+				Update("status", models.OutboxStatusSent).Error; err != nil {
+				t.Fatalf("mark request sent: %v", err)
+			}
+		}
+	}
+
+	// timeout 600s + grace 300s = stale after 15 minutes
+	seed("within-timeout", 10*time.Minute, 0, true)
+	seed("within-grace", 14*time.Minute, 0, true)
+	seed("stale", 20*time.Minute, 0, true)
+	seed("stale-requeued", 20*time.Minute, 1, true)
+	seed("stale-no-request", 20*time.Minute, 0, false)
+
+	recovery.recoverStale(now)
+
+	want := map[string]string{
+		"within-timeout":   ExecutionStatusRunning,
+		"within-grace":     ExecutionStatusRunning,
+		"stale":            ExecutionStatusQueued,
+		"stale-requeued":   ExecutionStatusFailed,
+		"stale-no-request": ExecutionStatusFailed,
+	}
+	for id, status := range want {
+		var execution models.ReportExecution
+		if err := db.First(&execution, "id = ?", id).Error; err != nil {
+			t.Fatalf("load %s: %v", id, err)
+		}
+		if execution.Status != status {
+			t.Errorf("%s status = %s, want %s", id, execution.Status, status)
+		}
+		if status == ExecutionStatusFailed && (execution.ErrorMessage == nil || execution.CompletedAt == nil) {
+			t.Errorf("%s failed without an error message and completed_at", id)
+		}
+	}
+
+	var requeued models.ReportExecution
+	db.First(&requeued, "id = ?", "stale")
+	if requeueCount(requeued.ExecutionContext) != 1 {
+		t.Errorf("stale requeue_count = %v, want 1", requeued.ExecutionContext[requeueCountKey])
+	}
+
+	var pending []models.ExecutionOutbox
+	if err := db.Where("status = ?", models.OutboxStatusPending).Find(&pending).Error; err != nil {
+		t.Fatalf("load outbox: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ExecutionID != "stale" {
+		t.Fatalf("pending outbox = %+v, want one resent request for the stale execution", pending)
+	}
+
+	// A second sweep finds nothing new: the re-queued execution is no longer running
+	recovery.recoverStale(now)
+	var count int64
+	db.Model(&models.ExecutionOutbox{}).Where("status = ?", models.OutboxStatusPending).Count(&count)
+	if count != 1 {
+		t.Errorf("second sweep left %d pending requests, want 1", count)
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"scheduling-report/config"
+	"scheduling-report/internal/testdb"
+	"scheduling-report/models"
+
+	gosqlite "github.com/glebarez/go-sqlite"
+	"gorm.io/gorm"
+)
+
+// useTestDB points config.DB at a fresh SQLite database for the duration of the test
+func useTestDB(tb testing.TB) *gorm.DB {
+	tb.Helper()
+
+	previous := config.DB
+	db := testdb.Open(tb)
+	config.DB = db
+	tb.Cleanup(func() { config.DB = previous })
+
+	return db
+}
+
+// seedConfig inserts a datasource and an active report config reading from it
+func seedConfig(tb testing.TB, db *gorm.DB) models.ReportConfig {
+	tb.Helper()
+
+	datasource := models.DataSource{
+		Name:          "reports",
+		DbType:        "mysql",
+		ConnectionURL: "report:report@tcp(localhost:3307)/reports",
+		IsActive:      true,
+		CreatedBy:     "test",
+		UpdatedBy:     "test",
+	}
+	if err := db.Create(&datasource).Error; err != nil {
+		tb.Fatalf("seed datasource: %v", err)
+	}
+
+	reportConfig := models.ReportConfig{
+		ReportName:     "Daily sales",
+		ReportQuery:    "SELECT id, total FROM sales",
+		OutputFormat:   "csv",
+		DatasourceID:   datasource.ID,
+		TimeoutSeconds: 300,
+		MaxRows:        10000,
+		IsActive:       true,
+		CreatedBy:      "test",
+		UpdatedBy:      "test",
+		Version:        1,
+	}
+	if err := db.Create(&reportConfig).Error; err != nil {
+		tb.Fatalf("seed config: %v", err)
+	}
+
+	return reportConfig
+}
+
+// sqliteSchemaQuery lists SQLite columns in the shape of schemaQueries
+const sqliteSchemaQuery = `SELECT 'main', m.name, CASE m.type WHEN 'view' THEN 'VIEW' ELSE 'BASE TABLE' END, NULL,
+	p.name, lower(p.type), CASE p."notnull" WHEN 1 THEN 'NO' ELSE 'YES' END, p.cid + 1
+FROM sqlite_master m JOIN pragma_table_info(m.name) p
+WHERE m.type IN ('table', 'view') AND m.name NOT LIKE 'sqlite_%'
+ORDER BY m.name, p.cid`
+
+var registerSleepOnce sync.Once
+
+// seedSQLiteDatasource creates a SQLite database file populated by statements and a datasource
+// pointing at it, registering "sqlite" as a datasource type for the duration of the test.
+// SLEEP_MS(n) blocks for n milliseconds, standing in for a slow query.
+func seedSQLiteDatasource(tb testing.TB, db *gorm.DB, statements ...string) models.DataSource {
+	tb.Helper()
+
+	registerSleepOnce.Do(func() {
+		gosqlite.MustRegisterScalarFunction("SLEEP_MS", 1, func(ctx *gosqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			ms, _ := args[0].(int64)
+			time.Sleep(time.Duration(ms) * time.Millisecond)
+			return ms, nil
+		})
+	})
+
+	sqlDriverNames["sqlite"] = "sqlite"
+	schemaQueries["sqlite"] = sqliteSchemaQuery
+	tb.Cleanup(func() {
+		delete(sqlDriverNames, "sqlite")
+		delete(schemaQueries, "sqlite")
+	})
+
+	dsn := "file:" + filepath.Join(tb.TempDir(), "reports.db")
+	reportsDB, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		tb.Fatalf("open reports database: %v", err)
+	}
+	defer reportsDB.Close()
+	for _, statement := range statements {
+		if _, err := reportsDB.Exec(statement); err != nil {
+			tb.Fatalf("seed reports database: %v", err)
+		}
+	}
+
+	datasource := models.DataSource{
+		Name:          "sqlite reports",
+		DbType:        "sqlite",
+		ConnectionURL: models.EncryptedString(dsn),
+		IsActive:      true,
+		CreatedBy:     "test",
+		UpdatedBy:     "test",
+	}
+	if err := db.Create(&datasource).Error; err != nil {
+		tb.Fatalf("seed datasource: %v", err)
+	}
+	// The schema cache outlives the test database, whose ids the next test reuses
+	InvalidateSchemaCache(datasource.ID)
+	tb.Cleanup(func() { InvalidateSchemaCache(datasource.ID) })
+	return datasource
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// LoadTimezone resolves an IANA timezone name; empty means UTC
+func LoadTimezone(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone '%s': %w", timezone, err)
+	}
+	return loc, nil
+}
+
+// ParseCronInLocation parses a 5-field cron expression whose fields are wall-clock
+// times in timezone. DST transitions follow an explicit policy:
+//   - gap (clocks spring forward): occurrences whose wall time does not exist fire
+//     once at the first instant after the gap, merged with any occurrence already there
+//   - overlap (clocks fall back): a repeated wall time fires once, on its first instance
+func ParseCronInLocation(cronExpr string, timezone string) (cron.Schedule, error) {
+	loc, err := LoadTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := cronParser.Parse(cronExpr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression '%s': %w", cronExpr, err)
+	}
+
+	return &zonedSchedule{schedule: schedule, loc: loc}, nil
+}
+
+// zonedSchedule evaluates the cron on wall-clock time, then maps each wall time to an instant in loc
+type zonedSchedule struct {
+	schedule cron.Schedule
+	loc      *time.Location
+}
+
+// Next returns the first occurrence strictly after t, or the zero time if there is none
+func (z *zonedSchedule) Next(t time.Time) time.Time {
+	t = t.In(z.loc)
+
+	// Wall-clock arithmetic runs in UTC, which has no transitions
+	wall := wallClock(t)
+	for {
+		wall = z.schedule.Next(wall)
+		if wall.IsZero() {
+			return time.Time{}
+		}
+
+		// Gap collapses and overlaps can map a later wall time onto an instant we already passed
+		if next := resolveWallClock(wall, z.loc); next.After(t) {
+			return next
+		}
+	}
+}
+
+// wallClock returns t's local date and time as the same fields in UTC
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// resolveWallClock maps a wall time (fields in UTC) to an instant in loc, applying the DST policy
+func resolveWallClock(wall time.Time, loc *time.Location) time.Time {
+	naive := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), loc)
+
+	// Offsets in force on either side of this wall time; they differ only near a transition
+	_, offsetBefore := naive.Add(-24 * time.Hour).Zone()
+	_, offsetAfter := naive.Add(24 * time.Hour).Zone()
+	if offsetBefore == offsetAfter {
+		return naive
+	}
+
+	earlier := wall.Add(-time.Duration(offsetBefore) * time.Second).In(loc)
+	later := wall.Add(-time.Duration(offsetAfter) * time.Second).In(loc)
+	if later.Before(earlier) {
+		earlier, later = later, earlier
+	}
+
+	earlierValid := wallClock(earlier).Equal(wall)
+	laterValid := wallClock(later).Equal(wall)
+	switch {
+	case earlierValid:
+		// Normal time, or the first instance of a repeated (overlap) wall time
+		return earlier
+	case laterValid:
+		return later
+	default:
+		// Gap: the wall time never happens; use the first instant after the transition
+		return transitionInstant(earlier, later, offsetAfter)
+	}
+}
+
+// transitionInstant finds the first instant in (lo, hi] whose UTC offset is offsetAfter
+func transitionInstant(lo time.Time, hi time.Time, offsetAfter int) time.Time {
+	for hi.Sub(lo) > time.Second {
+		mid := lo.Add(hi.Sub(lo) / 2)
+		if _, offset := mid.Zone(); offset == offsetAfter {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi.Truncate(time.Second)
+}
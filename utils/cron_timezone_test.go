@@ -0,0 +1,241 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone data for %s unavailable: %v", name, err)
+	}
+	return loc
+}
+
+func TestZonedScheduleNextAcrossDST(t *testing.T) {
+	utc := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		timezone string
+		cronExpr string
+		from     time.Time
+		want     []time.Time
+	}{
+		{
+			// New York springs forward at 02:00 EST on 2026-03-08; 02:30 never happens
+			name:     "gap fires at the end of the transition",
+			timezone: "America/New_York",
+			cronExpr: "30 2 * * *",
+			from:     utc(2026, 3, 7, 17, 0),
+			want:     []time.Time{utc(2026, 3, 8, 7, 0), utc(2026, 3, 9, 6, 30)},
+		},
+		{
+			name:     "gap occurrence merges with one already at the transition",
+			timezone: "America/New_York",
+			cronExpr: "0 2,3 * * *",
+			from:     utc(2026, 3, 8, 5, 0),
+			want:     []time.Time{utc(2026, 3, 8, 7, 0), utc(2026, 3, 9, 6, 0), utc(2026, 3, 9, 7, 0)},
+		},
+		{
+			// Berlin springs forward at 02:00 CET on 2026-03-29
+			name:     "gap east of UTC",
+			timezone: "Europe/Berlin",
+			cronExpr: "15 2 * * *",
+			from:     utc(2026, 3, 28, 12, 0),
+			want:     []time.Time{utc(2026, 3, 29, 1, 0), utc(2026, 3, 30, 0, 15)},
+		},
+		{
+			// New York falls back at 02:00 EDT on 2026-11-01; 01:00-02:00 happens twice
+			name:     "overlap fires once on the first instance",
+			timezone: "America/New_York",
+			cronExpr: "30 1 * * *",
+			from:     utc(2026, 10, 31, 16, 0),
+			want:     []time.Time{utc(2026, 11, 1, 5, 30), utc(2026, 11, 2, 6, 30)},
+		},
+		{
+			name:     "hourly schedule does not repeat the overlap hour",
+			timezone: "America/New_York",
+			cronExpr: "0 * * * *",
+			from:     utc(2026, 11, 1, 4, 30),
+			want:     []time.Time{utc(2026, 11, 1, 5, 0), utc(2026, 11, 1, 7, 0), utc(2026, 11, 1, 8, 0)},
+		},
+		{
+			name:     "next from the first instance of the overlap",
+			timezone: "America/New_York",
+			cronExpr: "45 1 * * *",
+			from:     utc(2026, 11, 1, 5, 15),
+			want:     []time.Time{utc(2026, 11, 1, 5, 45), utc(2026, 11, 2, 6, 45)},
+		},
+		{
+			// 01:15 EST is the repeated hour; 01:45 already fired at 01:45 EDT
+			name:     "next from the second instance of the overlap",
+			timezone: "America/New_York",
+			cronExpr: "45 1 * * *",
+			from:     utc(2026, 11, 1, 6, 15),
+			want:     []time.Time{utc(2026, 11, 2, 6, 45)},
+		},
+		{
+			name:     "next from inside the overlap skips the repeated hour",
+			timezone: "America/New_York",
+			cronExpr: "0 * * * *",
+			from:     utc(2026, 11, 1, 6, 30),
+			want:     []time.Time{utc(2026, 11, 1, 7, 0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mustLoadLocation(t, tt.timezone)
+
+			schedule, err := ParseCronInLocation(tt.cronExpr, tt.timezone)
+			if err != nil {
+				t.Fatalf("ParseCronInLocation: %v", err)
+			}
+
+			from := tt.from
+			for i, want := range tt.want {
+				next := schedule.Next(from)
+				if !next.Equal(want) {
+					t.Fatalf("occurrence %d after %s = %s, want %s", i+1, from.UTC(), next.UTC(), want)
+				}
+				from = next
+			}
+		})
+	}
+}
+
+func TestResolveWallClock(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		wall     time.Time
+		want     time.Time
+	}{
+		{
+			name:     "normal time",
+			timezone: "America/New_York",
+			wall:     time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC),
+			want:     time.Date(2026, 7, 1, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "day before the spring-forward",
+			timezone: "America/New_York",
+			wall:     time.Date(2026, 3, 7, 2, 30, 0, 0, time.UTC),
+			want:     time.Date(2026, 3, 7, 7, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "wall time in the gap",
+			timezone: "America/New_York",
+			wall:     time.Date(2026, 3, 8, 2, 30, 0, 0, time.UTC),
+			want:     time.Date(2026, 3, 8, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "wall time just after the gap",
+			timezone: "America/New_York",
+			wall:     time.Date(2026, 3, 8, 3, 0, 0, 0, time.UTC),
+			want:     time.Date(2026, 3, 8, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "wall time in the overlap resolves to its first instance",
+			timezone: "America/New_York",
+			wall:     time.Date(2026, 11, 1, 1, 30, 0, 0, time.UTC),
+			want:     time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "wall time just after the overlap",
+			timezone: "America/New_York",
+			wall:     time.Date(2026, 11, 1, 2, 0, 0, 0, time.UTC),
+			want:     time.Date(2026, 11, 1, 7, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := mustLoadLocation(t, tt.timezone)
+
+			if got := resolveWallClock(tt.wall, loc); !got.Equal(tt.want) {
+				t.Errorf("resolveWallClock(%s) = %s, want %s", tt.wall.Format("2006-01-02 15:04"), got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNewVariableContextInTimezone(t *testing.T) {
+	tests := []struct {
+		name          string
+		timezone      string
+		cronExpr      string
+		lastRunAt     *time.Time
+		executionTime time.Time
+		wantStart     string
+		wantEnd       string
+		wantHours     string
+	}{
+		{
+			// 21:00 UTC is 06:00 the next day in Tokyo
+			name:          "window is found from the cron in local time",
+			timezone:      "Asia/Tokyo",
+			cronExpr:      "0 6 * * *",
+			executionTime: time.Date(2026, 3, 2, 21, 0, 0, 0, time.UTC),
+			wantStart:     "2026-03-02 06:00:00",
+			wantEnd:       "2026-03-03 06:00:00",
+			wantHours:     "24.00",
+		},
+		{
+			name:          "daily window across the spring-forward is 23 hours",
+			timezone:      "America/New_York",
+			cronExpr:      "0 0 * * *",
+			executionTime: time.Date(2026, 3, 9, 4, 0, 0, 0, time.UTC),
+			wantStart:     "2026-03-08 00:00:00",
+			wantEnd:       "2026-03-09 00:00:00",
+			wantHours:     "23.00",
+		},
+		{
+			name:          "daily window across the fall-back is 25 hours",
+			timezone:      "America/New_York",
+			cronExpr:      "0 0 * * *",
+			executionTime: time.Date(2026, 11, 2, 5, 0, 0, 0, time.UTC),
+			wantStart:     "2026-11-01 00:00:00",
+			wantEnd:       "2026-11-02 00:00:00",
+			wantHours:     "25.00",
+		},
+		{
+			name:          "last_run_at is shown in the schedule timezone",
+			timezone:      "Europe/Berlin",
+			cronExpr:      "0 8 * * *",
+			lastRunAt:     func() *time.Time { t := time.Date(2026, 6, 30, 6, 0, 0, 0, time.UTC); return &t }(),
+			executionTime: time.Date(2026, 7, 1, 6, 0, 0, 0, time.UTC),
+			wantStart:     "2026-06-30 08:00:00",
+			wantEnd:       "2026-07-01 08:00:00",
+			wantHours:     "24.00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := mustLoadLocation(t, tt.timezone)
+
+			ctx := NewVariableContext(tt.lastRunAt, tt.cronExpr, tt.timezone, tt.executionTime)
+			if ctx.ExecutionTime.Location().String() != loc.String() || ctx.WindowStart.Location().String() != loc.String() {
+				t.Errorf("window in %s/%s, want %s", ctx.WindowStart.Location(), ctx.ExecutionTime.Location(), loc)
+			}
+
+			timeRange := CalculateTimeRange(tt.lastRunAt, tt.cronExpr, tt.timezone, tt.executionTime)
+			if timeRange["start_datetime"] != tt.wantStart {
+				t.Errorf("start_datetime = %v, want %s", timeRange["start_datetime"], tt.wantStart)
+			}
+			if timeRange["end_datetime"] != tt.wantEnd {
+				t.Errorf("end_datetime = %v, want %s", timeRange["end_datetime"], tt.wantEnd)
+			}
+			if timeRange["interval_hours"] != tt.wantHours {
+				t.Errorf("interval_hours = %v, want %s", timeRange["interval_hours"], tt.wantHours)
+			}
+		})
+	}
+}
@@ -3,13 +3,12 @@ package utils
 import (
 	"fmt"
 	"time"
-
-	"github.com/robfig/cron/v3"
 )
 
 // CronValidation holds validation results for cron expressions
 type CronValidation struct {
 	Valid            bool     `json:"valid"`
+	Timezone         string   `json:"timezone"`
 	IntervalMinutes  int      `json:"interval_minutes"`
 	ExecutionsPerDay float64  `json:"executions_per_day"`
 	NextExecutions   []string `json:"next_executions"`
@@ -24,25 +23,36 @@ const (
 	MaximumExecutionsPerDay = 288
 )
 
-// ValidateCronExpression validates cron expression and calculates interval
-func ValidateCronExpression(cronExpr string) CronValidation {
+// ValidateCronExpression validates cron expression and calculates interval.
+// Next executions are wall-clock times in timezone (empty means UTC).
+func ValidateCronExpression(cronExpr string, timezone string) CronValidation {
+	if timezone == "" {
+		timezone = "UTC"
+	}
 	result := CronValidation{
 		Valid:    true,
+		Timezone: timezone,
 		Warnings: []string{},
 		Errors:   []string{},
 	}
 
-	// Parse cron expression
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	schedule, err := parser.Parse(cronExpr)
+	loc, err := LoadTimezone(timezone)
 	if err != nil {
+		result.Valid = false
+		result.Errors = append(result.Errors, fmt.Sprintf("Invalid timezone: %v", err))
+		return result
+	}
+
+	// Parse cron expression
+	if _, err := cronParser.Parse(cronExpr); err != nil {
 		result.Valid = false
 		result.Errors = append(result.Errors, fmt.Sprintf("Invalid cron syntax: %v", err))
 		return result
 	}
+	schedule, _ := ParseCronInLocation(cronExpr, timezone)
 
 	// Calculate interval between executions
-	now := time.Now()
+	now := time.Now().In(loc)
 	next1 := schedule.Next(now)
 	next2 := schedule.Next(next1)
 	next3 := schedule.Next(next2)
@@ -89,29 +99,83 @@ func ValidateCronExpression(cronExpr string) CronValidation {
 	return result
 }
 
-// CalculateTimeRange calculates the time range for query based on interval
-func CalculateTimeRange(lastRunAt *time.Time, cronExpr string, executionTime time.Time) map[string]interface{} {
+// CalculateNextRun returns the first cron occurrence after "from", evaluated in the given IANA timezone
+func CalculateNextRun(cronExpr string, timezone string, from time.Time) (time.Time, error) {
+	schedule, err := ParseCronInLocation(cronExpr, timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return schedule.Next(from), nil
+}
+
+// CalculateRunsBetween lists the occurrences from "first" (itself an occurrence) up to and including "until",
+// evaluated in the given timezone. Only the most recent maxRuns are kept; total reports how many there were.
+func CalculateRunsBetween(cronExpr string, timezone string, first time.Time, until time.Time, maxRuns int) (runs []time.Time, total int, err error) {
+	loc, err := LoadTimezone(timezone)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	schedule, err := ParseCronInLocation(cronExpr, timezone)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Next returns the zero time for expressions that never fire again
+	for run := first.In(loc); !run.IsZero() && !run.After(until); run = schedule.Next(run) {
+		total++
+		runs = append(runs, run)
+		if maxRuns > 0 && len(runs) > maxRuns {
+			runs = runs[1:]
+		}
+	}
+
+	return runs, total, nil
+}
+
+// NewVariableContext derives the run window template variables are evaluated for.
+// Everything is in timezone (empty means UTC), so day-based variables step by
+// calendar days across DST changes.
+func NewVariableContext(lastRunAt *time.Time, cronExpr string, timezone string, executionTime time.Time) VariableContext {
+	loc, err := LoadTimezone(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	executionTime = executionTime.In(loc)
+
 	var startTime time.Time
 
 	if lastRunAt != nil {
 		// Use last_run_at for accurate time range
-		startTime = *lastRunAt
+		startTime = lastRunAt.In(loc)
 	} else {
 		// First run: calculate from cron expression
-		parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-		schedule, err := parser.Parse(cronExpr)
+		schedule, err := ParseCronInLocation(cronExpr, loc.String())
 		if err != nil {
-			// Fallback to 24 hours ago
-			startTime = executionTime.Add(-24 * time.Hour)
+			// Fallback to one calendar day ago
+			startTime = executionTime.AddDate(0, 0, -1)
 		} else {
 			// Get previous scheduled time
-			startTime = schedule.Next(executionTime.Add(-7 * 24 * time.Hour))
-			for schedule.Next(startTime).Before(executionTime) {
-				startTime = schedule.Next(startTime)
+			startTime = schedule.Next(executionTime.AddDate(0, 0, -7))
+			for next := schedule.Next(startTime); !next.IsZero() && next.Before(executionTime); next = schedule.Next(startTime) {
+				startTime = next
 			}
 		}
 	}
 
+	return VariableContext{
+		ExecutionTime:     executionTime,
+		WindowStart:       startTime,
+		CalculationMethod: getCalculationMethod(lastRunAt),
+	}
+}
+
+// CalculateTimeRange calculates the time range for query based on interval,
+// formatted as strings for display
+func CalculateTimeRange(lastRunAt *time.Time, cronExpr string, timezone string, executionTime time.Time) map[string]interface{} {
+	ctx := NewVariableContext(lastRunAt, cronExpr, timezone, executionTime)
+	startTime, executionTime := ctx.WindowStart, ctx.ExecutionTime
 	intervalHours := executionTime.Sub(startTime).Hours()
 
 	return map[string]interface{}{
@@ -120,9 +184,9 @@ func CalculateTimeRange(lastRunAt *time.Time, cronExpr string, executionTime tim
 		"start_date":          startTime.Format("2006-01-02"),
 		"end_date":            executionTime.Format("2006-01-02"),
 		"interval_hours":      fmt.Sprintf("%.2f", intervalHours),
-		"calculation_method":  getCalculationMethod(lastRunAt),
-		"yesterday":           executionTime.Add(-24 * time.Hour).Format("2006-01-02"),
-		"last_week":           executionTime.Add(-7 * 24 * time.Hour).Format("2006-01-02"),
+		"calculation_method":  ctx.CalculationMethod,
+		"yesterday":           executionTime.AddDate(0, 0, -1).Format("2006-01-02"),
+		"last_week":           executionTime.AddDate(0, 0, -7).Format("2006-01-02"),
 		"execution_time":      executionTime.Format("2006-01-02 15:04:05"),
 	}
 }
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// VersionETag is the strong ETag of an entity at the given version
+func VersionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// MetadataETag is the strong ETag of a config's catalog metadata at the given metadata version.
+// It cannot be mistaken for the VersionETag of the config itself.
+func MetadataETag(version int) string {
+	return `"metadata-` + strconv.Itoa(version) + `"`
+}
+
+// CompositeETag hashes the version tokens of several entities into one ETag,
+// so a change to any of them (or to which entities exist) changes the tag
+func CompositeETag(tokens []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(tokens, "\n")))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// ETagMatches reports whether an If-Match header value allows a write to an entity with etag.
+// An empty header means the client did not ask for a precondition.
+func ETagMatches(ifMatch string, etag string) bool {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "" || ifMatch == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
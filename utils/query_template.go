@@ -0,0 +1,507 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryParamType is the declared type of a query template parameter
+type QueryParamType string
+
+const (
+	QueryParamString   QueryParamType = "string"
+	QueryParamInt      QueryParamType = "int"
+	QueryParamNumber   QueryParamType = "number"
+	QueryParamBoolean  QueryParamType = "boolean"
+	QueryParamDate     QueryParamType = "date"
+	QueryParamDatetime QueryParamType = "datetime"
+	// QueryParamEnum values are checked against their options before rendering
+	QueryParamEnum QueryParamType = "enum"
+	// QueryParamList expands to one bind parameter per element, for IN (...) clauses
+	QueryParamList QueryParamType = "list"
+)
+
+const (
+	dateLayout     = "2006-01-02"
+	datetimeLayout = "2006-01-02 15:04:05"
+)
+
+// RenderedQuery is a report query with its {{variable}} placeholders replaced by bind parameters
+type RenderedQuery struct {
+	SQL  string        `json:"sql"`
+	Args []interface{} `json:"args"`
+}
+
+// A placeholder wrapped in single quotes (left over from literal substitution) binds as a whole
+var templateVariablePattern = regexp.MustCompile(`'(\{\{[^{}]*\}\})'|(\{\{[^{}]*\}\})`)
+
+// templateExpressionPattern parses {{name}}, {{name-3d}} and {{name|format:"20060102"}}
+var templateExpressionPattern = regexp.MustCompile(`^\{\{\s*(\w+)\s*(?:([+-])\s*(\d+)\s*(mo|h|d|b|w|q|y))?\s*(?:\|\s*format\s*:\s*"([^"]*)")?\s*\}\}$`)
+
+// RenderQuery turns {{variable}} placeholders into driver bind parameters for dbType
+// and returns the SQL with its ordered args. Each value is checked against its type in
+// schema; variables without a declared type are inferred from their Go value.
+// Date and datetime values accept an offset ({{today-3d}}) and a Go layout format
+// modifier ({{start_date|format:"20060102"}}), which binds the result as a string.
+// A placeholder may be a whole quoted literal ('{{name}}') but not part of one or of a
+// comment, where the bind parameter would not be seen by the driver.
+func RenderQuery(query string, dbType string, values map[string]interface{}, schema map[string]QueryParamType) (*RenderedQuery, error) {
+	rendered := &RenderedQuery{Args: []interface{}{}}
+	binds := map[string]string{}
+	spans := quotedAndCommentSpans(query, dbType)
+
+	render := func(expression string) (string, error) {
+		parts := templateExpressionPattern.FindStringSubmatch(expression)
+		if parts == nil {
+			return "", fmt.Errorf("invalid template expression '%s'", expression)
+		}
+		name, sign, amount, unit, layout := parts[1], parts[2], parts[3], parts[4], parts[5]
+		key := name + sign + amount + unit + "|" + layout
+
+		// PostgreSQL-style numbered parameters can be reused; positional ones cannot
+		if bind, seen := binds[key]; seen && reusesBindParams(dbType) {
+			return bind, nil
+		}
+
+		value, exists := values[name]
+		if !exists {
+			return "", fmt.Errorf("undefined template variable '%s'", name)
+		}
+
+		paramType, declared := schema[name]
+		if !declared {
+			paramType = inferQueryParamType(value)
+		}
+
+		if unit != "" || layout != "" {
+			t, ok := templateTime(value, paramType)
+			if !ok {
+				return "", fmt.Errorf("template variable '%s' is not a date or datetime; offsets and formats do not apply", name)
+			}
+			if unit != "" {
+				n, _ := strconv.Atoi(amount)
+				if sign == "-" {
+					n = -n
+				}
+				t, _ = ApplyTemplateOffset(t, n, unit)
+			}
+			value = t
+			if layout != "" {
+				value, paramType = t.Format(layout), QueryParamString
+			}
+		}
+
+		arg, err := CoerceQueryParam(name, paramType, value)
+		if err != nil {
+			return "", err
+		}
+
+		items, isList := arg.([]interface{})
+		if !isList {
+			items = []interface{}{arg}
+		}
+
+		placeholders := make([]string, len(items))
+		for i, item := range items {
+			rendered.Args = append(rendered.Args, item)
+			placeholder, err := bindPlaceholder(dbType, len(rendered.Args))
+			if err != nil {
+				return "", err
+			}
+			placeholders[i] = placeholder
+		}
+
+		bind := strings.Join(placeholders, ", ")
+		binds[key] = bind
+		return bind, nil
+	}
+
+	var sql strings.Builder
+	last := 0
+	for _, loc := range templateVariablePattern.FindAllStringSubmatchIndex(query, -1) {
+		var expression string
+		if loc[2] >= 0 {
+			// '{{name}}' must be the whole literal, not the tail and head of two neighbouring ones
+			expression = query[loc[2]:loc[3]]
+			if span, ok := spanAt(spans, loc[0]); !ok || span.kind != spanQuoted || span.start != loc[0] || span.end != loc[1] {
+				return nil, embeddedPlaceholderError(expression, spanQuoted)
+			}
+		} else {
+			expression = query[loc[4]:loc[5]]
+			if span, ok := spanAt(spans, loc[4]); ok {
+				return nil, embeddedPlaceholderError(expression, span.kind)
+			}
+		}
+
+		bind, err := render(expression)
+		if err != nil {
+			return nil, err
+		}
+		sql.WriteString(query[last:loc[0]])
+		sql.WriteString(bind)
+		last = loc[1]
+	}
+	sql.WriteString(query[last:])
+
+	rendered.SQL = sql.String()
+	return rendered, nil
+}
+
+// embeddedPlaceholderError explains why a placeholder inside a literal, identifier or comment cannot be bound
+func embeddedPlaceholderError(expression string, kind spanKind) error {
+	switch kind {
+	case spanComment:
+		return fmt.Errorf("template variable '%s' is inside a comment; remove it from the comment", expression)
+	case spanIdentifier:
+		return fmt.Errorf("template variable '%s' is inside a quoted identifier; table and column names cannot be parameters", expression)
+	}
+	return fmt.Errorf("template variable '%s' is inside a quoted string and cannot be bound there; "+
+		"build the string around it instead, e.g. LIKE CONCAT('%%', %s, '%%') rather than LIKE '%%%s%%'",
+		expression, expression, expression)
+}
+
+type spanKind int
+
+const (
+	spanQuoted spanKind = iota
+	spanIdentifier
+	spanComment
+)
+
+// sqlSpan is the [start, end) byte range of a quoted string, quoted identifier or comment
+type sqlSpan struct {
+	start, end int
+	kind       spanKind
+}
+
+// quotedAndCommentSpans lists the string literals, quoted identifiers and comments of query,
+// in order. Quotes are doubled to escape them; MySQL also honours backslash escapes in strings,
+// treats "..." as a string, `...` as an identifier and # as a line comment. Elsewhere "..."
+// is an identifier, as is [...] on SQL Server.
+func quotedAndCommentSpans(query string, dbType string) []sqlSpan {
+	mysql := dbType == "mysql"
+	var spans []sqlSpan
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case (mysql && c == '`') || (!mysql && c == '"') || (dbType == "sqlserver" && c == '['):
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			end := len(query)
+			for j := i + 1; j < len(query); j++ {
+				if query[j] == closing {
+					if j+1 < len(query) && query[j+1] == closing {
+						j++
+						continue
+					}
+					end = j + 1
+					break
+				}
+			}
+			spans = append(spans, sqlSpan{start: i, end: end, kind: spanIdentifier})
+			i = end - 1
+
+		case c == '\'' || (mysql && c == '"'):
+			end := len(query)
+			for j := i + 1; j < len(query); j++ {
+				if mysql && query[j] == '\\' {
+					j++
+					continue
+				}
+				if query[j] == c {
+					if j+1 < len(query) && query[j+1] == c {
+						j++
+						continue
+					}
+					end = j + 1
+					break
+				}
+			}
+			spans = append(spans, sqlSpan{start: i, end: end, kind: spanQuoted})
+			i = end - 1
+
+		case (c == '-' && strings.HasPrefix(query[i:], "--")) || (mysql && c == '#'):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i
+			}
+			spans = append(spans, sqlSpan{start: i, end: end, kind: spanComment})
+			i = end - 1
+
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i + 4
+			}
+			spans = append(spans, sqlSpan{start: i, end: end, kind: spanComment})
+			i = end - 1
+		}
+	}
+
+	return spans
+}
+
+// spanAt returns the span containing byte offset pos
+func spanAt(spans []sqlSpan, pos int) (sqlSpan, bool) {
+	for _, span := range spans {
+		if pos >= span.start && pos < span.end {
+			return span, true
+		}
+	}
+	return sqlSpan{}, false
+}
+
+// templateTime reads a date or datetime value as a time for offsets and formats
+func templateTime(value interface{}, paramType QueryParamType) (time.Time, bool) {
+	if paramType != QueryParamDate && paramType != QueryParamDatetime {
+		return time.Time{}, false
+	}
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range []string{datetimeLayout, dateLayout, time.RFC3339} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// bindPlaceholder returns the bind parameter syntax for the 1-based position
+func bindPlaceholder(dbType string, position int) (string, error) {
+	switch dbType {
+	case "mysql":
+		return "?", nil
+	case "postgresql":
+		return "$" + strconv.Itoa(position), nil
+	case "sqlserver":
+		return "@p" + strconv.Itoa(position), nil
+	case "oracle":
+		return ":" + strconv.Itoa(position), nil
+	default:
+		return "", fmt.Errorf("query parameters are not supported for datasource type '%s'", dbType)
+	}
+}
+
+func reusesBindParams(dbType string) bool {
+	return dbType != "mysql"
+}
+
+// inferQueryParamType picks a type for values without a declared schema entry
+func inferQueryParamType(value interface{}) QueryParamType {
+	switch v := value.(type) {
+	case []interface{}, []string:
+		return QueryParamList
+	case bool:
+		return QueryParamBoolean
+	case int, int32, int64:
+		return QueryParamInt
+	case float32, float64, json.Number:
+		return QueryParamNumber
+	case time.Time:
+		return QueryParamDatetime
+	case string:
+		if _, err := time.Parse(datetimeLayout, v); err == nil {
+			return QueryParamDatetime
+		}
+		if _, err := time.Parse(dateLayout, v); err == nil {
+			return QueryParamDate
+		}
+		return QueryParamString
+	default:
+		return QueryParamString
+	}
+}
+
+// isScalarQueryParam reports whether value can be bound as a single driver argument
+func isScalarQueryParam(value interface{}) bool {
+	switch value.(type) {
+	case string, bool, int, int32, int64, float32, float64, json.Number, time.Time:
+		return true
+	}
+	return false
+}
+
+// CoerceQueryParam checks value against paramType and converts it to a driver argument
+func CoerceQueryParam(name string, paramType QueryParamType, value interface{}) (interface{}, error) {
+	invalid := fmt.Errorf("parameter '%s' must be of type %s", name, paramType)
+
+	switch paramType {
+	case QueryParamString:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+		return nil, invalid
+
+	case QueryParamInt:
+		switch v := value.(type) {
+		case int:
+			return int64(v), nil
+		case int32:
+			return int64(v), nil
+		case int64:
+			return v, nil
+		case float64:
+			if v != math.Trunc(v) {
+				return nil, invalid
+			}
+			return int64(v), nil
+		case json.Number:
+			i, err := v.Int64()
+			if err != nil {
+				return nil, invalid
+			}
+			return i, nil
+		case string:
+			i, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, invalid
+			}
+			return i, nil
+		}
+		return nil, invalid
+
+	case QueryParamNumber:
+		switch v := value.(type) {
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case float32:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		case json.Number:
+			f, err := v.Float64()
+			if err != nil {
+				return nil, invalid
+			}
+			return f, nil
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, invalid
+			}
+			return f, nil
+		}
+		return nil, invalid
+
+	case QueryParamBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, invalid
+			}
+			return b, nil
+		}
+		return nil, invalid
+
+	case QueryParamDate:
+		switch v := value.(type) {
+		case time.Time:
+			return v.Format(dateLayout), nil
+		case string:
+			if _, err := time.Parse(dateLayout, v); err != nil {
+				return nil, invalid
+			}
+			return v, nil
+		}
+		return nil, invalid
+
+	case QueryParamDatetime:
+		switch v := value.(type) {
+		case time.Time:
+			return v.Format(datetimeLayout), nil
+		case string:
+			if _, err := time.Parse(datetimeLayout, v); err == nil {
+				return v, nil
+			}
+			if t, err := time.Parse(time.RFC3339, v); err == nil {
+				return t.Format(datetimeLayout), nil
+			}
+			return nil, invalid
+		}
+		return nil, invalid
+
+	case QueryParamEnum:
+		switch value.(type) {
+		case string, int, int64, float64, json.Number:
+			return value, nil
+		}
+		return nil, invalid
+
+	case QueryParamList:
+		var items []interface{}
+		switch v := value.(type) {
+		case []interface{}:
+			items = v
+		case []string:
+			items = make([]interface{}, len(v))
+			for i, item := range v {
+				items[i] = item
+			}
+		default:
+			return nil, invalid
+		}
+		if len(items) == 0 {
+			return nil, fmt.Errorf("parameter '%s' must not be an empty list", name)
+		}
+
+		// Elements are checked as the scalars they look like; a schema item_type narrows them further
+		coerced := make([]interface{}, len(items))
+		for i, item := range items {
+			if !isScalarQueryParam(item) {
+				return nil, fmt.Errorf("parameter '%s' items must be strings, numbers, booleans or dates", name)
+			}
+			var err error
+			if coerced[i], err = CoerceQueryParam(name, inferQueryParamType(item), item); err != nil {
+				return nil, err
+			}
+		}
+		return coerced, nil
+
+	default:
+		return nil, fmt.Errorf("parameter '%s' has unknown type '%s'", name, paramType)
+	}
+}
+
+// RenderReportQuery renders a report query with its resolved parameters and the
+// registered template variables evaluated for ctx; built-in variables take precedence
+// on name clashes. parameterTypes declares the parameters' types. Previews and the
+// worker both render through here so they produce the same SQL and args.
+func RenderReportQuery(query string, dbType string, parameters map[string]interface{}, parameterTypes map[string]QueryParamType, ctx VariableContext) (*RenderedQuery, error) {
+	values := map[string]interface{}{}
+	for name, value := range parameters {
+		values[name] = value
+	}
+	for name, value := range ResolveTemplateVariables(ctx) {
+		values[name] = value
+	}
+
+	types := map[string]QueryParamType{}
+	for name, paramType := range parameterTypes {
+		types[name] = paramType
+	}
+	for name, paramType := range TemplateVariableTypes() {
+		types[name] = paramType
+	}
+
+	return RenderQuery(query, dbType, values, types)
+}
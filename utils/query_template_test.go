@@ -0,0 +1,265 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderQueryBindStyles(t *testing.T) {
+	values := map[string]interface{}{
+		"region": "EU",
+		"status": []interface{}{"paid", "open"},
+		"limit":  10,
+	}
+	schema := map[string]QueryParamType{
+		"region": QueryParamString,
+		"status": QueryParamList,
+		"limit":  QueryParamInt,
+	}
+
+	tests := []struct {
+		name     string
+		dbType   string
+		query    string
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{
+			name:     "mysql positional binds repeat the arg",
+			dbType:   "mysql",
+			query:    "SELECT * FROM orders WHERE region = {{region}} OR ship_region = {{region}} LIMIT {{limit}}",
+			wantSQL:  "SELECT * FROM orders WHERE region = ? OR ship_region = ? LIMIT ?",
+			wantArgs: []interface{}{"EU", "EU", int64(10)},
+		},
+		{
+			name:     "postgresql numbered binds are reused",
+			dbType:   "postgresql",
+			query:    "SELECT * FROM orders WHERE region = {{region}} OR ship_region = {{region}} LIMIT {{limit}}",
+			wantSQL:  "SELECT * FROM orders WHERE region = $1 OR ship_region = $1 LIMIT $2",
+			wantArgs: []interface{}{"EU", int64(10)},
+		},
+		{
+			name:     "sqlserver named binds",
+			dbType:   "sqlserver",
+			query:    "SELECT TOP ({{limit}}) * FROM orders WHERE region = {{region}}",
+			wantSQL:  "SELECT TOP (@p1) * FROM orders WHERE region = @p2",
+			wantArgs: []interface{}{int64(10), "EU"},
+		},
+		{
+			name:     "mysql list expands to one bind per item",
+			dbType:   "mysql",
+			query:    "SELECT * FROM orders WHERE status IN ({{status}}) AND region = {{region}}",
+			wantSQL:  "SELECT * FROM orders WHERE status IN (?, ?) AND region = ?",
+			wantArgs: []interface{}{"paid", "open", "EU"},
+		},
+		{
+			name:     "postgresql list numbers each item",
+			dbType:   "postgresql",
+			query:    "SELECT * FROM orders WHERE region = {{region}} AND status IN ({{status}})",
+			wantSQL:  "SELECT * FROM orders WHERE region = $1 AND status IN ($2, $3)",
+			wantArgs: []interface{}{"EU", "paid", "open"},
+		},
+		{
+			name:     "sqlserver list numbers each item",
+			dbType:   "sqlserver",
+			query:    "SELECT * FROM orders WHERE status IN ({{status}})",
+			wantSQL:  "SELECT * FROM orders WHERE status IN (@p1, @p2)",
+			wantArgs: []interface{}{"paid", "open"},
+		},
+		{
+			name:     "whole quoted literal binds as a value",
+			dbType:   "mysql",
+			query:    "SELECT * FROM orders WHERE region = '{{region}}'",
+			wantSQL:  "SELECT * FROM orders WHERE region = ?",
+			wantArgs: []interface{}{"EU"},
+		},
+		{
+			name:     "literals with escaped quotes are skipped over",
+			dbType:   "postgresql",
+			query:    "SELECT 'it''s' AS note FROM orders WHERE region = {{region}}",
+			wantSQL:  "SELECT 'it''s' AS note FROM orders WHERE region = $1",
+			wantArgs: []interface{}{"EU"},
+		},
+		{
+			name:     "mysql backslash escapes do not end the literal",
+			dbType:   "mysql",
+			query:    `SELECT 'a\'b' AS note FROM orders WHERE region = {{region}}`,
+			wantSQL:  `SELECT 'a\'b' AS note FROM orders WHERE region = ?`,
+			wantArgs: []interface{}{"EU"},
+		},
+		{
+			name:     "quoted identifiers next to a placeholder are left alone",
+			dbType:   "postgresql",
+			query:    `SELECT "order id", [x] FROM "orders" WHERE region = {{region}}`,
+			wantSQL:  `SELECT "order id", [x] FROM "orders" WHERE region = $1`,
+			wantArgs: []interface{}{"EU"},
+		},
+		{
+			name:     "sqlserver bracket with an escaped closing bracket",
+			dbType:   "sqlserver",
+			query:    "SELECT [a]]b] FROM orders WHERE region = {{region}}",
+			wantSQL:  "SELECT [a]]b] FROM orders WHERE region = @p1",
+			wantArgs: []interface{}{"EU"},
+		},
+		{
+			name:     "concat keeps wildcards out of the bind",
+			dbType:   "mysql",
+			query:    "SELECT * FROM customers WHERE name LIKE CONCAT('%', {{region}}, '%')",
+			wantSQL:  "SELECT * FROM customers WHERE name LIKE CONCAT('%', ?, '%')",
+			wantArgs: []interface{}{"EU"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := RenderQuery(tt.query, tt.dbType, values, schema)
+			if err != nil {
+				t.Fatalf("RenderQuery: %v", err)
+			}
+			if rendered.SQL != tt.wantSQL {
+				t.Errorf("SQL = %q, want %q", rendered.SQL, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(rendered.Args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", rendered.Args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestRenderQueryRejectsEmbeddedPlaceholders(t *testing.T) {
+	values := map[string]interface{}{"name": "acme"}
+
+	tests := []struct {
+		name    string
+		dbType  string
+		query   string
+		wantErr string
+	}{
+		{
+			name:    "placeholder inside a LIKE pattern",
+			dbType:  "mysql",
+			query:   "SELECT * FROM customers WHERE name LIKE '%{{name}}%'",
+			wantErr: "CONCAT('%', {{name}}, '%')",
+		},
+		{
+			name:    "placeholder after an escaped quote",
+			dbType:  "postgresql",
+			query:   "SELECT * FROM customers WHERE note = 'it''s {{name}}'",
+			wantErr: "inside a quoted string",
+		},
+		{
+			name:    "mysql double-quoted string",
+			dbType:  "mysql",
+			query:   `SELECT * FROM customers WHERE name LIKE "{{name}}%"`,
+			wantErr: "inside a quoted string",
+		},
+		{
+			name:    "quoted placeholder spanning two literals",
+			dbType:  "mysql",
+			query:   "SELECT * FROM customers WHERE note = 'a '{{name}}' b'",
+			wantErr: "inside a quoted string",
+		},
+		{
+			name:    "postgresql double-quoted identifier",
+			dbType:  "postgresql",
+			query:   `SELECT "{{name}}" FROM customers`,
+			wantErr: "inside a quoted identifier",
+		},
+		{
+			name:    "mysql backtick identifier",
+			dbType:  "mysql",
+			query:   "SELECT `col_{{name}}` FROM customers",
+			wantErr: "inside a quoted identifier",
+		},
+		{
+			name:    "sqlserver bracketed identifier",
+			dbType:  "sqlserver",
+			query:   "SELECT [{{name}}] FROM customers",
+			wantErr: "inside a quoted identifier",
+		},
+		{
+			name:    "line comment",
+			dbType:  "postgresql",
+			query:   "SELECT * FROM customers -- filtered by {{name}}\nWHERE id > 0",
+			wantErr: "inside a comment",
+		},
+		{
+			name:    "block comment",
+			dbType:  "sqlserver",
+			query:   "SELECT * FROM customers /* {{name}} */ WHERE id > 0",
+			wantErr: "inside a comment",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RenderQuery(tt.query, tt.dbType, values, nil)
+			if err == nil {
+				t.Fatal("RenderQuery succeeded, want an error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRenderQueryListsWithoutSchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		wantArgs []interface{}
+		wantErr  string
+	}{
+		{
+			name:     "scalar items are bound",
+			value:    []interface{}{"paid", 3, 2.5, true},
+			wantArgs: []interface{}{"paid", int64(3), 2.5, true},
+		},
+		{
+			name:     "string slice",
+			value:    []string{"paid", "open"},
+			wantArgs: []interface{}{"paid", "open"},
+		},
+		{
+			name:    "nested list",
+			value:   []interface{}{"paid", []interface{}{"open"}},
+			wantErr: "items must be strings, numbers, booleans or dates",
+		},
+		{
+			name:    "object item",
+			value:   []interface{}{map[string]interface{}{"status": "paid"}},
+			wantErr: "items must be strings, numbers, booleans or dates",
+		},
+		{
+			name:    "null item",
+			value:   []interface{}{"paid", nil},
+			wantErr: "items must be strings, numbers, booleans or dates",
+		},
+		{
+			name:    "empty list",
+			value:   []interface{}{},
+			wantErr: "must not be an empty list",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := RenderQuery("SELECT * FROM orders WHERE status IN ({{status}})", "mysql",
+				map[string]interface{}{"status": tt.value}, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RenderQuery error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderQuery: %v", err)
+			}
+			if !reflect.DeepEqual(rendered.Args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", rendered.Args, tt.wantArgs)
+			}
+		})
+	}
+}
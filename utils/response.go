@@ -12,6 +12,7 @@ type StandardResponse struct {
 	Code    string      `json:"responseCode"`
 	Message string      `json:"responseMessage"`
 	Data    interface{} `json:"data,omitempty"`
+	Meta    interface{} `json:"meta,omitempty"` // pagination of list responses: next_cursor, total, limit
 }
 
 func BuildCode(httpCode int, errorCode int) string {
@@ -30,6 +31,27 @@ func SuccessResponse(c *fiber.Ctx, data interface{}, message string) error {
 	})
 }
 
+// SuccessListResponse is SuccessResponse for one page of a list, with its pagination metadata
+func SuccessListResponse(c *fiber.Ctx, data interface{}, meta interface{}, message string) error {
+	code := BuildCode(fiber.StatusOK, 0)
+	return c.Status(fiber.StatusOK).JSON(StandardResponse{
+		Code:    code,
+		Message: message,
+		Data:    data,
+		Meta:    meta,
+	})
+}
+
+// ErrorResponseWithData is ErrorResponse with a data payload describing the failure
+func ErrorResponseWithData(c *fiber.Ctx, httpCode int, errorCode int, message string, data interface{}) error {
+	code := BuildCode(httpCode, errorCode)
+	return c.Status(httpCode).JSON(StandardResponse{
+		Code:    code,
+		Message: message,
+		Data:    data,
+	})
+}
+
 func ErrorResponse(c *fiber.Ctx, httpCode int, errorCode int, message string) error {
 	code := BuildCode(httpCode, errorCode)
 	return c.Status(httpCode).JSON(StandardResponse{
@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SecretResolver looks up the value behind a secret reference. ref is the part after "scheme://".
+type SecretResolver interface {
+	Resolve(ref string) (string, error)
+}
+
+// SecretResolverFunc adapts a function to SecretResolver
+type SecretResolverFunc func(ref string) (string, error)
+
+func (f SecretResolverFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+var (
+	secretResolversMu sync.RWMutex
+	secretResolvers   = map[string]SecretResolver{
+		"env":   SecretResolverFunc(resolveEnvSecret),
+		"file":  SecretResolverFunc(resolveFileSecret),
+		"vault": &VaultSecretResolver{},
+	}
+)
+
+// RegisterSecretResolver adds or replaces the resolver for a reference scheme
+func RegisterSecretResolver(scheme string, resolver SecretResolver) {
+	secretResolversMu.Lock()
+	defer secretResolversMu.Unlock()
+	secretResolvers[scheme] = resolver
+}
+
+// splitSecretReference returns the scheme and ref of values like env://NAME
+func splitSecretReference(value string) (string, string, bool) {
+	scheme, ref, found := strings.Cut(value, "://")
+	if !found || ref == "" {
+		return "", "", false
+	}
+	secretResolversMu.RLock()
+	defer secretResolversMu.RUnlock()
+	if _, ok := secretResolvers[scheme]; !ok {
+		return "", "", false
+	}
+	return scheme, ref, true
+}
+
+// IsSecretReference reports whether value points at a secret (env://, file://, vault://)
+// rather than holding it
+func IsSecretReference(value string) bool {
+	_, _, ok := splitSecretReference(value)
+	return ok
+}
+
+// ResolveSecretReference returns the secret a reference points at; other values are returned unchanged
+func ResolveSecretReference(value string) (string, error) {
+	scheme, ref, ok := splitSecretReference(value)
+	if !ok {
+		return value, nil
+	}
+
+	secretResolversMu.RLock()
+	resolver := secretResolvers[scheme]
+	secretResolversMu.RUnlock()
+
+	secret, err := resolver.Resolve(ref)
+	if err != nil {
+		return "", &SecretReferenceError{Reference: value, Err: err}
+	}
+	return secret, nil
+}
+
+// SecretReferenceError reports a reference whose secret cannot be looked up
+type SecretReferenceError struct {
+	Reference string
+	Err       error
+}
+
+func (e *SecretReferenceError) Error() string {
+	return fmt.Sprintf("secret reference '%s' cannot be resolved: %v", e.Reference, e.Err)
+}
+
+func (e *SecretReferenceError) Unwrap() error {
+	return e.Err
+}
+
+// ResolveSecretReferences returns a copy of config with every secret field reference resolved.
+// Call it when the credentials are about to be used, never before storing or returning them.
+func ResolveSecretReferences(config map[string]interface{}) (map[string]interface{}, error) {
+	return TransformSecretFields(config, ResolveSecretReference)
+}
+
+// secretReferenceShape matches values written like a reference, registered scheme or not
+var secretReferenceShape = regexp.MustCompile(`^([a-z][a-z0-9+.-]*)://\S+$`)
+
+// ValidateSecretReferences checks that every reference in config resolves, so a bad
+// reference is reported on save rather than at execution time. A secret field written
+// like a reference with an unregistered scheme (a typo such as envv://NAME) is rejected
+// instead of being stored as a literal secret.
+func ValidateSecretReferences(config map[string]interface{}) error {
+	_, err := TransformSecretFields(config, func(value string) (string, error) {
+		if match := secretReferenceShape.FindStringSubmatch(value); match != nil && !IsSecretReference(value) {
+			return "", &SecretReferenceError{
+				Reference: value,
+				Err:       fmt.Errorf("unknown scheme '%s', expected one of %s", match[1], strings.Join(secretReferenceSchemes(), ", ")),
+			}
+		}
+		return ResolveSecretReference(value)
+	})
+	return err
+}
+
+// secretReferenceSchemes returns the registered reference schemes in order
+func secretReferenceSchemes() []string {
+	secretResolversMu.RLock()
+	defer secretResolversMu.RUnlock()
+
+	schemes := make([]string, 0, len(secretResolvers))
+	for scheme := range secretResolvers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+func resolveEnvSecret(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+func resolveFileSecret(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// VaultSecretResolver reads vault://<path>#<key> from a HashiCorp Vault KV engine (v1 or v2)
+type VaultSecretResolver struct {
+	Address string
+	Token   string
+	Client  *http.Client
+}
+
+// NewVaultSecretResolver builds a resolver for the Vault server at address
+func NewVaultSecretResolver(address string, token string, timeout time.Duration) *VaultSecretResolver {
+	return &VaultSecretResolver{
+		Address: strings.TrimRight(address, "/"),
+		Token:   token,
+		Client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (v *VaultSecretResolver) Resolve(ref string) (string, error) {
+	if v.Address == "" {
+		return "", errors.New("vault is not configured (VAULT_ADDR)")
+	}
+
+	path, key, found := strings.Cut(ref, "#")
+	if !found || path == "" || key == "" {
+		return "", errors.New("vault references must look like vault://<path>#<key>")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, v.Address+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status %d for %s", resp.StatusCode, path)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid vault response: %w", err)
+	}
+
+	// KV v2 nests the secret under data.data
+	data := body.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("key '%s' not found at %s", key, path)
+	}
+	secret, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("key '%s' at %s is not a string", key, path)
+	}
+	return secret, nil
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useSecretResolver registers resolver for scheme until the test ends
+func useSecretResolver(t *testing.T, scheme string, resolver SecretResolver) {
+	t.Helper()
+
+	secretResolversMu.RLock()
+	previous, existed := secretResolvers[scheme]
+	secretResolversMu.RUnlock()
+	t.Cleanup(func() {
+		secretResolversMu.Lock()
+		defer secretResolversMu.Unlock()
+		if existed {
+			secretResolvers[scheme] = previous
+		} else {
+			delete(secretResolvers, scheme)
+		}
+	})
+	RegisterSecretResolver(scheme, resolver)
+}
+
+func TestResolveSecretReference(t *testing.T) {
+	t.Setenv("REPORT_DB_PASSWORD", "from-env")
+	secretFile := filepath.Join(t.TempDir(), "db_password")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatalf("write secret file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr string
+	}{
+		{name: "env", value: "env://REPORT_DB_PASSWORD", want: "from-env"},
+		{name: "unset env", value: "env://REPORT_DB_PASSWORD_MISSING", wantErr: "REPORT_DB_PASSWORD_MISSING is not set"},
+		{name: "file with trailing newline", value: "file://" + secretFile, want: "from-file"},
+		{name: "missing file", value: "file://" + secretFile + ".missing", wantErr: "no such file"},
+		{name: "plain value", value: "s3cret", want: "s3cret"},
+		{name: "connection url is not a reference", value: "postgres://report:s3cret@db/reports", want: "postgres://report:s3cret@db/reports"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveSecretReference(tt.value)
+			if tt.wantErr != "" {
+				var refErr *SecretReferenceError
+				if !errors.As(err, &refErr) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ResolveSecretReference error = %v, want a SecretReferenceError mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveSecretReference: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveSecretReference = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSecretReferencesRejectsUnknownScheme(t *testing.T) {
+	t.Setenv("SMTP_PASSWORD", "s3cret")
+
+	valid := map[string]interface{}{
+		"smtp_password": "env://SMTP_PASSWORD",
+		"webhook_url":   "https://hooks.example.com/report",
+		"auth":          map[string]interface{}{"token": "plain-token"},
+	}
+	if err := ValidateSecretReferences(valid); err != nil {
+		t.Errorf("ValidateSecretReferences(valid) = %v", err)
+	}
+
+	err := ValidateSecretReferences(map[string]interface{}{
+		"auth": map[string]interface{}{"token": "envv://SMTP_PASSWORD"},
+	})
+	var refErr *SecretReferenceError
+	if !errors.As(err, &refErr) || !strings.Contains(err.Error(), "unknown scheme 'envv', expected one of env, file, vault") {
+		t.Fatalf("ValidateSecretReferences error = %v, want the unknown scheme rejected", err)
+	}
+
+	// A registered scheme is accepted from then on
+	useSecretResolver(t, "envv", SecretResolverFunc(func(ref string) (string, error) { return "custom", nil }))
+	if err := ValidateSecretReferences(map[string]interface{}{"token": "envv://SMTP_PASSWORD"}); err != nil {
+		t.Errorf("ValidateSecretReferences with a registered scheme = %v", err)
+	}
+	if got, err := ResolveSecretReference("envv://anything"); err != nil || got != "custom" {
+		t.Errorf("ResolveSecretReference = %q, %v; want the registered resolver used", got, err)
+	}
+}
+
+func TestVaultSecretResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/reports":
+			_, _ = w.Write([]byte(`{"data":{"data":{"password":"from-kv2","port":5432},"metadata":{"version":3}}}`))
+		case "/v1/kv/reports":
+			_, _ = w.Write([]byte(`{"data":{"password":"from-kv1"}}`))
+		case "/v1/secret/data/broken":
+			_, _ = w.Write([]byte(`not json`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	resolver := NewVaultSecretResolver(server.URL+"/", "test-token", time.Second)
+
+	tests := []struct {
+		name     string
+		resolver *VaultSecretResolver
+		ref      string
+		want     string
+		wantErr  string
+	}{
+		{name: "kv v2", resolver: resolver, ref: "secret/data/reports#password", want: "from-kv2"},
+		{name: "kv v1", resolver: resolver, ref: "/kv/reports#password", want: "from-kv1"},
+		{name: "missing key", resolver: resolver, ref: "secret/data/reports#api_key", wantErr: "key 'api_key' not found at secret/data/reports"},
+		{name: "non-string key", resolver: resolver, ref: "secret/data/reports#port", wantErr: "is not a string"},
+		{name: "unknown path", resolver: resolver, ref: "secret/data/other#password", wantErr: "vault returned status 404"},
+		{name: "wrong token", resolver: NewVaultSecretResolver(server.URL, "other-token", time.Second), ref: "secret/data/reports#password", wantErr: "vault returned status 403"},
+		{name: "invalid response", resolver: resolver, ref: "secret/data/broken#password", wantErr: "invalid vault response"},
+		{name: "no key in reference", resolver: resolver, ref: "secret/data/reports", wantErr: "must look like vault://<path>#<key>"},
+		{name: "not configured", resolver: &VaultSecretResolver{}, ref: "secret/data/reports#password", wantErr: "vault is not configured"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.resolver.Resolve(tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Registered as the vault scheme, references resolve through it
+	useSecretResolver(t, "vault", resolver)
+	if got, err := ResolveSecretReference("vault://secret/data/reports#password"); err != nil || got != "from-kv2" {
+		t.Errorf("ResolveSecretReference(vault) = %q, %v; want %q", got, err, "from-kv2")
+	}
+}
@@ -0,0 +1,316 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// MaskedSecret replaces secret values in API responses. Sending it back on update keeps the stored value.
+const MaskedSecret = "***MASKED***"
+
+// encryptedPrefix marks envelope-encrypted values: enc:v1:<key id>:<wrapped data key>:<ciphertext>
+const encryptedPrefix = "enc:v1:"
+
+// secretFieldNames are the JSON keys in connection and delivery configs whose values are encrypted at rest
+var secretFieldNames = map[string]bool{
+	"password":          true,
+	"passwd":            true,
+	"passphrase":        true,
+	"secret":            true,
+	"secret_key":        true,
+	"secret_access_key": true,
+	"access_key":        true,
+	"api_key":           true,
+	"token":             true,
+	"access_token":      true,
+	"refresh_token":     true,
+	"client_secret":     true,
+	"private_key":       true,
+	"authorization":     true,
+	"smtp_password":     true,
+	"sftp_password":     true,
+}
+
+// IsSecretField reports whether a config key holds a secret
+func IsSecretField(key string) bool {
+	return secretFieldNames[strings.ToLower(key)]
+}
+
+type secretKeyring struct {
+	keys        map[string][]byte
+	activeKeyID string
+}
+
+var keyring = &secretKeyring{keys: map[string][]byte{}}
+
+// InitSecretKeys loads master keys from "id:base64key,id2:base64key" and selects the active one
+// for new encryptions. Older keys stay available for decryption until re-encrypted.
+// With no keys configured, secrets are stored in plaintext.
+func InitSecretKeys(keys string, activeKeyID string) error {
+	loaded := &secretKeyring{keys: map[string][]byte{}}
+
+	for _, entry := range strings.Split(keys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, found := strings.Cut(entry, ":")
+		if !found || id == "" {
+			return fmt.Errorf("invalid encryption key entry, expected id:base64key")
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("encryption key '%s' is not valid base64: %w", id, err)
+		}
+		if len(key) != 32 {
+			return fmt.Errorf("encryption key '%s' must be 32 bytes, got %d", id, len(key))
+		}
+		loaded.keys[id] = key
+	}
+
+	if len(loaded.keys) > 0 {
+		if activeKeyID == "" {
+			return errors.New("ENCRYPTION_ACTIVE_KEY_ID is required when encryption keys are configured")
+		}
+		if _, ok := loaded.keys[activeKeyID]; !ok {
+			return fmt.Errorf("active encryption key '%s' is not configured", activeKeyID)
+		}
+		loaded.activeKeyID = activeKeyID
+	}
+
+	keyring = loaded
+	return nil
+}
+
+// SecretEncryptionEnabled reports whether a master key is configured
+func SecretEncryptionEnabled() bool {
+	return keyring.activeKeyID != ""
+}
+
+// ActiveSecretKeyID returns the key ID new secrets are encrypted with
+func ActiveSecretKeyID() string {
+	return keyring.activeKeyID
+}
+
+// IsEncryptedSecret reports whether value is an envelope-encrypted secret
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// SecretKeyID returns the master key ID an encrypted value was written with
+func SecretKeyID(value string) string {
+	if !IsEncryptedSecret(value) {
+		return ""
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(value, encryptedPrefix), ":")
+	return id
+}
+
+// EncryptSecret envelope-encrypts plaintext: a fresh data key encrypts the value and the
+// active master key wraps the data key. Already encrypted values and secret references
+// are returned unchanged.
+func EncryptSecret(plaintext string) (string, error) {
+	if plaintext == "" || IsEncryptedSecret(plaintext) || IsSecretReference(plaintext) || !SecretEncryptionEnabled() {
+		return plaintext, nil
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	ciphertext, err := sealAESGCM(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	wrappedKey, err := sealAESGCM(keyring.keys[keyring.activeKeyID], dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedPrefix + keyring.activeKeyID + ":" +
+		base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptSecret reverses EncryptSecret. Plaintext values (written before encryption was
+// enabled) are returned unchanged so they can be re-encrypted later.
+func DecryptSecret(value string) (string, error) {
+	if !IsEncryptedSecret(value) {
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, encryptedPrefix), ":")
+	if len(parts) != 3 {
+		return "", errors.New("malformed encrypted secret")
+	}
+	masterKey, ok := keyring.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("encryption key '%s' is not configured", parts[0])
+	}
+
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errors.New("malformed encrypted secret")
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.New("malformed encrypted secret")
+	}
+
+	dataKey, err := openAESGCM(masterKey, wrappedKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	plaintext, err := openAESGCM(dataKey, ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// sealAESGCM encrypts with a random nonce prepended to the ciphertext
+func sealAESGCM(key []byte, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func openAESGCM(key []byte, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// TransformSecretFields applies fn to every string secret in a config map, including nested maps
+func TransformSecretFields(config map[string]interface{}, fn func(string) (string, error)) (map[string]interface{}, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	result := make(map[string]interface{}, len(config))
+	for key, value := range config {
+		switch v := value.(type) {
+		case string:
+			if IsSecretField(key) {
+				transformed, err := fn(v)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", key, err)
+				}
+				result[key] = transformed
+				continue
+			}
+			result[key] = v
+		case map[string]interface{}:
+			nested, err := TransformSecretFields(v, fn)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = nested
+		default:
+			result[key] = v
+		}
+	}
+	return result, nil
+}
+
+// MaskSecretFields replaces every secret in a config map with MaskedSecret.
+// Secret references are kept, they only say where the secret lives.
+func MaskSecretFields(config map[string]interface{}) map[string]interface{} {
+	masked, _ := TransformSecretFields(config, func(value string) (string, error) {
+		if value == "" || IsSecretReference(value) {
+			return value, nil
+		}
+		return MaskedSecret, nil
+	})
+	return masked
+}
+
+// RestoreMaskedSecrets puts stored secrets back wherever an update echoed MaskedSecret
+func RestoreMaskedSecrets(incoming map[string]interface{}, existing map[string]interface{}) map[string]interface{} {
+	if incoming == nil {
+		return nil
+	}
+
+	result := make(map[string]interface{}, len(incoming))
+	for key, value := range incoming {
+		switch v := value.(type) {
+		case string:
+			if v == MaskedSecret && IsSecretField(key) {
+				if stored, ok := existing[key]; ok {
+					result[key] = stored
+					continue
+				}
+			}
+			result[key] = v
+		case map[string]interface{}:
+			nested, _ := existing[key].(map[string]interface{})
+			result[key] = RestoreMaskedSecrets(v, nested)
+		default:
+			result[key] = v
+		}
+	}
+	return result
+}
+
+// dsnPasswordPattern matches the password in user:password@host style DSNs
+var dsnPasswordPattern = regexp.MustCompile(`^([^:@/]+):([^@]*)@`)
+
+// MaskConnectionURL hides the password in a connection URL or DSN, keeping host and database visible
+func MaskConnectionURL(connectionURL string) string {
+	if connectionURL == "" || IsSecretReference(connectionURL) {
+		return connectionURL
+	}
+
+	if parsed, err := url.Parse(connectionURL); err == nil && parsed.Scheme != "" && parsed.User != nil {
+		if _, hasPassword := parsed.User.Password(); hasPassword {
+			masked := parsed.Scheme + "://" + parsed.User.Username() + ":" + MaskedSecret + "@" + parsed.Host + parsed.EscapedPath()
+			if parsed.RawQuery != "" {
+				masked += "?" + maskQuerySecrets(parsed.Query())
+			}
+			return masked
+		}
+		if parsed.RawQuery != "" {
+			return strings.SplitN(connectionURL, "?", 2)[0] + "?" + maskQuerySecrets(parsed.Query())
+		}
+		return connectionURL
+	}
+
+	return dsnPasswordPattern.ReplaceAllString(connectionURL, "${1}:"+MaskedSecret+"@")
+}
+
+func maskQuerySecrets(query url.Values) string {
+	for key := range query {
+		if IsSecretField(key) {
+			query.Set(key, MaskedSecret)
+		}
+	}
+	return query.Encode()
+}
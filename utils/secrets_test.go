@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testSecretKey returns a 32-byte base64 key filled with b
+func testSecretKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), 32)))
+}
+
+// useSecretKeys loads keys for the duration of the test and restores the previous keyring after
+func useSecretKeys(t *testing.T, keys string, activeKeyID string) {
+	t.Helper()
+
+	previous := keyring
+	t.Cleanup(func() { keyring = previous })
+	if err := InitSecretKeys(keys, activeKeyID); err != nil {
+		t.Fatalf("InitSecretKeys: %v", err)
+	}
+}
+
+func TestInitSecretKeysRejectsBadConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     string
+		activeID string
+		wantErr  string
+	}{
+		{name: "entry without id", keys: testSecretKey('a'), activeID: "k1", wantErr: "expected id:base64key"},
+		{name: "key not base64", keys: "k1:not base64!", activeID: "k1", wantErr: "not valid base64"},
+		{name: "short key", keys: "k1:" + base64.StdEncoding.EncodeToString([]byte("short")), activeID: "k1", wantErr: "must be 32 bytes"},
+		{name: "no active key", keys: "k1:" + testSecretKey('a'), wantErr: "ENCRYPTION_ACTIVE_KEY_ID is required"},
+		{name: "unknown active key", keys: "k1:" + testSecretKey('a'), activeID: "k2", wantErr: "'k2' is not configured"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := keyring
+			t.Cleanup(func() { keyring = previous })
+
+			err := InitSecretKeys(tt.keys, tt.activeID)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("InitSecretKeys error = %v, want it to mention %q", err, tt.wantErr)
+			}
+			if keyring != previous {
+				t.Error("a rejected key config replaced the loaded keyring")
+			}
+		})
+	}
+}
+
+func TestEncryptSecretRoundTrip(t *testing.T) {
+	useSecretKeys(t, "k1:"+testSecretKey('a'), "k1")
+
+	encrypted, err := EncryptSecret("s3cret")
+	if err != nil {
+		t.Fatalf("EncryptSecret: %v", err)
+	}
+	if !IsEncryptedSecret(encrypted) || SecretKeyID(encrypted) != "k1" || strings.Contains(encrypted, "s3cret") {
+		t.Fatalf("encrypted = %q, want an enc:v1:k1 envelope without the plaintext", encrypted)
+	}
+
+	again, err := EncryptSecret("s3cret")
+	if err != nil {
+		t.Fatalf("EncryptSecret: %v", err)
+	}
+	if again == encrypted {
+		t.Error("two encryptions of the same value are identical, want a fresh data key and nonce each time")
+	}
+
+	decrypted, err := DecryptSecret(encrypted)
+	if err != nil {
+		t.Fatalf("DecryptSecret: %v", err)
+	}
+	if decrypted != "s3cret" {
+		t.Errorf("decrypted = %q, want %q", decrypted, "s3cret")
+	}
+
+	// Values that are already encrypted, references and empty strings pass through untouched
+	for _, value := range []string{encrypted, "env://DB_PASSWORD", ""} {
+		if got, err := EncryptSecret(value); err != nil || got != value {
+			t.Errorf("EncryptSecret(%q) = %q, %v; want it unchanged", value, got, err)
+		}
+	}
+}
+
+func TestDecryptSecretUnderRotatedKey(t *testing.T) {
+	useSecretKeys(t, "k1:"+testSecretKey('a'), "k1")
+	encrypted, err := EncryptSecret("s3cret")
+	if err != nil {
+		t.Fatalf("EncryptSecret: %v", err)
+	}
+
+	// k2 becomes active; k1 stays loaded for values written before the rotation
+	useSecretKeys(t, "k1:"+testSecretKey('a')+",k2:"+testSecretKey('b'), "k2")
+	if decrypted, err := DecryptSecret(encrypted); err != nil || decrypted != "s3cret" {
+		t.Fatalf("DecryptSecret under rotated key = %q, %v; want %q", decrypted, err, "s3cret")
+	}
+	rewritten, err := EncryptSecret("s3cret")
+	if err != nil {
+		t.Fatalf("EncryptSecret: %v", err)
+	}
+	if SecretKeyID(rewritten) != "k2" {
+		t.Errorf("new value written with key %q, want k2", SecretKeyID(rewritten))
+	}
+
+	// Once k1 is dropped its values can no longer be read
+	useSecretKeys(t, "k2:"+testSecretKey('b'), "k2")
+	if _, err := DecryptSecret(encrypted); err == nil || !strings.Contains(err.Error(), "'k1' is not configured") {
+		t.Errorf("DecryptSecret without k1 error = %v, want the missing key named", err)
+	}
+}
+
+func TestSecretsPassThroughWhenEncryptionDisabled(t *testing.T) {
+	useSecretKeys(t, "", "")
+
+	if SecretEncryptionEnabled() {
+		t.Fatal("encryption enabled with no keys configured")
+	}
+	encrypted, err := EncryptSecret("s3cret")
+	if err != nil || encrypted != "s3cret" {
+		t.Errorf("EncryptSecret = %q, %v; want the plaintext stored as is", encrypted, err)
+	}
+	decrypted, err := DecryptSecret("s3cret")
+	if err != nil || decrypted != "s3cret" {
+		t.Errorf("DecryptSecret = %q, %v; want the plaintext returned as is", decrypted, err)
+	}
+}
+
+func TestDecryptSecretRejectsTamperedValues(t *testing.T) {
+	useSecretKeys(t, "k1:"+testSecretKey('a'), "k1")
+	encrypted, err := EncryptSecret("s3cret")
+	if err != nil {
+		t.Fatalf("EncryptSecret: %v", err)
+	}
+	parts := strings.Split(strings.TrimPrefix(encrypted, encryptedPrefix), ":")
+
+	flip := func(encoded string) string {
+		raw, _ := base64.RawStdEncoding.DecodeString(encoded)
+		raw[len(raw)-1] ^= 0x01
+		return base64.RawStdEncoding.EncodeToString(raw)
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{name: "ciphertext", value: encryptedPrefix + parts[0] + ":" + parts[1] + ":" + flip(parts[2]), wantErr: "failed to decrypt secret"},
+		{name: "wrapped data key", value: encryptedPrefix + parts[0] + ":" + flip(parts[1]) + ":" + parts[2], wantErr: "failed to unwrap data key"},
+		{name: "missing part", value: encryptedPrefix + parts[0] + ":" + parts[2], wantErr: "malformed encrypted secret"},
+		{name: "not base64", value: encryptedPrefix + parts[0] + ":" + parts[1] + ":!!", wantErr: "malformed encrypted secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecryptSecret(tt.value)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("DecryptSecret error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRestoreMaskedSecrets(t *testing.T) {
+	existing := map[string]interface{}{
+		"host":     "smtp.example.com",
+		"password": "stored",
+		"auth":     map[string]interface{}{"token": "stored-token"},
+	}
+	incoming := map[string]interface{}{
+		"host":     "smtp2.example.com",
+		"password": MaskedSecret,
+		"api_key":  MaskedSecret,
+		"subject":  MaskedSecret,
+		"auth":     map[string]interface{}{"token": MaskedSecret},
+	}
+
+	want := map[string]interface{}{
+		"host":     "smtp2.example.com",
+		"password": "stored",
+		// Nothing stored to restore, and subject is not a secret field
+		"api_key": MaskedSecret,
+		"subject": MaskedSecret,
+		"auth":    map[string]interface{}{"token": "stored-token"},
+	}
+	if got := RestoreMaskedSecrets(incoming, existing); !reflect.DeepEqual(got, want) {
+		t.Errorf("RestoreMaskedSecrets = %#v, want %#v", got, want)
+	}
+
+	// A new value replaces the stored one
+	got := RestoreMaskedSecrets(map[string]interface{}{"password": "rotated"}, existing)
+	if got["password"] != "rotated" {
+		t.Errorf("password = %v, want the new value", got["password"])
+	}
+}
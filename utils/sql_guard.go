@@ -0,0 +1,368 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SQLGuardViolation is one reason a query was rejected, with its 1-based position
+type SQLGuardViolation struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Offset  int    `json:"offset"`
+	Token   string `json:"token"`
+	Message string `json:"message"`
+}
+
+// SQLGuardError lists every violation found in a report query
+type SQLGuardError struct {
+	Violations []SQLGuardViolation `json:"violations"`
+}
+
+func (e *SQLGuardError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = fmt.Sprintf("line %d, column %d: %s", v.Line, v.Column, v.Message)
+	}
+	return "report query is not a single read-only statement: " + strings.Join(messages, "; ")
+}
+
+// writeKeywords change data, schema or permissions from inside an otherwise read-only
+// statement: data-modifying CTEs, SELECT ... INTO, FOR UPDATE locks. Statement-level
+// commands (CALL, SET, VACUUM, ...) are already rejected by the SELECT/WITH check.
+var writeKeywords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true, "UPSERT": true,
+	"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true,
+	"GRANT": true, "REVOKE": true, "EXEC": true, "EXECUTE": true,
+	// SELECT ... INTO creates tables (PostgreSQL, SQL Server) or writes files (MySQL OUTFILE/DUMPFILE)
+	"INTO": true,
+}
+
+// dangerousFunctions read files, reach other servers, stall or kill sessions, or change settings
+var dangerousFunctions = map[string]map[string]bool{
+	"mysql": {
+		"SLEEP": true, "BENCHMARK": true, "LOAD_FILE": true, "GET_LOCK": true, "RELEASE_LOCK": true,
+	},
+	"postgresql": {
+		"PG_SLEEP": true, "PG_SLEEP_FOR": true, "PG_SLEEP_UNTIL": true, "PG_READ_FILE": true,
+		"PG_READ_BINARY_FILE": true, "PG_LS_DIR": true, "PG_STAT_FILE": true, "PG_TERMINATE_BACKEND": true,
+		"PG_CANCEL_BACKEND": true, "PG_RELOAD_CONF": true, "SET_CONFIG": true, "LO_IMPORT": true,
+		"LO_EXPORT": true, "PG_ADVISORY_LOCK": true, "PG_ADVISORY_XACT_LOCK": true,
+		"PG_TRY_ADVISORY_LOCK": true, "NEXTVAL": true, "SETVAL": true,
+		// These run SQL passed to them as a string, out of the guard's sight
+		"QUERY_TO_XML": true, "QUERY_TO_XMLSCHEMA": true, "QUERY_TO_XML_AND_XMLSCHEMA": true,
+		"CURSOR_TO_XML": true, "DBLINK": true, "DBLINK_EXEC": true, "DBLINK_OPEN": true,
+		"DBLINK_SEND_QUERY": true, "DBLINK_CONNECT": true, "DBLINK_CONNECT_U": true,
+	},
+	"sqlserver": {
+		"OPENROWSET": true, "OPENQUERY": true, "OPENDATASOURCE": true, "OPENXML": true,
+		"XP_CMDSHELL": true, "XP_DIRTREE": true, "XP_FILEEXIST": true, "XP_REGREAD": true,
+		"SP_EXECUTESQL": true, "SP_EXECUTE": true, "SP_CONFIGURE": true, "SP_OACREATE": true,
+	},
+}
+
+type sqlToken struct {
+	kind   sqlTokenKind
+	text   string
+	offset int
+	line   int
+	column int
+}
+
+type sqlTokenKind int
+
+const (
+	sqlWord sqlTokenKind = iota
+	sqlQuoted
+	sqlString
+	sqlNumber
+	sqlPunct
+	sqlPlaceholder
+)
+
+// AnalyzeReadOnlyQuery checks that query is exactly one SELECT (or WITH ... SELECT)
+// statement with no write keywords or side-effecting functions for dbType.
+// Keywords and function names in allowed (upper case) are let through.
+func AnalyzeReadOnlyQuery(query string, dbType string, allowed map[string]bool) error {
+	tokens, violation := tokenizeSQL(query, dbType)
+	if violation != nil {
+		return &SQLGuardError{Violations: []SQLGuardViolation{*violation}}
+	}
+
+	var violations []SQLGuardViolation
+	reported := map[int]bool{}
+	reject := func(token sqlToken, message string) {
+		if reported[token.offset] {
+			return
+		}
+		reported[token.offset] = true
+		violations = append(violations, SQLGuardViolation{
+			Line: token.line, Column: token.column, Offset: token.offset, Token: token.text, Message: message,
+		})
+	}
+
+	// Statement separators: one trailing semicolon is fine, anything after it is a second statement
+	statementStart := true
+	seenStatement := false
+	for i, token := range tokens {
+		if token.kind == sqlPunct && token.text == ";" {
+			statementStart = true
+			continue
+		}
+		if statementStart {
+			if seenStatement {
+				reject(token, "multiple statements are not allowed")
+				break
+			}
+			seenStatement = true
+			statementStart = false
+
+			first := firstKeyword(tokens[i:])
+			if first.kind != sqlWord || (strings.ToUpper(first.text) != "SELECT" && strings.ToUpper(first.text) != "WITH") {
+				if !allowed[strings.ToUpper(first.text)] {
+					reject(first, fmt.Sprintf("only SELECT statements are allowed, found %s", strings.ToUpper(first.text)))
+				}
+			}
+		}
+	}
+	if !seenStatement {
+		return &SQLGuardError{Violations: []SQLGuardViolation{{Line: 1, Column: 1, Message: "query is empty"}}}
+	}
+
+	functions := dangerousFunctions[dbType]
+	for i, token := range tokens {
+		if token.kind != sqlWord {
+			continue
+		}
+		// t.delete or schema.update are column and table names, not keywords
+		if i > 0 && tokens[i-1].kind == sqlPunct && tokens[i-1].text == "." {
+			continue
+		}
+		word := strings.ToUpper(token.text)
+		if allowed[word] {
+			continue
+		}
+		nextIsParen := i+1 < len(tokens) && tokens[i+1].kind == sqlPunct && tokens[i+1].text == "("
+
+		switch {
+		case functions[word] && nextIsParen:
+			reject(token, fmt.Sprintf("function %s is not allowed in report queries", word))
+		case writeKeywords[word]:
+			reject(token, fmt.Sprintf("%s is not allowed in report queries", word))
+		}
+	}
+
+	if len(violations) > 0 {
+		return &SQLGuardError{Violations: violations}
+	}
+	return nil
+}
+
+// firstKeyword skips opening parentheses so (SELECT ...) UNION (SELECT ...) is accepted
+func firstKeyword(tokens []sqlToken) sqlToken {
+	for _, token := range tokens {
+		if token.kind == sqlPunct && token.text == "(" {
+			continue
+		}
+		return token
+	}
+	return tokens[0]
+}
+
+// tokenizeSQL splits query into tokens, dropping whitespace and comments. String
+// literals and quoted identifiers become single tokens so their content is never
+// mistaken for keywords; {{template}} placeholders are kept whole. MySQL executable
+// comments (/*! ... */, /*M! ... */) are run by the server, so their body is tokenized
+// as code; the escapes each dialect honours inside strings are followed the same way.
+func tokenizeSQL(query string, dbType string) ([]sqlToken, *SQLGuardViolation) {
+	var tokens []sqlToken
+	runes := []rune(query)
+	line, column, offset := 1, 1, 0
+	i := 0
+
+	advance := func(n int) {
+		for k := 0; k < n && i < len(runes); k++ {
+			if runes[i] == '\n' {
+				line++
+				column = 1
+			} else {
+				column++
+			}
+			offset += utf8.RuneLen(runes[i])
+			i++
+		}
+	}
+	peek := func(offset int) rune {
+		if i+offset < len(runes) {
+			return runes[i+offset]
+		}
+		return 0
+	}
+	unterminated := func(startLine, startColumn, startOffset int, what string) *SQLGuardViolation {
+		return &SQLGuardViolation{Line: startLine, Column: startColumn, Offset: startOffset, Message: "unterminated " + what}
+	}
+	// readUntil consumes through the closing delimiter, honouring doubled delimiters as escapes
+	readUntil := func(closing string, doubledEscape bool, backslashEscape bool) bool {
+		closeRunes := []rune(closing)
+		for i < len(runes) {
+			if backslashEscape && runes[i] == '\\' {
+				advance(2)
+				continue
+			}
+			if strings.HasPrefix(string(runes[i:]), closing) {
+				if doubledEscape && strings.HasPrefix(string(runes[i+len(closeRunes):]), closing) {
+					advance(2 * len(closeRunes))
+					continue
+				}
+				advance(len(closeRunes))
+				return true
+			}
+			advance(1)
+		}
+		return false
+	}
+
+	// Position of the open MySQL executable comment, if any
+	executableComment := false
+	var commentLine, commentColumn, commentOffset int
+
+	for i < len(runes) {
+		r := runes[i]
+		startLine, startColumn, startOffset, start := line, column, offset, i
+
+		emit := func(kind sqlTokenKind) {
+			tokens = append(tokens, sqlToken{kind: kind, text: string(runes[start:i]), offset: startOffset, line: startLine, column: startColumn})
+		}
+
+		switch {
+		case unicode.IsSpace(r):
+			advance(1)
+
+		case r == '-' && peek(1) == '-', r == '#' && dbType == "mysql":
+			for i < len(runes) && runes[i] != '\n' {
+				advance(1)
+			}
+
+		case executableComment && r == '*' && peek(1) == '/':
+			advance(2)
+			executableComment = false
+
+		case r == '/' && peek(1) == '*' && dbType == "mysql" && (peek(2) == '!' || (peek(2) == 'M' && peek(3) == '!')):
+			if executableComment {
+				return nil, &SQLGuardViolation{Line: startLine, Column: startColumn, Offset: startOffset, Message: "nested executable comment"}
+			}
+			advance(2)
+			if runes[i] == 'M' {
+				advance(1)
+			}
+			advance(1)
+			// Optional minimum server version, e.g. /*!50000
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				advance(1)
+			}
+			executableComment = true
+			commentLine, commentColumn, commentOffset = startLine, startColumn, startOffset
+
+		case r == '/' && peek(1) == '*':
+			advance(2)
+			if !readUntil("*/", false, false) {
+				return nil, unterminated(startLine, startColumn, startOffset, "comment")
+			}
+
+		case r == '{' && peek(1) == '{':
+			advance(2)
+			if !readUntil("}}", false, false) {
+				return nil, unterminated(startLine, startColumn, startOffset, "template placeholder")
+			}
+			emit(sqlPlaceholder)
+
+		case r == '\'':
+			advance(1)
+			if !readUntil("'", true, dbType == "mysql") {
+				return nil, unterminated(startLine, startColumn, startOffset, "string literal")
+			}
+			emit(sqlString)
+
+		case r == '"' && dbType == "mysql":
+			// Without ANSI_QUOTES, MySQL reads "..." as a string with backslash escapes
+			advance(1)
+			if !readUntil(`"`, true, true) {
+				return nil, unterminated(startLine, startColumn, startOffset, "string literal")
+			}
+			emit(sqlString)
+
+		case r == '"':
+			advance(1)
+			if !readUntil(`"`, true, false) {
+				return nil, unterminated(startLine, startColumn, startOffset, "quoted identifier")
+			}
+			emit(sqlQuoted)
+
+		case (r == 'E' || r == 'e') && peek(1) == '\'' && dbType == "postgresql":
+			// E'...' escape strings honour backslash escapes
+			advance(2)
+			if !readUntil("'", true, true) {
+				return nil, unterminated(startLine, startColumn, startOffset, "string literal")
+			}
+			emit(sqlString)
+
+		case r == '`' && dbType == "mysql":
+			advance(1)
+			if !readUntil("`", true, false) {
+				return nil, unterminated(startLine, startColumn, startOffset, "quoted identifier")
+			}
+			emit(sqlQuoted)
+
+		case r == '[' && dbType == "sqlserver":
+			advance(1)
+			if !readUntil("]", true, false) {
+				return nil, unterminated(startLine, startColumn, startOffset, "quoted identifier")
+			}
+			emit(sqlQuoted)
+
+		case r == '$' && dbType == "postgresql" && dollarQuoteTag(runes[i:]) != "":
+			tag := dollarQuoteTag(runes[i:])
+			advance(len([]rune(tag)))
+			if !readUntil(tag, false, false) {
+				return nil, unterminated(startLine, startColumn, startOffset, "dollar-quoted string")
+			}
+			emit(sqlString)
+
+		case unicode.IsLetter(r) || r == '_' || r == '@':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '$' || runes[i] == '@' || runes[i] == '#') {
+				advance(1)
+			}
+			emit(sqlWord)
+
+		case unicode.IsDigit(r):
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || unicode.IsLetter(runes[i])) {
+				advance(1)
+			}
+			emit(sqlNumber)
+
+		default:
+			advance(1)
+			emit(sqlPunct)
+		}
+	}
+
+	if executableComment {
+		return nil, unterminated(commentLine, commentColumn, commentOffset, "comment")
+	}
+	return tokens, nil
+}
+
+// dollarQuoteTag returns the $tag$ opening a PostgreSQL dollar-quoted string, or ""
+func dollarQuoteTag(runes []rune) string {
+	for j := 1; j < len(runes); j++ {
+		if runes[j] == '$' {
+			return string(runes[:j+1])
+		}
+		if !(unicode.IsLetter(runes[j]) || runes[j] == '_' || (j > 1 && unicode.IsDigit(runes[j]))) {
+			return ""
+		}
+	}
+	return ""
+}
@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeReadOnlyQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		dbType  string
+		query   string
+		allowed map[string]bool
+		// wantErr is empty for accepted queries, otherwise part of a violation message
+		wantErr string
+	}{
+		{
+			name:   "plain select",
+			dbType: "mysql",
+			query:  "SELECT id, name FROM customers WHERE created_at >= {{start_date}};",
+		},
+		{
+			name:   "keywords inside strings and quoted identifiers",
+			dbType: "postgresql",
+			query:  `SELECT 'DROP TABLE t; SELECT pg_sleep(1)' AS note, "delete" FROM t`,
+		},
+		{
+			name:   "qualified column named like a keyword",
+			dbType: "mysql",
+			query:  "SELECT t.update, t.delete FROM audit t",
+		},
+		{
+			name:   "mysql optimizer hint is a plain comment",
+			dbType: "mysql",
+			query:  "SELECT /*+ MAX_EXECUTION_TIME(1000) */ id FROM t",
+		},
+		{
+			name:   "mysql escaped quote inside a double-quoted string",
+			dbType: "mysql",
+			query:  `SELECT "say \"hi\"" AS greeting FROM t`,
+		},
+		{
+			name:   "postgresql escape string",
+			dbType: "postgresql",
+			query:  `SELECT E'tab\tand \'quote\'' AS note FROM t`,
+		},
+		{
+			name:    "allowlisted function",
+			dbType:  "mysql",
+			query:   "SELECT SLEEP(0) FROM t",
+			allowed: map[string]bool{"SLEEP": true},
+		},
+
+		// Bypasses of the tokenizer
+		{
+			name:    "mysql executable comment is code",
+			dbType:  "mysql",
+			query:   "SELECT 1 /*!50000 INTO OUTFILE '/tmp/x' */",
+			wantErr: "INTO is not allowed",
+		},
+		{
+			name:    "mariadb executable comment is code",
+			dbType:  "mysql",
+			query:   "SELECT 1 /*M!100100 , SLEEP(10) */",
+			wantErr: "function SLEEP is not allowed",
+		},
+		{
+			name:    "mysql executable comment without a version",
+			dbType:  "mysql",
+			query:   "SELECT 1 /*! ; DROP TABLE t */",
+			wantErr: "multiple statements",
+		},
+		{
+			name:    "unterminated mysql executable comment",
+			dbType:  "mysql",
+			query:   "SELECT 1 /*!50000 , SLEEP(10)",
+			wantErr: "unterminated comment",
+		},
+		{
+			name:    "backslash-escaped quote in a mysql double-quoted string",
+			dbType:  "mysql",
+			query:   `SELECT "\"", SLEEP(10) -- "`,
+			wantErr: "function SLEEP is not allowed",
+		},
+		{
+			name:    "backslash-escaped quote in a mysql single-quoted string",
+			dbType:  "mysql",
+			query:   `SELECT '\'', SLEEP(10) -- '`,
+			wantErr: "function SLEEP is not allowed",
+		},
+		{
+			name:    "backslash-escaped quote in a postgresql escape string",
+			dbType:  "postgresql",
+			query:   `SELECT E'\'', pg_sleep(10) --'`,
+			wantErr: "function PG_SLEEP is not allowed",
+		},
+		{
+			name:    "lower-case postgresql escape string",
+			dbType:  "postgresql",
+			query:   `SELECT e'\'', pg_sleep(10) --'`,
+			wantErr: "function PG_SLEEP is not allowed",
+		},
+
+		// Functions that run SQL handed to them as a string
+		{
+			name:    "query_to_xml",
+			dbType:  "postgresql",
+			query:   "SELECT query_to_xml('DROP TABLE t', true, false, '')",
+			wantErr: "function QUERY_TO_XML is not allowed",
+		},
+		{
+			name:    "cursor_to_xml",
+			dbType:  "postgresql",
+			query:   "SELECT cursor_to_xml('c', 10, true, false, '')",
+			wantErr: "function CURSOR_TO_XML is not allowed",
+		},
+		{
+			name:    "dblink",
+			dbType:  "postgresql",
+			query:   "SELECT * FROM dblink('dbname=prod', 'DELETE FROM t RETURNING 1') AS r(x int)",
+			wantErr: "function DBLINK is not allowed",
+		},
+		{
+			name:    "dblink_send_query",
+			dbType:  "postgresql",
+			query:   "SELECT dblink_send_query('conn', 'DROP TABLE t')",
+			wantErr: "function DBLINK_SEND_QUERY is not allowed",
+		},
+		{
+			name:    "openquery",
+			dbType:  "sqlserver",
+			query:   "SELECT * FROM OPENQUERY(linked, 'DELETE FROM t')",
+			wantErr: "function OPENQUERY is not allowed",
+		},
+
+		// Statement-level checks
+		{
+			name:    "second statement",
+			dbType:  "postgresql",
+			query:   "SELECT 1; DELETE FROM t",
+			wantErr: "multiple statements",
+		},
+		{
+			name:    "not a select",
+			dbType:  "mysql",
+			query:   "UPDATE t SET x = 1",
+			wantErr: "only SELECT statements",
+		},
+		{
+			name:    "data-modifying cte",
+			dbType:  "postgresql",
+			query:   "WITH gone AS (DELETE FROM t RETURNING *) SELECT * FROM gone",
+			wantErr: "DELETE is not allowed",
+		},
+		{
+			name:    "empty query",
+			dbType:  "mysql",
+			query:   "-- nothing here",
+			wantErr: "query is empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := AnalyzeReadOnlyQuery(tt.query, tt.dbType, tt.allowed)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("AnalyzeReadOnlyQuery rejected a read-only query: %v", err)
+				}
+				return
+			}
+
+			var guardErr *SQLGuardError
+			if !errors.As(err, &guardErr) {
+				t.Fatalf("AnalyzeReadOnlyQuery = %v, want a *SQLGuardError mentioning %q", err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
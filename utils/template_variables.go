@@ -0,0 +1,310 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// VariableContext is the point in time template variables are evaluated for
+type VariableContext struct {
+	// ExecutionTime is the run's scheduled time, in the schedule's timezone
+	ExecutionTime time.Time
+	// WindowStart is the start of the period the run covers
+	WindowStart time.Time
+	// CalculationMethod records how WindowStart was derived
+	CalculationMethod string
+}
+
+// TemplateVariable is a built-in {{variable}} available to every report query
+type TemplateVariable struct {
+	Name        string                                `json:"name"`
+	Type        QueryParamType                        `json:"type"`
+	Description string                                `json:"description"`
+	Resolve     func(ctx VariableContext) interface{} `json:"-"`
+}
+
+// TemplateOffsetUnits describes the units accepted in offset expressions like {{today-3d}}
+var TemplateOffsetUnits = map[string]string{
+	"h":  "hours",
+	"d":  "calendar days",
+	"b":  "business days (Monday to Friday)",
+	"w":  "weeks",
+	"mo": "months (clamped to the last day of a shorter month)",
+	"q":  "quarters (clamped like months)",
+	"y":  "years (clamped like months)",
+}
+
+var templateVariableRegistry = map[string]TemplateVariable{}
+
+// RegisterTemplateVariable adds or replaces a built-in template variable
+func RegisterTemplateVariable(variable TemplateVariable) {
+	if variable.Name == "" || variable.Resolve == nil {
+		panic("template variable requires a name and a resolver")
+	}
+	templateVariableRegistry[variable.Name] = variable
+}
+
+// ListTemplateVariables returns the registered variables ordered by name
+func ListTemplateVariables() []TemplateVariable {
+	variables := make([]TemplateVariable, 0, len(templateVariableRegistry))
+	for _, variable := range templateVariableRegistry {
+		variables = append(variables, variable)
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].Name < variables[j].Name
+	})
+	return variables
+}
+
+// IsTemplateVariable reports whether name is a registered built-in variable
+func IsTemplateVariable(name string) bool {
+	_, exists := templateVariableRegistry[name]
+	return exists
+}
+
+// TemplateVariableTypes returns the declared type of every registered variable
+func TemplateVariableTypes() map[string]QueryParamType {
+	types := make(map[string]QueryParamType, len(templateVariableRegistry))
+	for name, variable := range templateVariableRegistry {
+		types[name] = variable.Type
+	}
+	return types
+}
+
+// ResolveTemplateVariables evaluates every registered variable for ctx.
+// Date and datetime variables resolve to time.Time so offsets and formats can apply.
+func ResolveTemplateVariables(ctx VariableContext) map[string]interface{} {
+	values := make(map[string]interface{}, len(templateVariableRegistry))
+	for name, variable := range templateVariableRegistry {
+		values[name] = variable.Resolve(ctx)
+	}
+	return values
+}
+
+// ApplyTemplateOffset moves t by amount units (see TemplateOffsetUnits)
+func ApplyTemplateOffset(t time.Time, amount int, unit string) (time.Time, error) {
+	switch unit {
+	case "h":
+		return t.Add(time.Duration(amount) * time.Hour), nil
+	case "d":
+		return t.AddDate(0, 0, amount), nil
+	case "b":
+		return addBusinessDays(t, amount), nil
+	case "w":
+		return t.AddDate(0, 0, 7*amount), nil
+	case "mo":
+		return addMonths(t, amount), nil
+	case "q":
+		return addMonths(t, 3*amount), nil
+	case "y":
+		return addMonths(t, 12*amount), nil
+	default:
+		return time.Time{}, fmt.Errorf("unknown offset unit '%s'", unit)
+	}
+}
+
+// addMonths moves t by n calendar months, keeping the day of month where it exists and using
+// the month's last day otherwise (Jan 31 + 1mo is Feb 28, where AddDate would give Mar 3)
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	day := t.Day()
+	if lastDay := first.AddDate(0, 1, -1).Day(); day > lastDay {
+		day = lastDay
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// addBusinessDays steps n weekdays forward (or back for negative n), skipping weekends
+func addBusinessDays(t time.Time, n int) time.Time {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		t = t.AddDate(0, 0, step)
+		if t.Weekday() != time.Saturday && t.Weekday() != time.Sunday {
+			n--
+		}
+	}
+	return t
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek returns Monday 00:00 of t's ISO week
+func startOfWeek(t time.Time) time.Time {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return startOfDay(t).AddDate(0, 0, -daysSinceMonday)
+}
+
+func startOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+func startOfQuarter(t time.Time) time.Time {
+	month := time.Month((int(t.Month())-1)/3*3 + 1)
+	return time.Date(t.Year(), month, 1, 0, 0, 0, 0, t.Location())
+}
+
+func startOfYear(t time.Time) time.Time {
+	return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, t.Location())
+}
+
+// registerPeriodVariables registers <current|previous>_<period>_<start|end> for one period.
+// End values are the last second of the period, so they suit BETWEEN.
+func registerPeriodVariables(period string, start func(time.Time) time.Time, next func(time.Time) time.Time) {
+	RegisterTemplateVariable(TemplateVariable{
+		Name:        "current_" + period + "_start",
+		Type:        QueryParamDatetime,
+		Description: fmt.Sprintf("Start of the %s containing the execution time", period),
+		Resolve: func(ctx VariableContext) interface{} {
+			return start(ctx.ExecutionTime)
+		},
+	})
+	RegisterTemplateVariable(TemplateVariable{
+		Name:        "current_" + period + "_end",
+		Type:        QueryParamDatetime,
+		Description: fmt.Sprintf("Last second of the %s containing the execution time", period),
+		Resolve: func(ctx VariableContext) interface{} {
+			return next(start(ctx.ExecutionTime)).Add(-time.Second)
+		},
+	})
+	RegisterTemplateVariable(TemplateVariable{
+		Name:        "previous_" + period + "_start",
+		Type:        QueryParamDatetime,
+		Description: fmt.Sprintf("Start of the %s before the execution time's", period),
+		Resolve: func(ctx VariableContext) interface{} {
+			return start(start(ctx.ExecutionTime).Add(-time.Second))
+		},
+	})
+	RegisterTemplateVariable(TemplateVariable{
+		Name:        "previous_" + period + "_end",
+		Type:        QueryParamDatetime,
+		Description: fmt.Sprintf("Last second of the %s before the execution time's", period),
+		Resolve: func(ctx VariableContext) interface{} {
+			return start(ctx.ExecutionTime).Add(-time.Second)
+		},
+	})
+}
+
+func init() {
+	// Run window (the original CalculateTimeRange keys)
+	RegisterTemplateVariable(TemplateVariable{
+		Name: "start_datetime", Type: QueryParamDatetime,
+		Description: "Start of the window covered by the run",
+		Resolve:     func(ctx VariableContext) interface{} { return ctx.WindowStart },
+	})
+	RegisterTemplateVariable(TemplateVariable{
+		Name: "end_datetime", Type: QueryParamDatetime,
+		Description: "End of the window covered by the run (the execution time)",
+		Resolve:     func(ctx VariableContext) interface{} { return ctx.ExecutionTime },
+	})
+	RegisterTemplateVariable(TemplateVariable{
+		Name: "start_date", Type: QueryParamDate,
+		Description: "Date of start_datetime",
+		Resolve:     func(ctx VariableContext) interface{} { return startOfDay(ctx.WindowStart) },
+	})
+	RegisterTemplateVariable(TemplateVariable{
+		Name: "end_date", Type: QueryParamDate,
+		Description: "Date of end_datetime",
+		Resolve:     func(ctx VariableContext) interface{} { return startOfDay(ctx.ExecutionTime) },
+	})
+	RegisterTemplateVariable(TemplateVariable{
+		Name: "interval_hours", Type: QueryParamNumber,
+		Description: "Length of the run window in hours",
+		Resolve: func(ctx VariableContext) interface{} {
+			return math.Round(ctx.ExecutionTime.Sub(ctx.WindowStart).Hours()*100) / 100
+		},
+	})
+	RegisterTemplateVariable(TemplateVariable{
+		Name: "calculation_method", Type: QueryParamString,
+		Description: "How the window start was derived: last_run_at or cron_detection",
+		Resolve:     func(ctx VariableContext) interface{} { return ctx.CalculationMethod },
+	})
+	RegisterTemplateVariable(TemplateVariable{
+		Name: "yesterday", Type: QueryParamDate,
+		Description: "Calendar day before the execution date",
+		Resolve:     func(ctx VariableContext) interface{} { return startOfDay(ctx.ExecutionTime).AddDate(0, 0, -1) },
+	})
+	RegisterTemplateVariable(TemplateVariable{
+		Name: "last_week", Type: QueryParamDate,
+		Description: "Seven calendar days before the execution date",
+		Resolve:     func(ctx VariableContext) interface{} { return startOfDay(ctx.ExecutionTime).AddDate(0, 0, -7) },
+	})
+	RegisterTemplateVariable(TemplateVariable{
+		Name: "execution_time", Type: QueryParamDatetime,
+		Description: "Scheduled time of the run",
+		Resolve:     func(ctx VariableContext) interface{} { return ctx.ExecutionTime },
+	})
+
+	// Calendar anchors
+	RegisterTemplateVariable(TemplateVariable{
+		Name: "today", Type: QueryParamDate,
+		Description: "Execution date",
+		Resolve:     func(ctx VariableContext) interface{} { return startOfDay(ctx.ExecutionTime) },
+	})
+	RegisterTemplateVariable(TemplateVariable{
+		Name: "now", Type: QueryParamDatetime,
+		Description: "Execution time (alias of execution_time)",
+		Resolve:     func(ctx VariableContext) interface{} { return ctx.ExecutionTime },
+	})
+	registerPeriodVariables("day", startOfDay, func(t time.Time) time.Time { return t.AddDate(0, 0, 1) })
+	registerPeriodVariables("week", startOfWeek, func(t time.Time) time.Time { return t.AddDate(0, 0, 7) })
+	registerPeriodVariables("month", startOfMonth, func(t time.Time) time.Time { return t.AddDate(0, 1, 0) })
+	registerPeriodVariables("quarter", startOfQuarter, func(t time.Time) time.Time { return t.AddDate(0, 3, 0) })
+	registerPeriodVariables("year", startOfYear, func(t time.Time) time.Time { return t.AddDate(1, 0, 0) })
+
+	// Business days
+	RegisterTemplateVariable(TemplateVariable{
+		Name: "previous_business_day", Type: QueryParamDate,
+		Description: "Last weekday before the execution date",
+		Resolve:     func(ctx VariableContext) interface{} { return addBusinessDays(startOfDay(ctx.ExecutionTime), -1) },
+	})
+	RegisterTemplateVariable(TemplateVariable{
+		Name: "next_business_day", Type: QueryParamDate,
+		Description: "First weekday after the execution date",
+		Resolve:     func(ctx VariableContext) interface{} { return addBusinessDays(startOfDay(ctx.ExecutionTime), 1) },
+	})
+
+	// ISO weeks
+	RegisterTemplateVariable(TemplateVariable{
+		Name: "iso_week", Type: QueryParamInt,
+		Description: "ISO 8601 week number of the execution date",
+		Resolve: func(ctx VariableContext) interface{} {
+			_, week := ctx.ExecutionTime.ISOWeek()
+			return week
+		},
+	})
+	RegisterTemplateVariable(TemplateVariable{
+		Name: "iso_year", Type: QueryParamInt,
+		Description: "ISO 8601 week-numbering year of the execution date",
+		Resolve: func(ctx VariableContext) interface{} {
+			year, _ := ctx.ExecutionTime.ISOWeek()
+			return year
+		},
+	})
+	RegisterTemplateVariable(TemplateVariable{
+		Name: "previous_iso_week", Type: QueryParamInt,
+		Description: "ISO 8601 week number of the week before the execution date",
+		Resolve: func(ctx VariableContext) interface{} {
+			_, week := ctx.ExecutionTime.AddDate(0, 0, -7).ISOWeek()
+			return week
+		},
+	})
+
+	// Epoch seconds
+	RegisterTemplateVariable(TemplateVariable{
+		Name: "start_epoch", Type: QueryParamInt,
+		Description: "start_datetime as Unix epoch seconds",
+		Resolve:     func(ctx VariableContext) interface{} { return ctx.WindowStart.Unix() },
+	})
+	RegisterTemplateVariable(TemplateVariable{
+		Name: "end_epoch", Type: QueryParamInt,
+		Description: "end_datetime as Unix epoch seconds",
+		Resolve:     func(ctx VariableContext) interface{} { return ctx.ExecutionTime.Unix() },
+	})
+}
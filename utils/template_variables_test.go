@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderTemplateVariables(t *testing.T) {
+	// Saturday 31 January 02:00 in Jakarta is still Friday 30 January in UTC
+	loc := mustLoadLocation(t, "Asia/Jakarta")
+	values := ResolveTemplateVariables(VariableContext{
+		ExecutionTime:     time.Date(2026, 1, 31, 2, 0, 0, 0, loc),
+		WindowStart:       time.Date(2026, 1, 30, 2, 0, 0, 0, loc),
+		CalculationMethod: "last_run_at",
+	})
+	schema := TemplateVariableTypes()
+
+	tests := []struct {
+		expression string
+		want       interface{}
+	}{
+		{expression: "{{today}}", want: "2026-01-31"},
+		{expression: "{{yesterday}}", want: "2026-01-30"},
+		{expression: "{{now}}", want: "2026-01-31 02:00:00"},
+		{expression: "{{start_date}}", want: "2026-01-30"},
+		{expression: "{{interval_hours}}", want: 24.0},
+		{expression: "{{current_week_start}}", want: "2026-01-26 00:00:00"},
+		{expression: "{{current_month_end}}", want: "2026-01-31 23:59:59"},
+		{expression: "{{previous_month_start}}", want: "2025-12-01 00:00:00"},
+		{expression: "{{previous_quarter_end}}", want: "2025-12-31 23:59:59"},
+		{expression: "{{previous_business_day}}", want: "2026-01-30"},
+		{expression: "{{next_business_day}}", want: "2026-02-02"},
+		{expression: "{{iso_week}}", want: int64(5)},
+		{expression: "{{previous_iso_week}}", want: int64(4)},
+
+		{expression: "{{today-1d}}", want: "2026-01-30"},
+		{expression: "{{ today - 1w }}", want: "2026-01-24"},
+		{expression: "{{now-3h}}", want: "2026-01-30 23:00:00"},
+		{expression: "{{today-1b}}", want: "2026-01-30"},
+		{expression: "{{today+1b}}", want: "2026-02-02"},
+		{expression: "{{today+1mo}}", want: "2026-02-28"},
+		{expression: "{{today-2mo}}", want: "2025-11-30"},
+		{expression: "{{today+1q}}", want: "2026-04-30"},
+		{expression: "{{today+13mo}}", want: "2027-02-28"},
+		{expression: "{{today-1y}}", want: "2025-01-31"},
+		{expression: "{{current_month_end+1mo}}", want: "2026-02-28 23:59:59"},
+		{expression: `{{today|format:"20060102"}}`, want: "20260131"},
+		{expression: `{{previous_month_start-1mo|format:"Jan 2006"}}`, want: "Nov 2025"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			rendered, err := RenderQuery("SELECT * FROM sales WHERE d = "+tt.expression, "mysql", values, schema)
+			if err != nil {
+				t.Fatalf("RenderQuery: %v", err)
+			}
+			if want := []interface{}{tt.want}; !reflect.DeepEqual(rendered.Args, want) {
+				t.Errorf("args = %#v, want %#v", rendered.Args, want)
+			}
+		})
+	}
+}
+
+func TestRenderTemplateVariablesRejectsBadModifiers(t *testing.T) {
+	values := ResolveTemplateVariables(VariableContext{
+		ExecutionTime:     time.Date(2026, 1, 31, 2, 0, 0, 0, time.UTC),
+		WindowStart:       time.Date(2026, 1, 30, 2, 0, 0, 0, time.UTC),
+		CalculationMethod: "last_run_at",
+	})
+	schema := TemplateVariableTypes()
+
+	tests := []struct {
+		expression string
+		wantErr    string
+	}{
+		{expression: "{{today|upper}}", wantErr: "invalid template expression"},
+		{expression: "{{today|format:20060102}}", wantErr: "invalid template expression"},
+		{expression: "{{today-3m}}", wantErr: "invalid template expression"},
+		{expression: "{{today-d}}", wantErr: "invalid template expression"},
+		{expression: "{{iso_week-1d}}", wantErr: "is not a date or datetime"},
+		{expression: `{{calculation_method|format:"2006"}}`, wantErr: "is not a date or datetime"},
+		{expression: "{{tomorrow}}", wantErr: "undefined template variable 'tomorrow'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			_, err := RenderQuery("SELECT * FROM sales WHERE d = "+tt.expression, "mysql", values, schema)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("RenderQuery error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
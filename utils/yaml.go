@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"encoding/json"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// JSONToYAML re-encodes a JSON document as block-style YAML, keeping key order
+func JSONToYAML(data []byte) ([]byte, error) {
+	// JSON is valid YAML; decoding it into a node keeps the key order
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	clearNodeStyle(&node)
+	return yaml.Marshal(&node)
+}
+
+// clearNodeStyle drops the flow and quoting styles JSON input carries, so the encoder
+// picks block style and quotes only where YAML needs it
+func clearNodeStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearNodeStyle(child)
+	}
+}
+
+// YAMLToJSON converts a YAML document to JSON so it can be decoded with the json tags
+// shared by every API type
+func YAMLToJSON(data []byte) ([]byte, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}